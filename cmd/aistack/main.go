@@ -1,22 +1,35 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"aistack/internal/agent"
+	"aistack/internal/api"
 	"aistack/internal/config"
 	"aistack/internal/diag"
 	"aistack/internal/fsutil"
 	"aistack/internal/gpu"
 	"aistack/internal/gpulock"
 	"aistack/internal/logging"
+	"aistack/internal/metrics"
 	"aistack/internal/models"
+	"aistack/internal/notify"
+	"aistack/internal/registry"
 	"aistack/internal/services"
+	"aistack/internal/signing"
 	"aistack/internal/suspend"
 )
 
@@ -34,22 +47,54 @@ func main() {
 
 	command := strings.ToLower(os.Args[1])
 	if handler, ok := commandHandlers()[command]; ok {
+		if notifyingCommands[command] {
+			installNotifications()
+		}
+		if reconcilingCommands[command] {
+			reconcileInterruptedUpdates()
+		}
 		handler()
+		closeNotifications()
 		return
 	}
 
 	fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", os.Args[1])
 	printUsage()
-	os.Exit(1)
+	exit(1)
+}
+
+// commandContext returns a context cancelled on SIGINT/SIGTERM so long-running
+// operations can stop (and roll back) cleanly instead of being killed mid-way
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// notifyingCommands log the events notifications subscribe to (service health,
+// updates, suspend, GPU lock); other commands skip loading the notifier
+var notifyingCommands = map[string]bool{
+	"install": true, "profile": true, "start": true, "stop": true, "update": true,
+	"update-all": true, "rollback": true, "repair": true, "backend": true, "remove": true,
+	"uninstall": true, "purge": true, "health": true, "gpu-unlock": true, "models": true,
+	"agent": true, "api": true, "suspend": true,
+}
+
+// reconcilingCommands settle updates a crashed aistack process left pending before
+// they run, so they never act on a half-updated service. Only the daemons and the
+// commands that start or replace containers reconcile; read-only commands such as
+// status only report pending plans.
+var reconcilingCommands = map[string]bool{
+	"install": true, "start": true, "update": true, "update-all": true,
+	"rollback": true, "repair": true, "agent": true, "api": true,
 }
 
 func commandHandlers() map[string]func() {
 	return map[string]func(){
 		"install":    runInstall,
+		"profile":    runProfile,
 		"start":      func() { runServiceCommand("start") },
 		"stop":       func() { runServiceCommand("stop") },
 		"status":     runStatus,
-		"update":     func() { runServiceCommand("update") },
+		"update":     runUpdate,
 		"update-all": runUpdateAll,
 		"logs":       func() { runServiceCommand("logs") },
 		"remove":     runRemove,
@@ -57,11 +102,17 @@ func commandHandlers() map[string]func() {
 		"purge":      runPurge,
 		"backend":    runBackendSwitch,
 		"config":     runConfig,
+		"compose":    runCompose,
 		"gpu-check":  runGPUCheck,
 		"gpu-unlock": runGPUUnlock,
 		"models":     runModels,
 		"health":     runHealth,
 		"repair":     func() { runServiceCommand("repair") },
+		"rollback":   runRollback,
+		"agent":      runAgent,
+		"metrics":    runMetrics,
+		"api":        runAPI,
+		"notify":     runNotify,
 		"diag":       runDiag,
 		"versions":   runVersions,
 		"version":    runVersion,
@@ -78,6 +129,36 @@ func runVersion() {
 
 // runVersions displays version lock status and update policy (Story T-035)
 func runVersions() {
+	if len(os.Args) > 2 && os.Args[2] == "lock" {
+		if err := runVersionsLock(os.Args[3:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 2 && (os.Args[2] == "sign" || os.Args[2] == "keygen") {
+		run := runVersionsSign
+		if os.Args[2] == "keygen" {
+			run = runVersionsKeygen
+		}
+		if err := run(os.Args[3:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "verify" {
+		drift, err := runVersionsVerify(hasFlag(os.Args[3:], "--json"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		if drift {
+			exit(1)
+		}
+		return
+	}
+
 	fmt.Println("=== Version Lock & Update Policy ===")
 	fmt.Println()
 
@@ -93,6 +174,8 @@ func runVersions() {
 		} else {
 			fmt.Println("  ✓ Updates are ALLOWED")
 		}
+		fmt.Println()
+		displayUpdateSchedule(cfg.Updates)
 	}
 	fmt.Println()
 
@@ -115,6 +198,324 @@ func runVersions() {
 	}
 }
 
+// runVersionsLock pins the running stack: every running service gets the repo digest
+// of its container image in versions.lock. With --diff the changes are only shown.
+func runVersionsLock(args []string) error {
+	diffOnly := false
+	output, reason, format := "", "", ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--diff":
+			diffOnly = true
+		case "--output", "--reason", "--format":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a value", args[i])
+			}
+			switch args[i] {
+			case "--output":
+				output = args[i+1]
+			case "--reason":
+				reason = args[i+1]
+			default:
+				format = args[i+1]
+			}
+			i++
+		default:
+			return fmt.Errorf("unknown option %q (usage: aistack versions lock [--diff] [--reason <text>] [--format v1|v2] [--output <file>])", args[i])
+		}
+	}
+
+	manager, err := services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelWarn))
+	if err != nil {
+		return fmt.Errorf("failed to initialize service manager: %w", err)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	proposal, err := manager.ProposeVersionLock(ctx, reason)
+	if err != nil {
+		return err
+	}
+	converting := false
+	switch format {
+	case "":
+	case "v1", "v2":
+		target := services.VersionLockFormatLines
+		if format == "v2" {
+			target = services.VersionLockFormatYAML
+		}
+		converting = target != proposal.Format
+		proposal.Format = target
+	default:
+		return fmt.Errorf("invalid --format %q: expected v1 or v2", format)
+	}
+
+	target := output
+	if target == "" {
+		target = proposal.Path
+	}
+	if target == "" {
+		target = services.DefaultVersionLockPath()
+	}
+
+	current := proposal.Path
+	if current == "" {
+		current = "(none)"
+	}
+	fmt.Printf("Current lock: %s\n", current)
+	skipped := make([]string, 0, len(proposal.Skipped))
+	for name := range proposal.Skipped {
+		skipped = append(skipped, name)
+	}
+	sort.Strings(skipped)
+	for _, name := range skipped {
+		if entry, ok := proposal.Current[name]; ok {
+			fmt.Printf("  %s: kept %s (%s)\n", name, entry, proposal.Skipped[name])
+		} else {
+			fmt.Printf("  %s: not pinned (%s)\n", name, proposal.Skipped[name])
+		}
+	}
+	fmt.Println()
+
+	changes := proposal.Changes()
+	if len(changes) == 0 {
+		fmt.Printf("No changes: versions.lock already pins the running stack (%d entries)\n", len(proposal.Entries))
+		if diffOnly || (output == "" && !converting) {
+			return nil
+		}
+	} else {
+		printLockChanges(changes)
+		if diffOnly {
+			fmt.Printf("\n%d change(s); write them with: aistack versions lock\n", len(changes))
+			return nil
+		}
+	}
+
+	if err := proposal.Write(target, logging.NewLogger(logging.LevelWarn)); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrote %s (%d entries)\n", target, len(proposal.Entries))
+	if converting {
+		fmt.Printf("Converted to format %s; comments of the old file were not carried over\n", format)
+	}
+	if keys, err := signing.LoadTrustedKeys(services.TrustedKeysPath()); err == nil && keys.Len() > 0 && len(changes) > 0 {
+		fmt.Println("Trusted keys are configured: sign the new pins before restarting, with: aistack versions sign --key <private-key>")
+	}
+	if cfg, err := config.Load(); err == nil && cfg.Updates.Mode != "pinned" {
+		fmt.Println("Services start the pinned images from now on; set updates.mode to 'pinned' to also block updates")
+	}
+	return nil
+}
+
+// runVersionsSign signs the digest pins of versions.lock with a local ed25519 key
+func runVersionsSign(args []string) error {
+	keyPath, lockPath := "", ""
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--key" || arg == "--lock":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a file path", arg)
+			}
+			if arg == "--key" {
+				keyPath = args[i+1]
+			} else {
+				lockPath = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option %q (usage: aistack versions sign --key <private-key> [--lock <file>] [<service>...])", arg)
+		default:
+			names = append(names, arg)
+		}
+	}
+	if keyPath == "" {
+		return fmt.Errorf("usage: aistack versions sign --key <private-key> [--lock <file>] [<service>...]")
+	}
+	if lockPath == "" {
+		lockPath = locateVersionsLockFile()
+	}
+	if lockPath == "" {
+		return services.ErrNoVersionLock
+	}
+
+	key, err := signing.LoadPrivateKey(keyPath)
+	if err != nil {
+		return err
+	}
+	signed, err := services.SignVersionLock(lockPath, key, names, logging.NewLogger(logging.LevelWarn))
+	if err != nil {
+		return err
+	}
+	if len(signed) == 0 {
+		fmt.Printf("No digest pins to sign in %s\n", lockPath)
+		return nil
+	}
+	fmt.Printf("Signed %d pin(s) in %s: %s\n", len(signed), lockPath, strings.Join(signed, ", "))
+
+	// Pins signed by a key the host does not trust are refused like unsigned ones
+	keys, err := signing.LoadTrustedKeys(services.TrustedKeysPath())
+	probe := []byte("aistack-key-check")
+	if err != nil {
+		fmt.Printf("Warning: %v; services do not start or update until the trusted keys are fixed\n", err)
+	} else if keys.Len() == 0 {
+		fmt.Printf("Note: no trusted keys in %s, signatures are not enforced yet\n", services.TrustedKeysPath())
+	} else if _, err := keys.Verify(probe, signing.Sign(key, probe)); err != nil {
+		fmt.Printf("Warning: this key is not trusted; add its public key to %s or the pins are refused\n", services.TrustedKeysPath())
+	}
+	return nil
+}
+
+// runVersionsKeygen writes a new ed25519 signing key and its public key (<file>.pub)
+func runVersionsKeygen(args []string) error {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: aistack versions keygen <private-key-file>")
+	}
+	keyPath := args[0]
+	publicPath := keyPath + ".pub"
+	for _, path := range []string{keyPath, publicPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	key, err := signing.GenerateKey()
+	if err != nil {
+		return err
+	}
+	private, err := signing.EncodePrivateKey(key)
+	if err != nil {
+		return err
+	}
+	public, err := signing.EncodePublicKey(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, private, 0o600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(publicPath, public, 0o644); err != nil { // #nosec G306 -- public key
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	fmt.Printf("Private key: %s (keep it off the production host)\n", keyPath)
+	fmt.Printf("Public key:  %s\n", publicPath)
+	fmt.Printf("Trust it on a host with: sudo install -D -m 0644 %s %s/\n", publicPath, services.TrustedKeysPath())
+	return nil
+}
+
+// runVersionsVerify checks that running services use the images versions.lock pins;
+// it reports whether any service drifted
+func runVersionsVerify(asJSON bool) (bool, error) {
+	manager, err := services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelWarn))
+	if err != nil {
+		return false, fmt.Errorf("failed to initialize service manager: %w", err)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	verifications, err := manager.VerifyVersionLock(ctx)
+	if err != nil {
+		return false, err
+	}
+	drift := false
+	for _, verification := range verifications {
+		if verification.Status == services.LockDrift {
+			drift = true
+		}
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(verifications, "", "  ")
+		if err != nil {
+			return false, fmt.Errorf("failed to encode verification: %w", err)
+		}
+		fmt.Println(string(data))
+		return drift, nil
+	}
+
+	fmt.Printf("%-12s %-12s %-20s %s\n", "SERVICE", "STATUS", "RUNNING", "LOCKED")
+	for _, verification := range verifications {
+		running := historyImageID(verification.RunningDigest)
+		if running == "-" {
+			running = historyImageID(verification.RunningImageID)
+		}
+		locked := verification.Locked
+		if locked == "" {
+			locked = "-"
+		}
+		fmt.Printf("%-12s %-12s %-20s %s\n", verification.Service, verification.Status, running, locked)
+		if verification.Error != "" {
+			fmt.Printf("%-12s %s\n", "", verification.Error)
+		}
+	}
+	fmt.Println()
+	if drift {
+		fmt.Println("Drift detected: restart the drifted services to run the pinned images, or pin what runs with: aistack versions lock")
+		return true, nil
+	}
+	fmt.Println("All running services match versions.lock")
+	return false, nil
+}
+
+// printLockChanges prints a versions.lock diff: + added, ~ changed, - removed
+func printLockChanges(changes []services.LockChange) {
+	for _, change := range changes {
+		switch {
+		case change.Current == "":
+			fmt.Printf("+ %-12s %s\n", change.Service, change.Proposed)
+		case change.Proposed == "":
+			fmt.Printf("- %-12s %s\n", change.Service, change.Current)
+		default:
+			fmt.Printf("~ %-12s %s\n  %-12s -> %s\n", change.Service, change.Current, "", change.Proposed)
+		}
+	}
+}
+
+// displayUpdateSchedule prints the update schedule, its window and the last scheduled run
+func displayUpdateSchedule(cfg config.UpdatesConfig) {
+	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
+	scheduler, err := services.NewUpdateScheduler(cfg, nil, stateDir, logging.NewLogger(logging.LevelError))
+	if err != nil {
+		fmt.Printf("Update Schedule: invalid (%v)\n", err)
+		return
+	}
+	if scheduler == nil {
+		fmt.Println("Update Schedule: none (updates run only with 'aistack update-all')")
+		return
+	}
+
+	timezone := cfg.Timezone
+	if timezone == "" {
+		timezone = "local time"
+	}
+	fmt.Printf("Update Schedule: %s (%s)\n", cfg.Schedule, timezone)
+	fmt.Printf("  Window: %s\n", scheduler.Window())
+
+	state, err := scheduler.State()
+	if err != nil {
+		fmt.Printf("  Warning: %v\n", err)
+		return
+	}
+	switch {
+	case state.Deferred != nil:
+		fmt.Printf("  Pending since %s: %s\n", state.Deferred.Since.Format(time.RFC3339), state.Deferred.Reason)
+	case !state.LastScheduled.IsZero() && !scheduler.Next(state.LastScheduled).After(time.Now()):
+		fmt.Println("  Next Run: due (runs on the next agent check or aistack-update.timer)")
+	default:
+		fmt.Printf("  Next Run: %s\n", scheduler.Next(time.Now()).Format(time.RFC3339))
+	}
+	if run := state.LastRun; run != nil {
+		fmt.Printf("  Last Run: %s at %s", run.Outcome, run.Started.Format(time.RFC3339))
+		if run.Reason != "" {
+			fmt.Printf(" (%s)", run.Reason)
+		}
+		fmt.Println()
+	}
+}
+
 // locateVersionsLockFile tries to find versions.lock using same logic as loadVersionLock
 func locateVersionsLockFile() string {
 	// Check environment variable first
@@ -161,36 +562,35 @@ func locateVersionsLockFile() string {
 
 // displayVersionLockContents reads and displays the version lock file
 func displayVersionLockContents(path string) {
-	file, err := os.Open(filepath.Clean(path)) // #nosec G304 -- path is from controlled locations
+	lock, err := services.ReadVersionLock(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "    Error reading lock file: %v\n", err)
 		return
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", cerr)
-		}
-	}()
 
-	content, err := io.ReadAll(file)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "    Error reading lock file: %v\n", err)
+	fmt.Printf("    Format: v%d\n", lock.Format())
+	if len(lock.Services()) == 0 {
+		fmt.Println("    (empty lock file)")
 		return
 	}
-
-	lines := strings.Split(string(content), "\n")
-	hasEntries := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for _, name := range lock.Services() {
+		entry, _ := lock.Entry(name)
+		fmt.Printf("    %s: %s\n", name, entry.Reference())
+		if !entry.PinnedAt.IsZero() {
+			fmt.Printf("      pinned at %s", entry.PinnedAt.Format(time.RFC3339))
+			if entry.Reason != "" {
+				fmt.Printf(": %s", entry.Reason)
+			}
+			fmt.Println()
+		} else if entry.Reason != "" {
+			fmt.Printf("      %s\n", entry.Reason)
+		}
+		if entry.Platform != "" {
+			fmt.Printf("      platform %s\n", entry.Platform)
+		}
+		if entry.Signature != "" {
+			fmt.Println("      signed")
 		}
-		fmt.Printf("    %s\n", line)
-		hasEntries = true
-	}
-
-	if !hasEntries {
-		fmt.Println("    (empty lock file)")
 	}
 }
 
@@ -204,17 +604,23 @@ func runInstall() {
 	manager, err := services.NewManager(composeDir, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	ctx, stop := commandContext()
+	defer stop()
+
 	// Check for --profile flag
 	if len(os.Args) > 2 {
 		if os.Args[2] == "--profile" && len(os.Args) > 3 {
 			profile := os.Args[3]
 			fmt.Printf("Installing profile: %s\n", profile)
-			if err := manager.InstallProfile(profile); err != nil {
+			err := retryOnPortConflict(manager, func(manager *services.Manager) error {
+				return manager.InstallProfile(ctx, profile)
+			})
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error installing profile: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Printf("Profile %s installed successfully\n", profile)
 			return
@@ -222,111 +628,656 @@ func runInstall() {
 
 		// Install specific service
 		serviceName := os.Args[2]
-		service, err := manager.GetService(serviceName)
+		if _, err := manager.GetService(serviceName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+
+		fmt.Printf("Installing service: %s\n", serviceName)
+		err = retryOnPortConflict(manager, func(manager *services.Manager) error {
+			return manager.InstallService(ctx, serviceName)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error installing service: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Service %s installed successfully\n", serviceName)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Usage: aistack install [--profile <profile>|<service>]\n")
+	exit(1)
+}
+
+// runProfile handles install profile inspection and switching
+func runProfile() {
+	if len(os.Args) < 3 {
+		printProfileUsage()
+		exit(1)
+	}
+
+	logger := logging.NewLogger(logging.LevelInfo)
+	manager, err := services.NewManager(resolveComposeDir(), logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
+		exit(1)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	subcommand := strings.ToLower(os.Args[2])
+	switch subcommand {
+	case "list":
+		err = runProfileList(manager)
+	case "show":
+		err = runProfileShow(ctx, manager, os.Args[3:])
+	case "apply":
+		err = runProfileApply(ctx, manager, os.Args[3:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown profile subcommand: %s\n\n", subcommand)
+		printProfileUsage()
+		exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		exit(1)
+	}
+}
+
+func printProfileUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: aistack profile <subcommand>\n")
+	fmt.Fprintf(os.Stderr, "Subcommands:\n")
+	fmt.Fprintf(os.Stderr, "  list                 List built-in and configured install profiles\n")
+	fmt.Fprintf(os.Stderr, "  show <name>          Show a profile and how it differs from the current install\n")
+	fmt.Fprintf(os.Stderr, "  apply <name> [--yes] Install/remove services to match a profile (data volumes are kept)\n")
+}
+
+func runProfileList(manager *services.Manager) error {
+	profiles, err := manager.Profiles()
+	if err != nil {
+		return err
+	}
+
+	active := manager.ActiveProfile()
+	fmt.Println("Install profiles:")
+	for _, profile := range profiles {
+		marker := " "
+		if profile.Name == active {
+			marker = "*"
+		}
+		origin := "config"
+		if profile.Builtin {
+			origin = "built-in"
+		}
+		fmt.Printf("%s %-14s %-9s %s\n", marker, profile.Name, origin, strings.Join(profile.Services, ", "))
+	}
+	if active != "" {
+		fmt.Println()
+		fmt.Printf("* active profile: %s\n", active)
+	}
+	return nil
+}
+
+func runProfileShow(ctx context.Context, manager *services.Manager, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: aistack profile show <name>")
+	}
+
+	profile, err := manager.Profile(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile: %s\n", profile.Name)
+	if profile.Description != "" {
+		fmt.Printf("  %s\n", profile.Description)
+	}
+	fmt.Printf("Services: %s\n", strings.Join(profile.Services, ", "))
+
+	if len(profile.Overrides) > 0 {
+		fmt.Println("Overrides:")
+		for _, name := range profile.Services {
+			if override, ok := profile.Overrides[name]; ok {
+				printServiceOverride(name, override)
+			}
+		}
+	}
+
+	diff, err := manager.DiffProfile(ctx, profile.Name)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	printProfileDiff(diff)
+	return nil
+}
+
+func printServiceOverride(name string, override config.ServiceOverride) {
+	var parts []string
+	if override.Image != "" {
+		parts = append(parts, "image="+override.Image)
+	}
+	if override.HealthURL != "" {
+		parts = append(parts, "health_url="+override.HealthURL)
+	}
+	if override.GPULock != nil {
+		parts = append(parts, fmt.Sprintf("gpu_lock=%t", *override.GPULock))
+	}
+	fmt.Printf("  %s: %s\n", name, strings.Join(parts, " "))
+}
+
+func printProfileDiff(diff services.ProfileDiff) {
+	if diff.IsEmpty() {
+		fmt.Printf("Current install matches profile %s\n", diff.Profile)
+		return
+	}
+
+	fmt.Printf("Changes to match profile %s:\n", diff.Profile)
+	for _, name := range diff.Install {
+		fmt.Printf("  + install %s\n", name)
+	}
+	for _, name := range diff.Remove {
+		fmt.Printf("  - remove  %s (data volumes kept)\n", name)
+	}
+	for _, name := range diff.Keep {
+		fmt.Printf("  = keep    %s\n", name)
+	}
+}
+
+func runProfileApply(ctx context.Context, manager *services.Manager, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: aistack profile apply <name> [--yes]")
+	}
+
+	name := args[0]
+	skipConfirm := false
+	for _, arg := range args[1:] {
+		if arg == "--yes" || arg == "-y" {
+			skipConfirm = true
+		}
+	}
+
+	diff, err := manager.DiffProfile(ctx, name)
+	if err != nil {
+		return err
+	}
+	printProfileDiff(diff)
+
+	if len(diff.Remove) > 0 && !skipConfirm {
+		fmt.Println()
+		fmt.Print("Type 'yes' to apply: ")
+		var response string
+		if _, err := fmt.Scanln(&response); err != nil || response != confirmationYes {
+			return fmt.Errorf("profile apply canceled")
+		}
+	}
+
+	fmt.Println()
+	if _, err := manager.ApplyProfile(ctx, name); err != nil {
+		return fmt.Errorf("failed to apply profile %s: %w", name, err)
+	}
+
+	fmt.Printf("✓ Profile %s applied\n", name)
+	return nil
+}
+
+// runCompose handles compose subcommands
+func runCompose() {
+	if len(os.Args) < 4 || strings.ToLower(os.Args[2]) != "render" {
+		fmt.Fprintf(os.Stderr, "Usage: aistack compose render <service>\n")
+		exit(1)
+	}
+
+	logger := logging.NewLogger(logging.LevelWarn)
+	manager, err := services.NewManager(resolveComposeDir(), logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
+		exit(1)
+	}
+
+	rendered, err := manager.RenderCompose(os.Args[3])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		exit(1)
+	}
+
+	fmt.Print(string(rendered))
+}
+
+// runServiceCommand runs start/stop commands on services
+func runServiceCommand(command string) {
+	if client := apiClient(); client != nil && command != "logs" {
+		runServiceCommandViaAPI(client, command)
+		return
+	}
+
+	logger := logging.NewLogger(logging.LevelInfo)
+	composeDir := resolveComposeDir()
+
+	manager, err := services.NewManager(composeDir, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
+		exit(1)
+	}
+
+	if len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, "Usage: aistack %s <service>\n", command)
+		exit(1)
+	}
+
+	serviceName := os.Args[2]
+	service, err := manager.GetService(serviceName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	if err := executeServiceAction(ctx, command, serviceName, service, manager, os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		exit(1)
+	}
+}
+
+func executeServiceAction(ctx context.Context, command, serviceName string, service services.Service, manager *services.Manager, extraArgs []string) error {
+	switch command {
+	case "start":
+		if hasFlag(extraArgs, "--with-deps") {
+			return handleServiceStartWithDeps(ctx, serviceName, manager)
+		}
+		return handleServiceStart(ctx, serviceName, manager)
+	case "stop":
+		if hasFlag(extraArgs, "--with-deps") {
+			return handleServiceStopWithDeps(ctx, serviceName, manager)
+		}
+		warnRunningDependents(serviceName, manager)
+		return handleServiceStop(ctx, serviceName, manager)
+	case "update":
+		return handleServiceUpdate(ctx, serviceName, service)
+	case "logs":
+		return handleServiceLogs(ctx, serviceName, service, extraArgs)
+	case "repair":
+		return handleServiceRepair(ctx, serviceName, manager)
+	default:
+		return fmt.Errorf("unknown service command: %s", command)
+	}
+}
+
+func handleServiceStart(ctx context.Context, serviceName string, manager *services.Manager) error {
+	fmt.Printf("Starting service: %s\n", serviceName)
+	err := retryOnPortConflict(manager, func(manager *services.Manager) error {
+		return manager.StartService(ctx, serviceName)
+	})
+	if err != nil {
+		return fmt.Errorf("Error starting service: %w", err)
+	}
+	fmt.Printf("Service %s started successfully\n", serviceName)
+	return nil
+}
+
+func handleServiceStop(ctx context.Context, serviceName string, manager *services.Manager) error {
+	fmt.Printf("Stopping service: %s\n", serviceName)
+	if err := manager.StopService(ctx, serviceName); err != nil {
+		return fmt.Errorf("Error stopping service: %w", err)
+	}
+	fmt.Printf("Service %s stopped successfully\n", serviceName)
+	return nil
+}
+
+func handleServiceStartWithDeps(ctx context.Context, serviceName string, manager *services.Manager) error {
+	fmt.Printf("Starting service with dependencies: %s\n", serviceName)
+	var chain []string
+	err := retryOnPortConflict(manager, func(manager *services.Manager) error {
+		var err error
+		chain, err = manager.StartWithDependencies(ctx, serviceName)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Error starting service: %w", err)
+	}
+	fmt.Printf("Service %s started successfully (%s)\n", serviceName, strings.Join(chain, " → "))
+	return nil
+}
+
+func handleServiceStopWithDeps(ctx context.Context, serviceName string, manager *services.Manager) error {
+	fmt.Printf("Stopping service and its dependents: %s\n", serviceName)
+	order, err := manager.StopWithDependents(ctx, serviceName)
+	if err != nil {
+		return fmt.Errorf("Error stopping service: %w", err)
+	}
+	fmt.Printf("Stopped %s\n", strings.Join(order, " → "))
+	return nil
+}
+
+func warnRunningDependents(serviceName string, manager *services.Manager) {
+	dependents, err := manager.Dependents(serviceName)
+	if err != nil || len(dependents) == 0 {
+		return
+	}
+	fmt.Printf("⚠ %s depends on %s; use --with-deps to stop it first\n", strings.Join(dependents, ", "), serviceName)
+}
+
+// retryOnPortConflict runs action and, when it fails on a taken port, offers the
+// suggested free port; once saved to the config, action runs again with a reloaded manager
+func retryOnPortConflict(manager *services.Manager, action func(*services.Manager) error) error {
+	err := action(manager)
+	for offerAlternativePort(err) {
+		manager, err = services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelInfo))
 		if err != nil {
+			return fmt.Errorf("failed to reload service manager: %w", err)
+		}
+		err = action(manager)
+	}
+	return err
+}
+
+// offerAlternativePort asks whether to move a service to a free port and saves the answer
+func offerAlternativePort(err error) bool {
+	var conflict *services.PortConflictError
+	if !errors.As(err, &conflict) || conflict.Suggested == 0 {
+		return false
+	}
+
+	path := writableConfigPath()
+	fmt.Printf("⚠ Port %d is already in use by %s\n", conflict.Port, conflict.Owner())
+	fmt.Printf("Use port %d for %s instead and save it to %s? [y/N]: ", conflict.Suggested, conflict.Service, path)
+
+	var response string
+	if _, scanErr := fmt.Scanln(&response); scanErr != nil {
+		fmt.Println()
+		return false
+	}
+	if answer := strings.ToLower(response); answer != "y" && answer != confirmationYes {
+		return false
+	}
+
+	if saveErr := config.SetServicePort(path, conflict.Service, conflict.Suggested); saveErr != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save port: %v\n", saveErr)
+		return false
+	}
+	fmt.Printf("✓ services.%s.port = %d saved to %s\n", conflict.Service, conflict.Suggested, path)
+	return true
+}
+
+// writableConfigPath returns the config file a setting should be saved to: the user
+// config if it exists (it overrides the system file), else the system config as root
+func writableConfigPath() string {
+	userPath := config.UserConfigPath()
+	if _, err := os.Stat(userPath); err == nil || os.Geteuid() != 0 {
+		return userPath
+	}
+	return config.SystemConfigPath()
+}
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func handleServiceUpdate(ctx context.Context, serviceName string, service services.Service) error {
+	// Check update policy before proceeding (Story T-035)
+	cfg, err := config.Load()
+	if err != nil {
+		// Warn but allow update if config can't be loaded (backwards compatibility)
+		fmt.Fprintf(os.Stderr, "Warning: Could not load config, proceeding with update: %v\n", err)
+	} else if cfg.Updates.Mode == "pinned" {
+		return fmt.Errorf("updates are disabled: updates.mode is set to 'pinned' in configuration\nChange to 'rolling' in config.yaml to allow updates")
+	}
+
+	fmt.Printf("Updating service: %s\n", serviceName)
+	fmt.Println("This will pull the latest image and restart the service.")
+	fmt.Println("Health checks will be performed and rollback will occur on failure.")
+	fmt.Println()
+	if err := service.Update(ctx); err != nil {
+		return fmt.Errorf("\n❌ Update failed: %w", err)
+	}
+	fmt.Printf("\n✓ Service %s updated successfully\n", serviceName)
+	return nil
+}
+
+// reconcileInterruptedUpdates completes or rolls back update plans left pending by a
+// crashed process. Thin clients leave this to the API server, which ran it at startup.
+func reconcileInterruptedUpdates() {
+	if apiClient() != nil {
+		return
+	}
+	pending, err := services.PendingUpdates(fsutil.GetStateDir(fsutil.DefaultStateDir))
+	if err != nil || len(pending) == 0 {
+		return
+	}
+
+	manager, err := services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelWarn))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: interrupted update of %s not reconciled: %v\n", strings.Join(pending, ", "), err)
+		return
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	plans, err := manager.ReconcileUpdates(ctx)
+	for _, plan := range plans {
+		action := plan.Action
+		if action == "" {
+			action = "update"
+		}
+		fmt.Fprintf(os.Stderr, "⚠ Interrupted %s of %s reconciled: %s\n", action, plan.ServiceName, plan.Status)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to reconcile interrupted updates: %v\n", err)
+	}
+}
+
+// warnPendingUpdates reports updates a crashed process left pending without acting on them
+func warnPendingUpdates() {
+	pending, err := services.PendingUpdates(fsutil.GetStateDir(fsutil.DefaultStateDir))
+	if err != nil || len(pending) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "⚠ Interrupted update of %s pending; the next start, update or repair (or the agent) settles it\n\n", strings.Join(pending, ", "))
+}
+
+// runUpdate dispatches "update history <service>" and "update <service>"
+func runUpdate() {
+	if len(os.Args) > 2 && os.Args[2] == "history" {
+		runUpdateHistory()
+		return
+	}
+	if len(os.Args) > 2 && os.Args[2] == "--check" {
+		if err := runUpdateCheck(hasFlag(os.Args[3:], "--json")); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exit(1)
+		}
+		return
+	}
+	runServiceCommand("update")
+}
+
+// runUpdateCheck lists services with newer images by comparing registry digests
+// with the local images; nothing is pulled
+func runUpdateCheck(asJSON bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	manager, err := services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelWarn))
+	if err != nil {
+		return fmt.Errorf("failed to initialize service manager: %w", err)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	client := registry.New(cfg.Updates.Registries, time.Duration(cfg.Timeouts.InspectSeconds)*time.Second)
+	checks, err := manager.CheckUpdates(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode update check: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	pending := 0
+	fmt.Printf("%-12s %-17s %-20s %-20s %s\n", "SERVICE", "STATUS", "LOCAL", "REMOTE", "IMAGE")
+	for _, check := range checks {
+		image := check.Image
+		if check.Pinned {
+			image += " (versions.lock)"
+		}
+		fmt.Printf("%-12s %-17s %-20s %-20s %s\n", check.Service, check.Status, historyImageID(check.LocalDigest), historyImageID(check.RemoteDigest), image)
+		if check.Error != "" {
+			fmt.Printf("%-12s %s\n", "", check.Error)
+		}
+		if check.Status == services.UpdateCheckAvailable {
+			pending++
+		}
+	}
+	fmt.Println()
+	if pending == 0 {
+		fmt.Println("No updates pending")
+		return nil
+	}
+	fmt.Printf("%d update(s) pending; apply with: aistack update <service> or aistack update-all\n", pending)
+	if cfg.Updates.Mode == "pinned" {
+		fmt.Println("Note: updates.mode is 'pinned', updates are blocked until it is set to 'rolling'")
+	}
+	return nil
+}
+
+// runUpdateHistory prints the update ledger of a service
+func runUpdateHistory() {
+	if len(os.Args) < 4 {
+		fmt.Fprintf(os.Stderr, "Usage: aistack update history <service>\n")
+		exit(1)
+	}
+	serviceName := os.Args[3]
+
+	var entries []services.UpdateLedgerEntry
+	var err error
+	if client := apiClient(); client != nil {
+		ctx, stop := commandContext()
+		defer stop()
+		entries, err = client.UpdateHistory(ctx, serviceName)
+	} else {
+		var manager *services.Manager
+		manager, err = services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelWarn))
+		if err == nil {
+			entries, err = manager.UpdateHistory(serviceName)
 		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
 
-		fmt.Printf("Installing service: %s\n", serviceName)
-		if err := service.Install(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error installing service: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Service %s installed successfully\n", serviceName)
+	fmt.Printf("=== Update History: %s ===\n\n", serviceName)
+	if len(entries) == 0 {
+		fmt.Println("No updates recorded yet")
 		return
 	}
-
-	fmt.Fprintf(os.Stderr, "Usage: aistack install [--profile <profile>|<service>]\n")
-	os.Exit(1)
+	fmt.Printf("%-4s %-20s %-9s %-12s %-20s %-20s %s\n", "ID", "COMPLETED", "ACTION", "STATUS", "OLD IMAGE", "NEW IMAGE", "HEALTH")
+	for _, entry := range entries {
+		fmt.Printf("%-4d %-20s %-9s %-12s %-20s %-20s %s\n",
+			entry.ID,
+			entry.CompletedAt.Local().Format("2006-01-02 15:04:05"),
+			entry.Action,
+			entry.Status,
+			historyImageID(entry.OldImageID),
+			historyImageID(entry.NewImageID),
+			entry.Health)
+	}
+	fmt.Println()
+	fmt.Printf("Roll back with: aistack rollback %s [--to <id>]\n", serviceName)
 }
 
-// runServiceCommand runs start/stop commands on services
-func runServiceCommand(command string) {
-	logger := logging.NewLogger(logging.LevelInfo)
-	composeDir := resolveComposeDir()
-
-	manager, err := services.NewManager(composeDir, logger)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+// historyImageID abbreviates an image ID for the history table
+func historyImageID(id string) string {
+	if id == "" {
+		return "-"
 	}
+	return services.ShortImageID(id)
+}
 
+// runRollback restarts a service with a previous image from its update history
+func runRollback() {
 	if len(os.Args) < 3 {
-		fmt.Fprintf(os.Stderr, "Usage: aistack %s <service>\n", command)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Usage: aistack rollback <service> [--to <entry>]\n")
+		exit(1)
 	}
-
 	serviceName := os.Args[2]
-	service, err := manager.GetService(serviceName)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
 
-	if err := executeServiceAction(command, serviceName, service, manager, os.Args[3:]); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
+	entryID := 0
+	for i := 3; i < len(os.Args); i++ {
+		if os.Args[i] == "--to" && i+1 < len(os.Args) {
+			id, err := strconv.Atoi(os.Args[i+1])
+			if err != nil || id < 1 {
+				fmt.Fprintf(os.Stderr, "Invalid update history entry: %s\n", os.Args[i+1])
+				exit(1)
+			}
+			entryID = id
+			i++
+		}
 	}
-}
 
-func executeServiceAction(command, serviceName string, service services.Service, manager *services.Manager, extraArgs []string) error {
-	switch command {
-	case "start":
-		return handleServiceStart(serviceName, service)
-	case "stop":
-		return handleServiceStop(serviceName, service)
-	case "update":
-		return handleServiceUpdate(serviceName, service)
-	case "logs":
-		return handleServiceLogs(serviceName, service, extraArgs)
-	case "repair":
-		return handleServiceRepair(serviceName, manager)
-	default:
-		return fmt.Errorf("unknown service command: %s", command)
+	ctx, stop := commandContext()
+	defer stop()
+
+	if client := apiClient(); client != nil {
+		runRollbackViaAPI(ctx, client, serviceName, entryID)
+		return
 	}
-}
 
-func handleServiceStart(serviceName string, service services.Service) error {
-	fmt.Printf("Starting service: %s\n", serviceName)
-	if err := service.Start(); err != nil {
-		return fmt.Errorf("Error starting service: %w", err)
+	manager, err := services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelInfo))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
+		exit(1)
 	}
-	fmt.Printf("Service %s started successfully\n", serviceName)
-	return nil
-}
 
-func handleServiceStop(serviceName string, service services.Service) error {
-	fmt.Printf("Stopping service: %s\n", serviceName)
-	if err := service.Stop(); err != nil {
-		return fmt.Errorf("Error stopping service: %w", err)
+	fmt.Printf("Rolling back service: %s\n", serviceName)
+	plan, err := manager.RollbackService(ctx, serviceName, entryID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n❌ Rollback failed: %v\n", err)
+		exit(1)
 	}
-	fmt.Printf("Service %s stopped successfully\n", serviceName)
-	return nil
+	fmt.Printf("\n✓ Service %s now runs %s (health: %s)\n", serviceName, historyImageID(plan.NewImageID), plan.HealthAfterSwap)
 }
 
-func handleServiceUpdate(serviceName string, service services.Service) error {
-	// Check update policy before proceeding (Story T-035)
-	cfg, err := config.Load()
+// runRollbackViaAPI submits a rollback as an API job and waits for it
+func runRollbackViaAPI(ctx context.Context, client *api.Client, serviceName string, entryID int) {
+	job, err := client.Rollback(ctx, serviceName, entryID)
 	if err != nil {
-		// Warn but allow update if config can't be loaded (backwards compatibility)
-		fmt.Fprintf(os.Stderr, "Warning: Could not load config, proceeding with update: %v\n", err)
-	} else if cfg.Updates.Mode == "pinned" {
-		return fmt.Errorf("updates are disabled: updates.mode is set to 'pinned' in configuration\nChange to 'rolling' in config.yaml to allow updates")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
 	}
+	fmt.Printf("rollback %s: job %s submitted\n", serviceName, job.ID)
 
-	fmt.Printf("Updating service: %s\n", serviceName)
-	fmt.Println("This will pull the latest image and restart the service.")
-	fmt.Println("Health checks will be performed and rollback will occur on failure.")
-	fmt.Println()
-	if err := service.Update(); err != nil {
-		return fmt.Errorf("\n❌ Update failed: %w", err)
+	job, err = client.WaitJob(ctx, job.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error waiting for job: %v\n", err)
+		exit(1)
 	}
-	fmt.Printf("\n✓ Service %s updated successfully\n", serviceName)
-	return nil
+	if job.Status == api.JobFailed {
+		fmt.Fprintf(os.Stderr, "❌ rollback %s failed: %s\n", serviceName, job.Error)
+		exit(1)
+	}
+	fmt.Printf("✓ rollback %s succeeded\n", serviceName)
 }
 
-func handleServiceLogs(serviceName string, service services.Service, extraArgs []string) error {
+func handleServiceLogs(ctx context.Context, serviceName string, service services.Service, extraArgs []string) error {
 	tail := 100
 	if len(extraArgs) > 0 {
 		if _, err := fmt.Sscanf(extraArgs[0], "%d", &tail); err != nil {
@@ -334,7 +1285,7 @@ func handleServiceLogs(serviceName string, service services.Service, extraArgs [
 		}
 	}
 	fmt.Printf("=== Logs for %s (last %d lines) ===\n\n", serviceName, tail)
-	logs, err := service.Logs(tail)
+	logs, err := service.Logs(ctx, tail)
 	if err != nil {
 		return fmt.Errorf("Error getting logs: %w", err)
 	}
@@ -342,13 +1293,13 @@ func handleServiceLogs(serviceName string, service services.Service, extraArgs [
 	return nil
 }
 
-func handleServiceRepair(serviceName string, manager *services.Manager) error {
+func handleServiceRepair(ctx context.Context, serviceName string, manager *services.Manager) error {
 	fmt.Printf("Repairing service: %s\n", serviceName)
 	fmt.Println("This will stop, remove, and recreate the service.")
 	fmt.Println("Volumes will be preserved. Health checks will validate the repair.")
 	fmt.Println()
 
-	result, err := manager.RepairService(serviceName)
+	result, err := manager.RepairService(ctx, serviceName)
 	if err != nil {
 		return fmt.Errorf("\n❌ Repair failed: %w", err)
 	}
@@ -384,7 +1335,7 @@ func runRemove() {
 	manager, err := services.NewManager(composeDir, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(os.Args) < 3 {
@@ -392,15 +1343,15 @@ func runRemove() {
 		fmt.Println()
 		fmt.Println("Removes a service. Data volumes are kept by default.")
 		fmt.Println("Use --purge to also remove data volumes.")
-		os.Exit(1)
+		exit(1)
 	}
 
 	serviceName := os.Args[2]
 	service, err := manager.GetService(serviceName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		fmt.Println("Valid services: ollama, openwebui, localai")
-		os.Exit(1)
+		fmt.Printf("Valid services: %s\n", strings.Join(manager.ListServices(), ", "))
+		exit(1)
 	}
 
 	// Check for --purge flag
@@ -418,9 +1369,12 @@ func runRemove() {
 		fmt.Printf("Removing service %s (keeping data volumes)...\n", serviceName)
 	}
 
-	if err := service.Remove(keepData); err != nil {
+	ctx, stop := commandContext()
+	defer stop()
+
+	if err := service.Remove(ctx, keepData); err != nil {
 		fmt.Fprintf(os.Stderr, "\n❌ Remove failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("\n✓ Service %s removed successfully\n", serviceName)
@@ -445,26 +1399,29 @@ func runPurge() {
 	manager, err := services.NewManager(composeDir, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
+	ctx, stop := commandContext()
+	defer stop()
+
 	purgeManager := services.NewPurgeManager(manager, logger)
-	log, err := purgeManager.PurgeAll(options.removeConfigs)
+	log, err := purgeManager.PurgeAll(ctx, options.removeConfigs)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Purge failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	displayPurgeResults(log)
 
 	fmt.Println()
 	fmt.Println("Verifying cleanup...")
-	isClean, leftovers := purgeManager.VerifyClean()
+	isClean, leftovers := purgeManager.VerifyClean(ctx)
 	reportCleanupStatus(isClean, leftovers)
 	saveUninstallLog(purgeManager, logger, log)
 
 	if len(log.Errors) > 0 || !isClean {
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -494,7 +1451,7 @@ func requirePurgeFlags(options purgeOptions) {
 		return
 	}
 	printPurgeUsage()
-	os.Exit(1)
+	exit(1)
 }
 
 func printPurgeUsage() {
@@ -545,7 +1502,7 @@ func confirmPurge(options purgeOptions) {
 
 func exitPurgeCanceled() {
 	fmt.Fprintf(os.Stderr, "\nPurge canceled\n")
-	os.Exit(1)
+	exit(1)
 }
 
 func displayPurgeResults(log *services.UninstallLog) {
@@ -593,19 +1550,25 @@ func saveUninstallLog(purgeManager *services.PurgeManager, logger *logging.Logge
 
 // runStatus displays status of all services
 func runStatus() {
-	logger := logging.NewLogger(logging.LevelInfo)
-	composeDir := resolveComposeDir()
+	ctx, stop := commandContext()
+	defer stop()
 
-	manager, err := services.NewManager(composeDir, logger)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+	var statuses []services.ServiceStatus
+	var err error
+	if client := apiClient(); client != nil {
+		statuses, err = client.Services(ctx)
+	} else {
+		manager, managerErr := services.NewManager(resolveComposeDir(), logging.NewLogger(logging.LevelInfo))
+		if managerErr != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", managerErr)
+			exit(1)
+		}
+		warnPendingUpdates()
+		statuses, err = manager.StatusAll(ctx)
 	}
-
-	statuses, err := manager.StatusAll()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting status: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("Service Status:")
@@ -613,30 +1576,287 @@ func runStatus() {
 	for _, status := range statuses {
 		fmt.Printf("%-12s  State: %-10s  Health: %s\n",
 			status.Name, status.State, status.Health)
+		if status.Limits != nil {
+			fmt.Printf("%-12s  Limits: %s\n", "", status.Limits)
+		}
+		if status.Message != "" {
+			fmt.Printf("%-12s  %s\n", "", status.Message)
+		}
+		if status.LockDrift != "" {
+			fmt.Printf("%-12s  Drift: %s\n", "", status.LockDrift)
+		}
+	}
+}
+
+// runAgent runs the supervisor daemon until SIGINT/SIGTERM; SIGHUP reloads the config
+func runAgent() {
+	logger := logging.NewLogger(logging.LevelInfo)
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	supervisor := agent.New(agent.ConfigLoader(resolveComposeDir(), logger), logger)
+	if err := supervisor.Run(ctx, reload); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+}
+
+// runMetrics dispatches the metrics subcommands
+func runMetrics() {
+	if len(os.Args) < 3 || os.Args[2] != "serve" {
+		fmt.Fprintf(os.Stderr, "Usage: aistack metrics serve [--listen %s]\n", metrics.DefaultListenAddress)
+		exit(1)
+	}
+
+	listen := metrics.DefaultListenAddress
+	for i := 3; i < len(os.Args); i++ {
+		if os.Args[i] == "--listen" && i+1 < len(os.Args) {
+			listen = os.Args[i+1]
+			i++
+		}
+	}
+
+	if err := runMetricsServe(listen); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+}
+
+// runMetricsServe serves Prometheus metrics on listen until interrupted
+func runMetricsServe(listen string) error {
+	logger := logging.NewLogger(logging.LevelInfo)
+	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
+
+	manager, err := services.NewManager(resolveComposeDir(), logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service manager: %w", err)
+	}
+
+	collector := &metrics.Collector{
+		Services: manager,
+		GPULock:  gpulock.NewManager(stateDir, logger),
+		Suspend:  suspend.NewManager(logger),
+		ModelCaches: []metrics.ModelCacheSource{
+			models.NewStateManager(stateDir, models.ProviderOllama, logger),
+			models.NewStateManager(stateDir, models.ProviderLocalAI, logger),
+		},
+		UpdatePlans: func(serviceName string) (*services.UpdatePlan, error) {
+			return services.LoadUpdatePlan(serviceName, stateDir)
+		},
+		Logger: logger,
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	fmt.Printf("Serving Prometheus metrics on %s/metrics\n", listen)
+	return metrics.Serve(ctx, listen, collector, logger)
+}
+
+// runAPI dispatches the api subcommands
+func runAPI() {
+	if len(os.Args) < 3 || os.Args[2] != "serve" {
+		fmt.Fprintf(os.Stderr, "Usage: aistack api serve [--socket %s] [--group %s]\n", api.DefaultSocketPath, api.DefaultGroup)
+		exit(1)
+	}
+
+	socketPath, group := api.DefaultSocketPath, api.DefaultGroup
+	for i := 3; i < len(os.Args); i++ {
+		switch {
+		case os.Args[i] == "--socket" && i+1 < len(os.Args):
+			socketPath = os.Args[i+1]
+			i++
+		case os.Args[i] == "--group" && i+1 < len(os.Args):
+			group = os.Args[i+1]
+			i++
+		}
+	}
+
+	if err := runAPIServe(socketPath, group); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+}
+
+// runAPIServe serves the control API on socketPath until interrupted
+func runAPIServe(socketPath, group string) error {
+	logger := logging.NewLogger(logging.LevelInfo)
+	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
+
+	manager, err := services.NewManager(resolveComposeDir(), logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service manager: %w", err)
+	}
+
+	server := &api.Server{
+		Services: manager,
+		Models: map[models.Provider]api.ModelManager{
+			models.ProviderOllama:  models.NewOllamaManager(stateDir, logger),
+			models.ProviderLocalAI: models.NewLocalAIManager(stateDir, localAIModelsPath, logger),
+		},
+		Suspend: suspend.NewManager(logger),
+		Version: version,
+		Logger:  logger,
 	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	fmt.Printf("Serving the aistack API (%s) on %s\n", api.Version, socketPath)
+	return api.Serve(ctx, socketPath, group, server)
+}
+
+// apiClient returns a control API client when AISTACK_API_SOCKET names a socket;
+// the CLI then runs status and service commands through the API
+func apiClient() *api.Client {
+	socketPath := os.Getenv(api.SocketEnvVar)
+	if socketPath == "" {
+		return nil
+	}
+	return api.NewClient(socketPath)
+}
+
+// runServiceCommandViaAPI submits a service command as an API job and waits for it
+func runServiceCommandViaAPI(client *api.Client, command string) {
+	if len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, "Usage: aistack %s <service>\n", command)
+		exit(1)
+	}
+	serviceName := os.Args[2]
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	job, err := client.ServiceAction(ctx, serviceName, command, hasFlag(os.Args[3:], "--with-deps"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("%s %s: job %s submitted\n", command, serviceName, job.ID)
+
+	job, err = client.WaitJob(ctx, job.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error waiting for job: %v\n", err)
+		exit(1)
+	}
+	if job.Status == api.JobFailed {
+		fmt.Fprintf(os.Stderr, "❌ %s %s failed: %s\n", command, serviceName, job.Error)
+		exit(1)
+	}
+	fmt.Printf("✓ %s %s succeeded\n", command, serviceName)
+}
+
+// installNotifications subscribes the configured notification targets to the
+// events of this command; without targets (or a loadable config) it does nothing
+func installNotifications() {
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	if notifier := notify.New(cfg.Notifications, logging.NewLogger(logging.LevelWarn)); notifier != nil {
+		notifier.Install()
+		closeNotifications = notifier.Close
+	}
+}
+
+// closeNotifications delivers the queued notifications before the process exits
+var closeNotifications = func() {}
+
+// exit terminates the process once the queued notifications are delivered
+func exit(code int) {
+	closeNotifications()
+	os.Exit(code)
+}
+
+// runNotify dispatches the notify subcommands
+func runNotify() {
+	if len(os.Args) < 3 || os.Args[2] != "test" {
+		fmt.Fprintf(os.Stderr, "Usage: aistack notify test [--target <name>]\n")
+		exit(1)
+	}
+
+	var targetName string
+	for i := 3; i < len(os.Args); i++ {
+		if os.Args[i] == "--target" && i+1 < len(os.Args) {
+			targetName = os.Args[i+1]
+			i++
+		}
+	}
+
+	if err := runNotifyTest(targetName); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+}
+
+// runNotifyTest sends a test notification to the configured targets
+func runNotifyTest(targetName string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	notifier := notify.New(cfg.Notifications, logging.NewLogger(logging.LevelWarn))
+	if notifier == nil {
+		return errors.New("no notification targets configured (notifications.targets in config.yaml)")
+	}
+
+	results, err := notifier.Test(targetName)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("❌ %s: %v\n", result.Target, result.Err)
+			continue
+		}
+		fmt.Printf("✓ %s: test notification delivered\n", result.Target)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed", failed, len(results))
+	}
+	return nil
 }
 
 // runHealth generates a comprehensive health report
 // Story T-025: Health-Reporter (Services + GPU Smoke)
 func runHealth() {
+	if len(os.Args) > 2 && os.Args[2] == "history" {
+		if err := runHealthHistory(os.Args[3:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
 	logger := logging.NewLogger(logging.LevelInfo)
 	composeDir := resolveComposeDir()
 
 	manager, err := services.NewManager(composeDir, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Create health reporter
 	reporter := services.NewHealthReporter(manager, nil, logger)
 
+	ctx, stop := commandContext()
+	defer stop()
+
 	// Generate report
 	fmt.Println("Generating health report...")
-	report, err := reporter.GenerateReport()
+	report, err := reporter.GenerateReport(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating health report: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Display report
@@ -652,7 +1872,14 @@ func runHealth() {
 		if service.Message != "" {
 			fmt.Printf(" (%s)", service.Message)
 		}
+		if service.Flapping {
+			fmt.Print(" [flapping]")
+		}
 		fmt.Println()
+		if latency := service.Latency; latency != nil {
+			fmt.Printf("    %-12s  Latency: %dms (p50 %dms, p95 %dms over %d probes)\n",
+				"", latency.LastMs, latency.P50Ms, latency.P95Ms, latency.Samples)
+		}
 	}
 
 	fmt.Println()
@@ -687,13 +1914,86 @@ func runHealth() {
 
 	// Exit with error if not all healthy
 	if !report.GPU.OK {
-		os.Exit(1)
+		exit(1)
 	}
 	for _, service := range report.Services {
 		if service.Health != services.HealthGreen {
-			os.Exit(1)
+			exit(1)
+		}
+	}
+}
+
+// runHealthHistory prints the recorded health transitions and the current state per service
+func runHealthHistory(args []string) error {
+	usage := fmt.Errorf("usage: aistack health history [--service <name>] [--since <duration>]")
+	service := ""
+	window := 24 * time.Hour
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--service" && i+1 < len(args):
+			service = args[i+1]
+			i++
+		case args[i] == "--since" && i+1 < len(args):
+			parsed, err := time.ParseDuration(args[i+1])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid --since '%s': use a duration like 24h or 90m", args[i+1])
+			}
+			window = parsed
+			i++
+		default:
+			return usage
+		}
+	}
+
+	history := services.LoadHealthHistory(logging.NewLogger(logging.LevelInfo))
+	since := time.Now().Add(-window)
+	transitions, err := history.Transitions(service, since)
+	if err != nil {
+		return err
+	}
+	trends, err := history.Trends()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Health transitions since %s:\n", since.UTC().Format(time.RFC3339))
+	if len(transitions) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, transition := range transitions {
+		from := string(transition.From)
+		if from == "" {
+			from = "first probe"
+		}
+		fmt.Printf("  %s  %s %-12s  %s → %s", transition.Time.Format(time.RFC3339), getHealthIcon(transition.To), transition.Service, from, transition.To)
+		if transition.Message != "" {
+			fmt.Printf(" (%s)", transition.Message)
+		}
+		fmt.Println()
+	}
+
+	names := make([]string, 0, len(trends))
+	for name := range trends {
+		if service == "" || name == service {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Println()
+	fmt.Println("Current:")
+	if len(names) == 0 {
+		fmt.Println("  (no reports recorded yet; run 'aistack health')")
+	}
+	for _, name := range names {
+		trend := trends[name]
+		fmt.Printf("  %s %-12s  %s since %s (%d reports)", getHealthIcon(trend.Health), name, trend.Health, trend.Since.Format(time.RFC3339), trend.Streak)
+		if trend.Flapping {
+			fmt.Printf(" [flapping: changed too often in the last %dm]", int(history.FlapWindow().Minutes()))
 		}
+		fmt.Println()
 	}
+	return nil
 }
 
 func getHealthIcon(health services.HealthStatus) string {
@@ -743,7 +2043,7 @@ func runDiag() {
 	zipPath, err := packager.CreatePackage()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to create diagnostic package: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Get file size
@@ -835,7 +2135,7 @@ func runGPUCheck() {
 		reportPath := "/tmp/gpu_report.json"
 		if err := detector.SaveReport(gpuReport, reportPath); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to save report: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Printf("Detailed report saved to: %s\n", reportPath)
 	}
@@ -853,7 +2153,7 @@ func runGPUUnlock() {
 	status, err := manager.GetStatus()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to get GPU lock status: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if status.Holder == gpulock.HolderNone {
@@ -875,7 +2175,7 @@ func runGPUUnlock() {
 	var response string
 	if _, err := fmt.Scanln(&response); err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to read response: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if strings.ToLower(response) != confirmationYes {
@@ -886,7 +2186,7 @@ func runGPUUnlock() {
 	// Force unlock
 	if err := manager.ForceUnlock(); err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to force unlock GPU: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("✓ GPU lock forcibly removed")
@@ -897,24 +2197,37 @@ func runGPUUnlock() {
 // runUpdateAll updates all services sequentially with health-gating
 // Story T-029: Container-Update "all" mit Health-Gate
 func runUpdateAll() {
+	if len(os.Args) > 2 && os.Args[2] == "--scheduled" {
+		runScheduledUpdate()
+		return
+	}
+
 	logger := logging.NewLogger(logging.LevelInfo)
 	composeDir := resolveComposeDir()
 
-	fmt.Println("Updating all services (LocalAI → Ollama → Open WebUI)...")
-	fmt.Println()
-
 	// Create service manager
 	manager, err := services.NewManager(composeDir, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to initialize service manager: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
-	// Run update all
-	result, err := manager.UpdateAllServices()
+	updateOrder, err := manager.UpdateOrder()
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to order services: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Updating all services (%s)...\n", strings.Join(updateOrder, " → "))
+	fmt.Println()
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	// Run update all (Ctrl-C rolls back the in-flight service and skips the rest)
+	result, err := manager.UpdateAllServices(ctx)
+	if err != nil && result == nil {
 		fmt.Fprintf(os.Stderr, "❌ Update all failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Display summary
@@ -928,8 +2241,7 @@ func runUpdateAll() {
 
 	// Display per-service results
 	fmt.Println("=== Service Results ===")
-	services := []string{"localai", "ollama", "openwebui"}
-	for _, serviceName := range services {
+	for _, serviceName := range updateOrder {
 		if res, exists := result.ServiceResults[serviceName]; exists {
 			icon := getUpdateStatusIcon(res)
 			status := getUpdateStatusText(res)
@@ -942,9 +2254,14 @@ func runUpdateAll() {
 	fmt.Println()
 
 	// Exit with appropriate code
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Update all interrupted: %v\n", err)
+		exit(1)
+	}
+
 	if result.FailedCount > 0 {
 		fmt.Println("⚠ Some services failed to update. Check logs for details.")
-		os.Exit(1)
+		exit(1)
 	}
 
 	if result.SuccessfulCount > 0 {
@@ -954,6 +2271,59 @@ func runUpdateAll() {
 	}
 }
 
+// runScheduledUpdate runs update-all when updates.schedule is due and the
+// maintenance window allows it (entry point of aistack-update.timer)
+func runScheduledUpdate() {
+	logger := logging.NewLogger(logging.LevelInfo)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to load configuration: %v\n", err)
+		exit(1)
+	}
+	if cfg.Updates.Schedule == "" {
+		fmt.Println("No update schedule configured (updates.schedule)")
+		return
+	}
+
+	manager, err := services.NewManager(resolveComposeDir(), logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to initialize service manager: %v\n", err)
+		exit(1)
+	}
+	scheduler, err := services.NewUpdateScheduler(cfg.Updates, manager, fsutil.GetStateDir(fsutil.DefaultStateDir), logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		exit(1)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	run, err := scheduler.RunDue(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Scheduled update failed: %v\n", err)
+		exit(1)
+	}
+	if run == nil {
+		if state, stateErr := scheduler.State(); stateErr == nil && state.Deferred != nil {
+			fmt.Printf("Scheduled update deferred: %s\n", state.Deferred.Reason)
+			return
+		}
+		fmt.Printf("No scheduled update due (next: %s)\n", scheduler.Next(time.Now()).Format(time.RFC3339))
+		return
+	}
+
+	fmt.Printf("Scheduled update %s", run.Outcome)
+	if run.Reason != "" {
+		fmt.Printf(": %s", run.Reason)
+	}
+	fmt.Println()
+	if run.Outcome == services.ScheduledFailed || run.Outcome == services.ScheduledPartial {
+		exit(1)
+	}
+}
+
 func getUpdateStatusIcon(res services.UpdateResult) string {
 	if res.Success {
 		if res.Changed {
@@ -992,7 +2362,7 @@ func runBackendSwitch() {
 		fmt.Println()
 		fmt.Println("Switches the Open WebUI backend between Ollama and LocalAI.")
 		fmt.Println("The service will be restarted to apply the change.")
-		os.Exit(1)
+		exit(1)
 	}
 
 	backendArg := os.Args[2]
@@ -1006,34 +2376,34 @@ func runBackendSwitch() {
 	default:
 		fmt.Fprintf(os.Stderr, "❌ Invalid backend: %s\n", backendArg)
 		fmt.Println("Valid backends: ollama, localai")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Create manager and get OpenWebUI service
 	manager, err := services.NewManager(composeDir, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing service manager: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	service, err := manager.GetService("openwebui")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting Open WebUI service: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Cast to OpenWebUIService
 	openwebuiService, ok := service.(*services.OpenWebUIService)
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Error: service is not an OpenWebUIService\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Get current backend
 	currentBackend, err := openwebuiService.GetCurrentBackend()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting current backend: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("Current backend: %s\n", currentBackend)
@@ -1047,10 +2417,13 @@ func runBackendSwitch() {
 	fmt.Println("This will restart the Open WebUI service.")
 	fmt.Println()
 
+	ctx, stop := commandContext()
+	defer stop()
+
 	// Switch backend
-	if err := openwebuiService.SwitchBackend(backend); err != nil {
+	if err := openwebuiService.SwitchBackend(ctx, backend); err != nil {
 		fmt.Fprintf(os.Stderr, "\n❌ Backend switch failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("\n✓ Backend switched to %s successfully\n", backend)
@@ -1068,7 +2441,7 @@ func runConfig() {
 		fmt.Fprintf(os.Stderr, "Usage: aistack config <subcommand>\n")
 		fmt.Fprintf(os.Stderr, "Subcommands:\n")
 		fmt.Fprintf(os.Stderr, "  test [path]  Test configuration file for validity\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	subcommand := strings.ToLower(os.Args[2])
@@ -1079,7 +2452,7 @@ func runConfig() {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown config subcommand: %s\n", subcommand)
 		fmt.Fprintf(os.Stderr, "Valid subcommands: test\n")
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -1115,7 +2488,7 @@ func runConfigTest(logger *logging.Logger) {
 		logger.Error("config.validation.error", "Configuration validation failed", map[string]interface{}{
 			"error": configErr.Error(),
 		})
-		os.Exit(1)
+		exit(1)
 	}
 
 	// Display configuration summary
@@ -1141,7 +2514,7 @@ func runConfigTest(logger *logging.Logger) {
 func runModels() {
 	if len(os.Args) < 3 {
 		printModelsUsage()
-		os.Exit(1)
+		exit(1)
 	}
 
 	subcommand := strings.ToLower(os.Args[2])
@@ -1160,7 +2533,7 @@ func runModels() {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown models subcommand: %s\n\n", subcommand)
 		printModelsUsage()
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -1188,7 +2561,7 @@ func runModelsList() {
 	if len(os.Args) < 4 {
 		fmt.Fprintf(os.Stderr, "Usage: aistack models list <provider>\n")
 		fmt.Fprintf(os.Stderr, "Example: aistack models list ollama\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	providerStr := strings.ToLower(os.Args[3])
@@ -1196,7 +2569,7 @@ func runModelsList() {
 
 	if !provider.IsValid() {
 		fmt.Fprintf(os.Stderr, "❌ Invalid provider: %s (must be ollama or localai)\n", providerStr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
@@ -1226,7 +2599,7 @@ func runModelsList() {
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to list models: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(modelsList) == 0 {
@@ -1252,7 +2625,7 @@ func runModelsDownload() {
 	if len(os.Args) < 5 {
 		fmt.Fprintf(os.Stderr, "Usage: aistack models download <provider> <model-name>\n")
 		fmt.Fprintf(os.Stderr, "Example: aistack models download ollama qwen2:7b-instruct-q4\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	providerStr := strings.ToLower(os.Args[3])
@@ -1261,13 +2634,13 @@ func runModelsDownload() {
 
 	if !provider.IsValid() {
 		fmt.Fprintf(os.Stderr, "❌ Invalid provider: %s (must be ollama or localai)\n", providerStr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if provider != models.ProviderOllama {
 		fmt.Fprintf(os.Stderr, "❌ Model download is currently only supported for Ollama\n")
 		fmt.Fprintf(os.Stderr, "   LocalAI models must be manually placed in the models directory\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
@@ -1313,7 +2686,7 @@ func runModelsDownload() {
 		case err := <-done:
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "\n❌ Download failed: %v\n", err)
-				os.Exit(1)
+				exit(1)
 			}
 			fmt.Println()
 			fmt.Printf("Model %s is now available for use\n", modelName)
@@ -1329,7 +2702,7 @@ func runModelsDelete() {
 	if len(os.Args) < 5 {
 		fmt.Fprintf(os.Stderr, "Usage: aistack models delete <provider> <model-name>\n")
 		fmt.Fprintf(os.Stderr, "Example: aistack models delete ollama qwen2:7b-instruct-q4\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	providerStr := strings.ToLower(os.Args[3])
@@ -1338,7 +2711,7 @@ func runModelsDelete() {
 
 	if !provider.IsValid() {
 		fmt.Fprintf(os.Stderr, "❌ Invalid provider: %s (must be ollama or localai)\n", providerStr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
@@ -1350,7 +2723,7 @@ func runModelsDelete() {
 	var response string
 	if _, scanErr := fmt.Scanln(&response); scanErr != nil && !errors.Is(scanErr, io.EOF) {
 		fmt.Fprintf(os.Stderr, "Failed to read confirmation: %v\n", scanErr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if strings.ToLower(response) != confirmationYes {
@@ -1371,7 +2744,7 @@ func runModelsDelete() {
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to delete model: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("✓ Model %s deleted successfully\n", modelName)
@@ -1384,7 +2757,7 @@ func runModelsStats() {
 	if len(os.Args) < 4 {
 		fmt.Fprintf(os.Stderr, "Usage: aistack models stats <provider>\n")
 		fmt.Fprintf(os.Stderr, "Example: aistack models stats ollama\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	providerStr := strings.ToLower(os.Args[3])
@@ -1392,7 +2765,7 @@ func runModelsStats() {
 
 	if !provider.IsValid() {
 		fmt.Fprintf(os.Stderr, "❌ Invalid provider: %s (must be ollama or localai)\n", providerStr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
@@ -1412,7 +2785,7 @@ func runModelsStats() {
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to get stats: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("Cache Statistics for %s:\n\n", provider)
@@ -1435,7 +2808,7 @@ func runModelsEvictOldest() {
 	if len(os.Args) < 4 {
 		fmt.Fprintf(os.Stderr, "Usage: aistack models evict-oldest <provider>\n")
 		fmt.Fprintf(os.Stderr, "Example: aistack models evict-oldest ollama\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	providerStr := strings.ToLower(os.Args[3])
@@ -1443,7 +2816,7 @@ func runModelsEvictOldest() {
 
 	if !provider.IsValid() {
 		fmt.Fprintf(os.Stderr, "❌ Invalid provider: %s (must be ollama or localai)\n", providerStr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	stateDir := fsutil.GetStateDir(fsutil.DefaultStateDir)
@@ -1463,7 +2836,7 @@ func runModelsEvictOldest() {
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to evict oldest model: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Printf("✓ Evicted oldest model: %s\n", evicted.Name)
@@ -1493,12 +2866,17 @@ func printUsage() {
 
 Usage:
   aistack help                     Show this help message (default)
-  aistack install --profile <name> Install services from profile (standard-gpu, minimal)
-  aistack install <service>        Install a specific service (ollama, openwebui, localai)
-  aistack start <service>          Start a service
-  aistack stop <service>           Stop a service
+  aistack install --profile <name> Install services from profile (standard-gpu, minimal, dev, ...)
+  aistack profile <subcommand>     Install profiles (list, show <name>, apply <name> [--yes])
+  aistack install <service>        Install a specific service from the catalog (e.g. ollama)
+  aistack start <service> [--with-deps] Start a service (--with-deps: start dependencies first, wait until healthy)
+  aistack stop <service> [--with-deps]  Stop a service (--with-deps: stop dependent services first)
   aistack update <service>         Update a service to latest version (with rollback)
-  aistack update-all               Update all services sequentially (LocalAI → Ollama → OpenWebUI)
+  aistack update-all               Update all services sequentially in catalog update_order
+  aistack update-all --scheduled   Run update-all if updates.schedule is due and the maintenance window is open
+  aistack update --check [--json]  List services with newer images (registry digests, nothing is pulled)
+  aistack update history <service> Show the recorded updates and rollbacks of a service
+  aistack rollback <service> [--to <entry>]  Restart a service with a previous image from its update history (health-gated)
   aistack logs <service> [lines]   Show service logs (default: 100 lines)
   aistack remove <service> [--purge] Remove a service (keeps data by default)
   aistack uninstall <service> [--purge] Alias for remove
@@ -1506,13 +2884,23 @@ Usage:
   aistack backend <ollama|localai> Switch Open WebUI backend (restarts service)
   aistack status                   Show status of all services
   aistack health [--save]          Generate comprehensive health report (services + GPU)
+  aistack health history [--service <name>] [--since 24h]  Show recorded health transitions and flapping services
   aistack repair <service>         Repair a service (stop → remove → recreate with health check)
+  aistack agent                    Run the supervisor daemon (periodic health checks, auto-repair; SIGHUP reloads config)
+  aistack metrics serve [--listen :9469]  Serve Prometheus metrics on /metrics
+  aistack api serve [--socket PATH] [--group aistack]  Serve the control API on a unix socket
+  aistack notify test [--target <name>]  Send a test notification to the configured targets
   aistack config test [path]       Test configuration file for validity (defaults to system/user configs)
+  aistack compose render <service> Print the compose file rendered from config (generated secrets masked)
   aistack gpu-check [--save]       Check GPU and NVIDIA stack availability
   aistack gpu-unlock               Force unlock GPU mutex (recovery)
   aistack models <subcommand>      Model management (list, download, delete, stats, evict-oldest)
   aistack diag [--output path] [--no-logs] [--no-config]  Create diagnostic package (ZIP with logs, config, manifest)
   aistack versions                 Show version lock status and update policy (rolling/pinned)
+  aistack versions lock [--diff] [--reason <text>] [--format v1|v2] [--output <file>]  Pin running services to their image digests in versions.lock
+  aistack versions verify [--json] Check running containers against versions.lock (exit 1 on drift)
+  aistack versions sign --key <private-key> [--lock <file>] [<service>...]  Sign the digest pins of versions.lock with an ed25519 key
+  aistack versions keygen <private-key-file>  Create an ed25519 signing key and its public key (<file>.pub)
   aistack suspend <subcommand>     Auto-suspend management (enable, disable, status)
   aistack version                  Print version information
   aistack help                     Show this help message
@@ -1590,7 +2978,7 @@ func runSuspend() {
 		fmt.Fprintf(os.Stderr, "  status   Show suspend status\n")
 		fmt.Fprintf(os.Stderr, "  check    Check and execute suspend if idle (internal use)\n")
 		fmt.Fprintf(os.Stderr, "  reset    Reset activity timestamp (after resume, internal use)\n")
-		os.Exit(1)
+		exit(1)
 	}
 
 	subcommand := strings.ToLower(os.Args[2])
@@ -1609,7 +2997,7 @@ func runSuspend() {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown suspend subcommand: %s\n", subcommand)
 		fmt.Fprintf(os.Stderr, "Valid subcommands: enable, disable, status, check, reset\n")
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -1620,7 +3008,7 @@ func runSuspendEnable() {
 
 	if err := manager.Enable(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error enabling auto-suspend: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("✓ Auto-suspend enabled")
@@ -1636,7 +3024,7 @@ func runSuspendDisable() {
 
 	if err := manager.Disable(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error disabling auto-suspend: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("✓ Auto-suspend disabled")
@@ -1652,7 +3040,7 @@ func runSuspendStatus() {
 	state, err := manager.LoadState()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading suspend state: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	fmt.Println("=== Auto-Suspend Status ===")
@@ -1698,7 +3086,7 @@ func runSuspendCheck() {
 
 	if err := executor.CheckAndSuspend(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error during suspend check: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -1709,7 +3097,7 @@ func runSuspendReset() {
 
 	if err := manager.ResetActivityTimestamp(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error resetting activity timestamp: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	logger.Info("suspend.reset.done", "Activity timestamp reset successfully", nil)
@@ -480,6 +480,73 @@ deploy_systemd_units() {
     log_info "  Disable: aistack suspend disable"
 }
 
+# Deploy the supervisor agent (health checks and auto-repair)
+deploy_agent_unit() {
+    log_info "Deploying aistack agent service..."
+
+    local script_dir="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+    local systemd_dir="${script_dir}/assets/systemd"
+
+    if [[ ! -f "${systemd_dir}/aistack-agent.service" ]]; then
+        log_error "agent unit not found: ${systemd_dir}/aistack-agent.service"
+        exit 1
+    fi
+
+    cp -f "${systemd_dir}/aistack-agent.service" /etc/systemd/system/
+    chmod 644 /etc/systemd/system/aistack-agent.service
+
+    systemctl daemon-reload
+    systemctl enable aistack-agent.service
+    systemctl restart aistack-agent.service
+
+    log_info "✓ aistack agent deployed and started"
+    log_info "  Red services are repaired automatically (settings: agent.* in config.yaml)"
+    log_info "  Check status: systemctl status aistack-agent.service"
+}
+
+# Deploy the control API (unix socket for members of the aistack group)
+deploy_api_unit() {
+    log_info "Deploying aistack API service..."
+
+    local script_dir="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+    local systemd_dir="${script_dir}/assets/systemd"
+
+    if [[ ! -f "${systemd_dir}/aistack-api.service" ]]; then
+        log_error "api unit not found: ${systemd_dir}/aistack-api.service"
+        exit 1
+    fi
+
+    cp -f "${systemd_dir}/aistack-api.service" /etc/systemd/system/
+    chmod 644 /etc/systemd/system/aistack-api.service
+
+    systemctl daemon-reload
+    systemctl enable aistack-api.service
+    systemctl restart aistack-api.service
+
+    log_info "✓ aistack API deployed and started"
+    log_info "  Socket: /run/aistack/aistack.sock (group aistack; add users with: usermod -aG aistack <user>)"
+    log_info "  Check status: systemctl status aistack-api.service"
+}
+
+# Install the scheduled update timer (not enabled: the agent runs updates.schedule;
+# enable it on hosts without the agent)
+deploy_update_units() {
+    log_info "Installing scheduled update timer..."
+
+    local script_dir="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+    local systemd_dir="${script_dir}/assets/systemd"
+
+    cp -f "${systemd_dir}/aistack-update.service" /etc/systemd/system/
+    cp -f "${systemd_dir}/aistack-update.timer" /etc/systemd/system/
+    chmod 644 /etc/systemd/system/aistack-update.service
+    chmod 644 /etc/systemd/system/aistack-update.timer
+
+    systemctl daemon-reload
+
+    log_info "✓ aistack-update.timer installed (disabled; the agent runs scheduled updates)"
+    log_info "  Without the agent: systemctl enable --now aistack-update.timer"
+}
+
 # Deploy logrotate configuration - ALWAYS redeploy
 deploy_logrotate() {
     log_info "Deploying logrotate configuration (always overwrite)..."
@@ -533,6 +600,9 @@ main() {
     deploy_tmpfiles
     deploy_wol_persistence
     deploy_systemd_units
+    deploy_agent_unit
+    deploy_api_unit
+    deploy_update_units
 
     echo ""
     log_info "========================================="
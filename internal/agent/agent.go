@@ -0,0 +1,307 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"aistack/internal/logging"
+	"aistack/internal/services"
+)
+
+// shutdownGrace is how long a stop waits for a running check or repair to finish
+// before cancelling it (the systemd unit allows 90s)
+const shutdownGrace = 60 * time.Second
+
+// Agent periodically checks service health and repairs services that stay red
+type Agent struct {
+	load   Loader
+	logger *logging.Logger
+	now    func() time.Time
+
+	supervisor Supervisor
+	settings   Settings
+	services   map[string]*serviceState
+}
+
+// serviceState tracks red checks, repair backoff and the crash-loop circuit breaker of one service
+type serviceState struct {
+	redChecks int         // consecutive red health checks of this agent
+	failures  int         // consecutive failed repairs (drives the backoff)
+	retryAt   time.Time   // earliest next repair after a failed one
+	repairs   []time.Time // repair attempts within the breaker window
+	openUntil time.Time   // circuit breaker open: no repairs until then
+}
+
+// New creates an agent; the loader runs when Run starts
+func New(load Loader, logger *logging.Logger) *Agent {
+	return &Agent{
+		load:     load,
+		logger:   logger,
+		now:      time.Now,
+		services: make(map[string]*serviceState),
+	}
+}
+
+// Run checks services every interval until ctx is cancelled (SIGTERM). A value on
+// reload (SIGHUP) reloads the configuration; a failed reload keeps the current one.
+func (a *Agent) Run(ctx context.Context, reload <-chan os.Signal) error {
+	supervisor, settings, err := a.load()
+	if err != nil {
+		return fmt.Errorf("failed to start agent: %w", err)
+	}
+	a.supervisor, a.settings = supervisor, settings
+
+	a.logger.Info("agent.started", "Agent started", map[string]interface{}{
+		"interval":      a.settings.Interval.String(),
+		"red_threshold": a.settings.RedThreshold,
+	})
+
+	for {
+		a.runCheck(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		timer := time.NewTimer(a.settings.Interval)
+		select {
+		case <-ctx.Done():
+		case <-reload:
+			a.reload()
+		case <-timer.C:
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	a.logger.Info("agent.stopped", "Agent stopped", nil)
+	return nil
+}
+
+// reload swaps in a fresh supervisor and settings; per-service state is kept
+func (a *Agent) reload() {
+	supervisor, settings, err := a.load()
+	if err != nil {
+		a.logger.Error("agent.reload.failed", "Config reload failed, keeping the current config", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	a.supervisor, a.settings = supervisor, settings
+
+	a.logger.Info("agent.reloaded", "Configuration reloaded", map[string]interface{}{
+		"interval":      a.settings.Interval.String(),
+		"red_threshold": a.settings.RedThreshold,
+	})
+}
+
+// runCheck runs one check and a due scheduled update; a stop lets them finish
+// for up to shutdownGrace
+func (a *Agent) runCheck(ctx context.Context) {
+	checkCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.check(checkCtx)
+		if ctx.Err() == nil {
+			a.runScheduledUpdate(checkCtx)
+		}
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	a.logger.Info("agent.stopping", "Waiting for the running check to finish", map[string]interface{}{
+		"grace": shutdownGrace.String(),
+	})
+	timer := time.NewTimer(shutdownGrace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		cancel()
+		<-done
+	}
+}
+
+// check generates a health report and repairs supervised services that stayed red.
+// Services without a container or stopped by an operator are red too; they are left alone.
+func (a *Agent) check(ctx context.Context) {
+	report, err := a.supervisor.GenerateReport(ctx)
+	if err != nil {
+		a.logger.Warn("agent.check.failed", "Health report failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	supervised, err := a.supervisor.SupervisedServices(ctx)
+	if err != nil {
+		a.logger.Warn("agent.check.failed", "Could not list supervised services", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	running := make(map[string]bool, len(supervised))
+	for _, name := range supervised {
+		running[name] = true
+	}
+
+	seen := make(map[string]bool, len(report.Services))
+	for _, service := range report.Services {
+		seen[service.Name] = true
+		state := a.services[service.Name]
+		if state == nil {
+			state = &serviceState{}
+			a.services[service.Name] = state
+		}
+
+		if !running[service.Name] {
+			if service.Health == services.HealthRed {
+				a.logger.Debug("agent.service.unsupervised", "Service is not installed or was stopped, not repairing", map[string]interface{}{
+					"service": service.Name,
+				})
+			}
+			*state = serviceState{}
+			continue
+		}
+		if service.Health != services.HealthRed {
+			a.markHealthy(service.Name, state)
+			continue
+		}
+		a.handleRed(ctx, service, state)
+	}
+
+	// Forget services that are no longer in the catalog
+	for name := range a.services {
+		if !seen[name] {
+			delete(a.services, name)
+		}
+	}
+}
+
+// runScheduledUpdate runs update-all when the update schedule is due. Checks
+// and repairs pause while it runs, so restarting services are not repaired.
+func (a *Agent) runScheduledUpdate(ctx context.Context) {
+	if a.settings.Updates == nil || ctx.Err() != nil {
+		return
+	}
+	if _, err := a.settings.Updates.RunDue(ctx); err != nil {
+		a.logger.Warn("agent.update.failed", "Scheduled update check failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// markHealthy resets the red count and backoff; the breaker history is kept so a
+// service that keeps failing shortly after each repair is still caught
+func (a *Agent) markHealthy(name string, state *serviceState) {
+	if state.failures > 0 || state.redChecks >= a.settings.RedThreshold {
+		a.logger.Info("agent.service.recovered", "Service is healthy again", map[string]interface{}{
+			"service": name,
+		})
+	}
+	state.redChecks = 0
+	state.failures = 0
+	state.retryAt = time.Time{}
+}
+
+// handleRed repairs a red service unless it is below the threshold, backing off,
+// or its circuit breaker is open. Only the agent's own checks count towards the
+// threshold (reports of `aistack health` runs are in the history too); the history
+// marks flapping services, which wait for the threshold as well.
+func (a *Agent) handleRed(ctx context.Context, service services.ServiceHealthStatus, state *serviceState) {
+	state.redChecks++
+	if state.redChecks < a.settings.RedThreshold {
+		a.logger.Debug("agent.service.red", "Service is red, waiting for the threshold", map[string]interface{}{
+			"service":    service.Name,
+			"red_checks": state.redChecks,
+			"flapping":   service.Flapping,
+			"message":    service.Message,
+		})
+		return
+	}
+
+	now := a.now()
+	if !state.openUntil.IsZero() {
+		if now.Before(state.openUntil) {
+			return
+		}
+		state.openUntil = time.Time{}
+		state.repairs = nil
+		a.logger.Info("agent.circuit.closed", "Circuit breaker cooldown over, repairs resume", map[string]interface{}{
+			"service": service.Name,
+		})
+	}
+
+	if now.Before(state.retryAt) {
+		return
+	}
+
+	state.repairs = pruneBefore(state.repairs, now.Add(-a.settings.Window))
+	if len(state.repairs) >= a.settings.MaxRepairs {
+		state.openUntil = now.Add(a.settings.Cooldown)
+		a.logger.Error("agent.circuit.open", "Service is crash-looping, repairs paused", map[string]interface{}{
+			"service": service.Name,
+			"repairs": len(state.repairs),
+			"window":  a.settings.Window.String(),
+			"until":   state.openUntil.UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	a.repair(ctx, service, state, now)
+}
+
+func (a *Agent) repair(ctx context.Context, service services.ServiceHealthStatus, state *serviceState, now time.Time) {
+	state.repairs = append(state.repairs, now)
+	a.logger.Warn("agent.repair.started", "Repairing red service", map[string]interface{}{
+		"service":    service.Name,
+		"red_checks": state.redChecks,
+		"flapping":   service.Flapping,
+		"message":    service.Message,
+	})
+
+	result, err := a.supervisor.RepairService(ctx, service.Name)
+	if err == nil && result.Success {
+		state.redChecks = 0
+		state.failures = 0
+		state.retryAt = time.Time{}
+		a.logger.Info("agent.repair.succeeded", "Service repaired", map[string]interface{}{
+			"service": service.Name,
+		})
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	reason := result.ErrorMessage
+	if err != nil {
+		reason = err.Error()
+	}
+	state.failures++
+	delay := a.settings.backoff(state.failures)
+	state.retryAt = a.now().Add(delay)
+	a.logger.Warn("agent.repair.failed", "Repair failed, backing off", map[string]interface{}{
+		"service":  service.Name,
+		"error":    reason,
+		"failures": state.failures,
+		"retry_in": delay.String(),
+	})
+}
+
+// pruneBefore drops times before cutoff (times are in ascending order)
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	for len(times) > 0 && times[0].Before(cutoff) {
+		times = times[1:]
+	}
+	return times
+}
@@ -0,0 +1,349 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+	"aistack/internal/services"
+)
+
+// fakeSupervisor reports fixed health through an in-memory health history and records repairs
+type fakeSupervisor struct {
+	mu       sync.Mutex
+	health   map[string]services.HealthStatus
+	repairOK bool
+	repaired []string
+	reports  int
+	history  *services.HealthHistory
+	// unsupervised services are uninstalled or stopped by an operator
+	unsupervised map[string]bool
+}
+
+func (f *fakeSupervisor) GenerateReport(_ context.Context) (services.HealthReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reports++
+	if f.history == nil {
+		f.history = services.NewHealthHistory("", config.DefaultConfig().HealthHistory, logging.NewLogger(logging.LevelError))
+	}
+	report := services.HealthReport{}
+	for name, health := range f.health {
+		report.Services = append(report.Services, services.ServiceHealthStatus{Name: name, Health: health})
+	}
+	f.history.Record(report.Services)
+	return report, nil
+}
+
+func (f *fakeSupervisor) RepairService(_ context.Context, name string) (services.RepairResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repaired = append(f.repaired, name)
+	if !f.repairOK {
+		return services.RepairResult{ServiceName: name, ErrorMessage: "still red"}, nil
+	}
+	f.health[name] = services.HealthGreen
+	return services.RepairResult{ServiceName: name, Success: true}, nil
+}
+
+func (f *fakeSupervisor) SupervisedServices(context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for name := range f.health {
+		if !f.unsupervised[name] {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (f *fakeSupervisor) repairCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.repaired)
+}
+
+func testSettings() Settings {
+	return Settings{
+		Interval:       time.Minute,
+		RedThreshold:   2,
+		BackoffInitial: 30 * time.Second,
+		BackoffMax:     2 * time.Minute,
+		MaxRepairs:     3,
+		Window:         time.Hour,
+		Cooldown:       time.Hour,
+	}
+}
+
+// newTestAgent returns an agent wired to supervisor with a manual clock
+func newTestAgent(supervisor Supervisor) (*Agent, *time.Time) {
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := New(func() (Supervisor, Settings, error) { return supervisor, testSettings(), nil }, logging.NewLogger(logging.LevelError))
+	a.supervisor, a.settings = supervisor, testSettings()
+	a.now = func() time.Time { return clock }
+	return a, &clock
+}
+
+func TestSettings_Backoff(t *testing.T) {
+	settings := testSettings()
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 2 * time.Minute}
+	for i, expected := range want {
+		if got := settings.backoff(i + 1); got != expected {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, expected)
+		}
+	}
+}
+
+func TestAgent_RepairsAfterThreshold(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"ollama": services.HealthRed}, repairOK: true}
+	a, _ := newTestAgent(supervisor)
+
+	a.check(context.Background())
+	if supervisor.repairCount() != 0 {
+		t.Fatal("Expected no repair on the first red check")
+	}
+
+	a.check(context.Background())
+	if supervisor.repairCount() != 1 {
+		t.Fatalf("Expected one repair after %d red checks, got %d", testSettings().RedThreshold, supervisor.repairCount())
+	}
+	if state := a.services["ollama"]; state.redChecks != 0 || state.failures != 0 {
+		t.Errorf("Expected state reset after a successful repair, got %+v", state)
+	}
+}
+
+func TestAgent_OtherHealthReportsDoNotCountTowardsThreshold(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"ollama": services.HealthRed}, repairOK: true}
+	a, _ := newTestAgent(supervisor)
+
+	// Operators running `aistack health` during an outage add to the shared streak
+	for i := 0; i < 3; i++ {
+		if _, err := supervisor.GenerateReport(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.check(context.Background())
+	if supervisor.repairCount() != 0 {
+		t.Fatalf("Expected no repair on the agent's first red check, got %d", supervisor.repairCount())
+	}
+	a.check(context.Background())
+	if supervisor.repairCount() != 1 {
+		t.Errorf("Expected a repair after %d agent checks, got %d", testSettings().RedThreshold, supervisor.repairCount())
+	}
+}
+
+func TestAgent_FlappingServiceWaitsForThreshold(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{}, repairOK: true}
+	a, _ := newTestAgent(supervisor)
+
+	// red, green, red, green: never two red reports in a row
+	for i := 0; i < 4; i++ {
+		supervisor.health["ollama"] = []services.HealthStatus{services.HealthRed, services.HealthGreen}[i%2]
+		a.check(context.Background())
+	}
+
+	// The fourth change within the flap window marks the service as flapping; a
+	// single red report still does not reach the threshold
+	supervisor.health["ollama"] = services.HealthRed
+	a.check(context.Background())
+	if supervisor.repairCount() != 0 {
+		t.Fatalf("Expected no repair of a flapping service below the red threshold, got %d", supervisor.repairCount())
+	}
+
+	a.check(context.Background())
+	if supervisor.repairCount() != 1 {
+		t.Errorf("Expected a repair once the threshold is reached, got %d repairs", supervisor.repairCount())
+	}
+}
+
+func TestAgent_UnsupervisedServicesAreNotRepaired(t *testing.T) {
+	supervisor := &fakeSupervisor{
+		health: map[string]services.HealthStatus{
+			"ollama":    services.HealthRed, // stopped with aistack stop
+			"localai":   services.HealthRed, // never installed
+			"openwebui": services.HealthRed,
+		},
+		repairOK:     true,
+		unsupervised: map[string]bool{"ollama": true, "localai": true},
+	}
+	a, _ := newTestAgent(supervisor)
+
+	for i := 0; i < 5; i++ {
+		a.check(context.Background())
+	}
+	if len(supervisor.repaired) != 1 || supervisor.repaired[0] != "openwebui" {
+		t.Errorf("Expected only the installed, running service to be repaired, got %v", supervisor.repaired)
+	}
+}
+
+func TestAgent_YellowIsNotRepaired(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"openwebui": services.HealthYellow}}
+	a, _ := newTestAgent(supervisor)
+
+	for i := 0; i < 5; i++ {
+		a.check(context.Background())
+	}
+	if supervisor.repairCount() != 0 {
+		t.Errorf("Expected yellow services to be left alone, got %d repairs", supervisor.repairCount())
+	}
+}
+
+func TestAgent_BacksOffAfterFailedRepair(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"ollama": services.HealthRed}}
+	a, clock := newTestAgent(supervisor)
+
+	a.check(context.Background())
+	a.check(context.Background()) // repair 1 fails, retry in 30s
+	*clock = clock.Add(10 * time.Second)
+	a.check(context.Background())
+	if supervisor.repairCount() != 1 {
+		t.Fatalf("Expected no repair during backoff, got %d", supervisor.repairCount())
+	}
+
+	*clock = clock.Add(25 * time.Second)
+	a.check(context.Background()) // repair 2 fails, retry in 60s
+	if supervisor.repairCount() != 2 {
+		t.Fatalf("Expected a repair after the backoff, got %d", supervisor.repairCount())
+	}
+	if got := a.services["ollama"].retryAt.Sub(*clock); got != time.Minute {
+		t.Errorf("second backoff = %s, want 1m", got)
+	}
+}
+
+func TestAgent_CircuitBreakerStopsCrashLoop(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"localai": services.HealthRed}}
+	a, clock := newTestAgent(supervisor)
+
+	// Three failed repairs with backoff, then the breaker opens
+	for i := 0; i < 20; i++ {
+		a.check(context.Background())
+		*clock = clock.Add(2 * time.Minute)
+	}
+	if supervisor.repairCount() != 3 {
+		t.Fatalf("Expected %d repairs before the breaker opens, got %d", testSettings().MaxRepairs, supervisor.repairCount())
+	}
+	if a.services["localai"].openUntil.IsZero() {
+		t.Fatal("Expected the circuit breaker to be open")
+	}
+
+	// After the cooldown repairs resume
+	*clock = clock.Add(time.Hour)
+	a.check(context.Background())
+	if supervisor.repairCount() != 4 {
+		t.Errorf("Expected a repair after the cooldown, got %d repairs", supervisor.repairCount())
+	}
+}
+
+func TestAgent_CircuitBreakerCountsSuccessfulRepairs(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"ollama": services.HealthRed}, repairOK: true}
+	a, clock := newTestAgent(supervisor)
+
+	// Repaired every time, but red again right after: a crash loop
+	for i := 0; i < 10; i++ {
+		a.check(context.Background())
+		a.check(context.Background())
+		supervisor.health["ollama"] = services.HealthRed
+		*clock = clock.Add(time.Minute)
+	}
+	if supervisor.repairCount() != 3 {
+		t.Errorf("Expected the breaker to stop after %d repairs, got %d", testSettings().MaxRepairs, supervisor.repairCount())
+	}
+}
+
+// fakeUpdates counts scheduled update checks
+type fakeUpdates struct {
+	calls int
+	err   error
+}
+
+func (f *fakeUpdates) RunDue(context.Context) (*services.ScheduledRun, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func TestAgent_RunsScheduledUpdateAfterCheck(t *testing.T) {
+	supervisor := &fakeSupervisor{health: map[string]services.HealthStatus{"ollama": services.HealthGreen}}
+	a, _ := newTestAgent(supervisor)
+	updates := &fakeUpdates{err: errors.New("state dir not writable")}
+	a.settings.Updates = updates
+
+	a.runCheck(context.Background())
+	a.runCheck(context.Background())
+	if updates.calls != 2 || supervisor.reports != 2 {
+		t.Errorf("Expected a scheduled update check after each health check, got %d checks and %d reports", updates.calls, supervisor.reports)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.runScheduledUpdate(ctx)
+	if updates.calls != 2 {
+		t.Error("Expected no scheduled update once the agent is stopping")
+	}
+}
+
+func TestAgent_RunReloadsOnSIGHUP(t *testing.T) {
+	first := &fakeSupervisor{health: map[string]services.HealthStatus{}}
+	second := &fakeSupervisor{health: map[string]services.HealthStatus{}}
+
+	var mu sync.Mutex
+	loads := 0
+	loaded := make(chan struct{}, 4)
+	load := func() (Supervisor, Settings, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		defer func() { loaded <- struct{}{} }()
+		switch loads {
+		case 1:
+			return first, testSettings(), nil
+		case 2:
+			return nil, Settings{}, errors.New("invalid config")
+		default:
+			return second, testSettings(), nil
+		}
+	}
+
+	a := New(load, logging.NewLogger(logging.LevelError))
+	ctx, cancel := context.WithCancel(context.Background())
+	reload := make(chan os.Signal, 1)
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx, reload) }()
+
+	<-loaded
+	reload <- syscall.SIGHUP // fails: the first supervisor stays
+	<-loaded
+	reload <- syscall.SIGHUP
+	<-loaded
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if a.supervisor != second {
+		t.Error("Expected the supervisor from the last successful reload")
+	}
+
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	if first.reports < 2 {
+		t.Errorf("Expected the first supervisor to be used after the failed reload, got %d reports", first.reports)
+	}
+}
+
+func TestAgent_RunFailsWhenInitialLoadFails(t *testing.T) {
+	a := New(func() (Supervisor, Settings, error) {
+		return nil, Settings{}, errors.New("no runtime")
+	}, logging.NewLogger(logging.LevelError))
+
+	if err := a.Run(context.Background(), nil); err == nil {
+		t.Error("Expected Run to fail when the initial load fails")
+	}
+}
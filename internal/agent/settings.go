@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+	"aistack/internal/services"
+)
+
+// Settings are the agent intervals and repair limits (agent config section)
+type Settings struct {
+	Interval       time.Duration // time between health checks
+	RedThreshold   int           // consecutive red checks before a repair
+	BackoffInitial time.Duration // wait after the first failed repair
+	BackoffMax     time.Duration // upper bound for the doubling backoff
+	MaxRepairs     int           // repairs within Window before the circuit breaker opens
+	Window         time.Duration // crash-loop detection window
+	Cooldown       time.Duration // how long an open circuit breaker blocks repairs
+	Updates        UpdateRunner  // scheduled updates run after each check; nil when updates.schedule is empty
+}
+
+// UpdateRunner runs a scheduled update when one is due (services.UpdateScheduler)
+type UpdateRunner interface {
+	RunDue(ctx context.Context) (*services.ScheduledRun, error)
+}
+
+// SettingsFromConfig converts the agent config section into durations
+func SettingsFromConfig(cfg config.AgentConfig) Settings {
+	return Settings{
+		Interval:       time.Duration(cfg.IntervalSeconds) * time.Second,
+		RedThreshold:   cfg.RedThreshold,
+		BackoffInitial: time.Duration(cfg.BackoffInitialSeconds) * time.Second,
+		BackoffMax:     time.Duration(cfg.BackoffMaxSeconds) * time.Second,
+		MaxRepairs:     cfg.MaxRepairs,
+		Window:         time.Duration(cfg.WindowSeconds) * time.Second,
+		Cooldown:       time.Duration(cfg.CooldownSeconds) * time.Second,
+	}
+}
+
+// backoff returns the wait after the given number of consecutive failed repairs
+func (s Settings) backoff(failures int) time.Duration {
+	delay := s.BackoffInitial
+	for i := 1; i < failures && delay < s.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > s.BackoffMax {
+		delay = s.BackoffMax
+	}
+	return delay
+}
+
+// Supervisor is the service layer the agent checks and repairs
+type Supervisor interface {
+	GenerateReport(ctx context.Context) (services.HealthReport, error)
+	RepairService(ctx context.Context, serviceName string) (services.RepairResult, error)
+	// SupervisedServices returns the installed services no operator stopped;
+	// only these are repaired
+	SupervisedServices(ctx context.Context) ([]string, error)
+}
+
+// Loader builds the supervisor and settings from the current configuration.
+// It runs at startup and on every reload (SIGHUP).
+type Loader func() (Supervisor, Settings, error)
+
+// serviceSupervisor combines the health reporter and the manager's repair
+type serviceSupervisor struct {
+	*services.HealthReporter
+	manager *services.Manager
+}
+
+func (s *serviceSupervisor) RepairService(ctx context.Context, serviceName string) (services.RepairResult, error) {
+	return s.manager.RepairService(ctx, serviceName)
+}
+
+func (s *serviceSupervisor) SupervisedServices(ctx context.Context) ([]string, error) {
+	return s.manager.SupervisedServices(ctx)
+}
+
+// ConfigLoader loads the aistack config and creates a service manager for composeDir
+func ConfigLoader(composeDir string, logger *logging.Logger) Loader {
+	return func() (Supervisor, Settings, error) {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, Settings{}, fmt.Errorf("failed to load config: %w", err)
+		}
+
+		manager, err := services.NewManager(composeDir, logger)
+		if err != nil {
+			return nil, Settings{}, fmt.Errorf("failed to initialize service manager: %w", err)
+		}
+
+		supervisor := &serviceSupervisor{
+			HealthReporter: services.NewHealthReporter(manager, nil, logger),
+			manager:        manager,
+		}
+		settings := SettingsFromConfig(cfg.Agent)
+
+		scheduler, err := services.NewUpdateScheduler(cfg.Updates, manager, fsutil.GetStateDir(fsutil.DefaultStateDir), logger)
+		if err != nil {
+			return nil, Settings{}, err
+		}
+		if scheduler != nil {
+			settings.Updates = scheduler
+		}
+		return supervisor, settings, nil
+	}
+}
@@ -0,0 +1,82 @@
+// Package api serves the local aistack control API: versioned JSON over HTTP on a unix socket
+package api
+
+import (
+	"context"
+	"time"
+
+	"aistack/internal/models"
+	"aistack/internal/services"
+	"aistack/internal/suspend"
+)
+
+const (
+	// Version is the API version; every route is served below /<Version>/
+	Version = "v1"
+	// DefaultSocketPath is where `aistack api serve` listens by default
+	DefaultSocketPath = "/run/aistack/aistack.sock"
+	// DefaultGroup owns the socket; its members may use the API
+	DefaultGroup = "aistack"
+	// SocketEnvVar makes the CLI act as a client of the API socket it names
+	SocketEnvVar = "AISTACK_API_SOCKET"
+)
+
+// ServiceManager is the part of services.Manager the API exposes
+type ServiceManager interface {
+	GetService(name string) (services.Service, error)
+	StatusAll(ctx context.Context) ([]services.ServiceStatus, error)
+	StartService(ctx context.Context, name string) error
+	StopService(ctx context.Context, name string) error
+	StartWithDependencies(ctx context.Context, name string) ([]string, error)
+	StopWithDependents(ctx context.Context, name string) ([]string, error)
+	UpdateService(ctx context.Context, name string) error
+	RepairService(ctx context.Context, name string) (services.RepairResult, error)
+	UpdateHistory(name string) ([]services.UpdateLedgerEntry, error)
+	RollbackService(ctx context.Context, name string, entryID int) (*services.UpdatePlan, error)
+}
+
+// BackendSwitcher is implemented by the Open WebUI service
+type BackendSwitcher interface {
+	GetCurrentBackend() (services.BackendType, error)
+	SwitchBackend(ctx context.Context, backend services.BackendType) error
+}
+
+// ModelManager manages the model cache of one provider (models.OllamaManager, models.LocalAIManager)
+type ModelManager interface {
+	SyncState() error
+	List() ([]models.ModelInfo, error)
+	GetStats() (*models.CacheStats, error)
+	Delete(modelName string) error
+	EvictOldest() (*models.ModelInfo, error)
+}
+
+// ModelDownloader is implemented by model managers that can pull models (Ollama)
+type ModelDownloader interface {
+	Download(modelName string, progressChan chan<- models.DownloadProgress) error
+}
+
+// SuspendController manages auto-suspend (suspend.Manager)
+type SuspendController interface {
+	LoadState() (*suspend.State, error)
+	Enable() error
+	Disable() error
+	GetIdleDuration(state *suspend.State) time.Duration
+}
+
+// SuspendStatus is the response of the suspend endpoints
+type SuspendStatus struct {
+	Enabled     bool      `json:"enabled"`
+	LastActive  time.Time `json:"last_active"`
+	IdleSeconds float64   `json:"idle_seconds"`
+}
+
+// BackendStatus is the request and response body of the backend endpoints
+type BackendStatus struct {
+	Backend services.BackendType `json:"backend"`
+}
+
+// ErrorResponse is the body of every non-2xx response
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Job   *Job   `json:"job,omitempty"` // the running job on 409 Conflict
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"aistack/internal/services"
+)
+
+// clientBaseURL is the URL prefix of requests; the host is ignored on a unix socket
+const clientBaseURL = "http://aistack/" + Version
+
+// defaultPollInterval is how often WaitJob polls a job
+const defaultPollInterval = time.Second
+
+// Error is a non-2xx response of the API
+type Error struct {
+	StatusCode int
+	Message    string
+	Job        *Job // the running job on 409 Conflict
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// Client talks to the control API over its unix socket
+type Client struct {
+	httpClient   *http.Client
+	PollInterval time.Duration
+}
+
+// NewClient creates a client for the API socket at socketPath
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{
+		httpClient:   &http.Client{Transport: transport},
+		PollInterval: defaultPollInterval,
+	}
+}
+
+// Services returns the status of all services
+func (c *Client) Services(ctx context.Context) ([]services.ServiceStatus, error) {
+	var statuses []services.ServiceStatus
+	err := c.do(ctx, http.MethodGet, "/services", nil, &statuses)
+	return statuses, err
+}
+
+// ServiceAction submits start, stop, update or repair for a service and returns the job
+func (c *Client) ServiceAction(ctx context.Context, name, action string, withDeps bool) (*Job, error) {
+	path := "/services/" + url.PathEscape(name) + "/" + url.PathEscape(action)
+	if withDeps {
+		path += "?with_deps=true"
+	}
+	var job Job
+	if err := c.do(ctx, http.MethodPost, path, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateHistory returns the update ledger of a service, oldest entry first
+func (c *Client) UpdateHistory(ctx context.Context, name string) ([]services.UpdateLedgerEntry, error) {
+	var entries []services.UpdateLedgerEntry
+	err := c.do(ctx, http.MethodGet, "/services/"+url.PathEscape(name)+"/history", nil, &entries)
+	return entries, err
+}
+
+// Rollback submits a rollback of a service to an update history entry (0: the
+// image the current one replaced) and returns the job
+func (c *Client) Rollback(ctx context.Context, name string, entryID int) (*Job, error) {
+	path := "/services/" + url.PathEscape(name) + "/rollback"
+	if entryID != 0 {
+		path += "?to=" + strconv.Itoa(entryID)
+	}
+	var job Job
+	if err := c.do(ctx, http.MethodPost, path, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SwitchBackend submits a backend switch of Open WebUI and returns the job
+func (c *Client) SwitchBackend(ctx context.Context, backend services.BackendType) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/backend", BackendStatus{Backend: backend}, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Job returns the current state of a job
+func (c *Client) Job(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitJob polls a job until it has finished or ctx is cancelled
+func (c *Client) WaitJob(ctx context.Context, id string) (*Job, error) {
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status.Done() {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, clientBaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach aistack api: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var apiErr ErrorResponse
+		if decodeErr := json.NewDecoder(resp.Body).Decode(&apiErr); decodeErr != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: apiErr.Error, Job: apiErr.Job}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
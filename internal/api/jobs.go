@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JobStatus is the lifecycle state of a job
+type JobStatus string
+
+const (
+	// JobQueued means the job was accepted but has not started yet
+	JobQueued JobStatus = "queued"
+	// JobRunning means the operation is in progress
+	JobRunning JobStatus = "running"
+	// JobSucceeded means the operation finished without error
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed means the operation returned an error; see Job.Error
+	JobFailed JobStatus = "failed"
+)
+
+// Done reports whether the job has finished
+func (s JobStatus) Done() bool {
+	return s == JobSucceeded || s == JobFailed
+}
+
+// Job is a long-running operation clients poll via GET /v1/jobs/{id}
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`   // e.g. service.start, models.download
+	Target     string      `json:"target"` // what the job works on, e.g. service:ollama
+	Status     JobStatus   `json:"status"`
+	Progress   float64     `json:"progress,omitempty"` // percent, for jobs that report it
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// JobFunc runs the operation of a job; progress reports a percentage
+type JobFunc func(ctx context.Context, progress func(percent float64)) (interface{}, error)
+
+// JobConflictError is returned when a job for the same target is still running
+type JobConflictError struct {
+	Job Job
+}
+
+func (e *JobConflictError) Error() string {
+	return fmt.Sprintf("job %s (%s) is already running for %s", e.Job.ID, e.Job.Kind, e.Job.Target)
+}
+
+// defaultJobRetention is how many finished jobs are kept for polling
+const defaultJobRetention = 100
+
+// JobStore runs jobs in the background and keeps their state in memory.
+// Only one job per target runs at a time.
+type JobStore struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	order     []string          // job IDs, oldest first
+	active    map[string]string // target -> running job ID
+	retention int
+	closing   bool
+	now       func() time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobStore creates a job store that keeps up to retention finished jobs
+func NewJobStore(retention int) *JobStore {
+	if retention <= 0 {
+		retention = defaultJobRetention
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobStore{
+		jobs:      make(map[string]*Job),
+		active:    make(map[string]string),
+		retention: retention,
+		now:       time.Now,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Submit starts fn in the background and returns the queued job.
+// Jobs outlive the request that submitted them; Shutdown cancels them.
+func (s *JobStore) Submit(kind, target string, fn JobFunc) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, busy := s.active[target]; busy {
+		return Job{}, &JobConflictError{Job: *s.jobs[id]}
+	}
+	if s.closing {
+		return Job{}, fmt.Errorf("server is shutting down")
+	}
+
+	job := &Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		Target:    target,
+		Status:    JobQueued,
+		CreatedAt: s.now(),
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.active[target] = job.ID
+	s.prune()
+
+	s.wg.Add(1)
+	go s.run(job.ID, fn)
+	return *job, nil
+}
+
+func (s *JobStore) run(id string, fn JobFunc) {
+	defer s.wg.Done()
+
+	s.update(id, func(job *Job) {
+		started := s.now()
+		job.Status = JobRunning
+		job.StartedAt = &started
+	})
+
+	result, err := fn(s.ctx, func(percent float64) {
+		s.update(id, func(job *Job) { job.Progress = percent })
+	})
+
+	s.update(id, func(job *Job) {
+		finished := s.now()
+		job.FinishedAt = &finished
+		job.Result = result
+		job.Status = JobSucceeded
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+		delete(s.active, job.Target)
+	})
+}
+
+func (s *JobStore) update(id string, fn func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+// prune drops the oldest finished jobs beyond the retention limit; running jobs are kept
+func (s *JobStore) prune() {
+	finished := 0
+	for _, id := range s.order {
+		if s.jobs[id].Status.Done() {
+			finished++
+		}
+	}
+
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if finished > s.retention && s.jobs[id].Status.Done() {
+			delete(s.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// Get returns a copy of the job with the given ID
+func (s *JobStore) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns all retained jobs, newest first
+func (s *JobStore) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *s.jobs[s.order[i]])
+	}
+	return jobs
+}
+
+// Shutdown stops accepting jobs, lets running jobs finish for up to grace and
+// then cancels them; it returns once all jobs have returned
+func (s *JobStore) Shutdown(grace time.Duration) {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		s.cancel()
+		<-done
+	}
+	s.cancel()
+}
+
+func newJobID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
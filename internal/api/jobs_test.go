@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForJob polls the store until the job has finished
+func waitForJob(t *testing.T, store *JobStore, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := store.Get(id); ok && job.Status.Done() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestJobStore_RunsJobsAndRejectsConcurrentTarget(t *testing.T) {
+	store := NewJobStore(10)
+	release := make(chan struct{})
+
+	first, err := store.Submit("service.start", "service:ollama", func(_ context.Context, progress func(float64)) (interface{}, error) {
+		progress(50)
+		<-release
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conflict *JobConflictError
+	_, err = store.Submit("service.stop", "service:ollama", func(context.Context, func(float64)) (interface{}, error) {
+		return nil, nil
+	})
+	if !errors.As(err, &conflict) || conflict.Job.ID != first.ID {
+		t.Fatalf("Submit() on busy target error = %v, want conflict with %s", err, first.ID)
+	}
+
+	other, err := store.Submit("service.start", "service:localai", func(context.Context, func(float64)) (interface{}, error) {
+		return nil, errors.New("port taken")
+	})
+	if err != nil {
+		t.Fatalf("Submit() on another target error = %v", err)
+	}
+
+	close(release)
+	done := waitForJob(t, store, first.ID)
+	if done.Status != JobSucceeded || done.Result != "done" || done.Progress != 50 || done.StartedAt == nil || done.FinishedAt == nil {
+		t.Errorf("first job = %+v", done)
+	}
+	failed := waitForJob(t, store, other.ID)
+	if failed.Status != JobFailed || failed.Error != "port taken" {
+		t.Errorf("failed job = %+v", failed)
+	}
+
+	if _, err := store.Submit("service.stop", "service:ollama", func(context.Context, func(float64)) (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Errorf("Submit() after the job finished error = %v", err)
+	}
+	if jobs := store.List(); len(jobs) != 3 || jobs[2].ID != first.ID {
+		t.Errorf("List() = %+v, want 3 jobs, oldest last", jobs)
+	}
+}
+
+func TestJobStore_PrunesOldestFinishedJobs(t *testing.T) {
+	store := NewJobStore(2)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		job, err := store.Submit("models.download", "model:"+string(rune('a'+i)), func(context.Context, func(float64)) (interface{}, error) {
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitForJob(t, store, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	if _, ok := store.Get(ids[0]); ok {
+		t.Error("Expected the oldest finished job to be pruned")
+	}
+	if _, ok := store.Get(ids[3]); !ok {
+		t.Error("Expected the newest job to be kept")
+	}
+}
+
+func TestJobStore_ShutdownCancelsAfterGrace(t *testing.T) {
+	store := NewJobStore(10)
+	job, err := store.Submit("service.update", "service:ollama", func(ctx context.Context, _ func(float64)) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Shutdown(10 * time.Millisecond)
+
+	if got, _ := store.Get(job.ID); got.Status != JobFailed {
+		t.Errorf("job after shutdown = %+v, want failed", got)
+	}
+	if _, err := store.Submit("service.start", "service:ollama", func(context.Context, func(float64)) (interface{}, error) {
+		return nil, nil
+	}); err == nil {
+		t.Error("Expected Submit() to fail after Shutdown()")
+	}
+}
@@ -0,0 +1,491 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aistack/internal/logging"
+	"aistack/internal/models"
+	"aistack/internal/services"
+)
+
+const (
+	// shutdownTimeout bounds how long a stop waits for in-flight requests
+	shutdownTimeout = 5 * time.Second
+	// jobGracePeriod is how long running jobs may finish before a stop cancels them
+	jobGracePeriod = 60 * time.Second
+	// maxBodyBytes limits request bodies; the API only takes small JSON documents
+	maxBodyBytes = 64 << 10
+)
+
+// serviceActions are the service operations run as jobs
+var serviceActions = map[string]bool{"start": true, "stop": true, "update": true, "repair": true, "rollback": true}
+
+// Server exposes the aistack managers over HTTP. Services is required; a
+// provider missing from Models or a nil Suspend answers with 503.
+type Server struct {
+	Services ServiceManager
+	Models   map[models.Provider]ModelManager
+	Suspend  SuspendController
+	Version  string // aistack version reported by GET /v1/version
+	Logger   *logging.Logger
+
+	jobsOnce sync.Once
+	jobs     *JobStore
+}
+
+// Jobs returns the job store of the server
+func (s *Server) Jobs() *JobStore {
+	s.jobsOnce.Do(func() {
+		s.jobs = NewJobStore(defaultJobRetention)
+	})
+	return s.jobs
+}
+
+// Handler returns the routes of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	prefix := "/" + Version
+
+	mux.HandleFunc("GET "+prefix+"/version", s.handleVersion)
+
+	mux.HandleFunc("GET "+prefix+"/services", s.handleServices)
+	mux.HandleFunc("GET "+prefix+"/services/{name}", s.handleService)
+	mux.HandleFunc("GET "+prefix+"/services/{name}/history", s.handleServiceHistory)
+	mux.HandleFunc("POST "+prefix+"/services/{name}/{action}", s.handleServiceAction)
+
+	mux.HandleFunc("GET "+prefix+"/backend", s.handleBackend)
+	mux.HandleFunc("POST "+prefix+"/backend", s.handleBackendSwitch)
+
+	mux.HandleFunc("GET "+prefix+"/models/{provider}", s.handleModels)
+	mux.HandleFunc("GET "+prefix+"/models/{provider}/stats", s.handleModelStats)
+	mux.HandleFunc("POST "+prefix+"/models/{provider}/download", s.handleModelDownload)
+	mux.HandleFunc("POST "+prefix+"/models/{provider}/evict-oldest", s.handleModelEvict)
+	mux.HandleFunc("DELETE "+prefix+"/models/{provider}/{name...}", s.handleModelDelete)
+
+	mux.HandleFunc("GET "+prefix+"/suspend", s.handleSuspend)
+	mux.HandleFunc("POST "+prefix+"/suspend/{action}", s.handleSuspendToggle)
+
+	mux.HandleFunc("GET "+prefix+"/jobs", s.handleJobs)
+	mux.HandleFunc("GET "+prefix+"/jobs/{id}", s.handleJob)
+
+	return mux
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"api": Version, "version": s.Version})
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	statuses, err := s.Services.StatusAll(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (s *Server) handleService(w http.ResponseWriter, r *http.Request) {
+	service, err := s.Services.GetService(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	status, err := service.Status(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleServiceHistory(w http.ResponseWriter, r *http.Request) {
+	entries, err := s.Services.UpdateHistory(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if entries == nil {
+		entries = []services.UpdateLedgerEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) handleServiceAction(w http.ResponseWriter, r *http.Request) {
+	name, action := r.PathValue("name"), r.PathValue("action")
+	if !serviceActions[action] {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown service action: %s", action))
+		return
+	}
+	if _, err := s.Services.GetService(name); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	withDeps := r.URL.Query().Get("with_deps") == "true"
+	rollbackTo := 0
+	if to := r.URL.Query().Get("to"); to != "" {
+		var err error
+		if rollbackTo, err = strconv.Atoi(to); err != nil || rollbackTo < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid update history entry: %s", to))
+			return
+		}
+	}
+
+	s.submit(w, "service."+action, "service:"+name, func(ctx context.Context, _ func(float64)) (interface{}, error) {
+		switch action {
+		case "start":
+			if withDeps {
+				chain, err := s.Services.StartWithDependencies(ctx, name)
+				return map[string][]string{"services": chain}, err
+			}
+			return nil, s.Services.StartService(ctx, name)
+		case "stop":
+			if withDeps {
+				order, err := s.Services.StopWithDependents(ctx, name)
+				return map[string][]string{"services": order}, err
+			}
+			return nil, s.Services.StopService(ctx, name)
+		case "update":
+			return nil, s.Services.UpdateService(ctx, name)
+		case "rollback":
+			return s.Services.RollbackService(ctx, name, rollbackTo)
+		default:
+			result, err := s.Services.RepairService(ctx, name)
+			if err == nil && !result.Success {
+				err = fmt.Errorf("repair completed but service is not healthy: %s", result.ErrorMessage)
+			}
+			return result, err
+		}
+	})
+}
+
+// backendSwitcher returns the Open WebUI service, which owns the backend binding
+func (s *Server) backendSwitcher() (BackendSwitcher, error) {
+	service, err := s.Services.GetService("openwebui")
+	if err != nil {
+		return nil, err
+	}
+	switcher, ok := service.(BackendSwitcher)
+	if !ok {
+		return nil, fmt.Errorf("service openwebui does not support backend switching")
+	}
+	return switcher, nil
+}
+
+func (s *Server) handleBackend(w http.ResponseWriter, _ *http.Request) {
+	switcher, err := s.backendSwitcher()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	current, err := switcher.GetCurrentBackend()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, BackendStatus{Backend: current})
+}
+
+func (s *Server) handleBackendSwitch(w http.ResponseWriter, r *http.Request) {
+	var req BackendStatus
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Backend != services.BackendOllama && req.Backend != services.BackendLocalAI {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid backend %q (valid: ollama, localai)", req.Backend))
+		return
+	}
+	switcher, err := s.backendSwitcher()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.submit(w, "backend.switch", "service:openwebui", func(ctx context.Context, _ func(float64)) (interface{}, error) {
+		return BackendStatus{Backend: req.Backend}, switcher.SwitchBackend(ctx, req.Backend)
+	})
+}
+
+// modelManager resolves the {provider} path value
+func (s *Server) modelManager(w http.ResponseWriter, r *http.Request) (models.Provider, ModelManager, bool) {
+	provider := models.Provider(strings.ToLower(r.PathValue("provider")))
+	if !provider.IsValid() {
+		writeError(w, http.StatusNotFound, fmt.Errorf("invalid provider: %s (must be ollama or localai)", provider))
+		return provider, nil, false
+	}
+	manager, ok := s.Models[provider]
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("model management for %s is not available", provider))
+		return provider, nil, false
+	}
+	return provider, manager, true
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	provider, manager, ok := s.modelManager(w, r)
+	if !ok {
+		return
+	}
+	if err := manager.SyncState(); err != nil {
+		s.Logger.Warn("api.models.sync_failed", "Failed to sync model state", map[string]interface{}{
+			"provider": provider,
+			"error":    err.Error(),
+		})
+	}
+	list, err := manager.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if list == nil {
+		list = []models.ModelInfo{}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleModelStats(w http.ResponseWriter, r *http.Request) {
+	_, manager, ok := s.modelManager(w, r)
+	if !ok {
+		return
+	}
+	stats, err := manager.GetStats()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleModelDownload(w http.ResponseWriter, r *http.Request) {
+	provider, manager, ok := s.modelManager(w, r)
+	if !ok {
+		return
+	}
+	downloader, ok := manager.(ModelDownloader)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("model download is not supported for %s", provider))
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, errors.New("model name is required"))
+		return
+	}
+
+	s.submit(w, "models.download", fmt.Sprintf("model:%s/%s", provider, req.Name), func(_ context.Context, progress func(float64)) (interface{}, error) {
+		progressChan := make(chan models.DownloadProgress, 10)
+		drained := make(chan struct{})
+		go func() {
+			defer close(drained)
+			for event := range progressChan {
+				if event.Percentage > 0 {
+					progress(event.Percentage)
+				}
+			}
+		}()
+		err := downloader.Download(req.Name, progressChan)
+		close(progressChan)
+		<-drained
+		return map[string]string{"model": req.Name}, err
+	})
+}
+
+func (s *Server) handleModelDelete(w http.ResponseWriter, r *http.Request) {
+	_, manager, ok := s.modelManager(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	if err := manager.Delete(name); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"deleted": name})
+}
+
+func (s *Server) handleModelEvict(w http.ResponseWriter, r *http.Request) {
+	_, manager, ok := s.modelManager(w, r)
+	if !ok {
+		return
+	}
+	evicted, err := manager.EvictOldest()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, evicted)
+}
+
+func (s *Server) suspendStatus() (SuspendStatus, error) {
+	state, err := s.Suspend.LoadState()
+	if err != nil {
+		return SuspendStatus{}, err
+	}
+	return SuspendStatus{
+		Enabled:     state.Enabled,
+		LastActive:  time.Unix(state.LastActiveTimestamp, 0).UTC(),
+		IdleSeconds: s.Suspend.GetIdleDuration(state).Seconds(),
+	}, nil
+}
+
+func (s *Server) handleSuspend(w http.ResponseWriter, _ *http.Request) {
+	if s.Suspend == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("suspend management is not available"))
+		return
+	}
+	status, err := s.suspendStatus()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleSuspendToggle(w http.ResponseWriter, r *http.Request) {
+	if s.Suspend == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("suspend management is not available"))
+		return
+	}
+	var err error
+	switch action := r.PathValue("action"); action {
+	case "enable":
+		err = s.Suspend.Enable()
+	case "disable":
+		err = s.Suspend.Disable()
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown suspend action: %s", action))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.handleSuspend(w, r)
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.Jobs().List())
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.Jobs().Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown job: %s", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// submit starts a job and answers 202 Accepted with its location, or 409 when
+// a job for the same target is still running
+func (s *Server) submit(w http.ResponseWriter, kind, target string, fn JobFunc) {
+	job, err := s.Jobs().Submit(kind, target, func(ctx context.Context, progress func(float64)) (interface{}, error) {
+		result, err := fn(ctx, progress)
+		fields := map[string]interface{}{"kind": kind, "target": target}
+		if err != nil {
+			fields["error"] = err.Error()
+			s.Logger.Warn("api.job.failed", "Job failed", fields)
+		} else {
+			s.Logger.Info("api.job.succeeded", "Job succeeded", fields)
+		}
+		return result, err
+	})
+
+	var conflict *JobConflictError
+	switch {
+	case errors.As(err, &conflict):
+		writeJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error(), Job: &conflict.Job})
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	s.Logger.Info("api.job.submitted", "Job submitted", map[string]interface{}{
+		"id":     job.ID,
+		"kind":   kind,
+		"target": target,
+	})
+	w.Header().Set("Location", "/"+Version+"/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// Serve exposes the API on the unix socket at socketPath until ctx is cancelled;
+// running jobs get jobGracePeriod to finish before they are cancelled
+func Serve(ctx context.Context, socketPath, group string, server *Server) error {
+	listener, err := Listen(socketPath, group, server.Logger)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, listener, server)
+}
+
+func serve(ctx context.Context, listener net.Listener, server *Server) error {
+	httpServer := &http.Server{
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+
+	server.Logger.Info("api.serve.started", "Serving control API", map[string]interface{}{
+		"address": listener.Addr().String(),
+		"version": Version,
+	})
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("api server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	shutdownErr := httpServer.Shutdown(shutdownCtx)
+	server.Jobs().Shutdown(jobGracePeriod)
+	if shutdownErr != nil {
+		return fmt.Errorf("failed to stop api server: %w", shutdownErr)
+	}
+
+	server.Logger.Info("api.serve.stopped", "Control API stopped", nil)
+	return nil
+}
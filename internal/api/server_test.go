@@ -0,0 +1,400 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aistack/internal/logging"
+	"aistack/internal/models"
+	"aistack/internal/services"
+	"aistack/internal/suspend"
+)
+
+type fakeService struct {
+	name    string
+	mu      sync.Mutex
+	started bool
+	backend services.BackendType
+}
+
+func (f *fakeService) Name() string                       { return f.name }
+func (f *fakeService) Install(context.Context) error      { return nil }
+func (f *fakeService) Stop(context.Context) error         { return nil }
+func (f *fakeService) Remove(context.Context, bool) error { return nil }
+func (f *fakeService) Update(context.Context) error       { return nil }
+func (f *fakeService) Logs(context.Context, int) (string, error) {
+	return "", nil
+}
+func (f *fakeService) Health(context.Context) (services.HealthStatus, error) {
+	return services.HealthGreen, nil
+}
+
+func (f *fakeService) Start(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.started = true
+	return nil
+}
+
+func (f *fakeService) Status(context.Context) (services.ServiceStatus, error) {
+	return services.ServiceStatus{Name: f.name, State: "running", Health: services.HealthGreen}, nil
+}
+
+func (f *fakeService) GetCurrentBackend() (services.BackendType, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.backend, nil
+}
+
+func (f *fakeService) SwitchBackend(_ context.Context, backend services.BackendType) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.backend = backend
+	return nil
+}
+
+type fakeManager struct {
+	services map[string]*fakeService
+	repair   services.RepairResult
+}
+
+func (f *fakeManager) GetService(name string) (services.Service, error) {
+	service, ok := f.services[name]
+	if !ok {
+		return nil, errors.New("unknown service: " + name)
+	}
+	return service, nil
+}
+
+func (f *fakeManager) StatusAll(ctx context.Context) ([]services.ServiceStatus, error) {
+	var statuses []services.ServiceStatus
+	for _, name := range []string{"ollama", "openwebui"} {
+		status, _ := f.services[name].Status(ctx)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (f *fakeManager) StartService(ctx context.Context, name string) error {
+	return f.services[name].Start(ctx)
+}
+
+func (f *fakeManager) StopService(ctx context.Context, name string) error {
+	return f.services[name].Stop(ctx)
+}
+
+func (f *fakeManager) StartWithDependencies(context.Context, string) ([]string, error) {
+	return []string{"ollama", "openwebui"}, nil
+}
+
+func (f *fakeManager) StopWithDependents(context.Context, string) ([]string, error) {
+	return []string{"openwebui", "ollama"}, nil
+}
+
+func (f *fakeManager) UpdateService(context.Context, string) error {
+	return errors.New("updates are disabled")
+}
+
+func (f *fakeManager) UpdateHistory(name string) ([]services.UpdateLedgerEntry, error) {
+	if _, err := f.GetService(name); err != nil {
+		return nil, err
+	}
+	return []services.UpdateLedgerEntry{{ID: 1, Action: "update", Status: "completed"}}, nil
+}
+
+func (f *fakeManager) RollbackService(_ context.Context, name string, entryID int) (*services.UpdatePlan, error) {
+	return &services.UpdatePlan{ServiceName: name, Action: "rollback", NewImageID: "sha256:entry" + strconv.Itoa(entryID)}, nil
+}
+
+func (f *fakeManager) RepairService(_ context.Context, name string) (services.RepairResult, error) {
+	result := f.repair
+	result.ServiceName = name
+	return result, nil
+}
+
+type fakeModels struct {
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (f *fakeModels) SyncState() error { return nil }
+func (f *fakeModels) List() ([]models.ModelInfo, error) {
+	return []models.ModelInfo{{Name: "llama3:8b", Size: 42}}, nil
+}
+func (f *fakeModels) GetStats() (*models.CacheStats, error) {
+	return &models.CacheStats{Provider: models.ProviderOllama, TotalSize: 42, ModelCount: 1}, nil
+}
+func (f *fakeModels) EvictOldest() (*models.ModelInfo, error) {
+	return &models.ModelInfo{Name: "llama3:8b"}, nil
+}
+
+func (f *fakeModels) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+
+func (f *fakeModels) Download(name string, progress chan<- models.DownloadProgress) error {
+	progress <- models.DownloadProgress{ModelName: name, Status: "started"}
+	progress <- models.DownloadProgress{ModelName: name, Status: "progress", Percentage: 100}
+	return nil
+}
+
+type fakeSuspend struct{ state suspend.State }
+
+func (f *fakeSuspend) LoadState() (*suspend.State, error) { state := f.state; return &state, nil }
+func (f *fakeSuspend) Enable() error                      { f.state.Enabled = true; return nil }
+func (f *fakeSuspend) Disable() error                     { f.state.Enabled = false; return nil }
+func (f *fakeSuspend) GetIdleDuration(*suspend.State) time.Duration {
+	return 30 * time.Second
+}
+
+func testServer() (*Server, *fakeManager) {
+	manager := &fakeManager{services: map[string]*fakeService{
+		"ollama":    {name: "ollama"},
+		"openwebui": {name: "openwebui", backend: services.BackendOllama},
+	}}
+	return &Server{
+		Services: manager,
+		Models:   map[models.Provider]ModelManager{models.ProviderOllama: &fakeModels{}},
+		Suspend:  &fakeSuspend{},
+		Version:  "test",
+		Logger:   logging.NewLogger(logging.LevelError),
+	}, manager
+}
+
+func request(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+// submitAndWait posts a job request and returns the finished job
+func submitAndWait(t *testing.T, server *Server, path, body string) Job {
+	t.Helper()
+	recorder := request(t, server.Handler(), http.MethodPost, path, body)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("POST %s = %d %s", path, recorder.Code, recorder.Body.String())
+	}
+	var job Job
+	if err := json.Unmarshal(recorder.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if location := recorder.Header().Get("Location"); location != "/v1/jobs/"+job.ID {
+		t.Errorf("Location = %q", location)
+	}
+	return waitForJob(t, server.Jobs(), job.ID)
+}
+
+func TestServer_Services(t *testing.T) {
+	server, _ := testServer()
+	handler := server.Handler()
+
+	recorder := request(t, handler, http.MethodGet, "/v1/services", "")
+	var statuses []services.ServiceStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &statuses); err != nil || len(statuses) != 2 {
+		t.Fatalf("GET /v1/services = %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	if recorder := request(t, handler, http.MethodGet, "/v1/services/nope", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("GET unknown service = %d, want 404", recorder.Code)
+	}
+
+	recorder = request(t, handler, http.MethodGet, "/v1/services/ollama/history", "")
+	var entries []services.UpdateLedgerEntry
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entries); err != nil || len(entries) != 1 || entries[0].ID != 1 {
+		t.Errorf("GET history = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := request(t, handler, http.MethodGet, "/v1/services/nope/history", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("GET unknown service history = %d, want 404", recorder.Code)
+	}
+	if recorder := request(t, handler, http.MethodPost, "/v1/services/ollama/explode", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("POST unknown action = %d, want 404", recorder.Code)
+	}
+	if recorder := request(t, handler, http.MethodDelete, "/v1/services", ""); recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE /v1/services = %d, want 405", recorder.Code)
+	}
+}
+
+func TestServer_ServiceActionsRunAsJobs(t *testing.T) {
+	server, manager := testServer()
+
+	job := submitAndWait(t, server, "/v1/services/ollama/start", "")
+	if job.Status != JobSucceeded || job.Kind != "service.start" || job.Target != "service:ollama" || !manager.services["ollama"].started {
+		t.Errorf("start job = %+v", job)
+	}
+
+	job = submitAndWait(t, server, "/v1/services/ollama/start?with_deps=true", "")
+	if result, _ := job.Result.(map[string][]string); len(result["services"]) != 2 {
+		t.Errorf("start --with-deps result = %#v", job.Result)
+	}
+
+	job = submitAndWait(t, server, "/v1/services/ollama/update", "")
+	if job.Status != JobFailed || job.Error != "updates are disabled" {
+		t.Errorf("update job = %+v", job)
+	}
+
+	job = submitAndWait(t, server, "/v1/services/ollama/rollback?to=3", "")
+	if plan, _ := job.Result.(*services.UpdatePlan); job.Status != JobSucceeded || plan == nil || plan.NewImageID != "sha256:entry3" {
+		t.Errorf("rollback job = %+v", job)
+	}
+	if recorder := request(t, server.Handler(), http.MethodPost, "/v1/services/ollama/rollback?to=latest", ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("POST rollback?to=latest = %d, want 400", recorder.Code)
+	}
+
+	manager.repair = services.RepairResult{Success: false, ErrorMessage: "still red"}
+	job = submitAndWait(t, server, "/v1/services/ollama/repair", "")
+	if job.Status != JobFailed || !strings.Contains(job.Error, "still red") {
+		t.Errorf("repair job = %+v", job)
+	}
+}
+
+func TestServer_BackendSwitch(t *testing.T) {
+	server, manager := testServer()
+	handler := server.Handler()
+
+	if recorder := request(t, handler, http.MethodPost, "/v1/backend", `{"backend":"vllm"}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("POST invalid backend = %d, want 400", recorder.Code)
+	}
+	if recorder := request(t, handler, http.MethodPost, "/v1/backend", `{"backend":"localai","force":true}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("POST unknown field = %d, want 400", recorder.Code)
+	}
+
+	job := submitAndWait(t, server, "/v1/backend", `{"backend":"localai"}`)
+	if job.Status != JobSucceeded || manager.services["openwebui"].backend != services.BackendLocalAI {
+		t.Errorf("switch job = %+v", job)
+	}
+
+	recorder := request(t, handler, http.MethodGet, "/v1/backend", "")
+	if !strings.Contains(recorder.Body.String(), `"backend":"localai"`) {
+		t.Errorf("GET /v1/backend = %s", recorder.Body.String())
+	}
+}
+
+func TestServer_Models(t *testing.T) {
+	server, _ := testServer()
+	handler := server.Handler()
+	cache := server.Models[models.ProviderOllama].(*fakeModels)
+
+	if recorder := request(t, handler, http.MethodGet, "/v1/models/ollama", ""); !strings.Contains(recorder.Body.String(), "llama3:8b") {
+		t.Errorf("GET /v1/models/ollama = %s", recorder.Body.String())
+	}
+	if recorder := request(t, handler, http.MethodGet, "/v1/models/localai/stats", ""); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET stats for an unconfigured provider = %d, want 503", recorder.Code)
+	}
+	if recorder := request(t, handler, http.MethodGet, "/v1/models/gguf", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("GET invalid provider = %d, want 404", recorder.Code)
+	}
+
+	recorder := request(t, handler, http.MethodDelete, "/v1/models/ollama/library/llama3:8b", "")
+	if recorder.Code != http.StatusOK || len(cache.deleted) != 1 || cache.deleted[0] != "library/llama3:8b" {
+		t.Errorf("DELETE model = %d, deleted %v", recorder.Code, cache.deleted)
+	}
+
+	job := submitAndWait(t, server, "/v1/models/ollama/download", `{"name":"qwen2:7b"}`)
+	if job.Status != JobSucceeded || job.Progress != 100 || job.Target != "model:ollama/qwen2:7b" {
+		t.Errorf("download job = %+v", job)
+	}
+}
+
+func TestServer_Suspend(t *testing.T) {
+	server, _ := testServer()
+	handler := server.Handler()
+
+	recorder := request(t, handler, http.MethodPost, "/v1/suspend/enable", "")
+	var status SuspendStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil || !status.Enabled || status.IdleSeconds != 30 {
+		t.Errorf("POST /v1/suspend/enable = %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	server.Suspend = nil
+	if recorder := request(t, server.Handler(), http.MethodGet, "/v1/suspend", ""); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /v1/suspend without manager = %d, want 503", recorder.Code)
+	}
+}
+
+func TestServeAndClient_OverUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "aistack.sock")
+	server, _ := testServer()
+
+	// A leftover socket file from a crashed server is replaced
+	stale, err := Listen(socketPath, "", server.Logger)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	listener, err := Listen(socketPath, "aistack-no-such-group", server.Logger)
+	if err != nil {
+		t.Fatalf("Listen() over stale socket error = %v", err)
+	}
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %o, want 600 when the group is missing", perm)
+	}
+
+	if _, err := Listen(socketPath, "", server.Logger); err == nil || !strings.Contains(err.Error(), "another aistack api server") {
+		t.Errorf("Listen() on a live socket error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, listener, server) }()
+
+	client := NewClient(socketPath)
+	client.PollInterval = 10 * time.Millisecond
+
+	statuses, err := client.Services(ctx)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Services() = %v, %v", statuses, err)
+	}
+	job, err := client.ServiceAction(ctx, "ollama", "stop", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err = client.WaitJob(ctx, job.ID); err != nil || job.Status != JobSucceeded {
+		t.Errorf("WaitJob() = %+v, %v", job, err)
+	}
+
+	var apiErr *Error
+	if _, err := client.ServiceAction(ctx, "nope", "start", false); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("ServiceAction(unknown) error = %v, want 404", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve() did not stop after cancel")
+	}
+}
+
+func TestListen_RefusesNonSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aistack.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(path, "", logging.NewLogger(logging.LevelError)); err == nil {
+		t.Error("Expected Listen() to refuse replacing a regular file")
+	}
+}
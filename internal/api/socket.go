@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+// Listen opens the API socket at path. A stale socket left by a crashed server
+// is replaced; a socket another server still answers on is an error. The socket
+// is mode 0660 and owned by group; if the group does not exist it stays 0600
+// (root only).
+func Listen(path, group string, logger *logging.Logger) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	if err := restrictSocket(path, group, logger); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, dialErr := net.DialTimeout("unix", path, time.Second); dialErr == nil {
+		_ = conn.Close()
+		return fmt.Errorf("another aistack api server is listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+	return nil
+}
+
+func restrictSocket(path, group string, logger *logging.Logger) error {
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	if group == "" {
+		return nil
+	}
+
+	gid, err := lookupGID(group)
+	if err != nil {
+		logger.Warn("api.socket.group_missing", "Socket group not found, access limited to root", map[string]interface{}{
+			"group": group,
+			"error": err.Error(),
+		})
+		return nil
+	}
+	if err := os.Chown(path, -1, gid); err != nil {
+		return fmt.Errorf("failed to set socket group %s: %w", group, err)
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return nil
+}
+
+func lookupGID(group string) (int, error) {
+	entry, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	gid, err := strconv.Atoi(entry.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gid %q for group %s", entry.Gid, group)
+	}
+	return gid, nil
+}
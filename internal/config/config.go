@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 
 	"aistack/internal/configdir"
+	"aistack/internal/fsutil"
 )
 
 const (
@@ -141,6 +143,153 @@ func mergeConfig(dst, src *Config) {
 	if src.Updates.Mode != "" {
 		dst.Updates.Mode = src.Updates.Mode
 	}
+	if src.Updates.Strategy != "" {
+		dst.Updates.Strategy = src.Updates.Strategy
+	}
+	if src.Updates.Schedule != "" {
+		dst.Updates.Schedule = src.Updates.Schedule
+	}
+	if src.Updates.Timezone != "" {
+		dst.Updates.Timezone = src.Updates.Timezone
+	}
+	if src.Updates.Window.Start != "" || src.Updates.Window.End != "" || src.Updates.Window.Days != nil {
+		dst.Updates.Window = src.Updates.Window
+	}
+	if src.Updates.Registries.Mirrors != nil {
+		dst.Updates.Registries.Mirrors = src.Updates.Registries.Mirrors
+	}
+	if src.Updates.Registries.Insecure != nil {
+		dst.Updates.Registries.Insecure = src.Updates.Registries.Insecure
+	}
+
+	// Merge timeouts config
+	if src.Timeouts.ComposeSeconds != 0 {
+		dst.Timeouts.ComposeSeconds = src.Timeouts.ComposeSeconds
+	}
+	if src.Timeouts.PullSeconds != 0 {
+		dst.Timeouts.PullSeconds = src.Timeouts.PullSeconds
+	}
+	if src.Timeouts.InspectSeconds != 0 {
+		dst.Timeouts.InspectSeconds = src.Timeouts.InspectSeconds
+	}
+	if src.Timeouts.HealthCheckSeconds != 0 {
+		dst.Timeouts.HealthCheckSeconds = src.Timeouts.HealthCheckSeconds
+	}
+	if src.Timeouts.StartupWaitSeconds != 0 {
+		dst.Timeouts.StartupWaitSeconds = src.Timeouts.StartupWaitSeconds
+	}
+	if src.Timeouts.DependencyWaitSeconds != 0 {
+		dst.Timeouts.DependencyWaitSeconds = src.Timeouts.DependencyWaitSeconds
+	}
+	if src.Timeouts.StatusDeadlineSeconds != 0 {
+		dst.Timeouts.StatusDeadlineSeconds = src.Timeouts.StatusDeadlineSeconds
+	}
+	if src.Timeouts.HealthLatencyMs != 0 {
+		dst.Timeouts.HealthLatencyMs = src.Timeouts.HealthLatencyMs
+	}
+
+	// Merge agent config
+	if src.Agent.IntervalSeconds != 0 {
+		dst.Agent.IntervalSeconds = src.Agent.IntervalSeconds
+	}
+	if src.Agent.RedThreshold != 0 {
+		dst.Agent.RedThreshold = src.Agent.RedThreshold
+	}
+	if src.Agent.BackoffInitialSeconds != 0 {
+		dst.Agent.BackoffInitialSeconds = src.Agent.BackoffInitialSeconds
+	}
+	if src.Agent.BackoffMaxSeconds != 0 {
+		dst.Agent.BackoffMaxSeconds = src.Agent.BackoffMaxSeconds
+	}
+	if src.Agent.MaxRepairs != 0 {
+		dst.Agent.MaxRepairs = src.Agent.MaxRepairs
+	}
+	if src.Agent.WindowSeconds != 0 {
+		dst.Agent.WindowSeconds = src.Agent.WindowSeconds
+	}
+	if src.Agent.CooldownSeconds != 0 {
+		dst.Agent.CooldownSeconds = src.Agent.CooldownSeconds
+	}
+
+	// Merge health history config
+	if src.HealthHistory.MaxEntries != 0 {
+		dst.HealthHistory.MaxEntries = src.HealthHistory.MaxEntries
+	}
+	if src.HealthHistory.FlapTransitions != 0 {
+		dst.HealthHistory.FlapTransitions = src.HealthHistory.FlapTransitions
+	}
+	if src.HealthHistory.FlapWindowMinutes != 0 {
+		dst.HealthHistory.FlapWindowMinutes = src.HealthHistory.FlapWindowMinutes
+	}
+
+	// Merge notifications config (events and targets replace)
+	if src.Notifications.Events != nil {
+		dst.Notifications.Events = src.Notifications.Events
+	}
+	if src.Notifications.RetryAttempts != 0 {
+		dst.Notifications.RetryAttempts = src.Notifications.RetryAttempts
+	}
+	if src.Notifications.RetryBackoffSeconds != 0 {
+		dst.Notifications.RetryBackoffSeconds = src.Notifications.RetryBackoffSeconds
+	}
+	if src.Notifications.TimeoutSeconds != 0 {
+		dst.Notifications.TimeoutSeconds = src.Notifications.TimeoutSeconds
+	}
+	if src.Notifications.DeadLetterFile != "" {
+		dst.Notifications.DeadLetterFile = src.Notifications.DeadLetterFile
+	}
+	if src.Notifications.Targets != nil {
+		dst.Notifications.Targets = src.Notifications.Targets
+	}
+
+	// Merge network config
+	if src.Network.BindAddress != "" {
+		dst.Network.BindAddress = src.Network.BindAddress
+	}
+
+	// Merge GPU config
+	if src.GPU.Mode != "" {
+		dst.GPU.Mode = src.GPU.Mode
+	}
+	if src.GPU.Devices != nil {
+		dst.GPU.Devices = src.GPU.Devices
+	}
+
+	// Merge per-service settings (port, gpus and health replace, env merges by key)
+	for name, service := range src.Services {
+		if dst.Services == nil {
+			dst.Services = make(map[string]ServiceConfig)
+		}
+		merged := dst.Services[name]
+		if service.Port != 0 {
+			merged.Port = service.Port
+		}
+		if service.GPUs != nil {
+			merged.GPUs = service.GPUs
+		}
+		merged.Resources = merged.Resources.Merge(service.Resources)
+		if !service.Health.IsZero() {
+			merged.Health = service.Health
+		}
+		if service.UpdateStrategy != "" {
+			merged.UpdateStrategy = service.UpdateStrategy
+		}
+		for key, value := range service.Env {
+			if merged.Env == nil {
+				merged.Env = make(map[string]string)
+			}
+			merged.Env[key] = value
+		}
+		dst.Services[name] = merged
+	}
+
+	// Merge profiles (a later file replaces a profile of the same name)
+	for name, profile := range src.Profiles {
+		if dst.Profiles == nil {
+			dst.Profiles = make(map[string]ProfileConfig)
+		}
+		dst.Profiles[name] = profile
+	}
 }
 
 // formatValidationErrors formats validation errors for display
@@ -171,3 +320,61 @@ func UserConfigPath() string {
 	}
 	return filepath.Join(homeDir, userConfigDir, userConfigFile)
 }
+
+// SetServicePort sets services.<name>.port in the config file at path, keeping the
+// rest of the file (including comments) intact. The file is created if missing.
+func SetServicePort(path, service string, port int) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path is one of the known config locations
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse YAML: %w", err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s: top level is not a mapping", path)
+	}
+
+	entry := mappingChild(mappingChild(root, "services"), service)
+	portNode := mappingValue(entry, "port")
+	*portNode = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(port)}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return fsutil.AtomicWriteFile(path, out, 0o640, nil)
+}
+
+// mappingChild returns the mapping stored under key, creating it if needed
+func mappingChild(node *yaml.Node, key string) *yaml.Node {
+	value := mappingValue(node, key)
+	if value.Kind != yaml.MappingNode {
+		*value = yaml.Node{Kind: yaml.MappingNode}
+	}
+	return value
+}
+
+// mappingValue returns the value node for key, appending an empty one if missing
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
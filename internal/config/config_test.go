@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -182,6 +183,297 @@ func TestValidation_InvalidUpdatesMode(t *testing.T) {
 	}
 }
 
+func TestValidation_UpdateSchedule(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Updates.Schedule = "30 3 * * sun"
+	cfg.Updates.Timezone = "UTC"
+	cfg.Updates.Window = MaintenanceWindowConfig{Start: "23:00", End: "05:00", Days: []string{"sat", "sun"}}
+	if errors := cfg.Validate(); len(errors) != 0 {
+		t.Fatalf("Validate() returned errors for a valid schedule: %v", errors)
+	}
+
+	cfg.Updates.Schedule = "every night"
+	cfg.Updates.Timezone = "Mars/Olympus_Mons"
+	cfg.Updates.Window = MaintenanceWindowConfig{Start: "25:00", End: "05:00"}
+
+	errors := cfg.Validate()
+	paths := make([]string, 0, len(errors))
+	for _, err := range errors {
+		paths = append(paths, err.Path)
+	}
+	want := []string{"updates.schedule", "updates.timezone", "updates.window"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate() paths = %v, want %v", paths, want)
+	}
+}
+
+func TestValidation_UpdateStrategy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services = map[string]ServiceConfig{"openwebui": {UpdateStrategy: UpdateStrategyBlueGreen}}
+	if errors := cfg.Validate(); len(errors) != 0 {
+		t.Fatalf("Validate() returned errors for a valid strategy: %v", errors)
+	}
+
+	cfg.Updates.Strategy = "canary"
+	cfg.Services["ollama"] = ServiceConfig{UpdateStrategy: "rolling"}
+
+	errors := cfg.Validate()
+	paths := make([]string, 0, len(errors))
+	for _, err := range errors {
+		paths = append(paths, err.Path)
+	}
+	want := []string{"services.ollama.update_strategy", "updates.strategy"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate() paths = %v, want %v", paths, want)
+	}
+}
+
+func TestValidation_Registries(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Updates.Registries = RegistriesConfig{
+		Mirrors:  map[string][]string{"docker.io": {"https://mirror.gcr.io"}},
+		Insecure: []string{"registry.lan:5000"},
+	}
+	if errors := cfg.Validate(); len(errors) != 0 {
+		t.Fatalf("Validate() returned errors for valid registries: %v", errors)
+	}
+
+	cfg.Updates.Registries.Mirrors["docker.io"] = append(cfg.Updates.Registries.Mirrors["docker.io"], "mirror.lan")
+	cfg.Updates.Registries.Insecure = append(cfg.Updates.Registries.Insecure, "http://registry.lan")
+
+	errors := cfg.Validate()
+	paths := make([]string, 0, len(errors))
+	for _, err := range errors {
+		paths = append(paths, err.Path)
+	}
+	want := []string{"updates.registries.mirrors.docker.io[1]", "updates.registries.insecure[1]"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate() paths = %v, want %v", paths, want)
+	}
+}
+
+func TestValidation_InvalidTimeouts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Timeouts.PullSeconds = 0
+	cfg.Timeouts.StartupWaitSeconds = -1
+
+	errors := cfg.Validate()
+	if len(errors) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errors), errors)
+	}
+	if errors[0].Path != "timeouts.pull_seconds" {
+		t.Errorf("errors[0].Path = %s, want timeouts.pull_seconds", errors[0].Path)
+	}
+	if errors[1].Path != "timeouts.startup_wait_seconds" {
+		t.Errorf("errors[1].Path = %s, want timeouts.startup_wait_seconds", errors[1].Path)
+	}
+}
+
+func TestValidation_InvalidAgent(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agent.IntervalSeconds = 0
+	cfg.Agent.BackoffInitialSeconds = 600
+	cfg.Agent.BackoffMaxSeconds = 60
+
+	errors := cfg.Validate()
+	if len(errors) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errors), errors)
+	}
+	if errors[0].Path != "agent.interval_seconds" {
+		t.Errorf("errors[0].Path = %s, want agent.interval_seconds", errors[0].Path)
+	}
+	if errors[1].Path != "agent.backoff_max_seconds" {
+		t.Errorf("errors[1].Path = %s, want agent.backoff_max_seconds", errors[1].Path)
+	}
+}
+
+func TestValidation_InvalidHealthHistory(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.HealthHistory.MaxEntries = -5
+	cfg.HealthHistory.FlapWindowMinutes = 0
+
+	errors := cfg.Validate()
+	if len(errors) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errors), errors)
+	}
+	if errors[0].Path != "health_history.max_entries" || errors[1].Path != "health_history.flap_window_minutes" {
+		t.Errorf("errors = %v", errors)
+	}
+}
+
+func TestValidation_Notifications(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.Targets = []NotificationTarget{
+		{Name: "ops", Type: NotifyTypeSlack, URL: "https://hooks.slack.com/services/T/B/X"},
+		{Name: "phone", Type: NotifyTypeNtfy, URL: "https://ntfy.sh/aistack", Events: []string{"agent.*"}},
+	}
+	if errors := cfg.Validate(); len(errors) != 0 {
+		t.Fatalf("Validate() returned errors for valid targets: %v", errors)
+	}
+
+	cfg.Notifications.RetryAttempts = 0
+	cfg.Notifications.Events = []string{"suspend.executing", "agent*"}
+	cfg.Notifications.Targets = append(cfg.Notifications.Targets,
+		NotificationTarget{Name: "ops", Type: "email", URL: "ntfy.sh/aistack"},
+	)
+
+	errors := cfg.Validate()
+	paths := make([]string, 0, len(errors))
+	for _, err := range errors {
+		paths = append(paths, err.Path)
+	}
+	want := []string{
+		"notifications.retry_attempts",
+		"notifications.events[1]",
+		"notifications.targets[2].name",
+		"notifications.targets[2].type",
+		"notifications.targets[2].url",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Validate() paths = %v, want %v", paths, want)
+	}
+}
+
+func TestValidation_Profiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Profile = "lab"
+	cfg.Profiles = map[string]ProfileConfig{
+		"lab": {Services: []string{"ollama", "comfyui"}},
+		"dev": {Overrides: map[string]ServiceOverride{"ollama": {Image: "ollama/ollama:0.3.14"}}},
+	}
+
+	if errors := cfg.Validate(); len(errors) != 0 {
+		t.Fatalf("Validate() returned errors for valid profiles: %v", errors)
+	}
+
+	cfg.Profiles["empty"] = ProfileConfig{}
+	cfg.Profiles["bad"] = ProfileConfig{
+		Services:  []string{"Ollama"},
+		Overrides: map[string]ServiceOverride{"ollama": {HealthURL: "localhost:11434"}},
+	}
+
+	errors := cfg.Validate()
+	paths := make([]string, 0, len(errors))
+	for _, err := range errors {
+		paths = append(paths, err.Path)
+	}
+	want := []string{"profiles.bad.services[0]", "profiles.bad.overrides.ollama.health_url", "profiles.empty.services"}
+	if len(paths) != len(want) {
+		t.Fatalf("Validate() paths = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("errors[%d].Path = %s, want %s", i, paths[i], want[i])
+		}
+	}
+}
+
+func TestConfig_ProfileNames(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Profiles = map[string]ProfileConfig{
+		"lab": {Services: []string{"ollama"}},
+		"dev": {Description: "amended built-in"},
+	}
+
+	got := cfg.ProfileNames()
+	want := []string{"minimal", "standard-gpu", "dev", "lab"}
+	if len(got) != len(want) {
+		t.Fatalf("ProfileNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ProfileNames()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestValidation_NetworkAndServices(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Network.BindAddress = "localhost"
+	cfg.Services = map[string]ServiceConfig{
+		"ollama":    {Port: 70000, Env: map[string]string{"OLLAMA_KEEP_ALIVE": "5m"}},
+		"openwebui": {Env: map[string]string{"BAD-KEY": "x"}},
+	}
+
+	errors := cfg.Validate()
+	want := []string{"network.bind_address", "services.ollama.port", "services.openwebui.env.BAD-KEY"}
+	if len(errors) != len(want) {
+		t.Fatalf("Validate() returned %v, want paths %v", errors, want)
+	}
+	for i := range want {
+		if errors[i].Path != want[i] {
+			t.Errorf("errors[%d].Path = %s, want %s", i, errors[i].Path, want[i])
+		}
+	}
+}
+
+func TestMergeConfig_Services(t *testing.T) {
+	dst := DefaultConfig()
+	dst.Services = map[string]ServiceConfig{
+		"localai": {Port: 8081, Env: map[string]string{"THREADS": "4"}},
+	}
+	src := Config{
+		Network: NetworkConfig{BindAddress: "127.0.0.1"},
+		Services: map[string]ServiceConfig{
+			"localai": {Env: map[string]string{"CONTEXT_SIZE": "2048"}},
+		},
+	}
+
+	mergeConfig(&dst, &src)
+
+	if dst.Network.BindAddress != "127.0.0.1" {
+		t.Errorf("BindAddress = %s, want 127.0.0.1", dst.Network.BindAddress)
+	}
+	localai := dst.Services["localai"]
+	if localai.Port != 8081 {
+		t.Errorf("Port = %d, want 8081 (kept)", localai.Port)
+	}
+	if localai.Env["THREADS"] != "4" || localai.Env["CONTEXT_SIZE"] != "2048" {
+		t.Errorf("Env = %v, want merged THREADS and CONTEXT_SIZE", localai.Env)
+	}
+}
+
+func TestValidation_GPU(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GPU = GPUConfig{Mode: "always", Devices: []string{"GPU-1234", " "}}
+	cfg.Services = map[string]ServiceConfig{
+		"ollama": {GPUs: []string{""}},
+	}
+
+	errors := cfg.Validate()
+	want := []string{"services.ollama.gpus[0]", "gpu.mode", "gpu.devices[1]"}
+	if len(errors) != len(want) {
+		t.Fatalf("Validate() returned %v, want paths %v", errors, want)
+	}
+	for i := range want {
+		if errors[i].Path != want[i] {
+			t.Errorf("errors[%d].Path = %s, want %s", i, errors[i].Path, want[i])
+		}
+	}
+}
+
+func TestMergeConfig_GPU(t *testing.T) {
+	dst := DefaultConfig()
+	src := Config{
+		GPU: GPUConfig{Devices: []string{"0"}},
+		Services: map[string]ServiceConfig{
+			"localai": {GPUs: []string{"GPU-abcd"}},
+		},
+	}
+
+	mergeConfig(&dst, &src)
+
+	if dst.GPU.Mode != GPUModeAuto {
+		t.Errorf("Mode = %s, want default auto kept", dst.GPU.Mode)
+	}
+	if len(dst.GPU.Devices) != 1 || dst.GPU.Devices[0] != "0" {
+		t.Errorf("Devices = %v, want [0]", dst.GPU.Devices)
+	}
+	if gpus := dst.Services["localai"].GPUs; len(gpus) != 1 || gpus[0] != "GPU-abcd" {
+		t.Errorf("localai GPUs = %v", gpus)
+	}
+}
+
 func TestValidation_InvalidMACAddress(t *testing.T) {
 	tests := []struct {
 		name string
@@ -272,6 +564,41 @@ logging:
 	}
 }
 
+func TestLoadFrom_Profiles(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configContent := `
+profile: lab
+profiles:
+  lab:
+    description: Ollama with a pinned image
+    services: [ollama]
+    overrides:
+      ollama:
+        image: ollama/ollama:0.3.14
+        gpu_lock: true
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0o600); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	cfg, err := LoadFrom(configPath)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+
+	lab, ok := cfg.Profiles["lab"]
+	if !ok {
+		t.Fatal("Expected profile lab to be loaded")
+	}
+	override := lab.Overrides["ollama"]
+	if override.Image != "ollama/ollama:0.3.14" {
+		t.Errorf("Override image = %s, want ollama/ollama:0.3.14", override.Image)
+	}
+	if override.GPULock == nil || !*override.GPULock {
+		t.Error("Expected gpu_lock override to be set")
+	}
+}
+
 func TestLoadFrom_InvalidFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yaml")
@@ -329,6 +656,9 @@ func TestMergeConfig(t *testing.T) {
 		Logging: LoggingConfig{
 			Level: "warn",
 		},
+		Timeouts: TimeoutsConfig{
+			ComposeSeconds: 60,
+		},
 	}
 
 	mergeConfig(&dst, &src)
@@ -354,6 +684,12 @@ func TestMergeConfig(t *testing.T) {
 	if dst.Logging.Format != "json" {
 		t.Errorf("LogFormat = %s, want json (default)", dst.Logging.Format)
 	}
+	if dst.Timeouts.ComposeSeconds != 60 {
+		t.Errorf("Timeouts.ComposeSeconds = %d, want 60", dst.Timeouts.ComposeSeconds)
+	}
+	if dst.Timeouts.PullSeconds != 1800 {
+		t.Errorf("Timeouts.PullSeconds = %d, want 1800 (default)", dst.Timeouts.PullSeconds)
+	}
 }
 
 func TestSystemConfigPath(t *testing.T) {
@@ -420,3 +756,193 @@ func TestFormatValidationErrors_Empty(t *testing.T) {
 		t.Errorf("formatValidationErrors() = %s, want empty string", result)
 	}
 }
+
+func TestSetServicePort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	initial := `# system config
+profile: dev
+services:
+  ollama:
+    port: 11500 # custom
+`
+	if err := os.WriteFile(path, []byte(initial), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetServicePort(path, "openwebui", 3001); err != nil {
+		t.Fatalf("SetServicePort() error = %v", err)
+	}
+	if err := SetServicePort(path, "ollama", 11600); err != nil {
+		t.Fatalf("SetServicePort() error = %v", err)
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if cfg.Profile != "dev" {
+		t.Errorf("Profile = %s, want existing value kept", cfg.Profile)
+	}
+	if cfg.Services["openwebui"].Port != 3001 || cfg.Services["ollama"].Port != 11600 {
+		t.Errorf("Services = %+v", cfg.Services)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# system config") {
+		t.Errorf("Expected comments to be kept:\n%s", data)
+	}
+}
+
+func TestSetServicePort_CreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aistack", "config.yaml")
+
+	if err := SetServicePort(path, "localai", 8081); err != nil {
+		t.Fatalf("SetServicePort() error = %v", err)
+	}
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if cfg.Services["localai"].Port != 8081 {
+		t.Errorf("localai port = %d, want 8081", cfg.Services["localai"].Port)
+	}
+}
+
+func TestValidation_Resources(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services = map[string]ServiceConfig{
+		"localai": {Resources: ResourcesConfig{
+			CPUs:       -1,
+			Memory:     "8g",
+			MemorySwap: "4g",
+			ShmSize:    "lots",
+			PidsLimit:  -5,
+			Ulimits:    map[string]int64{"nofile": 65536, "files": 10, "nproc": 0},
+		}},
+		"ollama":    {Resources: ResourcesConfig{CPUs: 4, Memory: "16GiB", MemorySwap: "-1", ShmSize: "1g", PidsLimit: -1}},
+		"openwebui": {Resources: ResourcesConfig{MemorySwap: "2g"}},
+	}
+
+	errors := cfg.Validate()
+	want := []string{
+		"services.localai.resources.cpus",
+		"services.localai.resources.memory_swap",
+		"services.localai.resources.shm_size",
+		"services.localai.resources.pids_limit",
+		"services.localai.resources.ulimits.files",
+		"services.localai.resources.ulimits.nproc",
+		"services.openwebui.resources.memory_swap",
+	}
+	if len(errors) != len(want) {
+		t.Fatalf("Validate() returned %v, want paths %v", errors, want)
+	}
+	for i := range want {
+		if errors[i].Path != want[i] {
+			t.Errorf("errors[%d].Path = %s, want %s", i, errors[i].Path, want[i])
+		}
+	}
+}
+
+func TestValidation_HealthCheck(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Services = map[string]ServiceConfig{
+		"ollama": {Health: HealthCheckConfig{
+			Type:      HealthTypeAll,
+			LatencyMs: 3000,
+			Checks: []HealthCheckConfig{
+				{URL: "http://localhost:11434/api/version", JSONPath: "$.version", Regex: `^\d+\.`},
+				{Type: HealthTypeTCP, Address: "localhost:11434"},
+				{Type: HealthTypeExec, Command: []string{"ollama", "list"}},
+			},
+		}},
+	}
+	if errors := cfg.Validate(); len(errors) != 0 {
+		t.Fatalf("Validate() returned errors for a valid health check: %v", errors)
+	}
+
+	cfg.Services = map[string]ServiceConfig{
+		"ollama": {Health: HealthCheckConfig{
+			Type: HealthTypeAny,
+			Checks: []HealthCheckConfig{
+				{URL: "ftp://localhost", JSONPath: "version", Regex: "("},
+				{Type: HealthTypeTCP, Address: "localhost", LatencyMs: 100},
+				{Type: HealthTypeExec},
+				{Type: "grpc"},
+			},
+		}},
+		"localai": {Health: HealthCheckConfig{Type: HealthTypeAll, LatencyMs: -1}},
+	}
+
+	want := []string{
+		"services.localai.health.checks",
+		"services.localai.health.latency_ms",
+		"services.ollama.health.checks[0].url",
+		"services.ollama.health.checks[0].json_path",
+		"services.ollama.health.checks[0].regex",
+		"services.ollama.health.checks[1].latency_ms",
+		"services.ollama.health.checks[1].address",
+		"services.ollama.health.checks[2].command",
+		"services.ollama.health.checks[3].type",
+	}
+	errors := cfg.Validate()
+	if len(errors) != len(want) {
+		t.Fatalf("Validate() returned %d errors, want %d: %v", len(errors), len(want), errors)
+	}
+	for i, path := range want {
+		if errors[i].Path != path {
+			t.Errorf("errors[%d].Path = %s, want %s", i, errors[i].Path, path)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"1024", 1024},
+		{"512b", 512},
+		{"64k", 64 << 10},
+		{"512m", 512 << 20},
+		{"8g", 8 << 30},
+		{"8GB", 8 << 30},
+		{"2GiB", 2 << 30},
+		{"1.5g", 3 << 29},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "g", "8x", "-1g", "eight"} {
+		if _, err := ParseByteSize(invalid); err == nil {
+			t.Errorf("ParseByteSize(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestMergeConfig_Resources(t *testing.T) {
+	dst := DefaultConfig()
+	dst.Services = map[string]ServiceConfig{
+		"ollama": {Resources: ResourcesConfig{CPUs: 4, Memory: "16g", Ulimits: map[string]int64{"nofile": 1024}}},
+	}
+	src := Config{Services: map[string]ServiceConfig{
+		"ollama": {Resources: ResourcesConfig{Memory: "8g", Ulimits: map[string]int64{"memlock": -1}}},
+	}}
+
+	mergeConfig(&dst, &src)
+
+	resources := dst.Services["ollama"].Resources
+	if resources.CPUs != 4 || resources.Memory != "8g" {
+		t.Errorf("Resources = %+v, want cpus kept and memory replaced", resources)
+	}
+	if resources.Ulimits["nofile"] != 1024 || resources.Ulimits["memlock"] != -1 {
+		t.Errorf("Ulimits = %v, want merged", resources.Ulimits)
+	}
+}
@@ -27,7 +27,53 @@ func DefaultConfig() Config {
 			KeepCacheOnUninstall: true,
 		},
 		Updates: UpdatesConfig{
-			Mode: "rolling",
+			Mode:     "rolling",
+			Strategy: UpdateStrategyRecreate,
+		},
+		Network: NetworkConfig{
+			BindAddress: "0.0.0.0",
+		},
+		GPU: GPUConfig{
+			Mode: GPUModeAuto,
+		},
+		Timeouts: TimeoutsConfig{
+			ComposeSeconds:        300,
+			PullSeconds:           1800,
+			InspectSeconds:        30,
+			HealthCheckSeconds:    10,
+			StartupWaitSeconds:    5,
+			DependencyWaitSeconds: 120,
+			StatusDeadlineSeconds: 20,
+			HealthLatencyMs:       5000,
+		},
+		Agent: AgentConfig{
+			IntervalSeconds:       60,
+			RedThreshold:          2,
+			BackoffInitialSeconds: 30,
+			BackoffMaxSeconds:     900,
+			MaxRepairs:            3,
+			WindowSeconds:         3600,
+			CooldownSeconds:       3600,
+		},
+		HealthHistory: HealthHistoryConfig{
+			MaxEntries:        1000,
+			FlapTransitions:   4,
+			FlapWindowMinutes: 30,
+		},
+		Notifications: NotificationsConfig{
+			Events: []string{
+				"service.update.health_failed",
+				"services.update_all.rolled_back",
+				"services.update_all.failed",
+				"updates.scheduled.failed",
+				"agent.repair.failed",
+				"agent.circuit.open",
+				"suspend.executing",
+				"gpu.lock.stale_detected",
+			},
+			RetryAttempts:       3,
+			RetryBackoffSeconds: 2,
+			TimeoutSeconds:      5,
 		},
 	}
 }
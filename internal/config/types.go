@@ -2,15 +2,23 @@ package config
 
 // Config represents the complete aistack configuration
 type Config struct {
-	ContainerRuntime string                `yaml:"container_runtime"`
-	Profile          string                `yaml:"profile"`
-	GPULock          bool                  `yaml:"gpu_lock"`
-	Idle             IdleConfig            `yaml:"idle"`
-	PowerEstimation  PowerEstimationConfig `yaml:"power_estimation"`
-	WoL              WoLConfig             `yaml:"wol"`
-	Logging          LoggingConfig         `yaml:"logging"`
-	Models           ModelsConfig          `yaml:"models"`
-	Updates          UpdatesConfig         `yaml:"updates"`
+	ContainerRuntime string                   `yaml:"container_runtime"`
+	Profile          string                   `yaml:"profile"`
+	GPULock          bool                     `yaml:"gpu_lock"`
+	Idle             IdleConfig               `yaml:"idle"`
+	PowerEstimation  PowerEstimationConfig    `yaml:"power_estimation"`
+	WoL              WoLConfig                `yaml:"wol"`
+	Logging          LoggingConfig            `yaml:"logging"`
+	Models           ModelsConfig             `yaml:"models"`
+	Updates          UpdatesConfig            `yaml:"updates"`
+	Timeouts         TimeoutsConfig           `yaml:"timeouts"`
+	Profiles         map[string]ProfileConfig `yaml:"profiles"`
+	Network          NetworkConfig            `yaml:"network"`
+	Services         map[string]ServiceConfig `yaml:"services"`
+	GPU              GPUConfig                `yaml:"gpu"`
+	Agent            AgentConfig              `yaml:"agent"`
+	HealthHistory    HealthHistoryConfig      `yaml:"health_history"`
+	Notifications    NotificationsConfig      `yaml:"notifications"`
 }
 
 // IdleConfig represents idle detection configuration
@@ -45,7 +53,175 @@ type ModelsConfig struct {
 
 // UpdatesConfig represents update policy configuration
 type UpdatesConfig struct {
-	Mode string `yaml:"mode"`
+	Mode     string                  `yaml:"mode"`
+	Strategy string                  `yaml:"strategy"` // recreate (default) or blue-green
+	Schedule string                  `yaml:"schedule"` // cron expression for unattended update-all; empty disables it
+	Timezone string                  `yaml:"timezone"` // IANA zone of schedule and window; empty uses the host zone
+	Window   MaintenanceWindowConfig `yaml:"window"`
+	// Registries configures how `aistack update --check` reaches image registries
+	Registries RegistriesConfig `yaml:"registries"`
+}
+
+// RegistriesConfig lists registry mirrors and registries served over plain HTTP
+type RegistriesConfig struct {
+	Mirrors  map[string][]string `yaml:"mirrors"`  // registry host (docker.io) -> mirror base URLs, tried in order before the registry
+	Insecure []string            `yaml:"insecure"` // registry hosts (host[:port]) reached over plain HTTP
+}
+
+// MaintenanceWindowConfig restricts scheduled updates to a daily time range
+type MaintenanceWindowConfig struct {
+	Start string   `yaml:"start"` // HH:MM; empty allows scheduled updates at any time
+	End   string   `yaml:"end"`   // HH:MM; before start wraps past midnight
+	Days  []string `yaml:"days"`  // weekdays the window opens on (mon..sun); empty means every day
+}
+
+// TimeoutsConfig represents per-operation deadlines for container and health operations
+type TimeoutsConfig struct {
+	ComposeSeconds        int `yaml:"compose_seconds"`         // compose up/down
+	PullSeconds           int `yaml:"pull_seconds"`            // image pulls
+	InspectSeconds        int `yaml:"inspect_seconds"`         // inspect, tag, logs, volume and network calls
+	HealthCheckSeconds    int `yaml:"health_check_seconds"`    // a single health probe
+	StartupWaitSeconds    int `yaml:"startup_wait_seconds"`    // grace period before post-start health checks
+	DependencyWaitSeconds int `yaml:"dependency_wait_seconds"` // how long start --with-deps waits for a dependency to turn healthy
+	StatusDeadlineSeconds int `yaml:"status_deadline_seconds"` // overall deadline for status and health reports
+	HealthLatencyMs       int `yaml:"health_latency_ms"`       // a green probe slower than this is graded yellow
+}
+
+// AgentConfig controls the `aistack agent` supervisor daemon
+type AgentConfig struct {
+	IntervalSeconds       int `yaml:"interval_seconds"`        // time between health checks
+	RedThreshold          int `yaml:"red_threshold"`           // consecutive red checks before a repair
+	BackoffInitialSeconds int `yaml:"backoff_initial_seconds"` // wait after a failed repair; doubles per failure
+	BackoffMaxSeconds     int `yaml:"backoff_max_seconds"`     // upper bound for the repair backoff
+	MaxRepairs            int `yaml:"max_repairs"`             // repairs within window_seconds before the circuit breaker opens
+	WindowSeconds         int `yaml:"window_seconds"`          // crash-loop detection window
+	CooldownSeconds       int `yaml:"cooldown_seconds"`        // how long an open circuit breaker blocks repairs
+}
+
+// HealthHistoryConfig bounds the health transition history and sets flap detection
+type HealthHistoryConfig struct {
+	MaxEntries        int `yaml:"max_entries"`         // transitions kept in health_history.json
+	FlapTransitions   int `yaml:"flap_transitions"`    // transitions within the window that mark a service as flapping
+	FlapWindowMinutes int `yaml:"flap_window_minutes"` // flap detection window
+}
+
+// NotificationsConfig sends selected log events to webhook targets
+type NotificationsConfig struct {
+	Events              []string             `yaml:"events"`                // event types sent to every target; "prefix.*" matches a group
+	RetryAttempts       int                  `yaml:"retry_attempts"`        // deliveries per target before the event is dead-lettered
+	RetryBackoffSeconds int                  `yaml:"retry_backoff_seconds"` // wait before the first retry; doubles per retry
+	TimeoutSeconds      int                  `yaml:"timeout_seconds"`       // a single delivery
+	DeadLetterFile      string               `yaml:"dead_letter_file"`      // failed deliveries as JSON lines; empty uses the state directory
+	Targets             []NotificationTarget `yaml:"targets"`               // no targets disables notifications
+}
+
+// NotificationTarget is one webhook receiving notifications
+type NotificationTarget struct {
+	Name    string            `yaml:"name"`
+	Type    string            `yaml:"type"`    // webhook (generic JSON), ntfy or slack
+	URL     string            `yaml:"url"`     // for ntfy the topic URL, e.g. https://ntfy.sh/aistack
+	Events  []string          `yaml:"events"`  // replaces notifications.events for this target
+	Headers map[string]string `yaml:"headers"` // extra HTTP headers, e.g. Authorization
+}
+
+// NetworkConfig represents host networking for published service ports
+type NetworkConfig struct {
+	BindAddress string `yaml:"bind_address"`
+}
+
+// ServiceConfig holds per-service values rendered into the compose file
+type ServiceConfig struct {
+	Port      int               `yaml:"port"`      // host port; 0 keeps the catalog default
+	Env       map[string]string `yaml:"env"`       // merged over the catalog environment
+	GPUs      []string          `yaml:"gpus"`      // GPU UUIDs or indexes; replaces gpu.devices for this service
+	Resources ResourcesConfig   `yaml:"resources"` // container limits; fields override the catalog defaults
+	Health    HealthCheckConfig `yaml:"health"`    // health check; empty keeps the HTTP check on the catalog health_url
+	// UpdateStrategy overrides updates.strategy for this service
+	UpdateStrategy string `yaml:"update_strategy"`
+}
+
+// HealthCheckConfig selects how a service's health is checked
+type HealthCheckConfig struct {
+	Type           string              `yaml:"type"`            // http (default), tcp, exec, all or any
+	URL            string              `yaml:"url"`             // http: endpoint; defaults to the catalog health_url
+	ExpectedStatus int                 `yaml:"expected_status"` // http: default 200
+	JSONPath       string              `yaml:"json_path"`       // http: value that must exist in the JSON body, e.g. $.version
+	Regex          string              `yaml:"regex"`           // http: pattern the body (or the json_path value) must match
+	Address        string              `yaml:"address"`         // tcp: host:port; defaults to the health_url host and port
+	Command        []string            `yaml:"command"`         // exec: command run in the container; exit code 0 is healthy
+	Checks         []HealthCheckConfig `yaml:"checks"`          // all/any: checks to combine
+	LatencyMs      int                 `yaml:"latency_ms"`      // overrides timeouts.health_latency_ms for this service
+}
+
+// IsZero reports whether no health check is configured
+func (h HealthCheckConfig) IsZero() bool {
+	return h.Type == "" && h.URL == "" && h.ExpectedStatus == 0 && h.JSONPath == "" && h.Regex == "" &&
+		h.Address == "" && len(h.Command) == 0 && len(h.Checks) == 0 && h.LatencyMs == 0
+}
+
+// ResourcesConfig limits the resources of a service container (zero values leave a limit unset)
+type ResourcesConfig struct {
+	CPUs       float64          `yaml:"cpus"`        // CPU quota, e.g. 2.5
+	Memory     string           `yaml:"memory"`      // hard memory limit, e.g. 8g
+	MemorySwap string           `yaml:"memory_swap"` // memory + swap, e.g. 12g; -1 allows unlimited swap
+	ShmSize    string           `yaml:"shm_size"`    // size of /dev/shm, e.g. 1g
+	PidsLimit  int64            `yaml:"pids_limit"`  // max processes; -1 is unlimited
+	Ulimits    map[string]int64 `yaml:"ulimits"`     // soft and hard limit per ulimit name, e.g. nofile: 65536
+}
+
+// IsZero reports whether no limit is set
+func (r ResourcesConfig) IsZero() bool {
+	return r.CPUs == 0 && r.Memory == "" && r.MemorySwap == "" && r.ShmSize == "" && r.PidsLimit == 0 && len(r.Ulimits) == 0
+}
+
+// Merge returns r with the fields set in overlay applied
+func (r ResourcesConfig) Merge(overlay ResourcesConfig) ResourcesConfig {
+	if overlay.CPUs != 0 {
+		r.CPUs = overlay.CPUs
+	}
+	if overlay.Memory != "" {
+		r.Memory = overlay.Memory
+	}
+	if overlay.MemorySwap != "" {
+		r.MemorySwap = overlay.MemorySwap
+	}
+	if overlay.ShmSize != "" {
+		r.ShmSize = overlay.ShmSize
+	}
+	if overlay.PidsLimit != 0 {
+		r.PidsLimit = overlay.PidsLimit
+	}
+	if len(overlay.Ulimits) > 0 {
+		ulimits := make(map[string]int64, len(r.Ulimits)+len(overlay.Ulimits))
+		for name, value := range r.Ulimits {
+			ulimits[name] = value
+		}
+		for name, value := range overlay.Ulimits {
+			ulimits[name] = value
+		}
+		r.Ulimits = ulimits
+	}
+	return r
+}
+
+// GPUConfig controls the GPU reservation injected for catalog services with gpu: true
+type GPUConfig struct {
+	Mode    string   `yaml:"mode"`    // auto (reserve when the container toolkit works) or off (CPU only)
+	Devices []string `yaml:"devices"` // GPU UUIDs or indexes from gpu-check; empty reserves all GPUs
+}
+
+// ProfileConfig declares an install profile or amends a built-in one
+type ProfileConfig struct {
+	Description string                     `yaml:"description"`
+	Services    []string                   `yaml:"services"`  // replaces the built-in service list when set
+	Overrides   map[string]ServiceOverride `yaml:"overrides"` // keyed by service name
+}
+
+// ServiceOverride changes catalog fields of a service while its profile is active
+type ServiceOverride struct {
+	Image     string `yaml:"image"`
+	HealthURL string `yaml:"health_url"`
+	GPULock   *bool  `yaml:"gpu_lock"`
 }
 
 // ValidationError represents a configuration validation error
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteUnits maps size suffixes to multipliers (binary, as used by Docker and Compose)
+var byteUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// ParseByteSize parses sizes like 512m, 8g, 1.5GB or 2GiB into bytes
+func ParseByteSize(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+
+	// Split into number and unit ("8gib" -> "8", "gib")
+	split := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if split < 0 {
+		split = len(s)
+	}
+	unit := strings.TrimSuffix(s[split:], "ib")
+	if len(unit) == 2 && strings.HasSuffix(unit, "b") {
+		unit = unit[:1]
+	}
+
+	multiplier, ok := byteUnits[unit]
+	number, err := strconv.ParseFloat(s[:split], 64)
+	if !ok || err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size '%s' (use e.g. 512m or 8g)", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
@@ -2,14 +2,59 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"aistack/internal/schedule"
 )
 
+// BuiltinProfiles lists the install profiles shipped with aistack
+var BuiltinProfiles = []string{"minimal", "standard-gpu", "dev"}
+
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// UpdateStrategies lists the values of updates.strategy
+var UpdateStrategies = []string{UpdateStrategyRecreate, UpdateStrategyBlueGreen}
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 const (
 	// RuntimeDocker identifies the Docker container runtime option.
 	RuntimeDocker = "docker"
 	// RuntimePodman identifies the Podman container runtime option.
 	RuntimePodman = "podman"
+
+	// GPUModeAuto reserves GPUs for gpu services when the container toolkit works.
+	GPUModeAuto = "auto"
+	// GPUModeOff runs every service on the CPU.
+	GPUModeOff = "off"
+
+	// HealthTypeHTTP checks an HTTP endpoint (status code, optional body assertions).
+	HealthTypeHTTP = "http"
+	// HealthTypeTCP checks that a TCP port accepts connections.
+	HealthTypeTCP = "tcp"
+	// HealthTypeExec runs a command inside the service container.
+	HealthTypeExec = "exec"
+	// HealthTypeAll is healthy when every nested check is.
+	HealthTypeAll = "all"
+	// HealthTypeAny is healthy when at least one nested check is.
+	HealthTypeAny = "any"
+
+	// UpdateStrategyRecreate stops the running container before starting the new image.
+	UpdateStrategyRecreate = "recreate"
+	// UpdateStrategyBlueGreen health-checks the new image next to the running container before the cutover.
+	UpdateStrategyBlueGreen = "blue-green"
+
+	// NotifyTypeWebhook posts the event as generic JSON.
+	NotifyTypeWebhook = "webhook"
+	// NotifyTypeNtfy posts a plain-text message with ntfy title/priority headers.
+	NotifyTypeNtfy = "ntfy"
+	// NotifyTypeSlack posts a Slack-compatible {"text": ...} payload.
+	NotifyTypeSlack = "slack"
 )
 
 // Validate checks if the configuration is valid
@@ -18,11 +63,19 @@ func (c *Config) Validate() []ValidationError {
 
 	errors = append(errors, c.validateContainerRuntime()...)
 	errors = append(errors, c.validateProfile()...)
+	errors = append(errors, c.validateProfiles()...)
+	errors = append(errors, c.validateNetwork()...)
+	errors = append(errors, c.validateServices()...)
+	errors = append(errors, c.validateGPU()...)
 	errors = append(errors, c.validateIdle()...)
 	errors = append(errors, c.validatePowerEstimation()...)
 	errors = append(errors, c.validateWoL()...)
 	errors = append(errors, c.validateLogging()...)
 	errors = append(errors, c.validateUpdates()...)
+	errors = append(errors, c.validateTimeouts()...)
+	errors = append(errors, c.validateAgent()...)
+	errors = append(errors, c.validateHealthHistory()...)
+	errors = append(errors, c.validateNotifications()...)
 
 	return errors
 }
@@ -39,7 +92,7 @@ func (c *Config) validateContainerRuntime() []ValidationError {
 }
 
 func (c *Config) validateProfile() []ValidationError {
-	validProfiles := []string{"minimal", "standard-gpu", "dev"}
+	validProfiles := c.ProfileNames()
 	if contains(validProfiles, c.Profile) {
 		return nil
 	}
@@ -50,6 +103,312 @@ func (c *Config) validateProfile() []ValidationError {
 	}}
 }
 
+func (c *Config) validateProfiles() []ValidationError {
+	var errors []ValidationError
+
+	for _, name := range sortedProfileNames(c.Profiles) {
+		profile := c.Profiles[name]
+		path := "profiles." + name
+
+		if !profileNamePattern.MatchString(name) {
+			errors = append(errors, ValidationError{
+				Path:    path,
+				Message: "name must be lowercase letters, digits, '-' or '_'",
+			})
+			continue
+		}
+
+		if !contains(BuiltinProfiles, name) && len(profile.Services) == 0 {
+			errors = append(errors, ValidationError{
+				Path:    path + ".services",
+				Message: "must list at least one service",
+			})
+		}
+
+		for i, service := range profile.Services {
+			if !profileNamePattern.MatchString(service) {
+				errors = append(errors, ValidationError{
+					Path:    fmt.Sprintf("%s.services[%d]", path, i),
+					Message: fmt.Sprintf("invalid service name '%s'", service),
+				})
+			}
+		}
+
+		for _, service := range sortedOverrideNames(profile.Overrides) {
+			override := profile.Overrides[service]
+			if override.HealthURL == "" {
+				continue
+			}
+			parsed, err := url.Parse(override.HealthURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				errors = append(errors, ValidationError{
+					Path:    fmt.Sprintf("%s.overrides.%s.health_url", path, service),
+					Message: fmt.Sprintf("must be an http(s) URL, got '%s'", override.HealthURL),
+				})
+			}
+		}
+	}
+
+	return errors
+}
+
+func (c *Config) validateNetwork() []ValidationError {
+	if net.ParseIP(c.Network.BindAddress) != nil {
+		return nil
+	}
+
+	return []ValidationError{{
+		Path:    "network.bind_address",
+		Message: fmt.Sprintf("must be an IP address, got '%s'", c.Network.BindAddress),
+	}}
+}
+
+func (c *Config) validateServices() []ValidationError {
+	var errors []ValidationError
+
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := c.Services[name]
+		path := "services." + name
+
+		if service.Port < 0 || service.Port > 65535 {
+			errors = append(errors, ValidationError{
+				Path:    path + ".port",
+				Message: fmt.Sprintf("must be between 1 and 65535, got %d", service.Port),
+			})
+		}
+
+		if service.UpdateStrategy != "" && !contains(UpdateStrategies, service.UpdateStrategy) {
+			errors = append(errors, ValidationError{
+				Path:    path + ".update_strategy",
+				Message: fmt.Sprintf("must be one of %v, got '%s'", UpdateStrategies, service.UpdateStrategy),
+			})
+		}
+		errors = append(errors, validateGPUDevices(path+".gpus", service.GPUs)...)
+		errors = append(errors, validateResources(path+".resources", service.Resources)...)
+		if !service.Health.IsZero() {
+			errors = append(errors, validateHealthCheck(path+".health", service.Health)...)
+		}
+
+		keys := make([]string, 0, len(service.Env))
+		for key := range service.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !envKeyPattern.MatchString(key) {
+				errors = append(errors, ValidationError{
+					Path:    path + ".env." + key,
+					Message: "invalid environment variable name",
+				})
+			}
+		}
+	}
+
+	return errors
+}
+
+// validateHealthCheck checks a health check and its nested checks
+func validateHealthCheck(path string, check HealthCheckConfig) []ValidationError {
+	var errors []ValidationError
+	invalid := func(field, message string) {
+		errors = append(errors, ValidationError{Path: path + field, Message: message})
+	}
+
+	switch check.Type {
+	case "", HealthTypeHTTP:
+		if check.URL != "" {
+			if parsed, err := url.Parse(check.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				invalid(".url", fmt.Sprintf("must be an http(s) URL, got '%s'", check.URL))
+			}
+		}
+		if check.ExpectedStatus != 0 && (check.ExpectedStatus < 100 || check.ExpectedStatus > 599) {
+			invalid(".expected_status", fmt.Sprintf("must be an HTTP status code, got %d", check.ExpectedStatus))
+		}
+		if check.JSONPath != "" && !strings.HasPrefix(check.JSONPath, "$") {
+			invalid(".json_path", fmt.Sprintf("must start with '$', got '%s'", check.JSONPath))
+		}
+		if check.Regex != "" {
+			if _, err := regexp.Compile(check.Regex); err != nil {
+				invalid(".regex", fmt.Sprintf("invalid regular expression: %v", err))
+			}
+		}
+	case HealthTypeTCP:
+		if check.Address != "" {
+			if _, port, err := net.SplitHostPort(check.Address); err != nil || port == "" {
+				invalid(".address", fmt.Sprintf("must be host:port, got '%s'", check.Address))
+			}
+		}
+	case HealthTypeExec:
+		if len(check.Command) == 0 {
+			invalid(".command", "must not be empty for exec checks")
+		}
+	case HealthTypeAll, HealthTypeAny:
+		if len(check.Checks) == 0 {
+			invalid(".checks", fmt.Sprintf("must list at least one check for type '%s'", check.Type))
+		}
+		for i, nested := range check.Checks {
+			if nested.LatencyMs != 0 {
+				invalid(fmt.Sprintf(".checks[%d].latency_ms", i), "only applies to the top-level health check")
+			}
+			errors = append(errors, validateHealthCheck(fmt.Sprintf("%s.checks[%d]", path, i), nested)...)
+		}
+	default:
+		invalid(".type", fmt.Sprintf("must be one of [%s %s %s %s %s], got '%s'",
+			HealthTypeHTTP, HealthTypeTCP, HealthTypeExec, HealthTypeAll, HealthTypeAny, check.Type))
+	}
+
+	if check.LatencyMs < 0 {
+		invalid(".latency_ms", fmt.Sprintf("must be non-negative, got %d", check.LatencyMs))
+	}
+
+	return errors
+}
+
+// knownUlimits are the ulimit names Docker and Podman accept
+var knownUlimits = []string{
+	"core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue", "nice",
+	"nofile", "nproc", "rss", "rtprio", "rttime", "sigpending", "stack",
+}
+
+func validateResources(path string, resources ResourcesConfig) []ValidationError {
+	var errors []ValidationError
+
+	if resources.CPUs < 0 {
+		errors = append(errors, ValidationError{
+			Path:    path + ".cpus",
+			Message: fmt.Sprintf("must not be negative, got %g", resources.CPUs),
+		})
+	}
+
+	var memory int64
+	if resources.Memory != "" {
+		size, err := ParseByteSize(resources.Memory)
+		if err != nil || size == 0 {
+			errors = append(errors, ValidationError{Path: path + ".memory", Message: sizeMessage(resources.Memory, err)})
+		}
+		memory = size
+	}
+
+	if resources.MemorySwap != "" && resources.MemorySwap != "-1" {
+		swap, err := ParseByteSize(resources.MemorySwap)
+		switch {
+		case err != nil || swap == 0:
+			errors = append(errors, ValidationError{Path: path + ".memory_swap", Message: sizeMessage(resources.MemorySwap, err)})
+		case memory == 0:
+			errors = append(errors, ValidationError{Path: path + ".memory_swap", Message: "requires memory to be set"})
+		case swap < memory:
+			errors = append(errors, ValidationError{
+				Path:    path + ".memory_swap",
+				Message: fmt.Sprintf("must be at least memory (%s), got %s", resources.Memory, resources.MemorySwap),
+			})
+		}
+	}
+
+	if resources.ShmSize != "" {
+		if size, err := ParseByteSize(resources.ShmSize); err != nil || size == 0 {
+			errors = append(errors, ValidationError{Path: path + ".shm_size", Message: sizeMessage(resources.ShmSize, err)})
+		}
+	}
+
+	if resources.PidsLimit < -1 {
+		errors = append(errors, ValidationError{
+			Path:    path + ".pids_limit",
+			Message: fmt.Sprintf("must be -1 (unlimited) or positive, got %d", resources.PidsLimit),
+		})
+	}
+
+	names := make([]string, 0, len(resources.Ulimits))
+	for name := range resources.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := resources.Ulimits[name]
+		switch {
+		case !contains(knownUlimits, name):
+			errors = append(errors, ValidationError{
+				Path:    path + ".ulimits." + name,
+				Message: fmt.Sprintf("unknown ulimit (valid: %s)", strings.Join(knownUlimits, ", ")),
+			})
+		case value == 0 || value < -1:
+			errors = append(errors, ValidationError{
+				Path:    path + ".ulimits." + name,
+				Message: fmt.Sprintf("must be -1 (unlimited) or positive, got %d", value),
+			})
+		}
+	}
+
+	return errors
+}
+
+func sizeMessage(value string, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("must be greater than zero, got '%s'", value)
+}
+
+func (c *Config) validateGPU() []ValidationError {
+	var errors []ValidationError
+
+	if c.GPU.Mode != GPUModeAuto && c.GPU.Mode != GPUModeOff {
+		errors = append(errors, ValidationError{
+			Path:    "gpu.mode",
+			Message: fmt.Sprintf("must be '%s' or '%s', got '%s'", GPUModeAuto, GPUModeOff, c.GPU.Mode),
+		})
+	}
+
+	return append(errors, validateGPUDevices("gpu.devices", c.GPU.Devices)...)
+}
+
+func validateGPUDevices(path string, devices []string) []ValidationError {
+	var errors []ValidationError
+	for i, device := range devices {
+		if strings.TrimSpace(device) == "" {
+			errors = append(errors, ValidationError{
+				Path:    fmt.Sprintf("%s[%d]", path, i),
+				Message: "must be a GPU UUID or index",
+			})
+		}
+	}
+	return errors
+}
+
+// ProfileNames returns the built-in and configured profile names, built-ins first
+func (c *Config) ProfileNames() []string {
+	names := append([]string(nil), BuiltinProfiles...)
+	for _, name := range sortedProfileNames(c.Profiles) {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func sortedProfileNames(profiles map[string]ProfileConfig) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedOverrideNames(overrides map[string]ServiceOverride) []string {
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (c *Config) validateLogging() []ValidationError {
 	var errors []ValidationError
 	validLevels := []string{"debug", "info", "warn", "error"}
@@ -72,15 +431,235 @@ func (c *Config) validateLogging() []ValidationError {
 }
 
 func (c *Config) validateUpdates() []ValidationError {
+	var errors []ValidationError
+
 	validModes := []string{"rolling", "pinned"}
-	if contains(validModes, c.Updates.Mode) {
-		return nil
+	if !contains(validModes, c.Updates.Mode) {
+		errors = append(errors, ValidationError{
+			Path:    "updates.mode",
+			Message: fmt.Sprintf("must be one of %v, got '%s'", validModes, c.Updates.Mode),
+		})
 	}
 
-	return []ValidationError{{
-		Path:    "updates.mode",
-		Message: fmt.Sprintf("must be one of %v, got '%s'", validModes, c.Updates.Mode),
-	}}
+	if !contains(UpdateStrategies, c.Updates.Strategy) {
+		errors = append(errors, ValidationError{
+			Path:    "updates.strategy",
+			Message: fmt.Sprintf("must be one of %v, got '%s'", UpdateStrategies, c.Updates.Strategy),
+		})
+	}
+
+	if c.Updates.Schedule != "" {
+		if _, err := schedule.ParseCron(c.Updates.Schedule); err != nil {
+			errors = append(errors, ValidationError{Path: "updates.schedule", Message: err.Error()})
+		}
+	}
+	if c.Updates.Timezone != "" {
+		if _, err := time.LoadLocation(c.Updates.Timezone); err != nil {
+			errors = append(errors, ValidationError{
+				Path:    "updates.timezone",
+				Message: fmt.Sprintf("unknown time zone '%s'", c.Updates.Timezone),
+			})
+		}
+	}
+	window := c.Updates.Window
+	if _, err := schedule.ParseWindow(window.Start, window.End, window.Days); err != nil {
+		errors = append(errors, ValidationError{Path: "updates.window", Message: err.Error()})
+	}
+
+	return append(errors, c.validateRegistries()...)
+}
+
+func (c *Config) validateRegistries() []ValidationError {
+	var errors []ValidationError
+
+	registries := make([]string, 0, len(c.Updates.Registries.Mirrors))
+	for registry := range c.Updates.Registries.Mirrors {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	for _, registry := range registries {
+		for i, mirror := range c.Updates.Registries.Mirrors[registry] {
+			if parsed, err := url.Parse(mirror); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				errors = append(errors, ValidationError{
+					Path:    fmt.Sprintf("updates.registries.mirrors.%s[%d]", registry, i),
+					Message: fmt.Sprintf("must be an http(s) URL, got '%s'", mirror),
+				})
+			}
+		}
+	}
+
+	for i, registry := range c.Updates.Registries.Insecure {
+		if registry == "" || strings.Contains(registry, "/") {
+			errors = append(errors, ValidationError{
+				Path:    fmt.Sprintf("updates.registries.insecure[%d]", i),
+				Message: fmt.Sprintf("must be a registry host[:port], got '%s'", registry),
+			})
+		}
+	}
+
+	return errors
+}
+
+func (c *Config) validateTimeouts() []ValidationError {
+	var errors []ValidationError
+
+	positive := []struct {
+		path  string
+		value int
+	}{
+		{"timeouts.compose_seconds", c.Timeouts.ComposeSeconds},
+		{"timeouts.pull_seconds", c.Timeouts.PullSeconds},
+		{"timeouts.inspect_seconds", c.Timeouts.InspectSeconds},
+		{"timeouts.health_check_seconds", c.Timeouts.HealthCheckSeconds},
+		{"timeouts.dependency_wait_seconds", c.Timeouts.DependencyWaitSeconds},
+		{"timeouts.status_deadline_seconds", c.Timeouts.StatusDeadlineSeconds},
+		{"timeouts.health_latency_ms", c.Timeouts.HealthLatencyMs},
+	}
+	for _, field := range positive {
+		if field.value < 1 {
+			errors = append(errors, ValidationError{
+				Path:    field.path,
+				Message: fmt.Sprintf("must be at least 1, got %d", field.value),
+			})
+		}
+	}
+
+	if c.Timeouts.StartupWaitSeconds < 0 {
+		errors = append(errors, ValidationError{
+			Path:    "timeouts.startup_wait_seconds",
+			Message: fmt.Sprintf("must be non-negative, got %d", c.Timeouts.StartupWaitSeconds),
+		})
+	}
+
+	return errors
+}
+
+func (c *Config) validateAgent() []ValidationError {
+	var errors []ValidationError
+
+	positive := []struct {
+		path  string
+		value int
+	}{
+		{"agent.interval_seconds", c.Agent.IntervalSeconds},
+		{"agent.red_threshold", c.Agent.RedThreshold},
+		{"agent.backoff_initial_seconds", c.Agent.BackoffInitialSeconds},
+		{"agent.backoff_max_seconds", c.Agent.BackoffMaxSeconds},
+		{"agent.max_repairs", c.Agent.MaxRepairs},
+		{"agent.window_seconds", c.Agent.WindowSeconds},
+		{"agent.cooldown_seconds", c.Agent.CooldownSeconds},
+	}
+	for _, field := range positive {
+		if field.value < 1 {
+			errors = append(errors, ValidationError{
+				Path:    field.path,
+				Message: fmt.Sprintf("must be at least 1, got %d", field.value),
+			})
+		}
+	}
+
+	if c.Agent.BackoffMaxSeconds > 0 && c.Agent.BackoffMaxSeconds < c.Agent.BackoffInitialSeconds {
+		errors = append(errors, ValidationError{
+			Path:    "agent.backoff_max_seconds",
+			Message: fmt.Sprintf("must be at least backoff_initial_seconds (%d), got %d", c.Agent.BackoffInitialSeconds, c.Agent.BackoffMaxSeconds),
+		})
+	}
+
+	return errors
+}
+
+func (c *Config) validateHealthHistory() []ValidationError {
+	var errors []ValidationError
+
+	positive := []struct {
+		path  string
+		value int
+	}{
+		{"health_history.max_entries", c.HealthHistory.MaxEntries},
+		{"health_history.flap_transitions", c.HealthHistory.FlapTransitions},
+		{"health_history.flap_window_minutes", c.HealthHistory.FlapWindowMinutes},
+	}
+	for _, field := range positive {
+		if field.value < 1 {
+			errors = append(errors, ValidationError{
+				Path:    field.path,
+				Message: fmt.Sprintf("must be at least 1, got %d", field.value),
+			})
+		}
+	}
+
+	return errors
+}
+
+func (c *Config) validateNotifications() []ValidationError {
+	var errors []ValidationError
+	notifications := c.Notifications
+
+	positive := []struct {
+		path  string
+		value int
+	}{
+		{"notifications.retry_attempts", notifications.RetryAttempts},
+		{"notifications.retry_backoff_seconds", notifications.RetryBackoffSeconds},
+		{"notifications.timeout_seconds", notifications.TimeoutSeconds},
+	}
+	for _, field := range positive {
+		if field.value < 1 {
+			errors = append(errors, ValidationError{
+				Path:    field.path,
+				Message: fmt.Sprintf("must be at least 1, got %d", field.value),
+			})
+		}
+	}
+
+	errors = append(errors, validateEventPatterns("notifications.events", notifications.Events)...)
+
+	seen := make(map[string]bool)
+	for i, target := range notifications.Targets {
+		path := fmt.Sprintf("notifications.targets[%d]", i)
+
+		switch {
+		case target.Name == "":
+			errors = append(errors, ValidationError{Path: path + ".name", Message: "is required"})
+		case seen[target.Name]:
+			errors = append(errors, ValidationError{Path: path + ".name", Message: fmt.Sprintf("duplicate target '%s'", target.Name)})
+		}
+		seen[target.Name] = true
+
+		if target.Type != NotifyTypeWebhook && target.Type != NotifyTypeNtfy && target.Type != NotifyTypeSlack {
+			errors = append(errors, ValidationError{
+				Path:    path + ".type",
+				Message: fmt.Sprintf("must be '%s', '%s' or '%s', got '%s'", NotifyTypeWebhook, NotifyTypeNtfy, NotifyTypeSlack, target.Type),
+			})
+		}
+
+		if parsed, err := url.Parse(target.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errors = append(errors, ValidationError{
+				Path:    path + ".url",
+				Message: fmt.Sprintf("must be an http(s) URL, got '%s'", target.URL),
+			})
+		}
+
+		errors = append(errors, validateEventPatterns(path+".events", target.Events)...)
+	}
+
+	return errors
+}
+
+// validateEventPatterns checks event types and "prefix.*" patterns
+func validateEventPatterns(path string, patterns []string) []ValidationError {
+	var errors []ValidationError
+	for i, pattern := range patterns {
+		name := strings.TrimSuffix(pattern, ".*")
+		if pattern == "*" || (name != "" && !strings.ContainsAny(name, "* ")) {
+			continue
+		}
+		errors = append(errors, ValidationError{
+			Path:    fmt.Sprintf("%s[%d]", path, i),
+			Message: fmt.Sprintf("invalid event pattern '%s' (use an event type, 'prefix.*' or '*')", pattern),
+		})
+	}
+	return errors
 }
 
 func (c *Config) validateIdle() []ValidationError {
@@ -1,9 +1,11 @@
 package fsutil
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"syscall"
 
 	"aistack/internal/logging"
 )
@@ -17,6 +19,9 @@ const (
 	DefaultFilePermissions = 0o600
 )
 
+// ErrLocked reports that another process holds a lock taken with TryLock
+var ErrLocked = errors.New("lock held by another process")
+
 // GetStateDir returns the state directory from environment or uses the provided default.
 // It returns an absolute path when possible.
 func GetStateDir(defaultDir string) string {
@@ -74,3 +79,34 @@ func CloseWithError(closer func() error, logger *logging.Logger, resource string
 		}
 	}
 }
+
+// Lock takes an exclusive flock on path, waiting while another process holds it, and
+// returns the unlock function. Use it around short read-modify-write sequences.
+func Lock(path string) (func(), error) {
+	return lockFile(path, syscall.LOCK_EX)
+}
+
+// TryLock takes an exclusive flock on path without waiting and returns the unlock
+// function; ErrLocked when another process (or open file) holds it. The kernel
+// releases the lock when the holder exits, so a crashed process never leaves it behind.
+func TryLock(path string) (func(), error) {
+	return lockFile(path, syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+func lockFile(path string, how int) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, DefaultFilePermissions) // #nosec G304 -- lock paths are derived from the state directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), how); err != nil {
+		_ = file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		_ = file.Close()
+	}, nil
+}
@@ -1,9 +1,11 @@
 package fsutil
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"aistack/internal/logging"
 )
@@ -218,3 +220,55 @@ func TestCloseWithError(t *testing.T) {
 		})
 	}
 }
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	unlock, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if _, err := TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("TryLock() while held error = %v, want ErrLocked", err)
+	}
+
+	unlock()
+	unlock, err = TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() after unlock error = %v", err)
+	}
+	unlock()
+}
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	unlock, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("TryLock() while held error = %v, want ErrLocked", err)
+	}
+
+	// A second Lock waits until the first is released
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := Lock(path)
+		if err == nil {
+			unlock()
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Lock() returned while the lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lock() did not return after unlock")
+	}
+}
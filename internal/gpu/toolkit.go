@@ -4,12 +4,21 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 
 	"aistack/internal/logging"
 )
 
+// cdiSpecPaths are the locations `nvidia-ctk cdi generate` writes the NVIDIA CDI spec to
+var cdiSpecPaths = []string{
+	"/etc/cdi/nvidia.yaml",
+	"/etc/cdi/nvidia.json",
+	"/var/run/cdi/nvidia.yaml",
+	"/var/run/cdi/nvidia.json",
+}
+
 // ToolkitDetector handles NVIDIA Container Toolkit detection
 // Story T-010: NVIDIA Container Toolkit Detection
 type ToolkitDetector struct {
@@ -141,6 +150,22 @@ func (td *ToolkitDetector) getToolkitVersion() string {
 	return ""
 }
 
+// DetectCDISpec reports whether an NVIDIA CDI spec is installed and where.
+// Podman requests GPUs through CDI device names (nvidia.com/gpu=<uuid|all>).
+func (td *ToolkitDetector) DetectCDISpec() (string, bool) {
+	for _, path := range cdiSpecPaths {
+		if _, err := os.Stat(path); err == nil {
+			td.logger.Info("gpu.toolkit.cdi.detected", "NVIDIA CDI spec found", map[string]interface{}{
+				"path": path,
+			})
+			return path, true
+		}
+	}
+
+	td.logger.Info("gpu.toolkit.cdi.absent", "NVIDIA CDI spec not found (run: nvidia-ctk cdi generate)", nil)
+	return "", false
+}
+
 // QuickGPUCheck performs a quick GPU availability check without full detection
 // This is useful for fast pre-flight checks
 func (td *ToolkitDetector) QuickGPUCheck() bool {
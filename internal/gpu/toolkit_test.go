@@ -2,6 +2,8 @@ package gpu
 
 import (
 	"aistack/internal/logging"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -79,3 +81,25 @@ func TestGPUInfo_Structure(t *testing.T) {
 		t.Errorf("Expected memory 24576, got: %d", info.MemoryMB)
 	}
 }
+
+func TestToolkitDetector_DetectCDISpec(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "nvidia.yaml")
+
+	original := cdiSpecPaths
+	cdiSpecPaths = []string{filepath.Join(dir, "missing.yaml"), spec}
+	defer func() { cdiSpecPaths = original }()
+
+	detector := NewToolkitDetector(logging.NewLogger(logging.LevelError))
+	if _, ok := detector.DetectCDISpec(); ok {
+		t.Fatal("Expected no CDI spec before it is written")
+	}
+
+	if err := os.WriteFile(spec, []byte("cdiVersion: 0.5.0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path, ok := detector.DetectCDISpec()
+	if !ok || path != spec {
+		t.Errorf("DetectCDISpec() = %q, %v; want %q, true", path, ok, spec)
+	}
+}
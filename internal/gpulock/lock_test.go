@@ -341,3 +341,15 @@ func TestHolderIsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterHolder(t *testing.T) {
+	holder := Holder("comfyui")
+	if holder.IsValid() {
+		t.Fatal("Expected unregistered holder to be invalid")
+	}
+
+	RegisterHolder(holder)
+	if !holder.IsValid() {
+		t.Error("Expected registered holder to be valid")
+	}
+}
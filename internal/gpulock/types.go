@@ -1,6 +1,9 @@
 package gpulock
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // Holder represents a service that holds the GPU lock
 type Holder string
@@ -14,6 +17,21 @@ const (
 	HolderLocalAI Holder = "localai"
 )
 
+var (
+	extraHoldersMu sync.RWMutex
+	extraHolders   = make(map[Holder]bool)
+)
+
+// RegisterHolder allows an additional service (e.g. from the service catalog) to hold the lock
+func RegisterHolder(h Holder) {
+	if h == "" || h == HolderNone {
+		return
+	}
+	extraHoldersMu.Lock()
+	defer extraHoldersMu.Unlock()
+	extraHolders[h] = true
+}
+
 // LockInfo represents the GPU lock state
 // Story T-021: GPU-Mutex (Dateisperre + Lease)
 type LockInfo struct {
@@ -32,6 +50,8 @@ func (h Holder) IsValid() bool {
 	case HolderNone, HolderOpenWebUI, HolderLocalAI:
 		return true
 	default:
-		return false
+		extraHoldersMu.RLock()
+		defer extraHoldersMu.RUnlock()
+		return extraHolders[h]
 	}
 }
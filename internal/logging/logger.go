@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -32,6 +33,77 @@ type Event struct {
 	Payload   map[string]interface{} `json:"payload,omitempty"`
 }
 
+// Hook receives the events of every Logger, independent of the logger's level
+type Hook func(event Event)
+
+// hooks are process-wide so every logger created by a command reaches them
+var hooks = struct {
+	sync.RWMutex
+	next    int
+	entries map[int]Hook
+	flushes map[int]func(timeout time.Duration)
+}{entries: make(map[int]Hook), flushes: make(map[int]func(time.Duration))}
+
+// AddHook registers hook for the events of all loggers and returns a function
+// that removes it. Hooks run synchronously in the logging goroutine, so a hook
+// that does slow work should hand the event off (see AddFlush).
+func AddHook(hook Hook) (remove func()) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	id := hooks.next
+	hooks.next++
+	hooks.entries[id] = hook
+	return func() {
+		hooks.Lock()
+		defer hooks.Unlock()
+		delete(hooks.entries, id)
+	}
+}
+
+// AddFlush registers flush, which waits up to timeout for the events a hook still
+// handles in the background, and returns a function that removes it
+func AddFlush(flush func(timeout time.Duration)) (remove func()) {
+	hooks.Lock()
+	defer hooks.Unlock()
+	id := hooks.next
+	hooks.next++
+	hooks.flushes[id] = flush
+	return func() {
+		hooks.Lock()
+		defer hooks.Unlock()
+		delete(hooks.flushes, id)
+	}
+}
+
+// Flush waits up to timeout in total for hooks to finish the events they handle in
+// the background, e.g. before the system suspends
+func Flush(timeout time.Duration) {
+	hooks.RLock()
+	registered := make([]func(time.Duration), 0, len(hooks.flushes))
+	for _, flush := range hooks.flushes {
+		registered = append(registered, flush)
+	}
+	hooks.RUnlock()
+
+	deadline := time.Now().Add(timeout)
+	for _, flush := range registered {
+		flush(max(time.Until(deadline), 0))
+	}
+}
+
+func runHooks(event Event) {
+	hooks.RLock()
+	registered := make([]Hook, 0, len(hooks.entries))
+	for _, hook := range hooks.entries {
+		registered = append(registered, hook)
+	}
+	hooks.RUnlock()
+
+	for _, hook := range registered {
+		hook(event)
+	}
+}
+
 // Logger provides structured logging
 // Story T-027: Extended with file-based output and rotation support
 type Logger struct {
@@ -80,10 +152,6 @@ func (l *Logger) Close() error {
 
 // Log writes a structured log event
 func (l *Logger) Log(level Level, eventType, message string, payload map[string]interface{}) {
-	if !l.shouldLog(level) {
-		return
-	}
-
 	event := Event{
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Level:     level,
@@ -91,6 +159,11 @@ func (l *Logger) Log(level Level, eventType, message string, payload map[string]
 		Message:   message,
 		Payload:   payload,
 	}
+	runHooks(event)
+
+	if !l.shouldLog(level) {
+		return
+	}
 
 	data, err := json.Marshal(event)
 	if err != nil {
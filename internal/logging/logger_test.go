@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -361,3 +362,41 @@ func TestFileLogger_Append(t *testing.T) {
 		t.Error("Second event was not appended")
 	}
 }
+
+func TestAddHook_ReceivesFilteredEvents(t *testing.T) {
+	var received []Event
+	remove := AddHook(func(event Event) { received = append(received, event) })
+
+	var buf bytes.Buffer
+	logger := &Logger{minLevel: LevelError, output: &buf}
+	logger.Info("suspend.executing", "Suspending", map[string]interface{}{"idle": 300})
+
+	if len(received) != 1 || received[0].Type != "suspend.executing" || received[0].Payload["idle"] != 300 {
+		t.Errorf("hook received %+v", received)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected the info event to be filtered from the output, got %q", buf.String())
+	}
+
+	remove()
+	logger.Error("gpu.lock.stale_detected", "Stale lock", nil)
+	if len(received) != 1 {
+		t.Errorf("Expected no events after remove, got %d", len(received))
+	}
+}
+
+func TestFlush_WaitsForRegisteredFlushes(t *testing.T) {
+	var timeouts []time.Duration
+	remove := AddFlush(func(timeout time.Duration) { timeouts = append(timeouts, timeout) })
+
+	Flush(time.Second)
+	if len(timeouts) != 1 || timeouts[0] <= 0 || timeouts[0] > time.Second {
+		t.Errorf("flush timeouts = %v, want one within 1s", timeouts)
+	}
+
+	remove()
+	Flush(time.Second)
+	if len(timeouts) != 1 {
+		t.Errorf("Expected no flush after remove, got %v", timeouts)
+	}
+}
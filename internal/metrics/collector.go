@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+	"aistack/internal/models"
+	"aistack/internal/services"
+	"aistack/internal/suspend"
+)
+
+// StatusSource reports the state and health of all services (services.Manager)
+type StatusSource interface {
+	ListServices() []string
+	StatusAll(ctx context.Context) ([]services.ServiceStatus, error)
+}
+
+// GPULockSource reports the GPU lock (gpulock.Manager)
+type GPULockSource interface {
+	GetStatus() (*gpulock.LockInfo, error)
+}
+
+// SuspendSource reports the auto-suspend state (suspend.Manager)
+type SuspendSource interface {
+	LoadState() (*suspend.State, error)
+	GetIdleDuration(state *suspend.State) time.Duration
+}
+
+// ModelCacheSource reports the model cache of one provider (models.StateManager)
+type ModelCacheSource interface {
+	GetStats() (*models.CacheStats, error)
+}
+
+// UpdatePlanLoader returns the last update plan of a service, nil when there is none
+type UpdatePlanLoader func(serviceName string) (*services.UpdatePlan, error)
+
+// healthStates are exported one-hot so alerts can match a single series
+var healthStates = []services.HealthStatus{services.HealthGreen, services.HealthYellow, services.HealthRed}
+
+// updateResults are the UpdatePlan statuses exported one-hot
+var updateResults = []string{"pending", "completed", "rolled_back", "failed"}
+
+// Collector gathers metrics from the aistack managers on every scrape; a failing
+// source is reported through aistack_source_up and does not fail the scrape
+type Collector struct {
+	Services    StatusSource
+	GPULock     GPULockSource
+	Suspend     SuspendSource
+	ModelCaches []ModelCacheSource
+	UpdatePlans UpdatePlanLoader
+	Logger      *logging.Logger
+	Now         func() time.Time
+}
+
+// Collect gathers all metric families
+func (c *Collector) Collect(ctx context.Context) []*Family {
+	sourceUp := gauge("aistack_source_up", "Whether the last read of a metrics source succeeded.")
+	families := []*Family{sourceUp}
+	collect := func(source string, fn func() ([]*Family, error)) {
+		collected, err := fn()
+		if err != nil {
+			c.Logger.Warn("metrics.source.failed", "Metrics source failed", map[string]interface{}{
+				"source": source,
+				"error":  err.Error(),
+			})
+		}
+		sourceUp.add(boolValue(err == nil), "source", source)
+		families = append(families, collected...)
+	}
+
+	if c.Services != nil {
+		collect("services", func() ([]*Family, error) { return c.collectServices(ctx) })
+		if c.UpdatePlans != nil {
+			collect("updates", c.collectUpdates)
+		}
+	}
+	if c.GPULock != nil {
+		collect("gpu_lock", c.collectGPULock)
+	}
+	if c.Suspend != nil {
+		collect("suspend", c.collectSuspend)
+	}
+	if len(c.ModelCaches) > 0 {
+		collect("models", c.collectModels)
+	}
+	return families
+}
+
+func (c *Collector) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Collector) collectServices(ctx context.Context) ([]*Family, error) {
+	statuses, err := c.Services.StatusAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	up := gauge("aistack_service_up", "Whether the service container is running.")
+	health := gauge("aistack_service_health", "Service health; 1 for the current state (green, yellow, red).")
+	latency := gauge("aistack_service_health_probe_seconds", "Duration of the last health probe.")
+	for _, status := range statuses {
+		up.add(boolValue(status.State == "running"), "service", status.Name)
+		for _, state := range healthStates {
+			health.add(boolValue(status.Health == state), "service", status.Name, "health", string(state))
+		}
+		if status.Latency > 0 {
+			latency.add(status.Latency.Seconds(), "service", status.Name)
+		}
+	}
+	return []*Family{up, health, latency}, nil
+}
+
+func (c *Collector) collectUpdates() ([]*Family, error) {
+	result := gauge("aistack_update_last_result", "Result of the last update per service; 1 for the current status.")
+	finished := gauge("aistack_update_last_timestamp_seconds", "Unix time the last update finished (or started while pending).")
+
+	var firstErr error
+	for _, name := range c.Services.ListServices() {
+		plan, err := c.UpdatePlans(name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if plan == nil {
+			continue
+		}
+		for _, status := range updateResults {
+			result.add(boolValue(plan.Status == status), "service", name, "status", status)
+		}
+		at := plan.CompletedAt
+		if at.IsZero() {
+			at = plan.StartedAt
+		}
+		if !at.IsZero() {
+			finished.add(float64(at.Unix()), "service", name)
+		}
+	}
+	return []*Family{result, finished}, firstErr
+}
+
+func (c *Collector) collectGPULock() ([]*Family, error) {
+	lock, err := c.GPULock.GetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	held := gauge("aistack_gpu_lock_held", "Whether a service holds the GPU lock, labelled with the holder.")
+	age := gauge("aistack_gpu_lock_age_seconds", "Seconds since the GPU lock was acquired; 0 when free.")
+	if lock.Holder == gpulock.HolderNone || lock.Holder == "" {
+		held.add(0, "holder", gpulock.HolderNone.String())
+		age.add(0)
+		return []*Family{held, age}, nil
+	}
+	held.add(1, "holder", lock.Holder.String())
+	age.add(c.now().Sub(lock.SinceTS).Seconds())
+	return []*Family{held, age}, nil
+}
+
+func (c *Collector) collectSuspend() ([]*Family, error) {
+	state, err := c.Suspend.LoadState()
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := gauge("aistack_suspend_enabled", "Whether auto-suspend is enabled.")
+	idle := gauge("aistack_idle_seconds", "Seconds since the last recorded activity.")
+	enabled.add(boolValue(state.Enabled))
+	idle.add(c.Suspend.GetIdleDuration(state).Seconds())
+	return []*Family{enabled, idle}, nil
+}
+
+func (c *Collector) collectModels() ([]*Family, error) {
+	size := gauge("aistack_model_cache_bytes", "Total size of cached models per provider.")
+	count := gauge("aistack_model_cache_models", "Number of cached models per provider.")
+
+	var firstErr error
+	for _, cache := range c.ModelCaches {
+		stats, err := cache.GetStats()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		size.add(float64(stats.TotalSize), "provider", string(stats.Provider))
+		count.add(float64(stats.ModelCount), "provider", string(stats.Provider))
+	}
+	return []*Family{size, count}, firstErr
+}
@@ -0,0 +1,208 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+	"aistack/internal/models"
+	"aistack/internal/services"
+	"aistack/internal/suspend"
+)
+
+type fakeServices struct {
+	statuses []services.ServiceStatus
+	err      error
+}
+
+func (f fakeServices) ListServices() []string {
+	names := make([]string, 0, len(f.statuses))
+	for _, status := range f.statuses {
+		names = append(names, status.Name)
+	}
+	return names
+}
+
+func (f fakeServices) StatusAll(_ context.Context) ([]services.ServiceStatus, error) {
+	return f.statuses, f.err
+}
+
+type fakeGPULock struct{ lock gpulock.LockInfo }
+
+func (f fakeGPULock) GetStatus() (*gpulock.LockInfo, error) { return &f.lock, nil }
+
+type fakeSuspend struct {
+	state suspend.State
+	idle  time.Duration
+}
+
+func (f fakeSuspend) LoadState() (*suspend.State, error)             { return &f.state, nil }
+func (f fakeSuspend) GetIdleDuration(_ *suspend.State) time.Duration { return f.idle }
+
+type fakeModelCache struct {
+	stats *models.CacheStats
+	err   error
+}
+
+func (f fakeModelCache) GetStats() (*models.CacheStats, error) { return f.stats, f.err }
+
+func testCollector() *Collector {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &Collector{
+		Services: fakeServices{statuses: []services.ServiceStatus{
+			{Name: "ollama", State: "running", Health: services.HealthGreen, Latency: 250 * time.Millisecond},
+			{Name: "localai", State: "stopped", Health: services.HealthRed},
+		}},
+		GPULock: fakeGPULock{lock: gpulock.LockInfo{Holder: gpulock.HolderOpenWebUI, SinceTS: now.Add(-90 * time.Second)}},
+		Suspend: fakeSuspend{state: suspend.State{Enabled: true}, idle: 42 * time.Second},
+		ModelCaches: []ModelCacheSource{
+			fakeModelCache{stats: &models.CacheStats{Provider: models.ProviderOllama, TotalSize: 4 << 30, ModelCount: 2}},
+			fakeModelCache{err: errors.New("corrupt state file")},
+		},
+		UpdatePlans: func(name string) (*services.UpdatePlan, error) {
+			if name != "ollama" {
+				return nil, nil
+			}
+			return &services.UpdatePlan{ServiceName: name, Status: "rolled_back", CompletedAt: now.Add(-time.Hour)}, nil
+		},
+		Logger: logging.NewLogger(logging.LevelError),
+		Now:    func() time.Time { return now },
+	}
+}
+
+func TestWrite_Format(t *testing.T) {
+	family := gauge("aistack_test", "Help with a \\ backslash.")
+	family.add(1.5, "service", `say "hi"`+"\n")
+	family.add(2)
+
+	var out bytes.Buffer
+	if err := Write(&out, []*Family{family, gauge("aistack_empty", "Skipped.")}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# HELP aistack_test Help with a \\\\ backslash.\n" +
+		"# TYPE aistack_test gauge\n" +
+		"aistack_test{service=\"say \\\"hi\\\"\\n\"} 1.5\n" +
+		"aistack_test 2\n"
+	if out.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestCollector_Collect(t *testing.T) {
+	var out bytes.Buffer
+	if err := Write(&out, testCollector().Collect(context.Background())); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+
+	for _, line := range []string{
+		`aistack_service_up{service="ollama"} 1`,
+		`aistack_service_up{service="localai"} 0`,
+		`aistack_service_health{service="ollama",health="green"} 1`,
+		`aistack_service_health{service="localai",health="green"} 0`,
+		`aistack_service_health{service="localai",health="red"} 1`,
+		`aistack_service_health_probe_seconds{service="ollama"} 0.25`,
+		`aistack_update_last_result{service="ollama",status="rolled_back"} 1`,
+		`aistack_update_last_result{service="ollama",status="completed"} 0`,
+		`aistack_update_last_timestamp_seconds{service="ollama"} 1.7357292e+09`,
+		`aistack_gpu_lock_held{holder="openwebui"} 1`,
+		`aistack_gpu_lock_age_seconds 90`,
+		`aistack_suspend_enabled 1`,
+		`aistack_idle_seconds 42`,
+		`aistack_model_cache_bytes{provider="ollama"} 4.294967296e+09`,
+		`aistack_model_cache_models{provider="ollama"} 2`,
+		`aistack_source_up{source="services"} 1`,
+		`aistack_source_up{source="models"} 0`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, text)
+		}
+	}
+	if strings.Contains(text, `aistack_update_last_result{service="localai"`) {
+		t.Error("Expected no update metrics for a service without an update plan")
+	}
+}
+
+func TestCollector_FreeGPULockAndFailingServices(t *testing.T) {
+	collector := testCollector()
+	collector.GPULock = fakeGPULock{lock: gpulock.LockInfo{Holder: gpulock.HolderNone}}
+	collector.Services = fakeServices{err: errors.New("runtime unavailable")}
+
+	var out bytes.Buffer
+	if err := Write(&out, collector.Collect(context.Background())); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+
+	for _, line := range []string{
+		`aistack_gpu_lock_held{holder="none"} 0`,
+		`aistack_gpu_lock_age_seconds 0`,
+		`aistack_source_up{source="services"} 0`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, text)
+		}
+	}
+	if strings.Contains(text, "aistack_service_up") {
+		t.Error("Expected no service series when the status source fails")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Handler(testCollector()).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("GET /metrics = %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(recorder.Body.String(), "# TYPE aistack_service_up gauge") {
+		t.Errorf("body = %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	Handler(testCollector()).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /metrics = %d, want 405", recorder.Code)
+	}
+}
+
+func TestServe_StopsOnCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, listener, testCollector(), logging.NewLogger(logging.LevelError)) }()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(body), "aistack_gpu_lock_held") {
+		t.Errorf("body = %s", body)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve() did not stop after cancel")
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+// DefaultListenAddress is the address `aistack metrics serve` listens on by default
+const DefaultListenAddress = ":9469"
+
+// shutdownTimeout bounds how long a stop waits for in-flight scrapes
+const shutdownTimeout = 5 * time.Second
+
+// Handler serves the collected metrics; every request collects fresh values
+func Handler(collector *Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		families := collector.Collect(r.Context())
+		w.Header().Set("Content-Type", ContentType)
+		if err := Write(w, families); err != nil {
+			collector.Logger.Warn("metrics.write_failed", "Failed to write metrics response", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	})
+}
+
+// Serve exposes /metrics on listen until ctx is cancelled
+func Serve(ctx context.Context, listen string, collector *Collector, logger *logging.Logger) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	return serve(ctx, listener, collector, logger)
+}
+
+func serve(ctx context.Context, listener net.Listener, collector *Collector, logger *logging.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(collector))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "aistack metrics exporter: see /metrics")
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(listener)
+	}()
+
+	logger.Info("metrics.serve.started", "Serving Prometheus metrics", map[string]interface{}{
+		"address": listener.Addr().String(),
+	})
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop metrics server: %w", err)
+	}
+
+	logger.Info("metrics.serve.stopped", "Metrics server stopped", nil)
+	return nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"syscall"
+	"time"
+
+	"aistack/internal/fsutil"
+)
+
+// DownloadsDirName is the state subdirectory holding one marker per running download
+const DownloadsDirName = "downloads"
+
+// maxDownloadAge ignores markers older than this, in case the PID was reused
+const maxDownloadAge = 24 * time.Hour
+
+var unsafeMarkerChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ActiveDownload is a model download in progress (marker in the downloads directory)
+type ActiveDownload struct {
+	Provider Provider  `json:"provider"`
+	Model    string    `json:"model"`
+	PID      int       `json:"pid"`
+	Started  time.Time `json:"started"`
+}
+
+// BeginDownload records a running download of model so other processes (scheduled
+// updates) can wait for it; the returned function removes the marker
+func BeginDownload(stateDir string, provider Provider, model string) (func(), error) {
+	dir := filepath.Join(stateDir, DownloadsDirName)
+	if err := os.MkdirAll(dir, fsutil.DefaultStatePermissions); err != nil {
+		return nil, fmt.Errorf("failed to create downloads directory: %w", err)
+	}
+
+	marker := ActiveDownload{Provider: provider, Model: model, PID: os.Getpid(), Started: time.Now().UTC()}
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal download marker: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%d.json", provider, unsafeMarkerChars.ReplaceAllString(model, "_"), marker.PID)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, fsutil.DefaultFilePermissions); err != nil {
+		return nil, fmt.Errorf("failed to write download marker: %w", err)
+	}
+	return func() { _ = os.Remove(path) }, nil
+}
+
+// ActiveDownloads lists the downloads in progress, oldest first. Markers left by
+// processes that exited are removed.
+func ActiveDownloads(stateDir string) ([]ActiveDownload, error) {
+	dir := filepath.Join(stateDir, DownloadsDirName)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read downloads directory: %w", err)
+	}
+
+	var downloads []ActiveDownload
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // removed while listing
+		}
+
+		var marker ActiveDownload
+		if err := json.Unmarshal(data, &marker); err != nil || !processAlive(marker.PID) || time.Since(marker.Started) > maxDownloadAge {
+			_ = os.Remove(path)
+			continue
+		}
+		downloads = append(downloads, marker)
+	}
+
+	sort.Slice(downloads, func(i, j int) bool { return downloads[i].Started.Before(downloads[j].Started) })
+	return downloads, nil
+}
+
+// processAlive reports whether a process with pid exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestActiveDownloads(t *testing.T) {
+	stateDir := t.TempDir()
+
+	if downloads, err := ActiveDownloads(stateDir); err != nil || len(downloads) != 0 {
+		t.Fatalf("ActiveDownloads() without markers = %v, %v", downloads, err)
+	}
+
+	done, err := BeginDownload(stateDir, ProviderOllama, "library/llama3:8b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A marker of an exited process is stale and removed
+	stale, _ := json.Marshal(ActiveDownload{Provider: ProviderOllama, Model: "old", PID: 1 << 30, Started: time.Now()})
+	stalePath := filepath.Join(stateDir, DownloadsDirName, "ollama-old-1.json")
+	if err := os.WriteFile(stalePath, stale, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	downloads, err := ActiveDownloads(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(downloads) != 1 || downloads[0].Model != "library/llama3:8b" || downloads[0].PID != os.Getpid() {
+		t.Errorf("ActiveDownloads() = %+v", downloads)
+	}
+	if _, err := os.Stat(stalePath); !os.IsNotExist(err) {
+		t.Error("Expected the stale marker to be removed")
+	}
+
+	done()
+	if downloads, _ := ActiveDownloads(stateDir); len(downloads) != 0 {
+		t.Errorf("ActiveDownloads() after done = %+v", downloads)
+	}
+}
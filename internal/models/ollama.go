@@ -100,6 +100,17 @@ func (m *OllamaManager) Download(modelName string, progressChan chan<- DownloadP
 		"model":    modelName,
 	})
 
+	// Scheduled updates wait while the marker exists
+	done, err := BeginDownload(m.stateManager.stateDir, ProviderOllama, modelName)
+	if err != nil {
+		m.logger.Warn("model.download.marker_failed", "Failed to record running download", map[string]interface{}{
+			"model": modelName,
+			"error": err.Error(),
+		})
+	} else {
+		defer done()
+	}
+
 	// Send started event
 	if progressChan != nil {
 		progressChan <- DownloadProgress{
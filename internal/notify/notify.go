@@ -0,0 +1,359 @@
+// Package notify delivers selected log events to webhook, ntfy and Slack targets
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+)
+
+// DeadLetterFileName is the default dead-letter file in the state directory
+const DeadLetterFileName = "notifications_dead_letter.jsonl"
+
+// TestEventType is the event `aistack notify test` sends
+const TestEventType = "notify.test"
+
+// ownEventPrefix marks the notifier's own log events, which are never delivered
+const ownEventPrefix = "notify."
+
+const (
+	// queueSize bounds the events waiting for delivery; more are dead-lettered
+	queueSize = 100
+	// defaultCloseTimeout bounds how long Close waits for queued events before the process exits
+	defaultCloseTimeout = 10 * time.Second
+	// flushPollInterval is how often Flush checks whether the queue is delivered
+	flushPollInterval = 10 * time.Millisecond
+)
+
+// errNotDelivered dead-letters events that were still queued when the process exited
+var errNotDelivered = errors.New("not delivered before exit")
+
+// DeadLetter is a notification that could not be delivered
+type DeadLetter struct {
+	Time     time.Time     `json:"time"`
+	Target   string        `json:"target"`
+	Attempts int           `json:"attempts"`
+	Error    string        `json:"error"`
+	Event    logging.Event `json:"event"`
+}
+
+// permanentError is a delivery failure a retry cannot fix (4xx other than 429)
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Notifier sends log events to the configured targets. An installed notifier
+// queues matching events and delivers them in the background, so a slow or
+// unreachable target never holds up the code that logged the event; Flush and
+// Close wait for the queue before a suspend or an exit.
+type Notifier struct {
+	targets    []config.NotificationTarget
+	events     []string
+	attempts   int
+	backoff    time.Duration
+	timeout    time.Duration
+	deadLetter string
+	host       string
+	client     *http.Client
+	logger     *logging.Logger
+	sleep      func(time.Duration)
+
+	queue        chan logging.Event
+	pending      atomic.Int64 // queued or in-delivery events
+	stop         func()       // set by Install
+	closeTimeout time.Duration
+
+	mu sync.Mutex // serialises dead-letter writes
+}
+
+// New creates a notifier from the notifications config; nil when no target is configured
+func New(cfg config.NotificationsConfig, logger *logging.Logger) *Notifier {
+	if len(cfg.Targets) == 0 {
+		return nil
+	}
+
+	deadLetter := cfg.DeadLetterFile
+	if deadLetter == "" {
+		deadLetter = filepath.Join(fsutil.GetStateDir(fsutil.DefaultStateDir), DeadLetterFileName)
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	return &Notifier{
+		targets:      cfg.Targets,
+		events:       cfg.Events,
+		attempts:     max(cfg.RetryAttempts, 1),
+		backoff:      time.Duration(cfg.RetryBackoffSeconds) * time.Second,
+		timeout:      timeout,
+		deadLetter:   deadLetter,
+		host:         host,
+		client:       &http.Client{Timeout: timeout},
+		logger:       logger,
+		sleep:        time.Sleep,
+		queue:        make(chan logging.Event, queueSize),
+		closeTimeout: defaultCloseTimeout,
+	}
+}
+
+// Install subscribes the notifier to the events of all loggers and starts the
+// background delivery; Close unsubscribes it again
+func (n *Notifier) Install() {
+	removeHook := logging.AddHook(n.enqueue)
+	removeFlush := logging.AddFlush(func(timeout time.Duration) { n.Flush(timeout) })
+	n.stop = func() {
+		removeHook()
+		removeFlush()
+	}
+	go n.run()
+}
+
+// Close unsubscribes an installed notifier and waits up to 10s for the
+// queued events; those still queued then are dead-lettered
+func (n *Notifier) Close() {
+	if n.stop == nil {
+		return
+	}
+	n.stop()
+	n.stop = nil
+	if n.Flush(n.closeTimeout) {
+		return
+	}
+	for {
+		select {
+		case event := <-n.queue:
+			n.deadLetterAll(event, 0, errNotDelivered)
+			n.pending.Add(-1)
+		default:
+			return
+		}
+	}
+}
+
+// Flush waits up to timeout until every queued event is delivered or dead-lettered
+func (n *Notifier) Flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for n.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(flushPollInterval)
+	}
+	return true
+}
+
+// enqueue queues events a target subscribes to; a full queue dead-letters the
+// event instead of blocking the logging goroutine
+func (n *Notifier) enqueue(event logging.Event) {
+	if !n.wanted(event) {
+		return
+	}
+	n.pending.Add(1)
+	select {
+	case n.queue <- event:
+	default:
+		n.pending.Add(-1)
+		n.logger.Warn("notify.queue_full", "Notification queue is full", map[string]interface{}{
+			"event": event.Type,
+		})
+		n.deadLetterAll(event, 0, errors.New("notification queue full"))
+	}
+}
+
+func (n *Notifier) run() {
+	for event := range n.queue {
+		n.Handle(event)
+		n.pending.Add(-1)
+	}
+}
+
+// Handle delivers event to every target subscribed to its type
+func (n *Notifier) Handle(event logging.Event) {
+	if strings.HasPrefix(event.Type, ownEventPrefix) {
+		return
+	}
+	for _, target := range n.targets {
+		if n.subscribed(target, event.Type) {
+			_ = n.deliver(target, event)
+		}
+	}
+}
+
+// wanted reports whether any target subscribes to event
+func (n *Notifier) wanted(event logging.Event) bool {
+	if strings.HasPrefix(event.Type, ownEventPrefix) {
+		return false
+	}
+	for _, target := range n.targets {
+		if n.subscribed(target, event.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Notifier) subscribed(target config.NotificationTarget, eventType string) bool {
+	events := n.events
+	if target.Events != nil {
+		events = target.Events
+	}
+	return Matches(events, eventType)
+}
+
+// deadLetterAll dead-letters event for every target subscribed to it
+func (n *Notifier) deadLetterAll(event logging.Event, attempts int, cause error) {
+	for _, target := range n.targets {
+		if n.subscribed(target, event.Type) {
+			n.deadLetterEvent(target, event, attempts, cause)
+		}
+	}
+}
+
+// Matches reports whether eventType matches one of patterns: an exact type,
+// "prefix.*" for a group or "*" for every event
+func Matches(patterns []string, eventType string) bool {
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "*", pattern == eventType:
+			return true
+		case strings.HasSuffix(pattern, ".*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends event to target, retrying with exponential backoff; when every
+// attempt fails the event is appended to the dead-letter file
+func (n *Notifier) deliver(target config.NotificationTarget, event logging.Event) error {
+	var err error
+	attempt := 1
+	for ; attempt <= n.attempts; attempt++ {
+		if err = n.send(target, event); err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt == n.attempts {
+			break
+		}
+		n.sleep(n.backoff << (attempt - 1))
+	}
+
+	n.logger.Warn("notify.delivery_failed", "Notification could not be delivered", map[string]interface{}{
+		"target":   target.Name,
+		"event":    event.Type,
+		"attempts": attempt,
+		"error":    err.Error(),
+	})
+	n.deadLetterEvent(target, event, attempt, err)
+	return err
+}
+
+func (n *Notifier) deadLetterEvent(target config.NotificationTarget, event logging.Event, attempts int, cause error) {
+	if err := n.writeDeadLetter(DeadLetter{
+		Time:     time.Now().UTC(),
+		Target:   target.Name,
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Event:    event,
+	}); err != nil {
+		n.logger.Error("notify.dead_letter.failed", "Failed to write dead-letter file", map[string]interface{}{
+			"path":  n.deadLetter,
+			"error": err.Error(),
+		})
+	}
+}
+
+func (n *Notifier) send(target config.NotificationTarget, event logging.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+
+	req, err := buildRequest(ctx, target, event, n.host)
+	if err != nil {
+		return &permanentError{err: err}
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", target.Name, err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		err := fmt.Errorf("%s answered HTTP %d", target.Name, resp.StatusCode)
+		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return &permanentError{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (n *Notifier) writeDeadLetter(entry DeadLetter) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.deadLetter), fsutil.DefaultStatePermissions); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	file, err := os.OpenFile(n.deadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+	return nil
+}
+
+// TestResult is the outcome of a test notification to one target
+type TestResult struct {
+	Target string
+	Err    error
+}
+
+// Test sends a notify.test event once to every target, or to the named one,
+// regardless of their event filters; failures are returned, not dead-lettered
+func (n *Notifier) Test(targetName string) ([]TestResult, error) {
+	event := logging.Event{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Level:     logging.LevelInfo,
+		Type:      TestEventType,
+		Message:   "Test notification from aistack",
+		Payload:   map[string]interface{}{"host": n.host},
+	}
+
+	var results []TestResult
+	for _, target := range n.targets {
+		if targetName != "" && target.Name != targetName {
+			continue
+		}
+		results = append(results, TestResult{Target: target.Name, Err: n.send(target, event)})
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("unknown notification target: %s", targetName)
+	}
+	return results, nil
+}
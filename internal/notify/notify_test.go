@@ -0,0 +1,332 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   string
+}
+
+// recorder is a webhook endpoint answering with the queued status codes (200 when empty)
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: string(body)})
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func testNotifier(t *testing.T, targets ...config.NotificationTarget) (*Notifier, *[]time.Duration) {
+	t.Helper()
+	cfg := config.DefaultConfig().Notifications
+	cfg.Targets = targets
+	cfg.DeadLetterFile = filepath.Join(t.TempDir(), DeadLetterFileName)
+
+	notifier := New(cfg, logging.NewLogger(logging.LevelError))
+	notifier.host = "gpu-box"
+	var sleeps []time.Duration
+	notifier.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return notifier, &sleeps
+}
+
+func testEvent(eventType string) logging.Event {
+	return logging.Event{
+		Timestamp: "2025-01-01T12:00:00Z",
+		Level:     logging.LevelWarn,
+		Type:      eventType,
+		Message:   "Service update failed and rolled back",
+		Payload:   map[string]interface{}{"service": "ollama", "error": "health check failed"},
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []DeadLetter {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []DeadLetter
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry DeadLetter
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestNew_NoTargets(t *testing.T) {
+	if New(config.DefaultConfig().Notifications, logging.NewLogger(logging.LevelError)) != nil {
+		t.Error("Expected no notifier without targets")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		patterns  []string
+		eventType string
+		want      bool
+	}{
+		{[]string{"suspend.executing"}, "suspend.executing", true},
+		{[]string{"agent.*"}, "agent.circuit.open", true},
+		{[]string{"agent.*"}, "agents.started", false},
+		{[]string{"*"}, "anything", true},
+		{nil, "suspend.executing", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.patterns, tt.eventType); got != tt.want {
+			t.Errorf("Matches(%v, %q) = %v, want %v", tt.patterns, tt.eventType, got, tt.want)
+		}
+	}
+}
+
+func TestHandle_Payloads(t *testing.T) {
+	endpoint := &recorder{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, _ := testNotifier(t,
+		config.NotificationTarget{Name: "hook", Type: config.NotifyTypeWebhook, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer s3cret"}},
+		config.NotificationTarget{Name: "phone", Type: config.NotifyTypeNtfy, URL: server.URL},
+		config.NotificationTarget{Name: "chat", Type: config.NotifyTypeSlack, URL: server.URL},
+	)
+	notifier.Handle(testEvent("services.update_all.rolled_back"))
+
+	if len(endpoint.requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(endpoint.requests))
+	}
+
+	var webhook WebhookPayload
+	if err := json.Unmarshal([]byte(endpoint.requests[0].body), &webhook); err != nil {
+		t.Fatal(err)
+	}
+	if webhook.Event != "services.update_all.rolled_back" || webhook.Host != "gpu-box" || webhook.Payload["service"] != "ollama" {
+		t.Errorf("webhook payload = %+v", webhook)
+	}
+	if endpoint.requests[0].header.Get("Authorization") != "Bearer s3cret" {
+		t.Error("Expected the configured header on the webhook request")
+	}
+
+	ntfy := endpoint.requests[1]
+	if ntfy.header.Get("Title") != "aistack on gpu-box: services.update_all.rolled_back" || ntfy.header.Get("Tags") != "warning" {
+		t.Errorf("ntfy headers = %v", ntfy.header)
+	}
+	if ntfy.body != "Service update failed and rolled back\nerror: health check failed\nservice: ollama" {
+		t.Errorf("ntfy body = %q", ntfy.body)
+	}
+
+	var slack map[string]string
+	if err := json.Unmarshal([]byte(endpoint.requests[2].body), &slack); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(slack["text"], "*aistack on gpu-box* `services.update_all.rolled_back` (warn)") || !strings.Contains(slack["text"], "• service: ollama") {
+		t.Errorf("slack text = %q", slack["text"])
+	}
+}
+
+func TestHandle_FiltersEvents(t *testing.T) {
+	endpoint := &recorder{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, _ := testNotifier(t,
+		config.NotificationTarget{Name: "all", Type: config.NotifyTypeWebhook, URL: server.URL, Events: []string{"*"}},
+		config.NotificationTarget{Name: "default", Type: config.NotifyTypeWebhook, URL: server.URL},
+	)
+
+	notifier.Handle(testEvent("health.report.start"))
+	notifier.Handle(testEvent("notify.delivery_failed"))
+	notifier.Handle(testEvent("suspend.executing"))
+
+	if len(endpoint.requests) != 3 {
+		t.Errorf("got %d requests, want 3 (two for the wildcard target, one default subscription)", len(endpoint.requests))
+	}
+}
+
+func TestHandle_RetriesThenSucceeds(t *testing.T) {
+	endpoint := &recorder{statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, sleeps := testNotifier(t, config.NotificationTarget{Name: "hook", Type: config.NotifyTypeWebhook, URL: server.URL})
+	notifier.Handle(testEvent("gpu.lock.stale_detected"))
+
+	if len(endpoint.requests) != 3 {
+		t.Errorf("got %d requests, want 3", len(endpoint.requests))
+	}
+	if len(*sleeps) != 2 || (*sleeps)[0] != 2*time.Second || (*sleeps)[1] != 4*time.Second {
+		t.Errorf("backoff = %v, want [2s 4s]", *sleeps)
+	}
+	if entries := readDeadLetters(t, notifier.deadLetter); len(entries) != 0 {
+		t.Errorf("Expected no dead letters, got %+v", entries)
+	}
+}
+
+func TestHandle_DeadLetters(t *testing.T) {
+	endpoint := &recorder{statuses: []int{http.StatusNotFound}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, sleeps := testNotifier(t,
+		config.NotificationTarget{Name: "gone", Type: config.NotifyTypeWebhook, URL: server.URL},
+		config.NotificationTarget{Name: "down", Type: config.NotifyTypeSlack, URL: "http://127.0.0.1:1/hook"},
+	)
+	notifier.Handle(testEvent("service.update.health_failed"))
+
+	if len(endpoint.requests) != 1 {
+		t.Errorf("Expected a 404 not to be retried, got %d requests", len(endpoint.requests))
+	}
+	if len(*sleeps) != 2 {
+		t.Errorf("Expected the unreachable target to be retried twice, slept %v", *sleeps)
+	}
+
+	entries := readDeadLetters(t, notifier.deadLetter)
+	if len(entries) != 2 {
+		t.Fatalf("got %d dead letters, want 2", len(entries))
+	}
+	if entries[0].Target != "gone" || entries[0].Attempts != 1 || !strings.Contains(entries[0].Error, "HTTP 404") {
+		t.Errorf("first dead letter = %+v", entries[0])
+	}
+	if entries[1].Target != "down" || entries[1].Attempts != 3 || entries[1].Event.Type != "service.update.health_failed" {
+		t.Errorf("second dead letter = %+v", entries[1])
+	}
+}
+
+func TestInstall_DeliversLoggedEvents(t *testing.T) {
+	endpoint := &recorder{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, _ := testNotifier(t, config.NotificationTarget{Name: "hook", Type: config.NotifyTypeWebhook, URL: server.URL})
+	notifier.Install()
+	logging.NewLogger(logging.LevelError).Info("suspend.executing", "Idle timeout reached, suspending system", nil)
+	notifier.Close()
+	logging.NewLogger(logging.LevelError).Info("suspend.executing", "Idle timeout reached, suspending system", nil)
+
+	if len(endpoint.requests) != 1 || !strings.Contains(endpoint.requests[0].body, `"event":"suspend.executing"`) {
+		t.Errorf("requests = %+v", endpoint.requests)
+	}
+}
+
+func TestInstall_SlowTargetDoesNotBlockLogging(t *testing.T) {
+	release := make(chan struct{})
+	endpoint := &recorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		endpoint.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	notifier, _ := testNotifier(t, config.NotificationTarget{Name: "hook", Type: config.NotifyTypeWebhook, URL: server.URL})
+	notifier.Install()
+	defer notifier.Close()
+
+	start := time.Now()
+	logging.NewLogger(logging.LevelError).Warn("service.update.health_failed", "Update failed", nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("logging took %s, want the delivery in the background", elapsed)
+	}
+	if notifier.Flush(50 * time.Millisecond) {
+		t.Error("Flush() = true while the target has not answered")
+	}
+
+	close(release)
+	if !notifier.Flush(5 * time.Second) {
+		t.Fatal("Flush() = false after the target answered")
+	}
+	if len(endpoint.requests) != 1 {
+		t.Errorf("got %d requests, want 1", len(endpoint.requests))
+	}
+}
+
+func TestClose_DeadLettersUndeliveredEvents(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { <-release }))
+	defer server.Close()
+	defer close(release)
+
+	notifier, _ := testNotifier(t, config.NotificationTarget{Name: "hook", Type: config.NotifyTypeWebhook, URL: server.URL})
+	notifier.closeTimeout = 50 * time.Millisecond
+	notifier.Install()
+	logger := logging.NewLogger(logging.LevelError)
+	logger.Warn("service.update.health_failed", "Update failed", nil) // in delivery
+	logger.Warn("services.update_all.rolled_back", "Rolled back", nil)
+
+	notifier.Close()
+	entries := readDeadLetters(t, notifier.deadLetter)
+	if len(entries) != 1 || entries[0].Event.Type != "services.update_all.rolled_back" || entries[0].Error != errNotDelivered.Error() {
+		t.Errorf("dead letters = %+v, want the queued event", entries)
+	}
+}
+
+func TestEnqueue_FullQueueDeadLetters(t *testing.T) {
+	notifier, _ := testNotifier(t, config.NotificationTarget{Name: "hook", Type: config.NotifyTypeWebhook, URL: "http://127.0.0.1:1/hook"})
+
+	// Without Install nothing takes events off the queue
+	for i := 0; i <= queueSize; i++ {
+		notifier.enqueue(testEvent("gpu.lock.stale_detected"))
+	}
+	notifier.enqueue(testEvent("health.report.start")) // not subscribed, not queued
+
+	if got := notifier.pending.Load(); got != queueSize {
+		t.Errorf("pending = %d, want %d", got, queueSize)
+	}
+	entries := readDeadLetters(t, notifier.deadLetter)
+	if len(entries) != 1 || !strings.Contains(entries[0].Error, "queue full") {
+		t.Errorf("dead letters = %+v, want the overflowing event", entries)
+	}
+}
+
+func TestTest(t *testing.T) {
+	endpoint := &recorder{statuses: []int{http.StatusOK, http.StatusInternalServerError}}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	notifier, sleeps := testNotifier(t,
+		config.NotificationTarget{Name: "a", Type: config.NotifyTypeWebhook, URL: server.URL, Events: []string{"none"}},
+		config.NotificationTarget{Name: "b", Type: config.NotifyTypeNtfy, URL: server.URL},
+	)
+
+	results, err := notifier.Test("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("Test() = %+v", results)
+	}
+	if len(*sleeps) != 0 || len(readDeadLetters(t, notifier.deadLetter)) != 0 {
+		t.Error("Expected test notifications not to be retried or dead-lettered")
+	}
+
+	if _, err := notifier.Test("missing"); err == nil {
+		t.Error("Expected an error for an unknown target")
+	}
+}
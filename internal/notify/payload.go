@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+// WebhookPayload is the body of generic webhook notifications
+type WebhookPayload struct {
+	Event   string                 `json:"event"`
+	Level   logging.Level          `json:"level"`
+	Message string                 `json:"message"`
+	Host    string                 `json:"host"`
+	Time    string                 `json:"time"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+// buildRequest renders event in the format of the target type
+func buildRequest(ctx context.Context, target config.NotificationTarget, event logging.Event, host string) (*http.Request, error) {
+	var body []byte
+	headers := map[string]string{}
+
+	switch target.Type {
+	case config.NotifyTypeWebhook:
+		data, err := json.Marshal(WebhookPayload{
+			Event:   event.Type,
+			Level:   event.Level,
+			Message: event.Message,
+			Host:    host,
+			Time:    event.Timestamp,
+			Payload: event.Payload,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+		}
+		body = data
+		headers["Content-Type"] = "application/json"
+	case config.NotifyTypeNtfy:
+		body = []byte(strings.TrimSpace(event.Message + "\n" + formatPayload(event.Payload, "")))
+		headers["Content-Type"] = "text/plain; charset=utf-8"
+		headers["Title"] = fmt.Sprintf("aistack on %s: %s", host, event.Type)
+		headers["Priority"], headers["Tags"] = ntfyPriority(event.Level)
+	case config.NotifyTypeSlack:
+		text := fmt.Sprintf("*aistack on %s* `%s` (%s): %s", host, event.Type, event.Level, event.Message)
+		if details := formatPayload(event.Payload, "• "); details != "" {
+			text += "\n" + details
+		}
+		data, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal slack payload: %w", err)
+		}
+		body = data
+		headers["Content-Type"] = "application/json"
+	default:
+		return nil, fmt.Errorf("unsupported notification type: %s", target.Type)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification URL: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range target.Headers {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
+
+// ntfyPriority maps the log level to ntfy's Priority and Tags headers
+func ntfyPriority(level logging.Level) (priority, tags string) {
+	switch level {
+	case logging.LevelError:
+		return "high", "rotating_light"
+	case logging.LevelWarn:
+		return "default", "warning"
+	default:
+		return "default", "information_source"
+	}
+}
+
+// formatPayload renders payload fields as sorted "key: value" lines
+func formatPayload(payload map[string]interface{}, bullet string) string {
+	keys := make([]string, 0, len(payload))
+	for key := range payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s%s: %v", bullet, key, payload[key]))
+	}
+	return strings.Join(lines, "\n")
+}
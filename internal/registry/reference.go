@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DockerHub is the registry of references without a registry host
+const DockerHub = "docker.io"
+
+// dockerHubAPIHost serves the v2 API of Docker Hub
+const dockerHubAPIHost = "registry-1.docker.io"
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string // host[:port]; DockerHub when the reference names none
+	Repository string // official Docker Hub images get the library/ prefix
+	Tag        string // latest when neither tag nor digest is given
+	Digest     string // sha256:... of an image@digest reference
+}
+
+// ParseReference splits an image reference like ollama/ollama:latest,
+// ghcr.io/open-webui/open-webui:main or localhost:5000/demo@sha256:... into its parts
+func ParseReference(image string) (Reference, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return Reference{}, fmt.Errorf("empty image reference")
+	}
+
+	var ref Reference
+	name := image
+	if before, digest, ok := strings.Cut(name, "@"); ok {
+		name, ref.Digest = before, digest
+		if !strings.HasPrefix(digest, "sha256:") {
+			return Reference{}, fmt.Errorf("invalid digest in image reference %s", image)
+		}
+	}
+	// A colon after the last slash separates the tag (one before it is a registry port)
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	ref.Registry = DockerHub
+	if host, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		ref.Registry, name = host, rest
+	}
+	if ref.Registry == DockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" || strings.HasSuffix(name, "/") {
+		return Reference{}, fmt.Errorf("invalid image reference %s", image)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// manifestReference is the tag or digest the manifest endpoint is asked for
+func (r Reference) manifestReference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String returns the normalized reference
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
@@ -0,0 +1,270 @@
+// Package registry resolves image digests from Docker Registry HTTP API v2 registries
+// without pulling the images
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"aistack/internal/config"
+)
+
+// manifestMediaTypes are accepted for manifest lookups. Indexes come first so a
+// multi-arch tag resolves to the digest docker records as repo digest on pull.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ErrNotFound reports a repository or tag the registry does not know
+var ErrNotFound = errors.New("manifest unknown")
+
+// challengeParam matches the key="value" pairs of a WWW-Authenticate challenge
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Client looks up manifest digests. Bearer tokens are fetched anonymously on a
+// 401 challenge and cached per repository.
+type Client struct {
+	http     *http.Client
+	mirrors  map[string][]string
+	insecure map[string]bool
+
+	mu     sync.Mutex
+	tokens map[string]string // endpoint + repository -> bearer token
+}
+
+// New creates a client for the registries config; timeout bounds each request (0: none)
+func New(cfg config.RegistriesConfig, timeout time.Duration) *Client {
+	insecure := make(map[string]bool, len(cfg.Insecure))
+	for _, registry := range cfg.Insecure {
+		insecure[registry] = true
+	}
+	return &Client{
+		http:     &http.Client{Timeout: timeout},
+		mirrors:  cfg.Mirrors,
+		insecure: insecure,
+		tokens:   make(map[string]string),
+	}
+}
+
+// Digest returns the digest image resolves to in its registry. Mirrors of the
+// registry are asked first, in order; an image@digest reference returns its digest.
+func (c *Client) Digest(ctx context.Context, image string) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	var errs []error
+	for _, endpoint := range c.endpoints(ref.Registry) {
+		digest, err := c.manifestDigest(ctx, endpoint, ref)
+		if err == nil {
+			return digest, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+	return "", fmt.Errorf("failed to resolve %s: %w", image, errors.Join(errs...))
+}
+
+// endpoints returns the base URLs asked for a registry: its mirrors, then the
+// registry itself. Loopback registries are reached over HTTP, as docker does.
+func (c *Client) endpoints(registry string) []string {
+	endpoints := make([]string, 0, len(c.mirrors[registry])+1)
+	for _, mirror := range c.mirrors[registry] {
+		endpoints = append(endpoints, strings.TrimSuffix(mirror, "/"))
+	}
+
+	host := registry
+	if registry == DockerHub {
+		host = dockerHubAPIHost
+	}
+	scheme := "https"
+	if c.insecure[registry] || isLoopback(registry) {
+		scheme = "http"
+	}
+	return append(endpoints, scheme+"://"+host)
+}
+
+func isLoopback(registry string) bool {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// manifestDigest asks one endpoint for the manifest digest of ref
+func (c *Client) manifestDigest(ctx context.Context, endpoint string, ref Reference) (string, error) {
+	manifestURL := endpoint + "/v2/" + ref.Repository + "/manifests/" + ref.manifestReference()
+	cacheKey := endpoint + "/" + ref.Repository
+
+	resp, err := c.authorizedManifestRequest(ctx, http.MethodHead, manifestURL, cacheKey, ref.Repository)
+	if err != nil {
+		return "", err
+	}
+	closeResponse(resp)
+	if err := checkManifestResponse(resp); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// Some registries leave the header out of HEAD responses: hash the manifest
+	resp, err = c.authorizedManifestRequest(ctx, http.MethodGet, manifestURL, cacheKey, ref.Repository)
+	if err != nil {
+		return "", err
+	}
+	defer closeResponse(resp)
+	if err := checkManifestResponse(resp); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// authorizedManifestRequest sends a manifest request and, on a bearer challenge,
+// fetches a token and sends it once more
+func (c *Client) authorizedManifestRequest(ctx context.Context, method, manifestURL, cacheKey, repository string) (*http.Response, error) {
+	resp, err := c.manifestRequest(ctx, method, manifestURL, c.cachedToken(cacheKey))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	closeResponse(resp)
+
+	token, err := c.fetchToken(ctx, challenge, repository)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.tokens[cacheKey] = token
+	c.mu.Unlock()
+	return c.manifestRequest(ctx, method, manifestURL, token)
+}
+
+func (c *Client) manifestRequest(ctx context.Context, method, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build manifest request: %w", err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("manifest request failed: %w", err)
+	}
+	return resp, nil
+}
+
+func (c *Client) cachedToken(cacheKey string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[cacheKey]
+}
+
+// fetchToken answers a `Bearer realm="...",service="...",scope="..."` challenge with
+// an anonymous pull token
+func (c *Client) fetchToken(ctx context.Context, challenge, repository string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry requires %q authentication, only anonymous bearer tokens are supported", scheme)
+	}
+
+	values := make(map[string]string)
+	for _, match := range challengeParam.FindAllStringSubmatch(params, -1) {
+		values[strings.ToLower(match[1])] = match[2]
+	}
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Host == "" || (realm.Scheme != "http" && realm.Scheme != "https") {
+		return "", fmt.Errorf("invalid token realm in challenge %q", challenge)
+	}
+
+	query := realm.Query()
+	if service := values["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := values["scope"]
+	if scope == "" {
+		scope = "repository:" + repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer closeResponse(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request returned %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("token response contains no token")
+}
+
+func checkManifestResponse(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("manifest request returned %s", resp.Status)
+	}
+}
+
+// closeResponse drains and closes a response body so the connection can be reused
+func closeResponse(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
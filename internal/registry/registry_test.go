@@ -0,0 +1,185 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"aistack/internal/config"
+)
+
+// fakeRegistry serves manifests of tag -> digest pairs behind anonymous bearer tokens
+type fakeRegistry struct {
+	*httptest.Server
+	manifests map[string]string // "repository:tag" -> digest
+	noDigest  bool              // leave Docker-Content-Digest out, as some registries do
+
+	mu       sync.Mutex
+	requests []string // "METHOD path" of manifest requests
+	tokens   int      // token requests
+}
+
+const fakeManifest = `{"schemaVersion":2}`
+
+func newFakeRegistry(t *testing.T, manifests map[string]string) *fakeRegistry {
+	t.Helper()
+	registry := &fakeRegistry{manifests: manifests}
+	registry.Server = httptest.NewServer(http.HandlerFunc(registry.serve))
+	t.Cleanup(registry.Close)
+	return registry
+}
+
+func (f *fakeRegistry) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		f.mu.Lock()
+		f.tokens++
+		f.mu.Unlock()
+		if r.URL.Query().Get("service") != "fake" || !strings.HasPrefix(r.URL.Query().Get("scope"), "repository:") {
+			http.Error(w, "bad token request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token":"secret"}`))
+		return
+	}
+
+	repository, tag, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+f.URL+`/token",service="fake",scope="repository:`+repository+`:pull"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+		http.Error(w, "expected an index media type first", http.StatusBadRequest)
+		return
+	}
+	digest, found := f.manifests[repository+":"+tag]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !f.noDigest {
+		w.Header().Set("Docker-Content-Digest", digest)
+	}
+	if r.Method == http.MethodGet {
+		_, _ = w.Write([]byte(fakeManifest))
+	}
+}
+
+// host returns the registry host[:port] of the fake server
+func (f *fakeRegistry) host() string {
+	return strings.TrimPrefix(f.URL, "http://")
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Reference
+	}{
+		{"ollama/ollama", Reference{Registry: DockerHub, Repository: "ollama/ollama", Tag: "latest"}},
+		{"nginx:1.27", Reference{Registry: DockerHub, Repository: "library/nginx", Tag: "1.27"}},
+		{"ghcr.io/open-webui/open-webui:main", Reference{Registry: "ghcr.io", Repository: "open-webui/open-webui", Tag: "main"}},
+		{"localhost:5000/demo", Reference{Registry: "localhost:5000", Repository: "demo", Tag: "latest"}},
+		{"quay.io/go-skynet/local-ai:v2@sha256:abc", Reference{Registry: "quay.io", Repository: "go-skynet/local-ai", Tag: "v2", Digest: "sha256:abc"}},
+		{"localai/localai@sha256:def", Reference{Registry: DockerHub, Repository: "localai/localai", Digest: "sha256:def"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseReference(tt.image)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, %v, want %+v", tt.image, got, err, tt.want)
+		}
+	}
+
+	for _, image := range []string{"", "demo@md5:abc", "registry.lan/"} {
+		if _, err := ParseReference(image); err == nil {
+			t.Errorf("ParseReference(%q) error = nil", image)
+		}
+	}
+}
+
+func TestClient_DigestWithTokenAuth(t *testing.T) {
+	registry := newFakeRegistry(t, map[string]string{"team/demo:1.0": "sha256:111"})
+	client := New(config.RegistriesConfig{}, 0)
+
+	for i := 0; i < 2; i++ {
+		digest, err := client.Digest(context.Background(), registry.host()+"/team/demo:1.0")
+		if err != nil || digest != "sha256:111" {
+			t.Fatalf("Digest() = %q, %v", digest, err)
+		}
+	}
+	// The token is fetched once and reused; a HEAD is enough, nothing is pulled
+	if registry.tokens != 1 {
+		t.Errorf("token requests = %d, want 1", registry.tokens)
+	}
+	for _, request := range registry.requests {
+		if !strings.HasPrefix(request, http.MethodHead+" ") {
+			t.Errorf("unexpected manifest request %s", request)
+		}
+	}
+
+	if _, err := client.Digest(context.Background(), registry.host()+"/team/missing:1.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Digest() of an unknown tag error = %v, want ErrNotFound", err)
+	}
+	if digest, err := client.Digest(context.Background(), registry.host()+"/team/demo@sha256:222"); err != nil || digest != "sha256:222" {
+		t.Errorf("Digest() of a digest reference = %q, %v", digest, err)
+	}
+}
+
+func TestClient_DigestFromManifestBody(t *testing.T) {
+	registry := newFakeRegistry(t, map[string]string{"demo:latest": "ignored"})
+	registry.noDigest = true
+
+	digest, err := New(config.RegistriesConfig{}, 0).Digest(context.Background(), registry.host()+"/demo")
+	sum := sha256.Sum256([]byte(fakeManifest))
+	if want := "sha256:" + hex.EncodeToString(sum[:]); err != nil || digest != want {
+		t.Errorf("Digest() = %q, %v, want %s", digest, err, want)
+	}
+}
+
+func TestClient_DigestUsesMirrors(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+	mirror := newFakeRegistry(t, map[string]string{"library/nginx:latest": "sha256:333"})
+
+	// Docker Hub references resolve through the mirrors without reaching Docker Hub
+	client := New(config.RegistriesConfig{Mirrors: map[string][]string{DockerHub: {broken.URL, mirror.URL + "/"}}}, 0)
+	digest, err := client.Digest(context.Background(), "nginx")
+	if err != nil || digest != "sha256:333" {
+		t.Fatalf("Digest() = %q, %v", digest, err)
+	}
+	if len(mirror.requests) == 0 || mirror.requests[len(mirror.requests)-1] != "HEAD /v2/library/nginx/manifests/latest" {
+		t.Errorf("mirror requests = %v", mirror.requests)
+	}
+}
+
+func TestClient_Endpoints(t *testing.T) {
+	client := New(config.RegistriesConfig{Insecure: []string{"registry.lan:5000"}}, 0)
+
+	tests := map[string]string{
+		DockerHub:           "https://registry-1.docker.io",
+		"ghcr.io":           "https://ghcr.io",
+		"registry.lan:5000": "http://registry.lan:5000",
+		"localhost:5000":    "http://localhost:5000",
+		"127.0.0.1:5000":    "http://127.0.0.1:5000",
+	}
+	for registry, want := range tests {
+		if endpoints := client.endpoints(registry); len(endpoints) != 1 || endpoints[0] != want {
+			t.Errorf("endpoints(%s) = %v, want [%s]", registry, endpoints, want)
+		}
+	}
+}
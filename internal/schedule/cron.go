@@ -0,0 +1,227 @@
+// Package schedule parses cron expressions and daily maintenance windows
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit bounds Next for expressions that never match (e.g. "0 0 30 2 *")
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// macros are the supported @ shorthands
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// dayNames are the day-of-week names, Sunday first like time.Weekday
+var dayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// field describes the value range and names of one cron field
+type field struct {
+	name     string
+	min, max int
+	names    []string // names for min, min+1, ...
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: monthNames}
+	dowField    = field{name: "day of week", min: 0, max: 7, names: dayNames} // 7 is Sunday too
+)
+
+// Cron is a parsed five-field cron expression: minute hour day-of-month month day-of-week
+type Cron struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// When both day fields are restricted a day matches if either does (classic cron)
+	domAny bool
+	dowAny bool
+}
+
+// ParseCron parses a five-field cron expression or one of the @hourly, @daily,
+// @weekly, @monthly and @yearly shorthands. Fields accept *, numbers, names
+// (jan, mon), ranges (1-5), lists (1,15) and steps (*/15, 8-18/2).
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.ToLower(strings.TrimSpace(expr))
+	if macro, ok := macros[spec]; ok {
+		spec = macro
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("unknown cron shorthand %q", expr)
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week), got %d", expr, len(parts))
+	}
+
+	c := &Cron{expr: strings.TrimSpace(expr)}
+	var err error
+	if c.minute, err = parseField(parts[0], minuteField); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(parts[1], hourField); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(parts[2], domField); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(parts[3], monthField); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(parts[4], dowField); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domAny = strings.HasPrefix(parts[2], "*")
+	c.dowAny = strings.HasPrefix(parts[4], "*")
+	return c, nil
+}
+
+// String returns the expression as written
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next returns the first activation strictly after t, in t's location. Times
+// skipped by a DST change do not fire. The zero time is returned when the
+// expression matches no date within the next five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.Add(searchLimit)
+
+	for next.Before(limit) {
+		year, month, day := next.Date()
+		switch {
+		case !has(c.month, int(month)):
+			next = advance(next, time.Date(year, month+1, 1, 0, 0, 0, 0, loc))
+		case !c.dayMatches(next):
+			next = advance(next, time.Date(year, month, day+1, 0, 0, 0, 0, loc))
+		case !has(c.hour, next.Hour()):
+			next = advance(next, time.Date(year, month, day, next.Hour()+1, 0, 0, 0, loc))
+		case !has(c.minute, next.Minute()):
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := has(c.dom, t.Day())
+	dowMatch := has(c.dow, int(t.Weekday()))
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// advance moves to candidate, or by one minute when a DST change would not move forward
+func advance(current, candidate time.Time) time.Time {
+	if !candidate.After(current) {
+		return current.Add(time.Minute)
+	}
+	return candidate
+}
+
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
+
+// parseField parses a comma-separated cron field into a bit set
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(spec, ",") {
+		bits, err := parseItem(item, f)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q: %w", f.name, spec, err)
+		}
+		set |= bits
+	}
+	return set, nil
+}
+
+// parseItem parses *, a value or a range, each with an optional /step
+func parseItem(item string, f field) (uint64, error) {
+	rangeSpec, stepSpec, hasStep := strings.Cut(item, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepSpec)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("step must be a positive number, got %q", stepSpec)
+		}
+		step = n
+	}
+
+	var lo, hi int
+	switch {
+	case rangeSpec == "*":
+		lo, hi = f.min, f.max
+		if f.name == dowField.name {
+			hi = 6 // 7 would repeat Sunday
+		}
+	case strings.Contains(rangeSpec, "-"):
+		loSpec, hiSpec, _ := strings.Cut(rangeSpec, "-")
+		var err error
+		if lo, err = parseValue(loSpec, f); err != nil {
+			return 0, err
+		}
+		if hi, err = parseValue(hiSpec, f); err != nil {
+			return 0, err
+		}
+		if hi < lo {
+			return 0, fmt.Errorf("range %q ends before it starts", rangeSpec)
+		}
+	default:
+		value, err := parseValue(rangeSpec, f)
+		if err != nil {
+			return 0, err
+		}
+		lo, hi = value, value
+		if hasStep {
+			hi = f.max
+		}
+	}
+
+	var set uint64
+	for value := lo; value <= hi; value += step {
+		set |= 1 << uint(value)
+	}
+	return set, nil
+}
+
+func parseValue(spec string, f field) (int, error) {
+	for i, name := range f.names {
+		if spec == name {
+			return f.min + i, nil
+		}
+	}
+	value, err := strconv.Atoi(spec)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", spec)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%d is outside %d-%d", value, f.min, f.max)
+	}
+	return value, nil
+}
@@ -0,0 +1,126 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParseCron(t *testing.T, expr string) *Cron {
+	t.Helper()
+	c, err := ParseCron(expr)
+	if err != nil {
+		t.Fatalf("ParseCron(%q) error = %v", expr, err)
+	}
+	return c
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * foo *", "@fortnightly"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	from := time.Date(2025, 3, 14, 10, 17, 30, 0, time.UTC) // a Friday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"30 3 * * sun", time.Date(2025, 3, 16, 3, 30, 0, 0, time.UTC)},
+		{"30 3 * * 7", time.Date(2025, 3, 16, 3, 30, 0, 0, time.UTC)},
+		{"0 4 * * mon-fri", time.Date(2025, 3, 17, 4, 0, 0, 0, time.UTC)},
+		{"0 2 1 * *", time.Date(2025, 4, 1, 2, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 20th or any Monday
+		{"0 0 20 * mon", time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := mustParseCron(t, tt.expr).Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, want %v", tt.expr, from, got, tt.want)
+		}
+	}
+
+	if got := mustParseCron(t, "0 0 30 2 *").Next(from); !got.IsZero() {
+		t.Errorf("Expected no activation for February 30th, got %v", got)
+	}
+}
+
+func TestCron_NextInTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+
+	// 02:30 does not exist on 2025-03-30 in Berlin; the next match is a day later
+	from := time.Date(2025, 3, 29, 12, 0, 0, 0, berlin)
+	got := mustParseCron(t, "30 2 * * *").Next(from)
+	if want := time.Date(2025, 3, 31, 2, 30, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("Next() across DST = %v, want %v", got, want)
+	}
+
+	got = mustParseCron(t, "0 3 * * *").Next(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC).In(berlin))
+	if want := time.Date(2025, 6, 1, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() in Berlin = %v, want %v (03:00 CEST)", got, want)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	if w, err := ParseWindow("", "", nil); err != nil || w != nil {
+		t.Errorf("ParseWindow() without times = %v, %v, want nil window", w, err)
+	}
+	for _, tc := range [][3]string{{"02:00", "02:00", ""}, {"2am", "05:00", ""}, {"02:00", "", ""}, {"02:00", "05:00", "someday"}, {"", "", "mon"}} {
+		var days []string
+		if tc[2] != "" {
+			days = []string{tc[2]}
+		}
+		if _, err := ParseWindow(tc[0], tc[1], days); err == nil {
+			t.Errorf("ParseWindow(%q, %q, %v) succeeded, want error", tc[0], tc[1], days)
+		}
+	}
+}
+
+func TestWindow_Contains(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 3, day, hour, minute, 0, 0, time.UTC) // March 10th 2025 is a Monday
+	}
+
+	daily, err := ParseWindow("02:00", "05:00", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overnight, err := ParseWindow("23:30", "01:00", []string{"sat", "Sun"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		window *Window
+		t      time.Time
+		want   bool
+	}{
+		{daily, at(10, 2, 0), true},
+		{daily, at(10, 4, 59), true},
+		{daily, at(10, 5, 0), false},
+		{daily, at(10, 1, 59), false},
+		{overnight, at(15, 23, 45), true},  // Saturday night
+		{overnight, at(16, 0, 30), true},   // Sunday morning, opened Saturday
+		{overnight, at(17, 0, 30), true},   // Monday morning, opened Sunday
+		{overnight, at(14, 23, 45), false}, // Friday night
+		{overnight, at(15, 0, 30), false},  // Saturday morning, opened Friday
+		{nil, at(10, 12, 0), true},
+	}
+	for _, tt := range tests {
+		if got := tt.window.Contains(tt.t); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %v, want %v", tt.window, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+
+	if overnight.String() != "23:30-01:00 sun,sat" {
+		t.Errorf("String() = %q", overnight.String())
+	}
+}
@@ -0,0 +1,103 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily maintenance window. An end before the start wraps past
+// midnight; days restrict the weekdays the window opens on. A nil window is
+// always open.
+type Window struct {
+	start int // minutes after midnight
+	end   int
+	days  uint8 // bit per time.Weekday; 0 means every day
+}
+
+// ParseWindow parses a window from HH:MM start and end times and optional day
+// names (mon, tue, ...). It returns nil when start and end are both empty.
+func ParseWindow(start, end string, days []string) (*Window, error) {
+	if start == "" && end == "" {
+		if len(days) > 0 {
+			return nil, fmt.Errorf("window days need a start and end time")
+		}
+		return nil, nil
+	}
+
+	w := &Window{}
+	var err error
+	if w.start, err = parseClock(start); err != nil {
+		return nil, fmt.Errorf("invalid window start: %w", err)
+	}
+	if w.end, err = parseClock(end); err != nil {
+		return nil, fmt.Errorf("invalid window end: %w", err)
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("window start and end must differ")
+	}
+
+	for _, day := range days {
+		index := -1
+		for i, name := range dayNames {
+			if strings.ToLower(strings.TrimSpace(day)) == name {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("invalid window day %q (use %s)", day, strings.Join(dayNames, ", "))
+		}
+		w.days |= 1 << uint(index)
+	}
+	return w, nil
+}
+
+// Contains reports whether t falls inside the window, in t's location. A window
+// wrapping past midnight belongs to the day it opened on.
+func (w *Window) Contains(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case w.start < w.end:
+		if minute < w.start || minute >= w.end {
+			return false
+		}
+	case minute >= w.start:
+	case minute < w.end:
+		day = (day + 6) % 7 // opened yesterday
+	default:
+		return false
+	}
+	return w.days == 0 || w.days&(1<<uint(day)) != 0
+}
+
+// String renders the window as "HH:MM-HH:MM" followed by its days, if restricted
+func (w *Window) String() string {
+	if w == nil {
+		return "always"
+	}
+	text := fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+	if w.days != 0 {
+		var names []string
+		for i, name := range dayNames {
+			if w.days&(1<<uint(i)) != 0 {
+				names = append(names, name)
+			}
+		}
+		text += " " + strings.Join(names, ",")
+	}
+	return text
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
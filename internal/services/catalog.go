@@ -0,0 +1,331 @@
+package services
+
+import (
+	_ "embed"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/configdir"
+)
+
+// CatalogFileName is the user catalog file inside the config directory
+const CatalogFileName = "services.yaml"
+
+//go:embed catalog.yaml
+var defaultCatalogYAML []byte
+
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var (
+	defaultCatalogOnce sync.Once
+	defaultCatalog     *Catalog
+)
+
+// ServiceSpec declares a service in the catalog
+type ServiceSpec struct {
+	Name              string                   `yaml:"name"`
+	Image             string                   `yaml:"image"`
+	Compose           string                   `yaml:"compose"`
+	Volumes           []string                 `yaml:"volumes"`
+	HealthURL         string                   `yaml:"health_url"`
+	GPULock           bool                     `yaml:"gpu_lock"`
+	GPU               bool                     `yaml:"gpu"`
+	ParallelInstances bool                     `yaml:"parallel_instances"` // two containers may share the volumes (blue-green updates)
+	UpdateOrder       int                      `yaml:"update_order"`
+	Profiles          []string                 `yaml:"profiles"`
+	DependsOn         []string                 `yaml:"depends_on"`
+	Port              int                      `yaml:"port"`
+	Env               map[string]string        `yaml:"env"`
+	Resources         config.ResourcesConfig   `yaml:"resources"`
+	Health            config.HealthCheckConfig `yaml:"-"` // services.<name>.health from config.yaml
+	// UpdateStrategy is services.<name>.update_strategy, falling back to updates.strategy
+	UpdateStrategy string `yaml:"-"`
+}
+
+// ComposePath resolves the compose template against composeDir
+func (s ServiceSpec) ComposePath(composeDir string) string {
+	compose := s.Compose
+	if compose == "" {
+		compose = s.Name + ".yaml"
+	}
+	if filepath.IsAbs(compose) {
+		return filepath.Clean(compose)
+	}
+	return filepath.Join(composeDir, compose)
+}
+
+// InProfile reports whether the service belongs to the named install profile
+func (s ServiceSpec) InProfile(profile string) bool {
+	for _, p := range s.Profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogEntry is the on-disk form; the booleans are pointers so overrides can tell unset from false
+type catalogEntry struct {
+	Name              string                 `yaml:"name"`
+	Image             string                 `yaml:"image"`
+	Compose           string                 `yaml:"compose"`
+	Volumes           []string               `yaml:"volumes"`
+	HealthURL         string                 `yaml:"health_url"`
+	GPULock           *bool                  `yaml:"gpu_lock"`
+	GPU               *bool                  `yaml:"gpu"`
+	ParallelInstances *bool                  `yaml:"parallel_instances"`
+	UpdateOrder       int                    `yaml:"update_order"`
+	Profiles          []string               `yaml:"profiles"`
+	DependsOn         []string               `yaml:"depends_on"`
+	Port              int                    `yaml:"port"`
+	Env               map[string]string      `yaml:"env"`
+	Resources         config.ResourcesConfig `yaml:"resources"`
+}
+
+type catalogFile struct {
+	Services []catalogEntry `yaml:"services"`
+}
+
+// Catalog is the ordered set of services aistack manages
+type Catalog struct {
+	specs []ServiceSpec
+	index map[string]int
+}
+
+// DefaultCatalog returns the shipped service catalog
+func DefaultCatalog() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		catalog, err := parseCatalog(defaultCatalogYAML, nil, "embedded catalog")
+		if err != nil {
+			// The embedded catalog is covered by tests; failing here is a build defect
+			panic(err)
+		}
+		defaultCatalog = catalog
+	})
+	return defaultCatalog
+}
+
+// LoadCatalog loads the shipped catalog and merges <config dir>/services.yaml if present
+func LoadCatalog() (*Catalog, error) {
+	return LoadCatalogFrom(filepath.Join(configdir.ConfigDir(), CatalogFileName))
+}
+
+// LoadCatalogFrom loads the shipped catalog and merges the user catalog at path if present
+func LoadCatalogFrom(path string) (*Catalog, error) {
+	catalog := DefaultCatalog()
+
+	data, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path is derived from the config directory
+	if err != nil {
+		if os.IsNotExist(err) {
+			return catalog, nil
+		}
+		return nil, fmt.Errorf("failed to read service catalog: %w", err)
+	}
+
+	return parseCatalog(data, catalog, path)
+}
+
+// parseCatalog parses data and merges it over base (nil for a fresh catalog)
+func parseCatalog(data []byte, base *Catalog, source string) (*Catalog, error) {
+	var file catalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse service catalog %s: %w", source, err)
+	}
+
+	catalog := &Catalog{index: make(map[string]int)}
+	if base != nil {
+		catalog.specs = append(catalog.specs, base.specs...)
+		for name, idx := range base.index {
+			catalog.index[name] = idx
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range file.Services {
+		if !serviceNamePattern.MatchString(entry.Name) {
+			return nil, fmt.Errorf("service catalog %s: services[%d].name: invalid name '%s'", source, i, entry.Name)
+		}
+		if seen[entry.Name] {
+			return nil, fmt.Errorf("service catalog %s: duplicate service '%s'", source, entry.Name)
+		}
+		seen[entry.Name] = true
+
+		if idx, ok := catalog.index[entry.Name]; ok {
+			catalog.specs[idx] = mergeSpec(catalog.specs[idx], entry)
+			continue
+		}
+
+		catalog.index[entry.Name] = len(catalog.specs)
+		catalog.specs = append(catalog.specs, mergeSpec(ServiceSpec{Name: entry.Name}, entry))
+	}
+
+	for _, spec := range catalog.specs {
+		if err := validateSpec(spec); err != nil {
+			return nil, fmt.Errorf("service catalog %s: %w", source, err)
+		}
+		for i, dep := range spec.DependsOn {
+			if _, ok := catalog.index[dep]; (!ok && dep != BackendDependency) || dep == spec.Name {
+				return nil, fmt.Errorf("service catalog %s: %s.depends_on[%d]: unknown dependency '%s'", source, spec.Name, i, dep)
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+// mergeSpec overlays the fields set in entry onto spec
+func mergeSpec(spec ServiceSpec, entry catalogEntry) ServiceSpec {
+	if entry.Image != "" {
+		spec.Image = entry.Image
+	}
+	if entry.Compose != "" {
+		spec.Compose = entry.Compose
+	}
+	if entry.Volumes != nil {
+		spec.Volumes = append([]string(nil), entry.Volumes...)
+	}
+	if entry.HealthURL != "" {
+		spec.HealthURL = entry.HealthURL
+	}
+	if entry.GPULock != nil {
+		spec.GPULock = *entry.GPULock
+	}
+	if entry.GPU != nil {
+		spec.GPU = *entry.GPU
+	}
+	if entry.ParallelInstances != nil {
+		spec.ParallelInstances = *entry.ParallelInstances
+	}
+	if entry.UpdateOrder != 0 {
+		spec.UpdateOrder = entry.UpdateOrder
+	}
+	if entry.Profiles != nil {
+		spec.Profiles = append([]string(nil), entry.Profiles...)
+	}
+	if entry.DependsOn != nil {
+		spec.DependsOn = append([]string(nil), entry.DependsOn...)
+	}
+	if entry.Port != 0 {
+		spec.Port = entry.Port
+	}
+	if len(entry.Env) > 0 {
+		spec.Env = mergeEnv(spec.Env, entry.Env)
+	}
+	spec.Resources = spec.Resources.Merge(entry.Resources)
+	return spec
+}
+
+func validateSpec(spec ServiceSpec) error {
+	if spec.Image == "" {
+		return fmt.Errorf("%s.image: must not be empty", spec.Name)
+	}
+
+	parsed, err := url.Parse(spec.HealthURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s.health_url: must be an http(s) URL, got '%s'", spec.Name, spec.HealthURL)
+	}
+
+	if spec.Port < 0 || spec.Port > 65535 {
+		return fmt.Errorf("%s.port: must be between 1 and 65535, or 0 for no published port, got %d", spec.Name, spec.Port)
+	}
+
+	for i, volume := range spec.Volumes {
+		if !serviceNamePattern.MatchString(volume) {
+			return fmt.Errorf("%s.volumes[%d]: invalid volume name '%s'", spec.Name, i, volume)
+		}
+	}
+
+	return nil
+}
+
+// mergeEnv returns a copy of base with overlay applied
+func mergeEnv(base, overlay map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overlay))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Names returns service names in catalog order
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.specs))
+	for _, spec := range c.specs {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// Specs returns a copy of all service specs in catalog order
+func (c *Catalog) Specs() []ServiceSpec {
+	return append([]ServiceSpec(nil), c.specs...)
+}
+
+// Get returns the spec for a service
+func (c *Catalog) Get(name string) (ServiceSpec, bool) {
+	idx, ok := c.index[name]
+	if !ok {
+		return ServiceSpec{}, false
+	}
+	return c.specs[idx], true
+}
+
+// UpdateOrder returns service names sorted by update_order (ties keep catalog order)
+func (c *Catalog) UpdateOrder() []string {
+	specs := c.Specs()
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].UpdateOrder < specs[j].UpdateOrder
+	})
+
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// ProfileServices returns the services tagged with profile, in catalog order
+func (c *Catalog) ProfileServices(profile string) []string {
+	var names []string
+	for _, spec := range c.specs {
+		if spec.InProfile(profile) {
+			names = append(names, spec.Name)
+		}
+	}
+	return names
+}
+
+// ProfileTags returns every profile tag used in the catalog, in first-seen order
+func (c *Catalog) ProfileTags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, spec := range c.specs {
+		for _, tag := range spec.Profiles {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// Volumes returns all declared volumes in catalog order
+func (c *Catalog) Volumes() []string {
+	var volumes []string
+	for _, spec := range c.specs {
+		volumes = append(volumes, spec.Volumes...)
+	}
+	return volumes
+}
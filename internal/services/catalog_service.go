@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"aistack/internal/fsutil"
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+)
+
+// CatalogService is a service declared only in the service catalog (e.g. ComfyUI, SearXNG)
+type CatalogService struct {
+	*BaseService
+	updater *ServiceUpdater
+	spec    ServiceSpec
+}
+
+// NewCatalogService creates a generic service from a catalog entry
+func NewCatalogService(spec ServiceSpec, composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock, gpuLock *gpulock.Manager) *CatalogService {
+	base := NewBaseServiceFromSpec(spec, composeDir, runtime, logger)
+
+	stateDir := fsutil.GetStateDir(defaultStateDir)
+	updater := NewServiceUpdater(base, runtime, spec.Image, base.healthCheck, logger, stateDir, lock)
+
+	base.SetPreStartHook(func(ctx context.Context) error {
+		if err := updater.EnforceImagePolicy(ctx); err != nil {
+			return err
+		}
+		return acquireGPULock(spec, gpuLock)
+	})
+
+	base.SetPostStopHook(func(context.Context) error {
+		return releaseGPULock(spec, gpuLock)
+	})
+
+	return &CatalogService{
+		BaseService: base,
+		updater:     updater,
+		spec:        spec,
+	}
+}
+
+// Spec returns the catalog entry the service was built from
+func (s *CatalogService) Spec() ServiceSpec {
+	return s.spec
+}
+
+// Updater returns the updater that manages the service image
+func (s *CatalogService) Updater() *ServiceUpdater {
+	return s.updater
+}
+
+// SetTimeouts applies operation timeouts to the service and its updater
+func (s *CatalogService) SetTimeouts(timeouts OperationTimeouts) {
+	s.BaseService.SetTimeouts(timeouts)
+	s.updater.SetTimeouts(timeouts)
+}
+
+// Update updates the service image with health-gated rollback
+func (s *CatalogService) Update(ctx context.Context) error {
+	return s.updater.Update(ctx)
+}
+
+// acquireGPULock takes the GPU mutex for services that declare gpu_lock in the catalog
+// Story T-021: GPU-Mutex (Dateisperre + Lease)
+func acquireGPULock(spec ServiceSpec, gpuLock *gpulock.Manager) error {
+	if !spec.GPULock || gpuLock == nil {
+		return nil
+	}
+
+	holder := gpulock.Holder(spec.Name)
+	gpulock.RegisterHolder(holder)
+	if err := gpuLock.Acquire(holder); err != nil {
+		return fmt.Errorf("failed to acquire GPU lock: %w", err)
+	}
+	return nil
+}
+
+// releaseGPULock releases the GPU mutex held by a gpu_lock service
+func releaseGPULock(spec ServiceSpec, gpuLock *gpulock.Manager) error {
+	if !spec.GPULock || gpuLock == nil {
+		return nil
+	}
+
+	holder := gpulock.Holder(spec.Name)
+	gpulock.RegisterHolder(holder)
+	return gpuLock.Release(holder)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+)
+
+func TestDefaultCatalog_MatchesShippedServices(t *testing.T) {
+	catalog := DefaultCatalog()
+
+	expectedImages := map[string]string{
+		"ollama":    OllamaImageName,
+		"openwebui": OpenWebUIImageName,
+		"localai":   LocalAIImageName,
+	}
+
+	for name, image := range expectedImages {
+		spec, ok := catalog.Get(name)
+		if !ok {
+			t.Fatalf("Expected %s in default catalog", name)
+		}
+		if spec.Image != image {
+			t.Errorf("%s image = %s, want %s", name, spec.Image, image)
+		}
+	}
+
+	if got := catalog.UpdateOrder(); !reflect.DeepEqual(got, []string{"localai", "ollama", "openwebui"}) {
+		t.Errorf("UpdateOrder() = %v, want [localai ollama openwebui]", got)
+	}
+
+	if got := catalog.ProfileServices("minimal"); !reflect.DeepEqual(got, []string{"ollama"}) {
+		t.Errorf("ProfileServices(minimal) = %v, want [ollama]", got)
+	}
+
+	localai, _ := catalog.Get("localai")
+	if !localai.GPULock {
+		t.Error("Expected localai to hold the GPU lock")
+	}
+
+	for name, want := range map[string]bool{"ollama": true, "localai": true, "openwebui": false} {
+		if spec, _ := catalog.Get(name); spec.GPU != want {
+			t.Errorf("%s gpu = %v, want %v", name, spec.GPU, want)
+		}
+	}
+
+	for name, want := range map[string]bool{"ollama": true, "localai": false, "openwebui": false} {
+		if spec, _ := catalog.Get(name); spec.ParallelInstances != want {
+			t.Errorf("%s parallel_instances = %v, want %v", name, spec.ParallelInstances, want)
+		}
+	}
+}
+
+func TestLoadCatalogFrom_MissingFile(t *testing.T) {
+	catalog, err := LoadCatalogFrom(filepath.Join(t.TempDir(), "services.yaml"))
+	if err != nil {
+		t.Fatalf("LoadCatalogFrom() error = %v", err)
+	}
+	if len(catalog.Names()) != 3 {
+		t.Errorf("Expected 3 shipped services, got %v", catalog.Names())
+	}
+}
+
+func TestLoadCatalogFrom_OverrideAndAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	content := `services:
+  - name: ollama
+    image: ollama/ollama:0.3.14
+  - name: comfyui
+    image: ghcr.io/example/comfyui:latest
+    volumes: [comfyui_data]
+    health_url: http://localhost:8188/
+    gpu_lock: true
+    update_order: 5
+    profiles: [standard-gpu]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := LoadCatalogFrom(path)
+	if err != nil {
+		t.Fatalf("LoadCatalogFrom() error = %v", err)
+	}
+
+	ollama, _ := catalog.Get("ollama")
+	if ollama.Image != "ollama/ollama:0.3.14" {
+		t.Errorf("Expected overridden image, got %s", ollama.Image)
+	}
+	if ollama.HealthURL != "http://localhost:11434/api/tags" {
+		t.Errorf("Expected unset fields to keep defaults, got health_url %s", ollama.HealthURL)
+	}
+
+	comfy, ok := catalog.Get("comfyui")
+	if !ok {
+		t.Fatal("Expected comfyui to be appended")
+	}
+	if comfy.ComposePath("/compose") != "/compose/comfyui.yaml" {
+		t.Errorf("Expected default compose path, got %s", comfy.ComposePath("/compose"))
+	}
+
+	if got := catalog.UpdateOrder(); got[0] != "comfyui" {
+		t.Errorf("Expected comfyui first in update order, got %v", got)
+	}
+	if got := catalog.ProfileServices("standard-gpu"); len(got) != 4 {
+		t.Errorf("Expected 4 standard-gpu services, got %v", got)
+	}
+
+	// The shipped catalog must stay untouched
+	if spec, _ := DefaultCatalog().Get("ollama"); spec.Image != OllamaImageName {
+		t.Errorf("Default catalog was mutated: %s", spec.Image)
+	}
+}
+
+func TestLoadCatalogFrom_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "invalid name",
+			content: "services:\n  - name: Bad Name\n    image: x\n    health_url: http://localhost/\n",
+			wantErr: "invalid name",
+		},
+		{
+			name:    "duplicate",
+			content: "services:\n  - name: ollama\n  - name: ollama\n",
+			wantErr: "duplicate service",
+		},
+		{
+			name:    "missing image",
+			content: "services:\n  - name: extra\n    health_url: http://localhost/\n",
+			wantErr: "extra.image",
+		},
+		{
+			name:    "bad health url",
+			content: "services:\n  - name: extra\n    image: x\n    health_url: localhost:9000\n",
+			wantErr: "extra.health_url",
+		},
+		{
+			name:    "port out of range",
+			content: "services:\n  - name: extra\n    image: x\n    health_url: http://localhost/\n    port: 70000\n",
+			wantErr: "or 0 for no published port",
+		},
+		{
+			name:    "malformed yaml",
+			content: "services: [",
+			wantErr: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "services.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadCatalogFrom(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestManager_BuildService_FromCatalog(t *testing.T) {
+	logger := logging.NewLogger(logging.LevelInfo)
+	manager := &Manager{
+		runtime:    NewMockRuntime(),
+		logger:     logger,
+		composeDir: "./compose",
+		services:   make(map[string]Service),
+		gpuLock:    gpulock.NewManager(t.TempDir(), logger),
+	}
+
+	spec := ServiceSpec{
+		Name:      "searxng",
+		Image:     "searxng/searxng:latest",
+		Volumes:   []string{"searxng_data"},
+		HealthURL: "http://localhost:8888/",
+	}
+
+	service, ok := manager.buildService(spec).(*CatalogService)
+	if !ok {
+		t.Fatal("Expected catalog entry to build a CatalogService")
+	}
+	if service.Name() != "searxng" {
+		t.Errorf("Name() = %s, want searxng", service.Name())
+	}
+	if service.composeFile != filepath.Join("./compose", "searxng.yaml") {
+		t.Errorf("composeFile = %s", service.composeFile)
+	}
+
+	if _, ok := manager.buildService(defaultCatalogSpec(t, "ollama")).(*OllamaService); !ok {
+		t.Error("Expected ollama to keep its specialised service")
+	}
+}
+
+// defaultCatalogSpec returns a shipped spec or fails the test
+func defaultCatalogSpec(t *testing.T, name string) ServiceSpec {
+	t.Helper()
+	spec, ok := DefaultCatalog().Get(name)
+	if !ok {
+		t.Fatalf("missing %s in default catalog", name)
+	}
+	return spec
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+	"aistack/internal/secrets"
+)
+
+const (
+	renderedComposeDir = "compose"
+	redactedSecret     = "<redacted>"
+)
+
+// ComposeData is the data a compose template is rendered with
+type ComposeData struct {
+	Name          string            // service name
+	ContainerName string            // aistack-<name>
+	Image         string            // image the container runs (tag managed by versions.lock and updates)
+	PinnedImage   string            // reference versions.lock pulls behind Image
+	BindAddress   string            // host address ports are published on
+	Port          int               // host port
+	Env           map[string]string // catalog environment merged with config overrides
+	Volumes       []string          // named volumes from the catalog
+	Network       string            // shared aistack network
+}
+
+// composeRenderable is implemented by services whose compose file is rendered from a template
+type composeRenderable interface {
+	SetComposeRenderer(renderer *ComposeRenderer)
+	ComposeRenderer() *ComposeRenderer
+}
+
+// ComposeRenderer renders a service's compose template into the state directory
+type ComposeRenderer struct {
+	spec         ServiceSpec
+	templatePath string
+	bindAddress  string
+	lock         *VersionLock
+	stateDir     string
+	logger       *logging.Logger
+	gpu          *gpuAllocator
+	gpuDevices   []string
+}
+
+// NewComposeRenderer creates a renderer for a catalog entry
+func NewComposeRenderer(spec ServiceSpec, composeDir, bindAddress string, lock *VersionLock, stateDir string, logger *logging.Logger) *ComposeRenderer {
+	return &ComposeRenderer{
+		spec:         spec,
+		templatePath: spec.ComposePath(composeDir),
+		bindAddress:  bindAddress,
+		lock:         lock,
+		stateDir:     stateDir,
+		logger:       logger,
+	}
+}
+
+// useGPU makes the renderer inject a GPU reservation for catalog services with gpu: true
+func (r *ComposeRenderer) useGPU(allocator *gpuAllocator, devices []string) {
+	r.gpu = allocator
+	r.gpuDevices = devices
+}
+
+// Data resolves the template data for the service
+func (r *ComposeRenderer) Data() (ComposeData, error) {
+	ref, err := r.lock.Resolve(r.spec.Name, r.spec.Image)
+	if err != nil {
+		return ComposeData{}, err
+	}
+
+	return ComposeData{
+		Name:          r.spec.Name,
+		ContainerName: "aistack-" + r.spec.Name,
+		Image:         ref.TagRef,
+		PinnedImage:   ref.PullRef,
+		BindAddress:   portBindAddress(r.bindAddress),
+		Port:          r.spec.Port,
+		Env:           mergeEnv(nil, r.spec.Env),
+		Volumes:       append([]string(nil), r.spec.Volumes...),
+		Network:       AistackNetwork,
+	}, nil
+}
+
+// Render executes the compose template and injects the GPU reservation and resource
+// limits (if any); with redact set, generated secrets are masked. The front proxy of a
+// blue-green service follows as a second document.
+func (r *ComposeRenderer) Render(redact bool) ([]byte, error) {
+	data, err := r.Data()
+	if err != nil {
+		return nil, err
+	}
+	project, err := r.project()
+	if err != nil {
+		return nil, err
+	}
+	rendered, ports, err := r.render(data, project, redact)
+	if err != nil || len(ports) == 0 {
+		return rendered, err
+	}
+	front, err := r.renderFront(ports)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(rendered, "---\n"...), front...), nil
+}
+
+// render executes the template for data in project. The ports of a blue-green service
+// are taken out of the result and returned for its front proxy.
+func (r *ComposeRenderer) render(data ComposeData, project string, redact bool) ([]byte, []*yaml.Node, error) {
+	source, err := os.ReadFile(filepath.Clean(r.templatePath)) // #nosec G304 -- template path comes from the service catalog
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read compose template for %s: %w", r.spec.Name, err)
+	}
+
+	tmpl, err := template.New(filepath.Base(r.templatePath)).
+		Option("missingkey=error").
+		Funcs(r.templateFuncs(redact)).
+		Parse(string(source))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse compose template %s: %w", r.templatePath, err)
+	}
+
+	var out bytes.Buffer
+	if project != "" {
+		// Templates do not set a project name; the default is the directory name
+		fmt.Fprintf(&out, "name: %s\n", project)
+	}
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, nil, fmt.Errorf("failed to render compose template %s: %w", r.templatePath, err)
+	}
+
+	var edits []func(*yaml.Node) error
+	if r.spec.GPU {
+		if reservation := r.gpu.Reserve(r.spec.Name, r.gpuDevices); reservation != nil {
+			edits = append(edits, func(target *yaml.Node) error {
+				return applyGPUReservation(target, reservation)
+			})
+		}
+	}
+	if !r.spec.Resources.IsZero() {
+		edits = append(edits, func(target *yaml.Node) error {
+			return applyResourceLimits(target, r.spec.Resources)
+		})
+	}
+
+	var ports []*yaml.Node
+	if r.blueGreen() {
+		edits = append(edits, takePorts(&ports))
+	}
+
+	if len(edits) == 0 {
+		return out.Bytes(), nil, nil
+	}
+	rendered, err := editComposeService(out.Bytes(), r.spec.Name, edits...)
+	return rendered, ports, err
+}
+
+// Write renders the compose file to <state dir>/compose/<name>.yaml and returns its
+// path. The front proxy of a blue-green service is rendered to <name>-front.yaml.
+func (r *ComposeRenderer) Write() (string, error) {
+	data, err := r.Data()
+	if err != nil {
+		return "", err
+	}
+	project, err := r.project()
+	if err != nil {
+		return "", err
+	}
+	rendered, ports, err := r.render(data, project, false)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := r.writeFile(r.spec.Name+".yaml", rendered)
+	if err != nil || len(ports) == 0 {
+		return path, err
+	}
+	front, err := r.renderFront(ports)
+	if err != nil {
+		return "", err
+	}
+	if _, err := r.writeFile(filepath.Base(r.frontFile()), front); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writeCandidate renders the blue-green candidate of an update: the new image runs as
+// <container>-next in the compose project the service is not running in, so the live
+// container is left alone. A second proxy publishes the candidate on port for the
+// health check.
+func (r *ComposeRenderer) writeCandidate(port int) (string, error) {
+	data, err := r.Data()
+	if err != nil {
+		return "", err
+	}
+	current, err := r.project()
+	if err != nil {
+		return "", err
+	}
+
+	data.ContainerName = candidateContainerName(r.spec.Name)
+	data.Port = port
+	rendered, ports, err := r.render(data, r.alternateProject(current), false)
+	if err != nil {
+		return "", err
+	}
+	if len(ports) > 0 {
+		front, err := newProxyService(candidateFrontName(r.spec.Name), data.ContainerName, "no", ports)
+		if err != nil {
+			return "", fmt.Errorf("failed to render candidate proxy of %s: %w", r.spec.Name, err)
+		}
+		if rendered, err = addComposeService(rendered, r.spec.Name+"-front", front); err != nil {
+			return "", err
+		}
+	}
+	return r.writeFile(filepath.Base(r.candidateFile()), rendered)
+}
+
+// candidateFile is where writeCandidate renders the blue-green candidate
+func (r *ComposeRenderer) candidateFile() string {
+	return filepath.Join(r.stateDir, renderedComposeDir, r.spec.Name+"-candidate.yaml")
+}
+
+// renderedProject returns the compose project a rendered file names ("" is the default project)
+func renderedProject(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read compose file: %w", err)
+	}
+	first, _, _ := strings.Cut(string(data), "\n")
+	if project, ok := strings.CutPrefix(first, "name: "); ok {
+		return strings.TrimSpace(project), nil
+	}
+	return "", nil
+}
+
+func (r *ComposeRenderer) writeFile(name string, rendered []byte) (string, error) {
+	dir := filepath.Join(r.stateDir, renderedComposeDir)
+	if err := fsutil.EnsureStateDirectory(dir); err != nil {
+		return "", err
+	}
+
+	// 0600: rendered files can contain generated secrets
+	path := filepath.Join(dir, name)
+	if err := fsutil.AtomicWriteFile(path, rendered, 0o600, r.logger); err != nil {
+		return "", fmt.Errorf("failed to write compose file for %s: %w", r.spec.Name, err)
+	}
+
+	r.logger.Debug("compose.rendered", "Compose file rendered", map[string]interface{}{
+		"service":  r.spec.Name,
+		"template": r.templatePath,
+		"path":     path,
+	})
+	return path, nil
+}
+
+// project returns the compose project the service runs in; "" is the default
+// project, named after the rendered compose directory
+func (r *ComposeRenderer) project() (string, error) {
+	data, err := os.ReadFile(r.projectFile())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read compose project of %s: %w", r.spec.Name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// setProject moves the service to another compose project; blue-green updates
+// alternate between two so the previous container survives the next compose up
+func (r *ComposeRenderer) setProject(project string) error {
+	if project == "" {
+		if err := os.Remove(r.projectFile()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to reset compose project of %s: %w", r.spec.Name, err)
+		}
+		return nil
+	}
+
+	if err := fsutil.EnsureStateDirectory(filepath.Dir(r.projectFile())); err != nil {
+		return err
+	}
+	if err := fsutil.AtomicWriteFile(r.projectFile(), []byte(project+"\n"), fsutil.DefaultFilePermissions, r.logger); err != nil {
+		return fmt.Errorf("failed to save compose project of %s: %w", r.spec.Name, err)
+	}
+	return nil
+}
+
+// alternateProject returns the project a service leaves current for
+func (r *ComposeRenderer) alternateProject(current string) string {
+	if current == "" {
+		return "aistack-" + r.spec.Name + "-green"
+	}
+	return ""
+}
+
+func (r *ComposeRenderer) projectFile() string {
+	return filepath.Join(r.stateDir, renderedComposeDir, r.spec.Name+".project")
+}
+
+func (r *ComposeRenderer) templateFuncs(redact bool) template.FuncMap {
+	return template.FuncMap{
+		// quote renders a value as a double-quoted YAML scalar
+		"quote": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(fmt.Sprint(value))
+			return string(encoded), err
+		},
+		// hasKey reports whether the environment map sets key
+		"hasKey": func(values map[string]string, key string) bool {
+			_, ok := values[key]
+			return ok
+		},
+		// secret returns a generated value persisted in the secret store
+		"secret": func(name string) (string, error) {
+			if redact {
+				return redactedSecret, nil
+			}
+			return r.generatedSecret(name)
+		},
+	}
+}
+
+// generatedSecret loads a secret from the store under the state directory,
+// generating a random value on first use
+func (r *ComposeRenderer) generatedSecret(name string) (string, error) {
+	store, err := secrets.NewSecretStore(secrets.SecretStoreConfig{
+		SecretsDir:     filepath.Join(r.stateDir, "secrets"),
+		PassphraseFile: filepath.Join(r.stateDir, ".passphrase"),
+	}, r.logger)
+	if err != nil {
+		return "", fmt.Errorf("failed to open secret store: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(r.stateDir, "secrets", name+".enc")); err == nil {
+		value, err := store.RetrieveSecret(name)
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate secret %s: %w", name, err)
+	}
+	value := hex.EncodeToString(raw)
+	if err := store.StoreSecret(name, []byte(value)); err != nil {
+		return "", err
+	}
+
+	r.logger.Info("compose.secret.generated", "Generated service secret", map[string]interface{}{
+		"service": r.spec.Name,
+		"secret":  name,
+	})
+	return value, nil
+}
+
+// portBindAddress formats the bind address for a compose port spec; IPv6 addresses
+// are bracketed, "::1:11434:11434" would not parse
+func portBindAddress(bindAddress string) string {
+	if ip := net.ParseIP(bindAddress); ip != nil && ip.To4() == nil {
+		return "[" + bindAddress + "]"
+	}
+	return bindAddress
+}
+
+// withServiceConfig applies the config's bind address and per-service port/env/resources/health to a spec.
+// A health URL that points at the default port follows the configured one.
+func (s ServiceSpec) withServiceConfig(bindAddress string, cfg config.ServiceConfig) ServiceSpec {
+	defaultPort := s.Port
+	if cfg.Port != 0 {
+		s.Port = cfg.Port
+	}
+	if len(cfg.Env) > 0 {
+		s.Env = mergeEnv(s.Env, cfg.Env)
+	}
+	s.Resources = s.Resources.Merge(cfg.Resources)
+	if !cfg.Health.IsZero() {
+		s.Health = cfg.Health
+	}
+	if cfg.UpdateStrategy != "" {
+		s.UpdateStrategy = cfg.UpdateStrategy
+	}
+
+	parsed, err := url.Parse(s.HealthURL)
+	if err != nil || parsed.Port() == "" {
+		return s
+	}
+
+	host := parsed.Hostname()
+	if ip := net.ParseIP(bindAddress); ip != nil && !ip.IsUnspecified() {
+		host = bindAddress
+	}
+	port := parsed.Port()
+	if port == strconv.Itoa(defaultPort) && s.Port != 0 {
+		port = strconv.Itoa(s.Port)
+	}
+	parsed.Host = net.JoinHostPort(host, port)
+	s.HealthURL = parsed.String()
+
+	return s
+}
+
+// editComposeService parses a rendered compose file, applies edits to the node of
+// service and encodes the result again
+func editComposeService(rendered []byte, service string, edits ...func(*yaml.Node) error) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(rendered, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse rendered compose file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("rendered compose file has no top-level mapping")
+	}
+
+	services := yamlMappingValue(doc.Content[0], "services")
+	if services.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("rendered compose file has no services")
+	}
+	target := yamlMappingValue(services, service)
+	if target.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("rendered compose file does not define service %s", service)
+	}
+
+	for _, edit := range edits {
+		if err := edit(target); err != nil {
+			return nil, err
+		}
+	}
+	return encodeCompose(&doc)
+}
+
+// encodeCompose encodes a compose document with the indentation of the templates
+func encodeCompose(doc *yaml.Node) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode compose file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode compose file: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// yamlMappingChild returns the mapping stored under key, creating it if needed
+func yamlMappingChild(node *yaml.Node, key string) *yaml.Node {
+	value := yamlMappingValue(node, key)
+	if value.Kind != yaml.MappingNode {
+		*value = yaml.Node{Kind: yaml.MappingNode}
+	}
+	return value
+}
+
+// yamlMappingValue returns the value node for key, appending an empty one if missing
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+func yamlSequenceContains(node *yaml.Node, value string) bool {
+	for _, item := range node.Content {
+		if item.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+func TestComposeRenderer_RenderConfigValues(t *testing.T) {
+	composeDir := t.TempDir()
+	template := `services:
+  {{ .Name }}:
+    image: {{ .Image }}
+    container_name: {{ .ContainerName }}
+    ports:
+      - "{{ .BindAddress }}:{{ .Port }}:80"
+    environment:
+{{- range $key, $value := .Env }}
+      - {{ printf "%s=%s" $key $value | quote }}
+{{- end }}
+`
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := ServiceSpec{
+		Name:  "demo",
+		Image: "example/demo:latest",
+		Port:  8000,
+		Env:   map[string]string{"MODE": "prod", "THREADS": "4"},
+	}
+	spec = spec.withServiceConfig("127.0.0.1", config.ServiceConfig{
+		Port: 9000,
+		Env:  map[string]string{"THREADS": "8"},
+	})
+
+	lock := &VersionLock{entries: map[string]string{"demo": "example/demo@sha256:abc"}}
+	renderer := NewComposeRenderer(spec, composeDir, "127.0.0.1", lock, t.TempDir(), logging.NewLogger(logging.LevelError))
+
+	rendered, err := renderer.Render(false)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	var parsed struct {
+		Services map[string]struct {
+			Image         string   `yaml:"image"`
+			ContainerName string   `yaml:"container_name"`
+			Ports         []string `yaml:"ports"`
+			Environment   []string `yaml:"environment"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(rendered, &parsed); err != nil {
+		t.Fatalf("rendered compose is not valid YAML: %v\n%s", err, rendered)
+	}
+
+	demo := parsed.Services["demo"]
+	if demo.Image != "example/demo:latest" {
+		t.Errorf("image = %s, want the tag managed by versions.lock", demo.Image)
+	}
+	if demo.ContainerName != "aistack-demo" {
+		t.Errorf("container_name = %s", demo.ContainerName)
+	}
+	if len(demo.Ports) != 1 || demo.Ports[0] != "127.0.0.1:9000:80" {
+		t.Errorf("ports = %v, want [127.0.0.1:9000:80]", demo.Ports)
+	}
+	if strings.Join(demo.Environment, ",") != "MODE=prod,THREADS=8" {
+		t.Errorf("environment = %v", demo.Environment)
+	}
+
+	data, err := renderer.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.PinnedImage != "example/demo@sha256:abc" {
+		t.Errorf("PinnedImage = %s", data.PinnedImage)
+	}
+}
+
+func TestComposeRenderer_MissingKeyFails(t *testing.T) {
+	composeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte("image: {{ .Unknown }}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	renderer := NewComposeRenderer(ServiceSpec{Name: "demo", Image: "x"}, composeDir, "0.0.0.0", nil, t.TempDir(), logging.NewLogger(logging.LevelError))
+	if _, err := renderer.Render(false); err == nil {
+		t.Error("Expected unknown template field to fail rendering")
+	}
+}
+
+func TestComposeRenderer_WriteAndSecrets(t *testing.T) {
+	composeDir := t.TempDir()
+	stateDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(`key: {{ secret "demo_key" }}`+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logging.NewLogger(logging.LevelError)
+	renderer := NewComposeRenderer(ServiceSpec{Name: "demo", Image: "x"}, composeDir, "0.0.0.0", nil, stateDir, logger)
+
+	path, err := renderer.Write()
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if path != filepath.Join(stateDir, "compose", "demo.yaml") {
+		t.Errorf("Write() path = %s", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("rendered file mode = %o, want 600", info.Mode().Perm())
+	}
+
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(first), redactedSecret) || len(strings.TrimSpace(string(first))) <= len("key: ") {
+		t.Fatalf("Expected a generated secret, got %q", first)
+	}
+
+	// A second renderer must reuse the persisted secret
+	second, err := NewComposeRenderer(ServiceSpec{Name: "demo", Image: "x"}, composeDir, "0.0.0.0", nil, stateDir, logger).Render(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != string(second) {
+		t.Errorf("Expected stable secret, got %q then %q", first, second)
+	}
+
+	redacted, err := renderer.Render(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(redacted) != "key: "+redactedSecret+"\n" {
+		t.Errorf("Render(redact) = %q", redacted)
+	}
+}
+
+func TestServiceSpec_WithServiceConfig_HealthURL(t *testing.T) {
+	spec := ServiceSpec{Name: "openwebui", HealthURL: "http://localhost:3000/", Port: 3000}
+
+	if got := spec.withServiceConfig("0.0.0.0", config.ServiceConfig{}).HealthURL; got != "http://localhost:3000/" {
+		t.Errorf("unchanged config: HealthURL = %s", got)
+	}
+	if got := spec.withServiceConfig("0.0.0.0", config.ServiceConfig{Port: 3100}).HealthURL; got != "http://localhost:3100/" {
+		t.Errorf("port override: HealthURL = %s", got)
+	}
+	if got := spec.withServiceConfig("192.168.1.10", config.ServiceConfig{}).HealthURL; got != "http://192.168.1.10:3000/" {
+		t.Errorf("bind address: HealthURL = %s", got)
+	}
+}
+
+func TestShippedComposeTemplates_Render(t *testing.T) {
+	composeDir := filepath.Join("..", "..", "compose")
+	stateDir := t.TempDir()
+	logger := logging.NewLogger(logging.LevelError)
+
+	for _, spec := range DefaultCatalog().Specs() {
+		t.Run(spec.Name, func(t *testing.T) {
+			rendered, err := NewComposeRenderer(spec, composeDir, "0.0.0.0", nil, stateDir, logger).Render(true)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			var parsed map[string]interface{}
+			if err := yaml.Unmarshal(rendered, &parsed); err != nil {
+				t.Fatalf("rendered compose is not valid YAML: %v\n%s", err, rendered)
+			}
+			if !strings.Contains(string(rendered), "image: "+spec.Image) {
+				t.Errorf("Expected catalog image in rendered file:\n%s", rendered)
+			}
+			if strings.Contains(string(rendered), "{{") {
+				t.Errorf("Unrendered template action left in output:\n%s", rendered)
+			}
+		})
+	}
+}
+
+func TestShippedComposeTemplates_BindAddress(t *testing.T) {
+	composeDir := filepath.Join("..", "..", "compose")
+	logger := logging.NewLogger(logging.LevelError)
+	tests := map[string]string{
+		"0.0.0.0":   "0.0.0.0",
+		"127.0.0.1": "127.0.0.1",
+		"::":        "[::]",
+		"::1":       "[::1]",
+		"fd00::10":  "[fd00::10]",
+	}
+
+	for _, spec := range DefaultCatalog().Specs() {
+		for bindAddress, want := range tests {
+			t.Run(spec.Name+"/"+bindAddress, func(t *testing.T) {
+				rendered, err := NewComposeRenderer(spec, composeDir, bindAddress, nil, t.TempDir(), logger).Render(true)
+				if err != nil {
+					t.Fatalf("Render() error = %v", err)
+				}
+
+				var parsed struct {
+					Services map[string]struct {
+						Ports []string `yaml:"ports"`
+					} `yaml:"services"`
+				}
+				if err := yaml.Unmarshal(rendered, &parsed); err != nil {
+					t.Fatalf("rendered compose is not valid YAML: %v\n%s", err, rendered)
+				}
+				ports := parsed.Services[spec.Name].Ports
+				prefix := fmt.Sprintf("%s:%d:", want, spec.Port)
+				if len(ports) != 1 || !strings.HasPrefix(ports[0], prefix) {
+					t.Errorf("ports = %v, want %s<container port>", ports, prefix)
+				}
+			})
+		}
+	}
+}
@@ -8,6 +8,8 @@ const (
 	planStatusCompleted  = "completed"
 	planStatusFailed     = "failed"
 	planStatusRolledBack = "rolled_back"
+	planActionUpdate     = "update"
+	planActionRollback   = "rollback"
 )
 
 const (
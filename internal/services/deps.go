@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"aistack/internal/fsutil"
+)
+
+// BackendDependency is the depends_on placeholder for the backend Open WebUI is bound to
+const BackendDependency = "backend"
+
+const dependencyPollInterval = 2 * time.Second
+
+// ErrDependencyCycle is returned when service dependencies form a cycle
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// Dependencies returns the direct dependencies of a service, resolving the
+// backend placeholder through the current backend binding
+func (m *Manager) Dependencies(name string) ([]string, error) {
+	spec, ok := m.Catalog().Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown service: %s", name)
+	}
+
+	deps := make([]string, 0, len(spec.DependsOn))
+	for _, dep := range spec.DependsOn {
+		if dep == BackendDependency {
+			binding, err := NewBackendBindingManager(fsutil.GetStateDir(defaultStateDir), m.logger).GetBinding()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve backend of %s: %w", name, err)
+			}
+			dep = string(binding.ActiveBackend)
+		}
+		if _, exists := m.services[dep]; exists && !containsString(deps, dep) {
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// Dependents returns the services that directly depend on name
+func (m *Manager) Dependents(name string) ([]string, error) {
+	dependents := make([]string, 0)
+	for _, other := range m.ListServices() {
+		deps, err := m.Dependencies(other)
+		if err != nil {
+			return nil, err
+		}
+		if containsString(deps, name) {
+			dependents = append(dependents, other)
+		}
+	}
+	return dependents, nil
+}
+
+// StartOrder sorts names so dependencies come before their dependents.
+// Among independent services the given order is kept.
+func (m *Manager) StartOrder(names []string) ([]string, error) {
+	return topoSort(names, m.Dependencies)
+}
+
+// StopOrder sorts names so dependents are stopped before their dependencies
+func (m *Manager) StopOrder(names []string) ([]string, error) {
+	order, err := m.StartOrder(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order, nil
+}
+
+// DependencyChain returns name and its transitive dependencies in start order
+func (m *Manager) DependencyChain(name string) ([]string, error) {
+	chain := []string{name}
+	for i := 0; i < len(chain); i++ {
+		deps, err := m.Dependencies(chain[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			if !containsString(chain, dep) {
+				chain = append(chain, dep)
+			}
+		}
+	}
+	return m.StartOrder(chain)
+}
+
+// StartWithDependencies starts the dependency chain of a service in order and
+// waits for every dependency to report healthy before starting the next one
+func (m *Manager) StartWithDependencies(ctx context.Context, name string) ([]string, error) {
+	chain, err := m.DependencyChain(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("service.start.with_deps", "Starting service with dependencies", map[string]interface{}{
+		"service": name,
+		"chain":   chain,
+	})
+	if err := m.recordOperatorStops(chain, false); err != nil {
+		return nil, err
+	}
+
+	for _, serviceName := range chain {
+		service, err := m.GetService(serviceName)
+		if err != nil {
+			return nil, err
+		}
+		if err := service.Start(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start dependency %s: %w", serviceName, err)
+		}
+		if serviceName == name {
+			break
+		}
+		if err := m.waitHealthy(ctx, serviceName, service); err != nil {
+			return nil, err
+		}
+	}
+
+	return chain, nil
+}
+
+// waitHealthy polls a service until it is green or the dependency wait expires
+func (m *Manager) waitHealthy(ctx context.Context, name string, service Service) error {
+	waitCtx, cancel := withTimeout(ctx, m.timeouts.DependencyWait)
+	defer cancel()
+
+	for {
+		status, err := service.Health(waitCtx)
+		if err == nil && status == HealthGreen {
+			m.logger.Info("service.dependency.healthy", "Dependency is healthy", map[string]interface{}{
+				"service": name,
+			})
+			return nil
+		}
+
+		if sleepErr := sleepContext(waitCtx, dependencyPollInterval); sleepErr != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("waiting for %s interrupted: %w", name, ctx.Err())
+			}
+			return fmt.Errorf("dependency %s did not become healthy within %s", name, m.timeouts.DependencyWait)
+		}
+	}
+}
+
+// topoSort orders names so every node follows its dependencies (Kahn's algorithm).
+// Dependencies outside names are ignored; ties keep the input order.
+func topoSort(names []string, dependencies func(string) ([]string, error)) ([]string, error) {
+	pending := make(map[string][]string, len(names))
+	for _, name := range names {
+		deps, err := dependencies(name)
+		if err != nil {
+			return nil, err
+		}
+		inSet := make([]string, 0, len(deps))
+		for _, dep := range deps {
+			if containsString(names, dep) {
+				inSet = append(inSet, dep)
+			}
+		}
+		pending[name] = inSet
+	}
+
+	order := make([]string, 0, len(names))
+	done := make(map[string]bool, len(names))
+	for len(order) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if done[name] || !allDone(pending[name], done) {
+				continue
+			}
+			order = append(order, name)
+			done[name] = true
+			progressed = true
+			break
+		}
+		if !progressed {
+			var stuck []string
+			for _, name := range names {
+				if !done[name] {
+					stuck = append(stuck, name)
+				}
+			}
+			return nil, fmt.Errorf("%w between %s", ErrDependencyCycle, strings.Join(stuck, ", "))
+		}
+	}
+
+	return order, nil
+}
+
+func allDone(deps []string, done map[string]bool) bool {
+	for _, dep := range deps {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// StopWithDependents stops the services that depend on name (transitively) before name itself
+func (m *Manager) StopWithDependents(ctx context.Context, name string) ([]string, error) {
+	chain := []string{name}
+	for i := 0; i < len(chain); i++ {
+		dependents, err := m.Dependents(chain[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, dependent := range dependents {
+			if !containsString(chain, dependent) {
+				chain = append(chain, dependent)
+			}
+		}
+	}
+
+	order, err := m.StopOrder(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("service.stop.with_deps", "Stopping service with dependents", map[string]interface{}{
+		"service": name,
+		"chain":   order,
+	})
+	if err := m.recordOperatorStops(order, true); err != nil {
+		return nil, err
+	}
+
+	for _, serviceName := range order {
+		service, err := m.GetService(serviceName)
+		if err != nil {
+			return nil, err
+		}
+		if err := service.Stop(ctx); err != nil {
+			return nil, fmt.Errorf("failed to stop %s: %w", serviceName, err)
+		}
+	}
+
+	return order, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+// recordingService is a Service stub that records lifecycle calls in a shared log
+type recordingService struct {
+	name   string
+	calls  *[]string
+	health HealthStatus
+}
+
+func (s *recordingService) Name() string { return s.name }
+func (s *recordingService) Install(_ context.Context) error {
+	*s.calls = append(*s.calls, "install:"+s.name)
+	return nil
+}
+func (s *recordingService) Start(_ context.Context) error {
+	*s.calls = append(*s.calls, "start:"+s.name)
+	return nil
+}
+func (s *recordingService) Stop(_ context.Context) error {
+	*s.calls = append(*s.calls, "stop:"+s.name)
+	return nil
+}
+func (s *recordingService) Status(_ context.Context) (ServiceStatus, error) {
+	return ServiceStatus{Name: s.name, State: serviceStateRunning, Health: s.health}, nil
+}
+func (s *recordingService) Health(_ context.Context) (HealthStatus, error) {
+	return s.health, nil
+}
+func (s *recordingService) Remove(_ context.Context, _ bool) error {
+	*s.calls = append(*s.calls, "remove:"+s.name)
+	return nil
+}
+func (s *recordingService) Update(_ context.Context) error { return nil }
+func (s *recordingService) Logs(_ context.Context, _ int) (string, error) {
+	return "", nil
+}
+
+func newRecordingManager(t *testing.T, health HealthStatus) (*Manager, *[]string) {
+	t.Helper()
+
+	origStateDir := os.Getenv("AISTACK_STATE_DIR")
+	os.Setenv("AISTACK_STATE_DIR", t.TempDir())
+	t.Cleanup(func() { os.Setenv("AISTACK_STATE_DIR", origStateDir) })
+
+	calls := make([]string, 0)
+	manager := &Manager{
+		runtime:  NewMockRuntime(),
+		logger:   logging.NewLogger(logging.LevelInfo),
+		services: make(map[string]Service),
+		timeouts: OperationTimeouts{DependencyWait: 50 * time.Millisecond},
+	}
+	for _, name := range DefaultCatalog().Names() {
+		manager.services[name] = &recordingService{name: name, calls: &calls, health: health}
+	}
+	return manager, &calls
+}
+
+func TestTopoSort(t *testing.T) {
+	deps := map[string][]string{
+		"ui":     {"api"},
+		"api":    {"db", "cache"},
+		"db":     nil,
+		"cache":  nil,
+		"worker": {"db"},
+	}
+	lookup := func(name string) ([]string, error) { return deps[name], nil }
+
+	order, err := topoSort([]string{"ui", "worker", "api", "cache", "db"}, lookup)
+	if err != nil {
+		t.Fatalf("topoSort() error = %v", err)
+	}
+	want := []string{"cache", "db", "worker", "api", "ui"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("topoSort() = %v, want %v", order, want)
+	}
+
+	deps["db"] = []string{"ui"}
+	if _, err := topoSort([]string{"ui", "api", "db"}, lookup); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("Expected ErrDependencyCycle, got: %v", err)
+	}
+}
+
+func TestManager_StartOrder_FollowsBackendBinding(t *testing.T) {
+	manager, _ := newRecordingManager(t, HealthGreen)
+
+	deps, err := manager.Dependencies("openwebui")
+	if err != nil {
+		t.Fatalf("Dependencies() error = %v", err)
+	}
+	if !reflect.DeepEqual(deps, []string{"ollama"}) {
+		t.Errorf("Expected default binding to ollama, got %v", deps)
+	}
+
+	binding := NewBackendBindingManager(os.Getenv("AISTACK_STATE_DIR"), manager.logger)
+	if err := binding.SetBinding(BackendLocalAI); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := manager.StartOrder([]string{"openwebui", "ollama", "localai"})
+	if err != nil {
+		t.Fatalf("StartOrder() error = %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"ollama", "localai", "openwebui"}) {
+		t.Errorf("StartOrder() = %v", order)
+	}
+
+	stop, err := manager.StopOrder([]string{"localai", "openwebui"})
+	if err != nil {
+		t.Fatalf("StopOrder() error = %v", err)
+	}
+	if !reflect.DeepEqual(stop, []string{"openwebui", "localai"}) {
+		t.Errorf("StopOrder() = %v", stop)
+	}
+
+	updateOrder, err := manager.UpdateOrder()
+	if err != nil {
+		t.Fatalf("UpdateOrder() error = %v", err)
+	}
+	if !reflect.DeepEqual(updateOrder, []string{"localai", "ollama", "openwebui"}) {
+		t.Errorf("UpdateOrder() = %v", updateOrder)
+	}
+}
+
+func TestManager_StartWithDependencies(t *testing.T) {
+	manager, calls := newRecordingManager(t, HealthGreen)
+
+	chain, err := manager.StartWithDependencies(context.Background(), "openwebui")
+	if err != nil {
+		t.Fatalf("StartWithDependencies() error = %v", err)
+	}
+	if !reflect.DeepEqual(chain, []string{"ollama", "openwebui"}) {
+		t.Errorf("chain = %v", chain)
+	}
+	if !reflect.DeepEqual(*calls, []string{"start:ollama", "start:openwebui"}) {
+		t.Errorf("calls = %v", *calls)
+	}
+}
+
+func TestManager_StartWithDependencies_UnhealthyDependency(t *testing.T) {
+	manager, calls := newRecordingManager(t, HealthRed)
+
+	_, err := manager.StartWithDependencies(context.Background(), "openwebui")
+	if err == nil || !strings.Contains(err.Error(), "did not become healthy") {
+		t.Fatalf("Expected dependency health error, got: %v", err)
+	}
+	if !reflect.DeepEqual(*calls, []string{"start:ollama"}) {
+		t.Errorf("Expected openwebui not to start, calls = %v", *calls)
+	}
+}
+
+func TestManager_StopWithDependents(t *testing.T) {
+	manager, calls := newRecordingManager(t, HealthGreen)
+
+	order, err := manager.StopWithDependents(context.Background(), "ollama")
+	if err != nil {
+		t.Fatalf("StopWithDependents() error = %v", err)
+	}
+	if !reflect.DeepEqual(order, []string{"openwebui", "ollama"}) {
+		t.Errorf("order = %v", order)
+	}
+	if !reflect.DeepEqual(*calls, []string{"stop:openwebui", "stop:ollama"}) {
+		t.Errorf("calls = %v", *calls)
+	}
+}
+
+func TestLoadCatalogFrom_UnknownDependency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services.yaml")
+	content := "services:\n  - name: ollama\n    depends_on: [postgres]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadCatalogFrom(path)
+	if err == nil || !strings.Contains(err.Error(), "unknown dependency 'postgres'") {
+		t.Errorf("Expected unknown dependency error, got: %v", err)
+	}
+}
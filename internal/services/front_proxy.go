@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+)
+
+const (
+	// frontProxyImage runs the front proxies of blue-green services
+	frontProxyImage = "alpine/socat:1.8.0.0"
+	// frontProxyLockName is the versions.lock entry that pins frontProxyImage; the
+	// proxy image is pulled, tagged and trust-checked like a service image
+	frontProxyLockName = "front-proxy"
+)
+
+// frontContainerName is the proxy that publishes a blue-green service's ports and
+// forwards them to aistack-<service>
+func frontContainerName(service string) string {
+	return "aistack-" + service + "-front"
+}
+
+// candidateFrontName is the proxy that publishes the candidate of a blue-green update
+// on its temporary port
+func candidateFrontName(service string) string {
+	return candidateContainerName(service) + "-front"
+}
+
+// blueGreen reports whether the service runs behind a front proxy and is updated
+// blue-green: the strategy selects it and the catalog allows two containers on the
+// service's volumes. GPU-locked services never run twice.
+func (r *ComposeRenderer) blueGreen() bool {
+	return r.spec.UpdateStrategy == config.UpdateStrategyBlueGreen && r.spec.ParallelInstances && !r.spec.GPULock
+}
+
+// frontProxied reports whether the service runs behind a front proxy
+func (u *ServiceUpdater) frontProxied() bool {
+	renderable, ok := u.service.(composeRenderable)
+	return ok && renderable.ComposeRenderer() != nil && renderable.ComposeRenderer().blueGreen()
+}
+
+// enforceFrontProxyImage pulls the image versions.lock pins for the front proxy and
+// tags it as frontProxyImage, which the rendered proxies run
+func (u *ServiceUpdater) enforceFrontProxyImage(ctx context.Context) error {
+	ref, err := u.imageLock.Resolve(frontProxyLockName, frontProxyImage)
+	if err != nil {
+		return err
+	}
+	if ref.PullRef == ref.TagRef {
+		return nil
+	}
+	if err := u.runtime.PullImage(ctx, ref.PullRef); err != nil {
+		return fmt.Errorf("failed to pull front proxy image %s: %w", ref.PullRef, err)
+	}
+	if err := u.runtime.TagImage(ctx, ref.PullRef, ref.TagRef); err != nil {
+		return fmt.Errorf("failed to tag front proxy image %s as %s: %w", ref.PullRef, ref.TagRef, err)
+	}
+	return nil
+}
+
+// frontFile is where Write renders the front proxy of a blue-green service. The proxy
+// has a compose project of its own, so it keeps the published ports while the service
+// moves between projects.
+func (r *ComposeRenderer) frontFile() string {
+	return filepath.Join(r.stateDir, renderedComposeDir, r.spec.Name+"-front.yaml")
+}
+
+// hasFrontFile reports whether a front proxy was rendered for the service
+func (r *ComposeRenderer) hasFrontFile() bool {
+	_, err := os.Stat(r.frontFile())
+	return err == nil
+}
+
+// renderFront renders the front proxy for the ports taken from the service
+func (r *ComposeRenderer) renderFront(ports []*yaml.Node) ([]byte, error) {
+	front, err := newProxyService(frontContainerName(r.spec.Name), "aistack-"+r.spec.Name, "unless-stopped", ports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render front proxy of %s: %w", r.spec.Name, err)
+	}
+
+	doc := struct {
+		Name     string                     `yaml:"name"`
+		Services map[string]proxyService    `yaml:"services"`
+		Networks map[string]map[string]bool `yaml:"networks"`
+	}{
+		Name:     frontContainerName(r.spec.Name),
+		Services: map[string]proxyService{r.spec.Name + "-front": front},
+		Networks: map[string]map[string]bool{AistackNetwork: {"external": true}},
+	}
+
+	var node yaml.Node
+	if err := node.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode front proxy of %s: %w", r.spec.Name, err)
+	}
+	return encodeCompose(&node)
+}
+
+// proxyService is a compose service that publishes ports and forwards each container
+// port to the same port of a target container on the aistack network
+type proxyService struct {
+	Image         string       `yaml:"image"`
+	ContainerName string       `yaml:"container_name"`
+	Restart       string       `yaml:"restart"`
+	Init          bool         `yaml:"init"`
+	Entrypoint    []string     `yaml:"entrypoint"`
+	Command       []string     `yaml:"command"`
+	Ports         []*yaml.Node `yaml:"ports"`
+	Networks      []string     `yaml:"networks"`
+}
+
+func newProxyService(container, target, restart string, ports []*yaml.Node) (proxyService, error) {
+	var forwards []string
+	seen := make(map[int]bool)
+	for _, entry := range ports {
+		port, err := proxyTargetPort(entry)
+		if err != nil {
+			return proxyService{}, err
+		}
+		if seen[port] {
+			continue
+		}
+		seen[port] = true
+		// socat resolves the target for every connection, so the container that holds
+		// the name takes the next connection after a rename
+		forwards = append(forwards, fmt.Sprintf("socat TCP-LISTEN:%d,fork,reuseaddr TCP:%s:%d", port, target, port))
+	}
+
+	return proxyService{
+		Image:         frontProxyImage,
+		ContainerName: container,
+		Restart:       restart,
+		Init:          true,
+		Entrypoint:    []string{"/bin/sh", "-c"},
+		Command:       []string{strings.Join(forwards, " & ") + " & wait"},
+		Ports:         ports,
+		Networks:      []string{AistackNetwork},
+	}, nil
+}
+
+// proxyTargetPort returns the container port of a compose ports entry; the proxy
+// forwards single TCP ports only
+func proxyTargetPort(entry *yaml.Node) (int, error) {
+	var target, protocol string
+	switch entry.Kind {
+	case yaml.ScalarNode:
+		target, protocol, _ = strings.Cut(entry.Value, "/")
+		if i := strings.LastIndex(target, ":"); i >= 0 {
+			target = target[i+1:]
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(entry.Content); i += 2 {
+			switch entry.Content[i].Value {
+			case "target":
+				target = entry.Content[i+1].Value
+			case "protocol":
+				protocol = entry.Content[i+1].Value
+			}
+		}
+	}
+
+	port, err := strconv.Atoi(target)
+	if err != nil || port < 1 || port > 65535 || (protocol != "" && protocol != "tcp") {
+		return 0, fmt.Errorf("front proxy cannot forward port entry %q (single TCP ports only)", describePortEntry(entry))
+	}
+	return port, nil
+}
+
+func describePortEntry(entry *yaml.Node) string {
+	if entry.Kind == yaml.ScalarNode {
+		return entry.Value
+	}
+	out, _ := yaml.Marshal(entry)
+	return strings.TrimSpace(string(out))
+}
+
+// takePorts removes the ports of the edited service and appends them to ports
+func takePorts(ports *[]*yaml.Node) func(*yaml.Node) error {
+	return func(target *yaml.Node) error {
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			if target.Content[i].Value == "ports" {
+				*ports = append(*ports, target.Content[i+1].Content...)
+				target.Content = append(target.Content[:i], target.Content[i+2:]...)
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// addComposeService adds a service to a rendered compose file
+func addComposeService(rendered []byte, name string, service interface{}) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(rendered, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse rendered compose file: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("rendered compose file has no top-level mapping")
+	}
+
+	services := yamlMappingChild(doc.Content[0], "services")
+	slot := yamlMappingValue(services, name)
+	if slot.Kind == yaml.MappingNode {
+		return nil, fmt.Errorf("rendered compose file already defines service %s", name)
+	}
+	if err := slot.Encode(service); err != nil {
+		return nil, fmt.Errorf("failed to encode service %s: %w", name, err)
+	}
+	return encodeCompose(&doc)
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+	"aistack/internal/signing"
+)
+
+// proxyFile is the part of a rendered compose file the front proxy tests look at
+type proxyFile struct {
+	Name     string `yaml:"name"`
+	Services map[string]struct {
+		Image         string        `yaml:"image"`
+		ContainerName string        `yaml:"container_name"`
+		Command       []string      `yaml:"command"`
+		Ports         []interface{} `yaml:"ports"`
+	} `yaml:"services"`
+}
+
+func readProxyFile(t *testing.T, path string) proxyFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed proxyFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("rendered file does not parse: %v\n%s", err, data)
+	}
+	return parsed
+}
+
+func newFrontProxyRenderer(t *testing.T, strategy string) *ComposeRenderer {
+	t.Helper()
+	composeDir := t.TempDir()
+	template := `services:
+  {{ .Name }}:
+    image: {{ .Image }}
+    container_name: {{ .ContainerName }}
+    ports:
+      - "{{ .BindAddress }}:{{ .Port }}:80"
+      - target: 9090
+        published: 9090
+`
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec := ServiceSpec{Name: "demo", Image: "example/demo:latest", Port: 8080, ParallelInstances: true}
+	spec = spec.withServiceConfig("::1", config.ServiceConfig{UpdateStrategy: strategy})
+	return NewComposeRenderer(spec, composeDir, "::1", nil, t.TempDir(), logging.NewLogger(logging.LevelError))
+}
+
+func TestComposeRenderer_WriteFrontProxy(t *testing.T) {
+	renderer := newFrontProxyRenderer(t, config.UpdateStrategyBlueGreen)
+
+	path, err := renderer.Write()
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	service := readProxyFile(t, path).Services["demo"]
+	if service.ContainerName != "aistack-demo" || len(service.Ports) != 0 {
+		t.Errorf("Expected the service without published ports, got %+v", service)
+	}
+
+	front := readProxyFile(t, renderer.frontFile())
+	if front.Name != "aistack-demo-front" {
+		t.Errorf("front project = %q", front.Name)
+	}
+	proxy := front.Services["demo-front"]
+	if proxy.Image != frontProxyImage || proxy.ContainerName != "aistack-demo-front" {
+		t.Errorf("front proxy = %+v", proxy)
+	}
+	want := []string{"socat TCP-LISTEN:80,fork,reuseaddr TCP:aistack-demo:80 & socat TCP-LISTEN:9090,fork,reuseaddr TCP:aistack-demo:9090 & wait"}
+	if !reflect.DeepEqual(proxy.Command, want) {
+		t.Errorf("front command = %v, want %v", proxy.Command, want)
+	}
+
+	if rendered, _ := renderer.Render(true); !strings.Contains(string(rendered), "---\nname: aistack-demo-front\n") {
+		t.Errorf("Expected Render() to show the front proxy, got\n%s", rendered)
+	}
+
+	data, _ := os.ReadFile(renderer.frontFile())
+	ports, err := parsePublishedPorts(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []publishedPort{{Address: "::1", Port: 8080}, {Address: "0.0.0.0", Port: 9090}}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("front publishes %v, want %v", ports, want)
+	}
+}
+
+func TestComposeRenderer_WriteWithoutBlueGreenPublishesPorts(t *testing.T) {
+	renderer := newFrontProxyRenderer(t, config.UpdateStrategyRecreate)
+
+	path, err := renderer.Write()
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if service := readProxyFile(t, path).Services["demo"]; len(service.Ports) != 2 {
+		t.Errorf("ports = %v, want the service to publish them", service.Ports)
+	}
+	if renderer.hasFrontFile() {
+		t.Error("Expected no front proxy")
+	}
+}
+
+func TestComposeRenderer_WriteCandidate(t *testing.T) {
+	renderer := newFrontProxyRenderer(t, config.UpdateStrategyBlueGreen)
+
+	path, err := renderer.writeCandidate(41234)
+	if err != nil {
+		t.Fatalf("writeCandidate() error = %v", err)
+	}
+	candidate := readProxyFile(t, path)
+	if candidate.Name != "aistack-demo-green" {
+		t.Errorf("candidate project = %q", candidate.Name)
+	}
+	if service := candidate.Services["demo"]; service.ContainerName != "aistack-demo-next" || len(service.Ports) != 0 {
+		t.Errorf("candidate = %+v", service)
+	}
+
+	// The candidate's own proxy publishes the temporary port for the health check
+	proxy := candidate.Services["demo-front"]
+	if proxy.ContainerName != "aistack-demo-next-front" || proxy.Ports[0] != "[::1]:41234:80" {
+		t.Errorf("candidate proxy = %+v", proxy)
+	}
+	if !strings.Contains(proxy.Command[0], "TCP:aistack-demo-next:80") {
+		t.Errorf("candidate proxy command = %v", proxy.Command)
+	}
+}
+
+func TestProxyTargetPort(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  int
+		ok    bool
+	}{
+		{entry: `"127.0.0.1:8080:80"`, want: 80, ok: true},
+		{entry: `"[::1]:8080:80/tcp"`, want: 80, ok: true},
+		{entry: `"3000"`, want: 3000, ok: true},
+		{entry: `{target: 9090, published: 9091}`, want: 9090, ok: true},
+		{entry: `"5353:53/udp"`},
+		{entry: `"8000-8010:8000-8010"`},
+		{entry: `{target: 53, protocol: udp}`},
+	}
+
+	for _, tt := range tests {
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(tt.entry), &node); err != nil {
+			t.Fatal(err)
+		}
+		got, err := proxyTargetPort(node.Content[0])
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("proxyTargetPort(%s) = %d, %v; want %d, ok %v", tt.entry, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestServiceUpdater_FrontProxyImagePolicy(t *testing.T) {
+	key, ring := newTestSigningKey(t)
+	signed := func(name string, entry LockEntry) LockEntry {
+		entry.Signature = signing.Sign(key, lockEntryPayload(name, entry))
+		return entry
+	}
+	service := signed("demo", LockEntry{Image: "example/demo", Digest: testDigest("a")})
+	lock := &VersionLock{
+		entries: map[string]string{"demo": service.Reference()},
+		details: map[string]LockEntry{"demo": service},
+	}
+
+	updater, runtime, _, _ := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+	updater.imageLock = lock
+	updater.SetTrustedKeys(ring)
+
+	// With trusted keys the proxy in front of the service needs a signed pin as well
+	err := updater.EnforceImagePolicy(context.Background())
+	if !errors.Is(err, ErrUntrustedImage) || !strings.Contains(err.Error(), frontProxyLockName) {
+		t.Fatalf("EnforceImagePolicy() without a front-proxy pin error = %v, want it refused", err)
+	}
+
+	proxy := signed(frontProxyLockName, LockEntry{Image: "alpine/socat", Digest: testDigest("f")})
+	lock.entries[frontProxyLockName], lock.details[frontProxyLockName] = proxy.Reference(), proxy
+	if err := updater.EnforceImagePolicy(context.Background()); err != nil {
+		t.Fatalf("EnforceImagePolicy() with signed pins error = %v", err)
+	}
+	want := proxy.Reference() + " -> " + frontProxyImage
+	if !slices.Contains(runtime.tagged, want) {
+		t.Errorf("tagged = %v, want %s", runtime.tagged, want)
+	}
+}
+
+func TestManager_ProposeVersionLockPinsFrontProxy(t *testing.T) {
+	runtime := NewMockRuntime()
+	runtime.containerStatuses["aistack-demo"] = ServiceStatus{State: serviceStateRunning}
+	runtime.containerStatuses["aistack-demo-front"] = ServiceStatus{State: serviceStateRunning}
+	runtime.containerImages = map[string]string{"aistack-demo": "sha256:demoimage", "aistack-demo-front": "sha256:proxyimage"}
+	runtime.imageDigests = map[string]string{"sha256:demoimage": testDigest("a"), "sha256:proxyimage": testDigest("f")}
+
+	manager := newLockTestManager(t, runtime, nil)
+	service := manager.services["demo"].(*CatalogService)
+	service.SetComposeRenderer(newFrontProxyRenderer(t, config.UpdateStrategyBlueGreen))
+
+	proposal, err := manager.ProposeVersionLock(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ProposeVersionLock() error = %v", err)
+	}
+	if entry := proposal.Entries[frontProxyLockName]; entry.Reference() != "alpine/socat@"+testDigest("f") {
+		t.Errorf("front-proxy entry = %+v, want the running proxy's digest", entry)
+	}
+	if err := (&VersionLock{entries: map[string]string{frontProxyLockName: frontProxyImage}}).validateServices(DefaultCatalog()); err != nil {
+		t.Errorf("validateServices() error = %v, want front-proxy accepted", err)
+	}
+}
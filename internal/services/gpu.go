@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/gpu"
+	"aistack/internal/logging"
+)
+
+const (
+	gpuRuntimeDocker = "docker"
+	gpuRuntimePodman = "podman"
+	// cdiDevicePrefix names NVIDIA GPUs in the Container Device Interface (Podman)
+	cdiDevicePrefix = "nvidia.com/gpu="
+)
+
+// GPUReservation is the GPU request injected into a service's compose file
+type GPUReservation struct {
+	Runtime string   // docker (deploy reservations) or podman (CDI devices)
+	Devices []string // GPU UUIDs; empty reserves all GPUs
+}
+
+// gpuDetection is the host GPU state, probed once per manager
+type gpuDetection struct {
+	Toolkit bool   // container toolkit (Docker) or CDI spec (Podman) present
+	Reason  string // why the toolkit is not usable
+	Report  gpu.GPUReport
+}
+
+// gpuAllocator decides per service whether, and which, GPUs are reserved
+type gpuAllocator struct {
+	mode    string
+	devices []string
+	runtime string
+	logger  *logging.Logger
+	detect  func() gpuDetection
+
+	once     sync.Once
+	detected gpuDetection
+}
+
+// newGPUAllocator creates an allocator for the gpu config section; detection is lazy
+func newGPUAllocator(cfg config.GPUConfig, runtime string, logger *logging.Logger) *gpuAllocator {
+	allocator := &gpuAllocator{
+		mode:    cfg.Mode,
+		devices: cfg.Devices,
+		runtime: runtime,
+		logger:  logger,
+	}
+	allocator.detect = allocator.detectHost
+	return allocator
+}
+
+// detectHost runs NVML detection and checks the runtime's GPU integration
+func (a *gpuAllocator) detectHost() gpuDetection {
+	toolkit := gpu.NewToolkitDetector(a.logger)
+	detection := gpuDetection{Report: gpu.NewDetector(a.logger).DetectGPUs()}
+
+	if a.runtime == gpuRuntimePodman {
+		if _, ok := toolkit.DetectCDISpec(); ok {
+			detection.Toolkit = true
+		} else {
+			detection.Reason = "no NVIDIA CDI spec (run: nvidia-ctk cdi generate --output=/etc/cdi/nvidia.yaml)"
+		}
+		return detection
+	}
+
+	report := toolkit.DetectContainerToolkit()
+	detection.Toolkit = report.DockerSupport
+	detection.Reason = report.ErrorMessage
+	return detection
+}
+
+// Reserve returns the reservation for a service, nil when it runs on the CPU.
+// requested (services.<name>.gpus) takes precedence over gpu.devices.
+func (a *gpuAllocator) Reserve(service string, requested []string) *GPUReservation {
+	if a == nil || a.mode == config.GPUModeOff {
+		return nil
+	}
+
+	a.once.Do(func() { a.detected = a.detect() })
+	detection := a.detected
+
+	if !detection.Toolkit {
+		return a.cpuFallback(service, "NVIDIA container toolkit not usable: "+detection.Reason)
+	}
+	if detection.Report.NVMLOk && len(detection.Report.GPUs) == 0 {
+		return a.cpuFallback(service, "no NVIDIA GPUs detected")
+	}
+
+	wanted := requested
+	if len(wanted) == 0 {
+		wanted = a.devices
+	}
+
+	reservation := &GPUReservation{Runtime: a.runtime}
+	if len(wanted) > 0 {
+		if !detection.Report.NVMLOk {
+			return a.cpuFallback(service, "cannot resolve configured GPUs without NVML: "+detection.Report.ErrorMessage)
+		}
+		devices, err := resolveGPUDevices(detection.Report, wanted)
+		if err != nil {
+			return a.cpuFallback(service, err.Error())
+		}
+		reservation.Devices = devices
+	}
+
+	a.logger.Info("gpu.reservation", "Reserving GPUs for service", map[string]interface{}{
+		"service": service,
+		"runtime": reservation.Runtime,
+		"devices": reservation.describeDevices(),
+	})
+	return reservation
+}
+
+func (a *gpuAllocator) cpuFallback(service, reason string) *GPUReservation {
+	a.logger.Warn("gpu.reservation.cpu_fallback", "No GPU reserved, service runs on the CPU", map[string]interface{}{
+		"service": service,
+		"reason":  reason,
+	})
+	return nil
+}
+
+func (r *GPUReservation) describeDevices() string {
+	if len(r.Devices) == 0 {
+		return "all"
+	}
+	return strings.Join(r.Devices, ",")
+}
+
+// resolveGPUDevices maps configured GPU UUIDs or indexes to UUIDs from the report
+func resolveGPUDevices(report gpu.GPUReport, wanted []string) ([]string, error) {
+	var devices []string
+	for _, id := range wanted {
+		id = strings.TrimSpace(id)
+		found := ""
+		for _, info := range report.GPUs {
+			if info.UUID == id || strconv.Itoa(info.Index) == id {
+				found = info.UUID
+				break
+			}
+		}
+		if found == "" {
+			return nil, fmt.Errorf("configured GPU %s not found (detected: %s)", id, detectedGPUs(report))
+		}
+		if !containsString(devices, found) {
+			devices = append(devices, found)
+		}
+	}
+	return devices, nil
+}
+
+func detectedGPUs(report gpu.GPUReport) string {
+	if len(report.GPUs) == 0 {
+		return "none"
+	}
+	ids := make([]string, 0, len(report.GPUs))
+	for _, info := range report.GPUs {
+		ids = append(ids, fmt.Sprintf("%d=%s", info.Index, info.UUID))
+	}
+	return strings.Join(ids, ", ")
+}
+
+// runtimeBinary returns docker or podman for a detected runtime
+func runtimeBinary(runtime Runtime) string {
+	if named, ok := runtime.(interface{ Binary() string }); ok {
+		return named.Binary()
+	}
+	return gpuRuntimeDocker
+}
+
+// applyGPUReservation sets deploy.resources.reservations.devices (Docker) or adds
+// CDI device names under devices (Podman) on a compose service node
+func applyGPUReservation(target *yaml.Node, reservation *GPUReservation) error {
+	if reservation.Runtime == gpuRuntimePodman {
+		devices := yamlMappingValue(target, "devices")
+		if devices.Kind != yaml.SequenceNode {
+			*devices = yaml.Node{Kind: yaml.SequenceNode}
+		}
+		for _, name := range reservation.cdiDevices() {
+			if !yamlSequenceContains(devices, name) {
+				devices.Content = append(devices.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name})
+			}
+		}
+		return nil
+	}
+
+	device := map[string]interface{}{
+		"driver":       "nvidia",
+		"capabilities": []string{"gpu"},
+	}
+	if len(reservation.Devices) > 0 {
+		device["device_ids"] = reservation.Devices
+	} else {
+		device["count"] = "all"
+	}
+
+	reservations := yamlMappingChild(yamlMappingChild(yamlMappingChild(target, "deploy"), "resources"), "reservations")
+	if err := yamlMappingValue(reservations, "devices").Encode([]interface{}{device}); err != nil {
+		return fmt.Errorf("failed to encode GPU reservation: %w", err)
+	}
+	return nil
+}
+
+// cdiDevices returns the CDI device names for the reservation
+func (r *GPUReservation) cdiDevices() []string {
+	if len(r.Devices) == 0 {
+		return []string{cdiDevicePrefix + "all"}
+	}
+	names := make([]string, 0, len(r.Devices))
+	for _, uuid := range r.Devices {
+		names = append(names, cdiDevicePrefix+uuid)
+	}
+	return names
+}
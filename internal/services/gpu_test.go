@@ -0,0 +1,248 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/gpu"
+	"aistack/internal/logging"
+)
+
+// newTestGPUAllocator returns an allocator with a fixed detection result
+func newTestGPUAllocator(cfg config.GPUConfig, runtime string, detection gpuDetection) (*gpuAllocator, *int) {
+	calls := 0
+	allocator := newGPUAllocator(cfg, runtime, logging.NewLogger(logging.LevelError))
+	allocator.detect = func() gpuDetection {
+		calls++
+		return detection
+	}
+	return allocator, &calls
+}
+
+func twoGPUReport() gpu.GPUReport {
+	return gpu.GPUReport{
+		NVMLOk: true,
+		GPUs: []gpu.GPUInfo{
+			{Index: 0, UUID: "GPU-aaaa", Name: "RTX 4090"},
+			{Index: 1, UUID: "GPU-bbbb", Name: "RTX 4090"},
+		},
+	}
+}
+
+func TestGPUAllocator_Reserve(t *testing.T) {
+	withToolkit := gpuDetection{Toolkit: true, Report: twoGPUReport()}
+
+	tests := []struct {
+		name      string
+		cfg       config.GPUConfig
+		detection gpuDetection
+		requested []string
+		want      *GPUReservation
+	}{
+		{
+			name:      "all GPUs by default",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto},
+			detection: withToolkit,
+			want:      &GPUReservation{Runtime: "docker"},
+		},
+		{
+			name:      "configured index resolves to UUID",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto, Devices: []string{"1"}},
+			detection: withToolkit,
+			want:      &GPUReservation{Runtime: "docker", Devices: []string{"GPU-bbbb"}},
+		},
+		{
+			name:      "service devices replace gpu.devices",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto, Devices: []string{"1"}},
+			detection: withToolkit,
+			requested: []string{"GPU-aaaa", "0"},
+			want:      &GPUReservation{Runtime: "docker", Devices: []string{"GPU-aaaa"}},
+		},
+		{
+			name:      "unknown GPU falls back to CPU",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto, Devices: []string{"GPU-zzzz"}},
+			detection: withToolkit,
+		},
+		{
+			name:      "missing toolkit falls back to CPU",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto},
+			detection: gpuDetection{Reason: "NVIDIA runtime not listed in docker info", Report: twoGPUReport()},
+		},
+		{
+			name:      "no GPUs detected falls back to CPU",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto},
+			detection: gpuDetection{Toolkit: true, Report: gpu.GPUReport{NVMLOk: true}},
+		},
+		{
+			name:      "without NVML all GPUs are reserved",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto},
+			detection: gpuDetection{Toolkit: true, Report: gpu.GPUReport{ErrorMessage: "NVML disabled"}},
+			want:      &GPUReservation{Runtime: "docker"},
+		},
+		{
+			name:      "without NVML configured GPUs cannot be resolved",
+			cfg:       config.GPUConfig{Mode: config.GPUModeAuto, Devices: []string{"0"}},
+			detection: gpuDetection{Toolkit: true, Report: gpu.GPUReport{ErrorMessage: "NVML disabled"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocator, _ := newTestGPUAllocator(tt.cfg, "docker", tt.detection)
+			got := allocator.Reserve("ollama", tt.requested)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reserve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGPUAllocator_OffSkipsDetection(t *testing.T) {
+	allocator, calls := newTestGPUAllocator(config.GPUConfig{Mode: config.GPUModeOff}, "docker", gpuDetection{Toolkit: true})
+
+	if got := allocator.Reserve("ollama", nil); got != nil {
+		t.Errorf("Reserve() = %+v, want nil with gpu.mode off", got)
+	}
+	if *calls != 0 {
+		t.Errorf("detect called %d times, want 0", *calls)
+	}
+
+	var nilAllocator *gpuAllocator
+	if nilAllocator.Reserve("ollama", nil) != nil {
+		t.Error("Expected nil allocator to reserve nothing")
+	}
+}
+
+func TestGPUAllocator_DetectsOnce(t *testing.T) {
+	allocator, calls := newTestGPUAllocator(config.GPUConfig{Mode: config.GPUModeAuto}, "podman", gpuDetection{Toolkit: true})
+
+	allocator.Reserve("ollama", nil)
+	allocator.Reserve("localai", nil)
+	if *calls != 1 {
+		t.Errorf("detect called %d times, want 1", *calls)
+	}
+}
+
+const gpuTestCompose = `services:
+  ollama:
+    image: ollama/ollama:latest
+    ports:
+      - "0.0.0.0:11434:11434"
+`
+
+// gpuReservationEdit is the compose edit the renderer applies for a reservation
+func gpuReservationEdit(reservation *GPUReservation) func(*yaml.Node) error {
+	return func(target *yaml.Node) error {
+		return applyGPUReservation(target, reservation)
+	}
+}
+
+func TestApplyGPUReservation_Docker(t *testing.T) {
+	var parsed struct {
+		Services map[string]struct {
+			Image  string `yaml:"image"`
+			Deploy struct {
+				Resources struct {
+					Reservations struct {
+						Devices []struct {
+							Driver       string   `yaml:"driver"`
+							Count        string   `yaml:"count"`
+							DeviceIDs    []string `yaml:"device_ids"`
+							Capabilities []string `yaml:"capabilities"`
+						} `yaml:"devices"`
+					} `yaml:"reservations"`
+				} `yaml:"resources"`
+			} `yaml:"deploy"`
+		} `yaml:"services"`
+	}
+
+	out, err := editComposeService([]byte(gpuTestCompose), "ollama", gpuReservationEdit(&GPUReservation{Runtime: "docker", Devices: []string{"GPU-aaaa"}}))
+	if err != nil {
+		t.Fatalf("editComposeService() error = %v", err)
+	}
+	if err := yaml.Unmarshal(out, &parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	ollama := parsed.Services["ollama"]
+	if ollama.Image != "ollama/ollama:latest" {
+		t.Errorf("Expected existing keys to be kept, got image %q", ollama.Image)
+	}
+	devices := ollama.Deploy.Resources.Reservations.Devices
+	if len(devices) != 1 || devices[0].Driver != "nvidia" || !reflect.DeepEqual(devices[0].DeviceIDs, []string{"GPU-aaaa"}) ||
+		!reflect.DeepEqual(devices[0].Capabilities, []string{"gpu"}) {
+		t.Errorf("devices = %+v", devices)
+	}
+
+	out, err = editComposeService([]byte(gpuTestCompose), "ollama", gpuReservationEdit(&GPUReservation{Runtime: "docker"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "count: all") {
+		t.Errorf("Expected count: all without device IDs:\n%s", out)
+	}
+}
+
+func TestApplyGPUReservation_Podman(t *testing.T) {
+	out, err := editComposeService([]byte(gpuTestCompose), "ollama", gpuReservationEdit(&GPUReservation{Runtime: "podman", Devices: []string{"GPU-aaaa", "GPU-bbbb"}}))
+	if err != nil {
+		t.Fatalf("editComposeService() error = %v", err)
+	}
+
+	var parsed struct {
+		Services map[string]struct {
+			Devices []string `yaml:"devices"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(out, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"nvidia.com/gpu=GPU-aaaa", "nvidia.com/gpu=GPU-bbbb"}
+	if got := parsed.Services["ollama"].Devices; !reflect.DeepEqual(got, want) {
+		t.Errorf("devices = %v, want %v", got, want)
+	}
+}
+
+func TestEditComposeService_UnknownService(t *testing.T) {
+	if _, err := editComposeService([]byte(gpuTestCompose), "localai", gpuReservationEdit(&GPUReservation{Runtime: "docker"})); err == nil {
+		t.Error("Expected error for a service missing from the compose file")
+	}
+}
+
+func TestComposeRenderer_InjectsGPUForGPUServices(t *testing.T) {
+	composeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(composeDir, "ollama.yaml"), []byte(gpuTestCompose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	allocator, _ := newTestGPUAllocator(config.GPUConfig{Mode: config.GPUModeAuto}, "docker", gpuDetection{Toolkit: true, Report: twoGPUReport()})
+	logger := logging.NewLogger(logging.LevelError)
+
+	spec := ServiceSpec{Name: "ollama", Image: "ollama/ollama:latest", GPU: true}
+	renderer := NewComposeRenderer(spec, composeDir, "0.0.0.0", nil, t.TempDir(), logger)
+	renderer.useGPU(allocator, []string{"0"})
+
+	rendered, err := renderer.Render(true)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(string(rendered), "GPU-aaaa") {
+		t.Errorf("Expected GPU reservation in rendered file:\n%s", rendered)
+	}
+
+	spec.GPU = false
+	cpuOnly := NewComposeRenderer(spec, composeDir, "0.0.0.0", nil, t.TempDir(), logger)
+	cpuOnly.useGPU(allocator, nil)
+	rendered, err = cpuOnly.Render(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rendered), "deploy") {
+		t.Errorf("Expected no reservation for a service without gpu: true:\n%s", rendered)
+	}
+}
@@ -1,12 +1,19 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"regexp"
 	"time"
 )
 
+// maxHealthBodySize bounds how much of a response body assertions read
+const maxHealthBodySize = 1 << 20
+
 // HealthStatus represents the health status of a service
 type HealthStatus string
 
@@ -21,7 +28,7 @@ const (
 
 // HealthChecker is an interface for performing health checks
 type HealthChecker interface {
-	Check() (HealthStatus, error)
+	Check(ctx context.Context) (HealthStatus, error)
 }
 
 // HealthCheck represents a health check configuration
@@ -29,6 +36,8 @@ type HealthCheck struct {
 	URL            string
 	Timeout        time.Duration
 	ExpectedStatus int
+	JSONPath       string         // value that must exist in the JSON body (optional)
+	BodyPattern    *regexp.Regexp // pattern the body, or the JSONPath value, must match (optional)
 }
 
 // DefaultHealthCheck returns a default health check configuration
@@ -41,12 +50,17 @@ func DefaultHealthCheck(url string) HealthCheck {
 }
 
 // Check performs the health check
-func (hc HealthCheck) Check() (HealthStatus, error) {
+func (hc HealthCheck) Check(ctx context.Context) (HealthStatus, error) {
 	client := &http.Client{
 		Timeout: hc.Timeout,
 	}
 
-	resp, err := client.Get(hc.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hc.URL, nil)
+	if err != nil {
+		return HealthRed, fmt.Errorf("invalid health check request: %w", err)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return HealthRed, fmt.Errorf("health check failed: %w", err)
 	}
@@ -60,22 +74,60 @@ func (hc HealthCheck) Check() (HealthStatus, error) {
 		return HealthYellow, fmt.Errorf("unexpected status code: got %d, want %d", resp.StatusCode, hc.ExpectedStatus)
 	}
 
+	if hc.JSONPath == "" && hc.BodyPattern == nil {
+		return HealthGreen, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBodySize))
+	if err != nil {
+		return HealthRed, fmt.Errorf("failed to read health check response: %w", err)
+	}
+	if err := hc.checkBody(body); err != nil {
+		// Reachable, but not answering as expected
+		return HealthYellow, err
+	}
+
 	return HealthGreen, nil
 }
 
+// checkBody applies the JSONPath and pattern assertions to a response body
+func (hc HealthCheck) checkBody(body []byte) error {
+	value := string(body)
+	if hc.JSONPath != "" {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Errorf("response is not JSON: %w", err)
+		}
+		found, err := jsonPathLookup(doc, hc.JSONPath)
+		if err != nil {
+			return err
+		}
+		value = jsonValueString(found)
+	}
+
+	if hc.BodyPattern != nil && !hc.BodyPattern.MatchString(value) {
+		if hc.JSONPath != "" {
+			return fmt.Errorf("%s = %q does not match %s", hc.JSONPath, value, hc.BodyPattern)
+		}
+		return fmt.Errorf("response body does not match %s", hc.BodyPattern)
+	}
+	return nil
+}
+
 // CheckWithRetries performs health check with retries
-func (hc HealthCheck) CheckWithRetries(maxRetries int, retryDelay time.Duration) (HealthStatus, error) {
+func (hc HealthCheck) CheckWithRetries(ctx context.Context, maxRetries int, retryDelay time.Duration) (HealthStatus, error) {
 	var lastErr error
 
 	for i := 0; i < maxRetries; i++ {
-		status, err := hc.Check()
+		status, err := hc.Check(ctx)
 		if err == nil && status == HealthGreen {
 			return HealthGreen, nil
 		}
 
 		lastErr = err
 		if i < maxRetries-1 {
-			time.Sleep(retryDelay)
+			if sleepErr := sleepContext(ctx, retryDelay); sleepErr != nil {
+				return HealthRed, fmt.Errorf("health check cancelled: %w", sleepErr)
+			}
 		}
 	}
 
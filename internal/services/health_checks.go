@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"aistack/internal/config"
+)
+
+// TCPHealthCheck is healthy when the address accepts a TCP connection
+type TCPHealthCheck struct {
+	Address string
+	Timeout time.Duration
+}
+
+// Check dials the address
+func (c TCPHealthCheck) Check(ctx context.Context) (HealthStatus, error) {
+	dialer := net.Dialer{Timeout: c.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Address)
+	if err != nil {
+		return HealthRed, fmt.Errorf("tcp health check failed: %w", err)
+	}
+	_ = conn.Close()
+	return HealthGreen, nil
+}
+
+// ExecHealthCheck is healthy when a command inside the container exits with 0
+type ExecHealthCheck struct {
+	Runtime   Runtime
+	Container string
+	Command   []string
+}
+
+// Check runs the command via the runtime
+func (c ExecHealthCheck) Check(ctx context.Context) (HealthStatus, error) {
+	result, err := c.Runtime.ExecInContainer(ctx, c.Container, c.Command)
+	if err != nil {
+		return HealthRed, fmt.Errorf("exec health check failed: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return HealthRed, fmt.Errorf("exec health check %q exited with %d: %s",
+			strings.Join(c.Command, " "), result.ExitCode, strings.TrimSpace(result.Output))
+	}
+	return HealthGreen, nil
+}
+
+// CompositeHealthCheck combines checks: with RequireAll the worst result counts,
+// otherwise the best one does
+type CompositeHealthCheck struct {
+	Checks     []HealthChecker
+	RequireAll bool
+}
+
+// Check runs the checks in order; "any" stops at the first green one
+func (c CompositeHealthCheck) Check(ctx context.Context) (HealthStatus, error) {
+	var failures []error
+	worst, best := HealthGreen, HealthRed
+
+	for i, check := range c.Checks {
+		status, err := check.Check(ctx)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("check %d: %w", i+1, err))
+		}
+		if !c.RequireAll && status == HealthGreen {
+			return HealthGreen, nil
+		}
+		if healthRank(status) > healthRank(worst) {
+			worst = status
+		}
+		if healthRank(status) < healthRank(best) {
+			best = status
+		}
+		if ctx.Err() != nil {
+			return HealthRed, fmt.Errorf("health check interrupted: %w", ctx.Err())
+		}
+	}
+
+	if c.RequireAll {
+		return worst, errors.Join(failures...)
+	}
+	return best, errors.Join(failures...)
+}
+
+// healthRank orders statuses from best (green) to worst (red)
+func healthRank(status HealthStatus) int {
+	switch status {
+	case HealthGreen:
+		return 0
+	case HealthYellow:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// newHealthChecker builds the checker configured in services.<name>.health;
+// an empty config keeps the HTTP check on the catalog health URL
+func newHealthChecker(spec ServiceSpec, check config.HealthCheckConfig, runtime Runtime) (HealthChecker, error) {
+	switch check.Type {
+	case "", config.HealthTypeHTTP:
+		hc := DefaultHealthCheck(spec.HealthURL)
+		if check.URL != "" {
+			hc.URL = check.URL
+		}
+		if check.ExpectedStatus != 0 {
+			hc.ExpectedStatus = check.ExpectedStatus
+		}
+		hc.JSONPath = check.JSONPath
+		if check.Regex != "" {
+			pattern, err := regexp.Compile(check.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid health check regex: %w", err)
+			}
+			hc.BodyPattern = pattern
+		}
+		return hc, nil
+
+	case config.HealthTypeTCP:
+		address := check.Address
+		if address == "" {
+			parsed, err := url.Parse(spec.HealthURL)
+			if err != nil || parsed.Hostname() == "" {
+				return nil, fmt.Errorf("tcp health check needs an address (no usable health_url)")
+			}
+			port := parsed.Port()
+			if port == "" {
+				port = strconv.Itoa(spec.Port)
+			}
+			address = net.JoinHostPort(parsed.Hostname(), port)
+		}
+		return TCPHealthCheck{Address: address, Timeout: DefaultHealthCheck("").Timeout}, nil
+
+	case config.HealthTypeExec:
+		if len(check.Command) == 0 {
+			return nil, fmt.Errorf("exec health check needs a command")
+		}
+		return ExecHealthCheck{Runtime: runtime, Container: "aistack-" + spec.Name, Command: check.Command}, nil
+
+	case config.HealthTypeAll, config.HealthTypeAny:
+		composite := CompositeHealthCheck{RequireAll: check.Type == config.HealthTypeAll}
+		for _, nested := range check.Checks {
+			checker, err := newHealthChecker(spec, nested, runtime)
+			if err != nil {
+				return nil, err
+			}
+			composite.Checks = append(composite.Checks, checker)
+		}
+		if len(composite.Checks) == 0 {
+			return nil, fmt.Errorf("%s health check needs nested checks", check.Type)
+		}
+		return composite, nil
+	}
+
+	return nil, fmt.Errorf("unknown health check type '%s'", check.Type)
+}
+
+// jsonPathLookup resolves a simple JSONPath ($.a.b[0].c) in a decoded JSON document
+func jsonPathLookup(doc interface{}, path string) (interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSONPath '%s': must start with '$'", path)
+	}
+
+	current := doc
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSONPath '%s': empty key", path)
+			}
+			object, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: %s is not an object", path, key)
+			}
+			value, ok := object[key]
+			if !ok || value == nil {
+				return nil, fmt.Errorf("%s not found in response", path)
+			}
+			current = value
+
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath '%s': missing ']'", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath '%s': index must be a number", path)
+			}
+			rest = rest[end+1:]
+			array, ok := current.([]interface{})
+			if !ok || index < 0 || index >= len(array) || array[index] == nil {
+				return nil, fmt.Errorf("%s not found in response", path)
+			}
+			current = array[index]
+
+		default:
+			return nil, fmt.Errorf("invalid JSONPath '%s'", path)
+		}
+	}
+	return current, nil
+}
+
+// jsonValueString formats a JSONPath result for pattern matching
+func jsonValueString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+// staticCheck returns a fixed result
+type staticCheck struct {
+	status HealthStatus
+	err    error
+	calls  *int
+}
+
+func (c staticCheck) Check(_ context.Context) (HealthStatus, error) {
+	if c.calls != nil {
+		*c.calls++
+	}
+	return c.status, c.err
+}
+
+func TestHealthCheck_BodyAssertions(t *testing.T) {
+	server := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"0.3.14","models":[{"name":"llama3"}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		jsonPath string
+		pattern  string
+		want     HealthStatus
+	}{
+		{name: "path exists", jsonPath: "$.version", want: HealthGreen},
+		{name: "path and regex", jsonPath: "$.version", pattern: `^0\.\d+`, want: HealthGreen},
+		{name: "array index", jsonPath: "$.models[0].name", pattern: "^llama", want: HealthGreen},
+		{name: "regex on body", pattern: `"version"`, want: HealthGreen},
+		{name: "missing path", jsonPath: "$.build", want: HealthYellow},
+		{name: "value mismatch", jsonPath: "$.version", pattern: `^1\.`, want: HealthYellow},
+		{name: "index out of range", jsonPath: "$.models[3]", want: HealthYellow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := DefaultHealthCheck(server.URL)
+			hc.JSONPath = tt.jsonPath
+			if tt.pattern != "" {
+				hc.BodyPattern = regexp.MustCompile(tt.pattern)
+			}
+
+			status, err := hc.Check(context.Background())
+			if status != tt.want {
+				t.Errorf("Check() = %s (%v), want %s", status, err, tt.want)
+			}
+			if (err == nil) != (tt.want == HealthGreen) {
+				t.Errorf("Check() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestHealthCheck_JSONPathOnNonJSON(t *testing.T) {
+	server := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("Ollama is running"))
+	}))
+	defer server.Close()
+
+	hc := DefaultHealthCheck(server.URL)
+	hc.JSONPath = "$.version"
+	if status, err := hc.Check(context.Background()); status != HealthYellow || err == nil {
+		t.Errorf("Check() = %s, %v; want yellow with error", status, err)
+	}
+}
+
+func TestTCPHealthCheck(t *testing.T) {
+	listener, port := listenLocal(t)
+	address := listener.Addr().String()
+
+	check := TCPHealthCheck{Address: address, Timeout: time.Second}
+	if status, err := check.Check(context.Background()); status != HealthGreen || err != nil {
+		t.Errorf("Check() = %s, %v; want green", status, err)
+	}
+
+	_ = listener.Close()
+	if status, _ := check.Check(context.Background()); status != HealthRed {
+		t.Errorf("Check() on closed port %d = %s, want red", port, status)
+	}
+}
+
+func TestExecHealthCheck(t *testing.T) {
+	runtime := NewMockRuntime()
+	check := ExecHealthCheck{Runtime: runtime, Container: "aistack-ollama", Command: []string{"ollama", "list"}}
+
+	if status, err := check.Check(context.Background()); status != HealthGreen || err != nil {
+		t.Errorf("Check() = %s, %v; want green", status, err)
+	}
+	if !reflect.DeepEqual(runtime.execCommands, [][]string{{"ollama", "list"}}) {
+		t.Errorf("exec commands = %v", runtime.execCommands)
+	}
+
+	runtime.execResults["aistack-ollama"] = ExecResult{ExitCode: 1, Output: "could not connect to ollama app\n"}
+	status, err := check.Check(context.Background())
+	if status != HealthRed || err == nil || !strings.Contains(err.Error(), "could not connect") {
+		t.Errorf("Check() = %s, %v; want red with command output", status, err)
+	}
+
+	runtime.missingContainers["aistack-ollama"] = true
+	if status, _ := check.Check(context.Background()); status != HealthRed {
+		t.Errorf("Check() without container = %s, want red", status)
+	}
+}
+
+func TestCompositeHealthCheck(t *testing.T) {
+	green := staticCheck{status: HealthGreen}
+	yellow := staticCheck{status: HealthYellow, err: errors.New("slow")}
+	red := staticCheck{status: HealthRed, err: errors.New("down")}
+
+	tests := []struct {
+		name       string
+		requireAll bool
+		checks     []HealthChecker
+		want       HealthStatus
+	}{
+		{name: "all green", requireAll: true, checks: []HealthChecker{green, green}, want: HealthGreen},
+		{name: "all takes the worst", requireAll: true, checks: []HealthChecker{green, yellow, red}, want: HealthRed},
+		{name: "all with yellow", requireAll: true, checks: []HealthChecker{green, yellow}, want: HealthYellow},
+		{name: "any green wins", checks: []HealthChecker{red, green}, want: HealthGreen},
+		{name: "any takes the best", checks: []HealthChecker{red, yellow}, want: HealthYellow},
+		{name: "any all red", checks: []HealthChecker{red, red}, want: HealthRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := CompositeHealthCheck{Checks: tt.checks, RequireAll: tt.requireAll}.Check(context.Background())
+			if status != tt.want {
+				t.Errorf("Check() = %s, want %s", status, tt.want)
+			}
+			if (err == nil) != (tt.want == HealthGreen) {
+				t.Errorf("Check() error = %v", err)
+			}
+		})
+	}
+
+	calls := 0
+	CompositeHealthCheck{Checks: []HealthChecker{green, staticCheck{status: HealthRed, calls: &calls}}}.Check(context.Background())
+	if calls != 0 {
+		t.Error("Expected any to stop at the first green check")
+	}
+}
+
+func TestNewHealthChecker(t *testing.T) {
+	runtime := NewMockRuntime()
+	spec := ServiceSpec{Name: "ollama", HealthURL: "http://localhost:11434/api/tags", Port: 11434}
+
+	checker, err := newHealthChecker(spec, config.HealthCheckConfig{}, runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc, ok := checker.(HealthCheck); !ok || hc.URL != spec.HealthURL || hc.ExpectedStatus != http.StatusOK {
+		t.Errorf("default checker = %#v, want HTTP check on the health_url", checker)
+	}
+
+	checker, err = newHealthChecker(spec, config.HealthCheckConfig{
+		Type: config.HealthTypeAll,
+		Checks: []config.HealthCheckConfig{
+			{URL: "http://localhost:11434/api/version", JSONPath: "$.version", Regex: `^\d`},
+			{Type: config.HealthTypeTCP},
+			{Type: config.HealthTypeExec, Command: []string{"ollama", "list"}},
+		},
+	}, runtime)
+	if err != nil {
+		t.Fatalf("newHealthChecker() error = %v", err)
+	}
+
+	composite, ok := checker.(CompositeHealthCheck)
+	if !ok || !composite.RequireAll || len(composite.Checks) != 3 {
+		t.Fatalf("checker = %#v, want composite of 3", checker)
+	}
+	if hc := composite.Checks[0].(HealthCheck); hc.URL != "http://localhost:11434/api/version" || hc.JSONPath != "$.version" || hc.BodyPattern == nil {
+		t.Errorf("http check = %#v", hc)
+	}
+	if tcp := composite.Checks[1].(TCPHealthCheck); tcp.Address != "localhost:11434" {
+		t.Errorf("tcp address = %s, want the health_url host and port", tcp.Address)
+	}
+	if exec := composite.Checks[2].(ExecHealthCheck); exec.Container != "aistack-ollama" {
+		t.Errorf("exec container = %s", exec.Container)
+	}
+
+	if _, err := newHealthChecker(spec, config.HealthCheckConfig{Type: "grpc"}, runtime); err == nil {
+		t.Error("Expected error for an unknown type")
+	}
+}
+
+func TestNewBaseServiceFromSpec_UsesConfiguredHealthCheck(t *testing.T) {
+	runtime := NewMockRuntime()
+	spec := ServiceSpec{Name: "ollama", Image: "ollama/ollama:latest", HealthURL: "http://localhost:11434/api/tags", Port: 11434}
+	spec = spec.withServiceConfig("0.0.0.0", config.ServiceConfig{
+		Health: config.HealthCheckConfig{Type: config.HealthTypeExec, Command: []string{"ollama", "list"}},
+	})
+
+	service := NewBaseServiceFromSpec(spec, t.TempDir(), runtime, logging.NewLogger(logging.LevelError))
+	if status, err := service.Health(context.Background()); status != HealthGreen || err != nil {
+		t.Errorf("Health() = %s, %v; want green from the exec check", status, err)
+	}
+	if len(runtime.execCommands) != 1 {
+		t.Errorf("Expected the exec check to run, got %d execs", len(runtime.execCommands))
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+)
+
+// healthHistoryFile keeps health transitions in the state directory
+const healthHistoryFile = "health_history.json"
+
+// HealthTransition is a change of a service's health between two probes
+type HealthTransition struct {
+	Time    time.Time    `json:"time"`
+	Service string       `json:"service"`
+	From    HealthStatus `json:"from,omitempty"` // empty for the first probe of a service
+	To      HealthStatus `json:"to"`
+	Message string       `json:"message,omitempty"`
+}
+
+// HealthTrend is the current health of a service across probes
+type HealthTrend struct {
+	Health   HealthStatus `json:"health"`
+	Since    time.Time    `json:"since"`              // first probe with this health
+	Streak   int          `json:"streak"`             // consecutive probes with this health
+	Flapping bool         `json:"flapping,omitempty"` // computed from the transitions, not stored
+}
+
+// healthHistoryDoc is the on-disk form of the history
+type healthHistoryDoc struct {
+	Current     map[string]HealthTrend `json:"current"`
+	Transitions []HealthTransition     `json:"transitions"` // oldest first
+}
+
+// HealthHistory records health transitions per service so decisions can look at
+// more than one probe; with a path set it is shared by the CLI and the agent
+type HealthHistory struct {
+	mu         sync.Mutex
+	path       string
+	maxEntries int
+	flapCount  int
+	flapWindow time.Duration
+	doc        healthHistoryDoc
+	logger     *logging.Logger
+	now        func() time.Time
+}
+
+// NewHealthHistory creates a history stored at path (empty keeps it in memory)
+func NewHealthHistory(path string, cfg config.HealthHistoryConfig, logger *logging.Logger) *HealthHistory {
+	return &HealthHistory{
+		path:       path,
+		maxEntries: cfg.MaxEntries,
+		flapCount:  cfg.FlapTransitions,
+		flapWindow: time.Duration(cfg.FlapWindowMinutes) * time.Minute,
+		doc:        healthHistoryDoc{Current: make(map[string]HealthTrend)},
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// LoadHealthHistory opens the history in the state directory with the configured limits
+func LoadHealthHistory(logger *logging.Logger) *HealthHistory {
+	cfg := loadManagerConfig(logger)
+	stateDir := fsutil.GetStateDir(defaultStateDir)
+	return NewHealthHistory(filepath.Join(stateDir, healthHistoryFile), cfg.HealthHistory, logger)
+}
+
+// Record adds one round of probe results, appending a transition for every service
+// whose health changed, and fills in Since, Streak and Flapping of statuses
+func (h *HealthHistory) Record(statuses []ServiceHealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The agent and CLI runs update the file concurrently; re-read it under the file
+	// lock so their transitions are kept
+	unlock := lockStateFile(h.path, h.logger)
+	defer unlock()
+	if err := h.refresh(); err != nil {
+		h.logger.Warn("health.history.load_failed", "Could not read health history", map[string]interface{}{
+			"path":  h.path,
+			"error": err.Error(),
+		})
+	}
+
+	now := h.now().UTC()
+	for i := range statuses {
+		status := &statuses[i]
+		trend, known := h.doc.Current[status.Name]
+		if known && trend.Health == status.Health {
+			trend.Streak++
+		} else {
+			h.doc.Transitions = append(h.doc.Transitions, HealthTransition{
+				Time:    now,
+				Service: status.Name,
+				From:    trend.Health,
+				To:      status.Health,
+				Message: status.Message,
+			})
+			if known {
+				h.logger.Info("health.history.transition", "Service health changed", map[string]interface{}{
+					"service": status.Name,
+					"from":    trend.Health,
+					"to":      status.Health,
+				})
+			}
+			trend = HealthTrend{Health: status.Health, Since: now, Streak: 1}
+		}
+		h.doc.Current[status.Name] = trend
+
+		since := trend.Since
+		status.Since = &since
+		status.Streak = trend.Streak
+		status.Flapping = h.flapping(status.Name, now)
+	}
+
+	if excess := len(h.doc.Transitions) - h.maxEntries; h.maxEntries > 0 && excess > 0 {
+		h.doc.Transitions = append([]HealthTransition(nil), h.doc.Transitions[excess:]...)
+	}
+
+	if err := h.save(); err != nil {
+		h.logger.Warn("health.history.save_failed", "Could not save health history", map[string]interface{}{
+			"path":  h.path,
+			"error": err.Error(),
+		})
+	}
+}
+
+// Transitions returns the transitions since the given time, oldest first;
+// service filters them to one service when set
+func (h *HealthHistory) Transitions(service string, since time.Time) ([]HealthTransition, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.refresh(); err != nil {
+		return nil, err
+	}
+
+	var transitions []HealthTransition
+	for _, transition := range h.doc.Transitions {
+		if transition.Time.Before(since) || (service != "" && transition.Service != service) {
+			continue
+		}
+		transitions = append(transitions, transition)
+	}
+	return transitions, nil
+}
+
+// Trends returns the current health trend of every recorded service
+func (h *HealthHistory) Trends() (map[string]HealthTrend, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.refresh(); err != nil {
+		return nil, err
+	}
+
+	now := h.now()
+	trends := make(map[string]HealthTrend, len(h.doc.Current))
+	for name, trend := range h.doc.Current {
+		trend.Flapping = h.flapping(name, now)
+		trends[name] = trend
+	}
+	return trends, nil
+}
+
+// Flapping reports whether a service changed health at least flap_transitions
+// times within the flap window
+func (h *HealthHistory) Flapping(service string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.refresh(); err != nil {
+		return false
+	}
+	return h.flapping(service, h.now())
+}
+
+// FlapWindow returns the flap detection window
+func (h *HealthHistory) FlapWindow() time.Duration {
+	return h.flapWindow
+}
+
+// flapping counts changes (not first probes) of service within the window ending at now
+func (h *HealthHistory) flapping(service string, now time.Time) bool {
+	if h.flapCount <= 0 {
+		return false
+	}
+	cutoff := now.Add(-h.flapWindow)
+	count := 0
+	for i := len(h.doc.Transitions) - 1; i >= 0; i-- {
+		transition := h.doc.Transitions[i]
+		if transition.Time.Before(cutoff) {
+			break
+		}
+		if transition.Service == service && transition.From != "" {
+			count++
+		}
+	}
+	return count >= h.flapCount
+}
+
+// lockStateFile takes the cross-process lock of a shared state file (<path>.lock) and
+// returns the unlock function. Without a path, or when the lock cannot be taken, the
+// update runs unlocked and only a warning is logged.
+func lockStateFile(path string, logger *logging.Logger) func() {
+	if path == "" {
+		return func() {}
+	}
+	var unlock func()
+	err := fsutil.EnsureStateDirectory(filepath.Dir(path))
+	if err == nil {
+		unlock, err = fsutil.Lock(path + ".lock")
+	}
+	if err != nil {
+		logger.Warn("state.lock.failed", "Could not lock state file, updating it unlocked", map[string]interface{}{
+			"path":  path,
+			"error": err.Error(),
+		})
+		return func() {}
+	}
+	return unlock
+}
+
+// refresh replaces the in-memory history with the file; a missing file keeps it
+func (h *HealthHistory) refresh() error {
+	if h.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Clean(h.path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read health history: %w", err)
+	}
+
+	var doc healthHistoryDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse health history: %w", err)
+	}
+	if doc.Current == nil {
+		doc.Current = make(map[string]HealthTrend)
+	}
+	sort.SliceStable(doc.Transitions, func(i, j int) bool {
+		return doc.Transitions[i].Time.Before(doc.Transitions[j].Time)
+	})
+	h.doc = doc
+	return nil
+}
+
+func (h *HealthHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	if err := fsutil.EnsureStateDirectory(filepath.Dir(h.path)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h.doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal health history: %w", err)
+	}
+	return fsutil.AtomicWriteFile(h.path, data, fsutil.DefaultFilePermissions, h.logger)
+}
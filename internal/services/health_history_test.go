@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+// newTestHistory returns a history with a manual clock
+func newTestHistory(path string, cfg config.HealthHistoryConfig) (*HealthHistory, *time.Time) {
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := NewHealthHistory(path, cfg, logging.NewLogger(logging.LevelError))
+	history.now = func() time.Time { return clock }
+	return history, &clock
+}
+
+func recordHealth(history *HealthHistory, name string, health HealthStatus) ServiceHealthStatus {
+	statuses := []ServiceHealthStatus{{Name: name, Health: health}}
+	history.Record(statuses)
+	return statuses[0]
+}
+
+func TestHealthHistory_RecordsTransitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), healthHistoryFile)
+	history, clock := newTestHistory(path, config.DefaultConfig().HealthHistory)
+
+	recordHealth(history, "ollama", HealthGreen)
+	*clock = clock.Add(time.Minute)
+	recordHealth(history, "ollama", HealthGreen)
+	*clock = clock.Add(time.Minute)
+	redAt := *clock
+	recordHealth(history, "ollama", HealthRed)
+	*clock = clock.Add(time.Minute)
+	status := recordHealth(history, "ollama", HealthRed)
+
+	if status.Streak != 2 || status.Since == nil || !status.Since.Equal(redAt) || status.Flapping {
+		t.Errorf("status = %+v, want a red streak of 2 since %s", status, redAt)
+	}
+
+	// A second history on the same file (another process) sees the same transitions
+	reader, _ := newTestHistory(path, config.DefaultConfig().HealthHistory)
+	transitions, err := reader.Transitions("", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 || transitions[0].From != "" || transitions[1].From != HealthGreen || transitions[1].To != HealthRed {
+		t.Errorf("transitions = %+v, want the first probe and green → red", transitions)
+	}
+
+	if transitions, _ := reader.Transitions("ollama", redAt.Add(time.Second)); len(transitions) != 0 {
+		t.Errorf("Transitions(since after the change) = %+v, want none", transitions)
+	}
+	if transitions, _ := reader.Transitions("openwebui", time.Time{}); len(transitions) != 0 {
+		t.Errorf("Transitions(other service) = %+v, want none", transitions)
+	}
+}
+
+func TestHealthHistory_ConcurrentWritersKeepTransitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), healthHistoryFile)
+
+	// Each history stands for a separate process (agent, CLI runs) on the same file
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			history, _ := newTestHistory(path, config.DefaultConfig().HealthHistory)
+			recordHealth(history, name, HealthRed)
+		}(fmt.Sprintf("svc%d", i))
+	}
+	wg.Wait()
+
+	reader, _ := newTestHistory(path, config.DefaultConfig().HealthHistory)
+	transitions, err := reader.Transitions("", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 50 {
+		t.Errorf("recorded %d transitions, want all 50", len(transitions))
+	}
+}
+
+func TestHealthHistory_DetectsFlapping(t *testing.T) {
+	history, clock := newTestHistory("", config.HealthHistoryConfig{MaxEntries: 100, FlapTransitions: 3, FlapWindowMinutes: 10})
+
+	for i, health := range []HealthStatus{HealthGreen, HealthRed, HealthGreen} {
+		if status := recordHealth(history, "ollama", health); status.Flapping {
+			t.Fatalf("probe %d: flapping after %d changes", i, i)
+		}
+		*clock = clock.Add(time.Minute)
+	}
+
+	if status := recordHealth(history, "ollama", HealthYellow); !status.Flapping {
+		t.Error("Expected three changes within 10m to be flapping")
+	}
+	if !history.Flapping("ollama") || history.Flapping("openwebui") {
+		t.Error("Flapping() disagrees with the recorded transitions")
+	}
+
+	// Once the changes age out of the window the service is stable again
+	*clock = clock.Add(15 * time.Minute)
+	if history.Flapping("ollama") {
+		t.Error("Expected the service to settle after the flap window")
+	}
+}
+
+func TestHealthHistory_BoundsEntries(t *testing.T) {
+	history, clock := newTestHistory("", config.HealthHistoryConfig{MaxEntries: 5, FlapTransitions: 100, FlapWindowMinutes: 10})
+
+	for i := 0; i < 12; i++ {
+		recordHealth(history, "ollama", []HealthStatus{HealthGreen, HealthRed}[i%2])
+		*clock = clock.Add(time.Minute)
+	}
+
+	transitions, err := history.Transitions("", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 5 {
+		t.Fatalf("kept %d transitions, want 5", len(transitions))
+	}
+	if last := transitions[4]; last.To != HealthRed {
+		t.Errorf("last transition = %+v, want the newest one", last)
+	}
+}
+
+func TestManager_RepairService_RepairsFlappingService(t *testing.T) {
+	logger := logging.NewLogger(logging.LevelError)
+	runtime := NewMockRuntime()
+	manager := &Manager{
+		runtime:  runtime,
+		logger:   logger,
+		services: map[string]Service{"ollama": NewBaseService("ollama", "/tmp", &MockHealthCheck{status: HealthGreen}, nil, runtime, logger)},
+	}
+
+	history := manager.HealthHistory()
+	for _, health := range []HealthStatus{HealthGreen, HealthRed, HealthGreen, HealthRed, HealthGreen} {
+		recordHealth(history, "ollama", health)
+	}
+
+	result, err := manager.RepairService(context.Background(), "ollama")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SkippedReason != "" || !result.Success {
+		t.Errorf("result = %+v, want a repair of the flapping service although it is green now", result)
+	}
+}
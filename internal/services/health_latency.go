@@ -0,0 +1,135 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+)
+
+const (
+	// latencyHistoryFile keeps recent probe latencies in the state directory
+	latencyHistoryFile = "health_latency.json"
+	// latencyWindow is the number of recent probes per service p50/p95 cover
+	latencyWindow = 20
+)
+
+// ProbeLatency summarizes the health probe timings of a service
+type ProbeLatency struct {
+	LastMs  int64 `json:"last_ms"`
+	P50Ms   int64 `json:"p50_ms"`
+	P95Ms   int64 `json:"p95_ms"`
+	Samples int   `json:"samples"` // probes the percentiles are computed over
+}
+
+// latencyHistory keeps the latest probe latencies (milliseconds, oldest first) per
+// service; with a path set it is persisted so separate `aistack health` runs add up
+type latencyHistory struct {
+	mu      sync.Mutex
+	path    string
+	samples map[string][]int64
+	logger  *logging.Logger
+}
+
+func newLatencyHistory(path string, logger *logging.Logger) *latencyHistory {
+	return &latencyHistory{
+		path:    path,
+		samples: make(map[string][]int64),
+		logger:  logger,
+	}
+}
+
+// record appends the latencies of one report and returns the summary per service
+func (h *latencyHistory) record(latencies map[string]time.Duration) map[string]ProbeLatency {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Merge with the samples other runs saved, under the same lock as the save
+	unlock := lockStateFile(h.path, h.logger)
+	defer unlock()
+	if h.path != "" {
+		if samples, err := h.load(); err == nil {
+			h.samples = samples
+		} else if !os.IsNotExist(err) {
+			h.logger.Warn("health.latency.load_failed", "Could not read probe latency history", map[string]interface{}{
+				"path":  h.path,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	summaries := make(map[string]ProbeLatency, len(latencies))
+	for name, latency := range latencies {
+		samples := append(h.samples[name], latency.Milliseconds())
+		if len(samples) > latencyWindow {
+			samples = samples[len(samples)-latencyWindow:]
+		}
+		h.samples[name] = samples
+		summaries[name] = summarizeLatency(samples)
+	}
+
+	if h.path != "" {
+		if err := h.save(); err != nil {
+			h.logger.Warn("health.latency.save_failed", "Could not save probe latency history", map[string]interface{}{
+				"path":  h.path,
+				"error": err.Error(),
+			})
+		}
+	}
+	return summaries
+}
+
+func (h *latencyHistory) load() (map[string][]int64, error) {
+	data, err := os.ReadFile(filepath.Clean(h.path))
+	if err != nil {
+		return nil, err
+	}
+	samples := make(map[string][]int64)
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("failed to parse latency history: %w", err)
+	}
+	return samples, nil
+}
+
+func (h *latencyHistory) save() error {
+	if err := fsutil.EnsureStateDirectory(filepath.Dir(h.path)); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h.samples, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal latency history: %w", err)
+	}
+	return fsutil.AtomicWriteFile(h.path, data, fsutil.DefaultFilePermissions, h.logger)
+}
+
+// summarizeLatency computes last, p50 and p95 of samples (oldest first)
+func summarizeLatency(samples []int64) ProbeLatency {
+	if len(samples) == 0 {
+		return ProbeLatency{}
+	}
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return ProbeLatency{
+		LastMs:  samples[len(samples)-1],
+		P50Ms:   percentile(sorted, 50),
+		P95Ms:   percentile(sorted, 95),
+		Samples: len(samples),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
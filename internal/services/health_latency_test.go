@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+// slowCheck answers green after a delay
+type slowCheck struct {
+	delay time.Duration
+}
+
+func (c slowCheck) Check(ctx context.Context) (HealthStatus, error) {
+	select {
+	case <-time.After(c.delay):
+		return HealthGreen, nil
+	case <-ctx.Done():
+		return HealthRed, ctx.Err()
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	samples := []int64{120, 15, 10, 30, 900, 20, 25, 12, 18, 40}
+	got := summarizeLatency(samples)
+	want := ProbeLatency{LastMs: 40, P50Ms: 20, P95Ms: 900, Samples: 10}
+	if got != want {
+		t.Errorf("summarizeLatency() = %+v, want %+v", got, want)
+	}
+
+	if got := summarizeLatency([]int64{7}); got.P50Ms != 7 || got.P95Ms != 7 {
+		t.Errorf("summarizeLatency(single) = %+v", got)
+	}
+}
+
+func TestLatencyHistory_PersistsAndTrims(t *testing.T) {
+	path := filepath.Join(t.TempDir(), latencyHistoryFile)
+	logger := logging.NewLogger(logging.LevelError)
+
+	first := newLatencyHistory(path, logger)
+	for i := 0; i < latencyWindow; i++ {
+		first.record(map[string]time.Duration{"ollama": 10 * time.Millisecond})
+	}
+
+	// A second history (another CLI run) continues from the file
+	second := newLatencyHistory(path, logger)
+	summary := second.record(map[string]time.Duration{"ollama": 2 * time.Second})["ollama"]
+	if summary.Samples != latencyWindow {
+		t.Errorf("Samples = %d, want the window of %d", summary.Samples, latencyWindow)
+	}
+	if summary.LastMs != 2000 || summary.P50Ms != 10 || summary.P95Ms != 10 {
+		t.Errorf("summary = %+v", summary)
+	}
+}
+
+func TestBaseService_GradesSlowProbesYellow(t *testing.T) {
+	runtime := NewMockRuntime()
+	service := NewBaseService("slow", t.TempDir(), slowCheck{delay: 30 * time.Millisecond}, nil, runtime, logging.NewLogger(logging.LevelError))
+
+	timeouts := DefaultOperationTimeouts()
+	timeouts.HealthLatency = 5 * time.Millisecond
+	service.SetTimeouts(timeouts)
+
+	status, err := service.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Health != HealthYellow || !strings.Contains(status.Message, "health check took") {
+		t.Errorf("Status() = %s (%q), want yellow for a slow probe", status.Health, status.Message)
+	}
+	if status.Latency < 30*time.Millisecond {
+		t.Errorf("Latency = %s, want at least the probe delay", status.Latency)
+	}
+	if health, err := service.Health(context.Background()); health != HealthYellow || err == nil {
+		t.Errorf("Health() = %s, %v; want yellow with the reason", health, err)
+	}
+
+	// The per-service limit overrides the global one
+	service.latencyLimit = time.Second
+	if status, _ := service.Status(context.Background()); status.Health != HealthGreen {
+		t.Errorf("Status() with a 1s limit = %s, want green", status.Health)
+	}
+}
+
+func TestHealthReporter_ReportsLatencyPercentiles(t *testing.T) {
+	logger := logging.NewLogger(logging.LevelError)
+	runtime := NewMockRuntime()
+	manager := &Manager{
+		runtime:  runtime,
+		logger:   logger,
+		services: map[string]Service{"ollama": NewBaseService("ollama", "/tmp", slowCheck{delay: 2 * time.Millisecond}, nil, runtime, logger)},
+	}
+	reporter := NewHealthReporter(manager, &MockGPUHealthChecker{shouldPass: true}, logger)
+
+	var report HealthReport
+	for i := 0; i < 3; i++ {
+		var err error
+		if report, err = reporter.GenerateReport(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latency := report.Services[0].Latency
+	if latency == nil || latency.Samples != 3 || latency.LastMs < 2 || latency.P95Ms < latency.P50Ms {
+		t.Errorf("Latency = %+v, want three samples of at least 2ms", latency)
+	}
+}
@@ -1,9 +1,11 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"aistack/internal/gpu"
@@ -21,9 +23,15 @@ type HealthReport struct {
 
 // ServiceHealthStatus represents health status of a single service
 type ServiceHealthStatus struct {
-	Name    string       `json:"name"`
-	Health  HealthStatus `json:"health"`
-	Message string       `json:"message,omitempty"`
+	Name    string        `json:"name"`
+	Health  HealthStatus  `json:"health"`
+	Message string        `json:"message,omitempty"`
+	Latency *ProbeLatency `json:"latency,omitempty"` // set when a health probe ran
+	// From the health history: when the current health began, for how many
+	// consecutive reports it has held, and whether the service keeps flipping
+	Since    *time.Time `json:"since,omitempty"`
+	Streak   int        `json:"streak,omitempty"`
+	Flapping bool       `json:"flapping,omitempty"`
 }
 
 // GPUHealthStatus represents GPU health status
@@ -36,6 +44,7 @@ type GPUHealthStatus struct {
 type HealthReporter struct {
 	manager    *Manager
 	gpuChecker GPUHealthChecker
+	latency    *latencyHistory
 	logger     *logging.Logger
 }
 
@@ -91,16 +100,24 @@ func NewHealthReporter(manager *Manager, gpuChecker GPUHealthChecker, logger *lo
 		gpuChecker = NewDefaultGPUHealthChecker(logger)
 	}
 
+	// Managers built from config keep the latency history in the state directory
+	latencyPath := ""
+	if manager.compose != nil {
+		latencyPath = filepath.Join(manager.compose.stateDir, latencyHistoryFile)
+	}
+
 	return &HealthReporter{
 		manager:    manager,
 		gpuChecker: gpuChecker,
+		latency:    newLatencyHistory(latencyPath, logger),
 		logger:     logger,
 	}
 }
 
 // GenerateReport generates a comprehensive health report
 // Story T-025: HTTP/Port-Probes, GPU-Schnelltest, aggregierter Report
-func (r *HealthReporter) GenerateReport() (HealthReport, error) {
+// Services are probed concurrently and reported in name order.
+func (r *HealthReporter) GenerateReport(ctx context.Context) (HealthReport, error) {
 	r.logger.Info("health.report.start", "Generating health report", nil)
 
 	report := HealthReport{
@@ -108,41 +125,38 @@ func (r *HealthReporter) GenerateReport() (HealthReport, error) {
 		Services:  make([]ServiceHealthStatus, 0),
 	}
 
-	// Check all services
-	for _, serviceName := range r.manager.ListServices() {
-		service, err := r.manager.GetService(serviceName)
-		if err != nil {
-			r.logger.Warn("health.report.service.error", "Failed to get service", map[string]interface{}{
-				"service": serviceName,
-				"error":   err.Error(),
-			})
-			continue
-		}
+	// GPU smoke test runs alongside the service probes
+	gpuResult := make(chan GPUHealthStatus, 1)
+	go func() {
+		gpuResult <- r.gpuChecker.CheckGPU()
+	}()
 
-		serviceHealth := ServiceHealthStatus{
-			Name: serviceName,
-		}
+	// Probe all services concurrently under the overall report deadline
+	probeCtx, cancel := withTimeout(ctx, r.manager.timeouts.StatusDeadline)
+	defer cancel()
 
-		// Get service status
-		status, err := service.Status()
-		if err != nil {
-			serviceHealth.Health = HealthRed
-			serviceHealth.Message = fmt.Sprintf("Status check failed: %v", err)
-		} else {
-			serviceHealth.Health = status.Health
-			serviceHealth.Message = status.Message
-		}
+	names := r.manager.sortedServiceNames()
+	results := runProbes(probeCtx, names, maxProbeWorkers, r.probeService)
 
-		report.Services = append(report.Services, serviceHealth)
+	if ctx.Err() != nil {
+		return report, fmt.Errorf("health report interrupted: %w", ctx.Err())
+	}
 
-		r.logger.Info("health.report.service", "Service health checked", map[string]interface{}{
-			"service": serviceName,
-			"health":  serviceHealth.Health,
-		})
+	for i, result := range results {
+		serviceHealth := result.value
+		if result.err != nil {
+			serviceHealth = ServiceHealthStatus{
+				Name:    names[i],
+				Health:  HealthRed,
+				Message: fmt.Sprintf("Health probe timed out after %s", r.manager.timeouts.StatusDeadline),
+			}
+		}
+		report.Services = append(report.Services, serviceHealth)
 	}
+	r.summarizeLatency(report.Services)
+	r.manager.HealthHistory().Record(report.Services)
 
-	// Check GPU
-	report.GPU = r.gpuChecker.CheckGPU()
+	report.GPU = <-gpuResult
 
 	r.logger.Info("health.report.complete", "Health report generated", map[string]interface{}{
 		"service_count": len(report.Services),
@@ -152,6 +166,68 @@ func (r *HealthReporter) GenerateReport() (HealthReport, error) {
 	return report, nil
 }
 
+// probeService checks a single service for the report; it only fails when ctx is done
+func (r *HealthReporter) probeService(ctx context.Context, serviceName string) (ServiceHealthStatus, error) {
+	serviceHealth := ServiceHealthStatus{
+		Name: serviceName,
+	}
+
+	service, err := r.manager.GetService(serviceName)
+	if err != nil {
+		serviceHealth.Health = HealthRed
+		serviceHealth.Message = err.Error()
+		return serviceHealth, nil
+	}
+
+	// Get service status
+	status, err := service.Status(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return serviceHealth, ctx.Err()
+		}
+		serviceHealth.Health = HealthRed
+		serviceHealth.Message = fmt.Sprintf("Status check failed: %v", err)
+	} else {
+		serviceHealth.Health = status.Health
+		serviceHealth.Message = status.Message
+		if status.Latency > 0 {
+			serviceHealth.Latency = &ProbeLatency{LastMs: status.Latency.Milliseconds()}
+		}
+	}
+
+	fields := map[string]interface{}{
+		"service": serviceName,
+		"health":  serviceHealth.Health,
+	}
+	if serviceHealth.Latency != nil {
+		fields["latency_ms"] = serviceHealth.Latency.LastMs
+	}
+	r.logger.Info("health.report.service", "Service health checked", fields)
+
+	return serviceHealth, nil
+}
+
+// summarizeLatency records the measured probe latencies and fills in p50/p95
+// over the recent probes of each service
+func (r *HealthReporter) summarizeLatency(statuses []ServiceHealthStatus) {
+	latencies := make(map[string]time.Duration)
+	for _, status := range statuses {
+		if status.Latency != nil {
+			latencies[status.Name] = time.Duration(status.Latency.LastMs) * time.Millisecond
+		}
+	}
+	if len(latencies) == 0 {
+		return
+	}
+
+	summaries := r.latency.record(latencies)
+	for i := range statuses {
+		if summary, ok := summaries[statuses[i].Name]; ok {
+			statuses[i].Latency = &summary
+		}
+	}
+}
+
 // SaveReport saves the health report to a JSON file
 func (r *HealthReporter) SaveReport(report HealthReport, filepath string) error {
 	r.logger.Info("health.report.save", "Saving health report", map[string]interface{}{
@@ -175,8 +251,8 @@ func (r *HealthReporter) SaveReport(report HealthReport, filepath string) error
 }
 
 // CheckAllHealthy returns true if all services and GPU are healthy
-func (r *HealthReporter) CheckAllHealthy() (bool, error) {
-	report, err := r.GenerateReport()
+func (r *HealthReporter) CheckAllHealthy(ctx context.Context) (bool, error) {
+	report, err := r.GenerateReport(ctx)
 	if err != nil {
 		return false, err
 	}
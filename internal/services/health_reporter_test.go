@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -107,7 +108,7 @@ func TestHealthReporter_GenerateReport(t *testing.T) {
 			reporter := NewHealthReporter(manager, gpuChecker, logger)
 
 			// Generate report
-			report, err := reporter.GenerateReport()
+			report, err := reporter.GenerateReport(context.Background())
 			if err != nil {
 				t.Fatalf("GenerateReport() error = %v", err)
 			}
@@ -274,7 +275,7 @@ func TestHealthReporter_CheckAllHealthy(t *testing.T) {
 
 			reporter := NewHealthReporter(manager, gpuChecker, logger)
 
-			healthy, err := reporter.CheckAllHealthy()
+			healthy, err := reporter.CheckAllHealthy(context.Background())
 			if err != nil {
 				t.Fatalf("CheckAllHealthy() error = %v", err)
 			}
@@ -292,7 +293,7 @@ type MockHealthCheck struct {
 	err    error
 }
 
-func (m *MockHealthCheck) Check() (HealthStatus, error) {
+func (m *MockHealthCheck) Check(_ context.Context) (HealthStatus, error) {
 	if m.err != nil {
 		return HealthRed, m.err
 	}
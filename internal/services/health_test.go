@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"net"
 	"net/http"
@@ -23,7 +24,7 @@ func TestHealthCheck_Check_Success(t *testing.T) {
 		ExpectedStatus: http.StatusOK,
 	}
 
-	status, err := hc.Check()
+	status, err := hc.Check(context.Background())
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
@@ -46,7 +47,7 @@ func TestHealthCheck_Check_WrongStatus(t *testing.T) {
 		ExpectedStatus: http.StatusOK,
 	}
 
-	status, err := hc.Check()
+	status, err := hc.Check(context.Background())
 	if err == nil {
 		t.Error("Expected error for wrong status code")
 	}
@@ -70,7 +71,7 @@ func TestHealthCheck_Check_Timeout(t *testing.T) {
 		ExpectedStatus: http.StatusOK,
 	}
 
-	status, err := hc.Check()
+	status, err := hc.Check(context.Background())
 	if err == nil {
 		t.Error("Expected timeout error")
 	}
@@ -98,7 +99,7 @@ func TestHealthCheck_CheckWithRetries(t *testing.T) {
 		ExpectedStatus: http.StatusOK,
 	}
 
-	status, err := hc.CheckWithRetries(5, 10*time.Millisecond)
+	status, err := hc.CheckWithRetries(context.Background(), 5, 10*time.Millisecond)
 	if err != nil {
 		t.Fatalf("Expected success after retries, got error: %v", err)
 	}
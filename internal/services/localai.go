@@ -1,7 +1,7 @@
 package services
 
 import (
-	"fmt"
+	"context"
 
 	"aistack/internal/fsutil"
 	"aistack/internal/gpulock"
@@ -9,7 +9,7 @@ import (
 )
 
 const (
-	// LocalAIImageName is the Docker image for LocalAI
+	// LocalAIImageName is the default Docker image for LocalAI (see catalog.yaml)
 	LocalAIImageName = "quay.io/go-skynet/local-ai:latest"
 )
 
@@ -23,15 +23,17 @@ type LocalAIService struct {
 	gpuLock  *gpulock.Manager
 }
 
-// NewLocalAIService creates a new LocalAI service
+// NewLocalAIService creates a new LocalAI service from the shipped catalog entry
 func NewLocalAIService(composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock, gpuLock *gpulock.Manager) *LocalAIService {
-	healthCheck := DefaultHealthCheck("http://localhost:8080/healthz")
-	volumes := []string{"localai_models"}
+	spec, _ := DefaultCatalog().Get("localai")
+	return newLocalAIService(spec, composeDir, runtime, logger, lock, gpuLock)
+}
 
-	base := NewBaseService("localai", composeDir, healthCheck, volumes, runtime, logger)
+func newLocalAIService(spec ServiceSpec, composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock, gpuLock *gpulock.Manager) *LocalAIService {
+	base := NewBaseServiceFromSpec(spec, composeDir, runtime, logger)
 
 	stateDir := fsutil.GetStateDir(defaultStateDir)
-	updater := NewServiceUpdater(base, runtime, LocalAIImageName, healthCheck, logger, stateDir, lock)
+	updater := NewServiceUpdater(base, runtime, spec.Image, base.healthCheck, logger, stateDir, lock)
 	registry := NewLocalAIModelsRegistry(stateDir, logger)
 
 	service := &LocalAIService{
@@ -41,8 +43,8 @@ func NewLocalAIService(composeDir string, runtime Runtime, logger *logging.Logge
 		gpuLock:     gpuLock,
 	}
 
-	base.SetPreStartHook(func() error {
-		if err := updater.EnforceImagePolicy(); err != nil {
+	base.SetPreStartHook(func(ctx context.Context) error {
+		if err := updater.EnforceImagePolicy(ctx); err != nil {
 			return err
 		}
 		if err := registry.Ensure(); err != nil {
@@ -51,25 +53,32 @@ func NewLocalAIService(composeDir string, runtime Runtime, logger *logging.Logge
 
 		// Acquire GPU lock
 		// Story T-021: GPU-Mutex (Dateisperre + Lease)
-		if err := gpuLock.Acquire(gpulock.HolderLocalAI); err != nil {
-			return fmt.Errorf("failed to acquire GPU lock: %w", err)
-		}
-
-		return nil
+		return acquireGPULock(spec, gpuLock)
 	})
 
-	base.SetPostStopHook(func() error {
+	base.SetPostStopHook(func(context.Context) error {
 		// Release GPU lock
-		return gpuLock.Release(gpulock.HolderLocalAI)
+		return releaseGPULock(spec, gpuLock)
 	})
 
 	return service
 }
 
+// Updater returns the updater that manages the service image
+func (s *LocalAIService) Updater() *ServiceUpdater {
+	return s.updater
+}
+
+// SetTimeouts applies operation timeouts to the service and its updater
+func (s *LocalAIService) SetTimeouts(timeouts OperationTimeouts) {
+	s.BaseService.SetTimeouts(timeouts)
+	s.updater.SetTimeouts(timeouts)
+}
+
 // Update updates the LocalAI service to the latest version
-func (s *LocalAIService) Update() error {
+func (s *LocalAIService) Update(ctx context.Context) error {
 	if err := s.registry.Ensure(); err != nil {
 		return err
 	}
-	return s.updater.Update()
+	return s.updater.Update(ctx)
 }
@@ -1,12 +1,18 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
 
 	"aistack/internal/config"
 	"aistack/internal/fsutil"
 	"aistack/internal/gpulock"
 	"aistack/internal/logging"
+	"aistack/internal/signing"
 )
 
 // Manager coordinates all services
@@ -16,21 +22,69 @@ type Manager struct {
 	composeDir string
 	services   map[string]Service
 	imageLock  *VersionLock
+	trustKeys  *signing.KeyRing
+	policyErr  error // invalid versions.lock or trusted keys; start and update refuse to run with it
 	gpuLock    *gpulock.Manager
+	timeouts   OperationTimeouts
+	catalog    *Catalog
+	profiles   map[string]config.ProfileConfig
+	compose    *composeSettings
+
+	history     *HealthHistory
+	historyOnce sync.Once
+}
+
+// composeSettings are the config values compose templates are rendered with
+type composeSettings struct {
+	bindAddress    string
+	services       map[string]config.ServiceConfig
+	stateDir       string
+	gpu            *gpuAllocator
+	updateStrategy string
 }
 
 // NewManager creates a new service manager
+// Services are built from the service catalog (shipped defaults + <config dir>/services.yaml).
+// Runtime calls are bounded by the deadlines from the timeouts config section and
+// the overrides of the active install profile are applied.
 func NewManager(composeDir string, logger *logging.Logger) (*Manager, error) {
+	catalog, err := LoadCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := loadManagerConfig(logger)
+	timeouts := TimeoutsFromConfig(cfg.Timeouts)
+
 	// Detect container runtime
-	runtime, err := DetectRuntime()
+	detectCtx, cancel := withTimeout(context.Background(), timeouts.Inspect)
+	defer cancel()
+
+	detected, err := DetectRuntime(detectCtx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect container runtime: %w", err)
 	}
+	runtime := WithTimeouts(detected, timeouts)
 
 	lock, err := loadVersionLock()
 	if err != nil {
 		return nil, err
 	}
+	// The versions commands must keep working to repair the lock or the keys, so only
+	// start and update refuse an unknown service or an unreadable trusted key
+	lockErr := lock.validateServices(catalog)
+	if lockErr != nil {
+		logger.Warn("versions.lock.invalid", "Services will not start or update until versions.lock is fixed", map[string]interface{}{
+			"error": lockErr.Error(),
+		})
+	}
+	trustKeys, keysErr := signing.LoadTrustedKeys(TrustedKeysPath())
+	if keysErr != nil {
+		logger.Warn("signing.keys.invalid", "Services will not start or update until the trusted keys are fixed", map[string]interface{}{
+			"dir":   TrustedKeysPath(),
+			"error": keysErr.Error(),
+		})
+	}
 
 	stateDir := fsutil.GetStateDir(defaultStateDir)
 
@@ -43,17 +97,139 @@ func NewManager(composeDir string, logger *logging.Logger) (*Manager, error) {
 		composeDir: composeDir,
 		services:   make(map[string]Service),
 		imageLock:  lock,
+		trustKeys:  trustKeys,
+		policyErr:  errors.Join(lockErr, keysErr),
 		gpuLock:    gpuLockManager,
+		timeouts:   timeouts,
+		catalog:    catalog,
+		profiles:   cfg.Profiles,
+		compose: &composeSettings{
+			bindAddress:    cfg.Network.BindAddress,
+			services:       cfg.Services,
+			stateDir:       stateDir,
+			gpu:            newGPUAllocator(cfg.GPU, runtimeBinary(detected), logger),
+			updateStrategy: cfg.Updates.Strategy,
+		},
+		history: NewHealthHistory(filepath.Join(stateDir, healthHistoryFile), cfg.HealthHistory, logger),
 	}
 
-	// Register services
-	manager.services["ollama"] = NewOllamaService(composeDir, runtime, logger, lock)
-	manager.services["openwebui"] = NewOpenWebUIService(composeDir, runtime, logger, lock, gpuLockManager)
-	manager.services["localai"] = NewLocalAIService(composeDir, runtime, logger, lock, gpuLockManager)
+	// Register services from the catalog
+	for _, spec := range catalog.Specs() {
+		manager.services[spec.Name] = manager.newService(spec)
+	}
+
+	manager.applyActiveProfile(cfg.Profile)
 
 	return manager, nil
 }
 
+// applyActiveProfile applies the overrides of the last applied profile, or of the
+// configured profile when none has been applied yet
+func (m *Manager) applyActiveProfile(configured string) {
+	name := m.ActiveProfile()
+	if name == "" {
+		name = configured
+	}
+
+	profile, err := m.Profile(name)
+	if err != nil {
+		m.logger.Warn("profile.overrides.skipped", "Ignoring overrides of unresolvable profile", map[string]interface{}{
+			"profile": name,
+			"error":   err.Error(),
+		})
+		return
+	}
+	m.applyProfileOverrides(profile)
+}
+
+// newService builds a service and applies timeouts and compose rendering.
+// Managers without compose settings (tests) use the compose files as-is.
+func (m *Manager) newService(spec ServiceSpec) Service {
+	if m.compose != nil {
+		spec = spec.withServiceConfig(m.compose.bindAddress, m.compose.services[spec.Name])
+		if spec.UpdateStrategy == "" {
+			spec.UpdateStrategy = m.compose.updateStrategy
+		}
+	}
+
+	service := m.buildService(spec)
+	if configurable, ok := service.(timeoutConfigurable); ok {
+		configurable.SetTimeouts(m.timeouts)
+	}
+	if u, ok := service.(updatable); ok && u.Updater() != nil {
+		u.Updater().SetTrustedKeys(m.trustKeys)
+		u.Updater().SetPolicyError(m.policyErr)
+	}
+	if m.compose != nil {
+		if renderable, ok := service.(composeRenderable); ok {
+			renderer := NewComposeRenderer(spec, m.composeDir, m.compose.bindAddress, m.imageLock, m.compose.stateDir, m.logger)
+			renderer.useGPU(m.compose.gpu, m.compose.services[spec.Name].GPUs)
+			renderable.SetComposeRenderer(renderer)
+		}
+	}
+	return service
+}
+
+// RenderCompose returns the effective compose file of a service (generated secrets masked)
+func (m *Manager) RenderCompose(name string) ([]byte, error) {
+	service, err := m.GetService(name)
+	if err != nil {
+		return nil, err
+	}
+
+	renderable, ok := service.(composeRenderable)
+	if !ok || renderable.ComposeRenderer() == nil {
+		return nil, fmt.Errorf("service %s does not use a compose template", name)
+	}
+	return renderable.ComposeRenderer().Render(true)
+}
+
+// buildService creates the service for a catalog entry; the shipped services keep
+// their specialised behaviour (backend binding, model registry), others are generic
+func (m *Manager) buildService(spec ServiceSpec) Service {
+	switch spec.Name {
+	case "ollama":
+		return newOllamaService(spec, m.composeDir, m.runtime, m.logger, m.imageLock)
+	case "openwebui":
+		return newOpenWebUIService(spec, m.composeDir, m.runtime, m.logger, m.imageLock, m.gpuLock)
+	case "localai":
+		return newLocalAIService(spec, m.composeDir, m.runtime, m.logger, m.imageLock, m.gpuLock)
+	default:
+		return NewCatalogService(spec, m.composeDir, m.runtime, m.logger, m.imageLock, m.gpuLock)
+	}
+}
+
+// Catalog returns the service catalog the manager was built from
+func (m *Manager) Catalog() *Catalog {
+	if m.catalog == nil {
+		return DefaultCatalog()
+	}
+	return m.catalog
+}
+
+// loadManagerConfig reads the config for timeouts and profiles (fail open to defaults)
+func loadManagerConfig(logger *logging.Logger) config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Warn("manager.config_failed", "Failed to load config, using defaults", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return config.DefaultConfig()
+	}
+	return cfg
+}
+
+// HealthHistory returns the health transition history shared by reports and repairs;
+// managers not built by NewManager keep it in memory
+func (m *Manager) HealthHistory() *HealthHistory {
+	m.historyOnce.Do(func() {
+		if m.history == nil {
+			m.history = NewHealthHistory("", config.DefaultConfig().HealthHistory, m.logger)
+		}
+	})
+	return m.history
+}
+
 // GetService returns a service by name
 func (m *Manager) GetService(name string) (Service, error) {
 	service, exists := m.services[name]
@@ -63,30 +239,46 @@ func (m *Manager) GetService(name string) (Service, error) {
 	return service, nil
 }
 
-// ListServices returns all available service names
+// ListServices returns all available service names in catalog order
 func (m *Manager) ListServices() []string {
+	catalog := m.Catalog()
 	names := make([]string, 0, len(m.services))
+	for _, name := range catalog.Names() {
+		if _, ok := m.services[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	// Services registered outside the catalog follow in name order
+	extra := make([]string, 0)
 	for name := range m.services {
-		names = append(names, name)
+		if _, ok := catalog.Get(name); !ok {
+			extra = append(extra, name)
+		}
 	}
-	return names
+	sort.Strings(extra)
+
+	return append(names, extra...)
 }
 
 // InstallProfile installs services based on a profile
-func (m *Manager) InstallProfile(profile string) error {
+func (m *Manager) InstallProfile(ctx context.Context, profile string) error {
 	m.logger.Info("profile.install", "Installing profile", map[string]interface{}{
 		"profile": profile,
 	})
 
-	var servicesToInstall []string
+	resolved, err := m.Profile(profile)
+	if err != nil {
+		return err
+	}
+	m.applyProfileOverrides(resolved)
 
-	switch profile {
-	case "standard-gpu":
-		servicesToInstall = []string{"ollama", "openwebui", "localai"}
-	case "minimal":
-		servicesToInstall = []string{"ollama"}
-	default:
-		return fmt.Errorf("unknown profile: %s", profile)
+	servicesToInstall, err := m.StartOrder(resolved.Services)
+	if err != nil {
+		return fmt.Errorf("failed to order profile %s: %w", profile, err)
+	}
+	if err := m.recordOperatorStops(servicesToInstall, false); err != nil {
+		return err
 	}
 
 	for _, serviceName := range servicesToInstall {
@@ -95,11 +287,15 @@ func (m *Manager) InstallProfile(profile string) error {
 			return err
 		}
 
-		if err := service.Install(); err != nil {
+		if err := service.Install(ctx); err != nil {
 			return fmt.Errorf("failed to install %s: %w", serviceName, err)
 		}
 	}
 
+	if err := m.recordActiveProfile(profile); err != nil {
+		return err
+	}
+
 	m.logger.Info("profile.installed", "Profile installed successfully", map[string]interface{}{
 		"profile":  profile,
 		"services": servicesToInstall,
@@ -109,24 +305,61 @@ func (m *Manager) InstallProfile(profile string) error {
 }
 
 // StatusAll returns status of all services
-func (m *Manager) StatusAll() ([]ServiceStatus, error) {
-	statuses := make([]ServiceStatus, 0, len(m.services))
+func (m *Manager) StatusAll(ctx context.Context) ([]ServiceStatus, error) {
+	probeCtx, cancel := withTimeout(ctx, m.timeouts.StatusDeadline)
+	defer cancel()
+
+	names := m.sortedServiceNames()
+	results := runProbes(probeCtx, names, maxProbeWorkers, func(ctx context.Context, name string) (ServiceStatus, error) {
+		status, err := m.services[name].Status(ctx)
+		if err == nil && status.State == serviceStateRunning {
+			status.LockDrift = m.lockDrift(ctx, name)
+		}
+		return status, err
+	})
 
-	for _, service := range m.services {
-		status, err := service.Status()
-		if err != nil {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("status interrupted: %w", ctx.Err())
+	}
+
+	statuses := make([]ServiceStatus, 0, len(names))
+	for i, result := range results {
+		if result.err != nil {
+			if probeCtx.Err() != nil {
+				// Overall deadline hit: report the service instead of dropping it
+				statuses = append(statuses, ServiceStatus{
+					Name:    names[i],
+					State:   "unknown",
+					Health:  HealthRed,
+					Message: fmt.Sprintf("status probe timed out after %s", m.timeouts.StatusDeadline),
+				})
+				continue
+			}
 			m.logger.Warn("service.status.error", "Failed to get service status", map[string]interface{}{
-				"service": service.Name(),
-				"error":   err.Error(),
+				"service": names[i],
+				"error":   result.err.Error(),
 			})
 			continue
 		}
-		statuses = append(statuses, status)
+		statuses = append(statuses, result.value)
 	}
 
 	return statuses, nil
 }
 
+// lockDrift describes how a running service deviates from its versions.lock pin;
+// empty when it is in sync or not pinned
+func (m *Manager) lockDrift(ctx context.Context, name string) string {
+	if _, pinned := m.imageLock.Entry(name); !pinned {
+		return ""
+	}
+	u, ok := m.services[name].(updatable)
+	if !ok {
+		return ""
+	}
+	return u.Updater().VerifyLock(ctx).Drift()
+}
+
 // UpdateAllResult represents the result of updating all services
 // Story T-029: Container-Update "all" mit Health-Gate
 type UpdateAllResult struct {
@@ -147,11 +380,18 @@ type UpdateResult struct {
 	ErrorMessage string `json:"error_message,omitempty"`
 }
 
+// UpdateOrder returns the update-all order: catalog update_order, adjusted so
+// dependencies are updated before their dependents
+func (m *Manager) UpdateOrder() ([]string, error) {
+	return m.StartOrder(m.Catalog().UpdateOrder())
+}
+
 // UpdateAllServices updates all services sequentially with health-gating
-// Order: LocalAI → Ollama → Open WebUI (as specified in T-029)
+// Order: UpdateOrder (shipped: LocalAI → Ollama → Open WebUI, as specified in T-029)
 // Story T-029: Each service is updated independently; failure in one does not affect others
 // Story T-035: Enforces update policy (pinned vs rolling mode)
-func (m *Manager) UpdateAllServices() (*UpdateAllResult, error) {
+// When ctx is cancelled, the in-flight update rolls back and remaining services are skipped.
+func (m *Manager) UpdateAllServices(ctx context.Context) (*UpdateAllResult, error) {
 	// Check update policy before proceeding
 	if err := m.checkUpdatePolicy(); err != nil {
 		return nil, err
@@ -159,15 +399,31 @@ func (m *Manager) UpdateAllServices() (*UpdateAllResult, error) {
 
 	m.logger.Info("services.update_all.start", "Starting sequential update of all services", nil)
 
+	updateOrder, err := m.UpdateOrder()
+	if err != nil {
+		return nil, err
+	}
+
 	result := &UpdateAllResult{
-		TotalServices:  3,
+		TotalServices:  len(updateOrder),
 		ServiceResults: make(map[string]UpdateResult),
 	}
 
-	// Define update order as per T-029: LocalAI → Ollama → Open WebUI
-	updateOrder := []string{"localai", "ollama", "openwebui"}
-
 	for _, serviceName := range updateOrder {
+		if ctx.Err() != nil {
+			result.ServiceResults[serviceName] = UpdateResult{
+				Success:      false,
+				Health:       "unknown",
+				ErrorMessage: fmt.Sprintf("skipped: %v", ctx.Err()),
+			}
+			result.FailedCount++
+			m.logger.Warn("services.update_all.skipped", "Skipping service, update-all cancelled", map[string]interface{}{
+				"service": serviceName,
+				"error":   ctx.Err().Error(),
+			})
+			continue
+		}
+
 		service, err := m.GetService(serviceName)
 		if err != nil {
 			m.logger.Error("services.update_all.service_not_found", "Service not found", map[string]interface{}{
@@ -187,10 +443,10 @@ func (m *Manager) UpdateAllServices() (*UpdateAllResult, error) {
 		})
 
 		// Update service
-		updateErr := service.Update()
+		updateErr := service.Update(ctx)
 
-		// Get status after update
-		status, statusErr := service.Status()
+		// Get status after update (detached so a cancelled run still reports health)
+		status, statusErr := service.Status(context.WithoutCancel(ctx))
 		health := "unknown"
 		if statusErr == nil {
 			health = string(status.Health)
@@ -203,7 +459,7 @@ func (m *Manager) UpdateAllServices() (*UpdateAllResult, error) {
 
 		if updateErr != nil {
 			// Check if it was a rollback
-			if updateErr.Error() == "update failed health check, rolled back to previous version" {
+			if errors.Is(updateErr, ErrUpdateRolledBack) {
 				serviceResult.RolledBack = true
 				serviceResult.Success = false
 				serviceResult.ErrorMessage = updateErr.Error()
@@ -256,9 +512,107 @@ func (m *Manager) UpdateAllServices() (*UpdateAllResult, error) {
 		"unchanged":   result.UnchangedCount,
 	})
 
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("update-all interrupted: %w", ctx.Err())
+	}
+
 	return result, nil
 }
 
+// UpdateService updates a single service, enforcing the update policy like UpdateAllServices
+func (m *Manager) UpdateService(ctx context.Context, name string) error {
+	if err := m.checkUpdatePolicy(); err != nil {
+		return err
+	}
+	service, err := m.GetService(name)
+	if err != nil {
+		return err
+	}
+	return service.Update(ctx)
+}
+
+// updatable is implemented by services whose image is managed by a ServiceUpdater
+type updatable interface {
+	Updater() *ServiceUpdater
+}
+
+// serviceUpdater returns the updater of a service
+func (m *Manager) serviceUpdater(name string) (*ServiceUpdater, error) {
+	service, err := m.GetService(name)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := service.(updatable)
+	if !ok {
+		return nil, fmt.Errorf("service %s does not support updates", name)
+	}
+	return u.Updater(), nil
+}
+
+// UpdateHistory returns the update ledger of a service, oldest entry first
+func (m *Manager) UpdateHistory(name string) ([]UpdateLedgerEntry, error) {
+	updater, err := m.serviceUpdater(name)
+	if err != nil {
+		return nil, err
+	}
+	return updater.History()
+}
+
+// RollbackService restarts a service with an image from its update ledger (see
+// ServiceUpdater.RollbackTo); entryID 0 restores the image the current one replaced
+func (m *Manager) RollbackService(ctx context.Context, name string, entryID int) (*UpdatePlan, error) {
+	updater, err := m.serviceUpdater(name)
+	if err != nil {
+		return nil, err
+	}
+	return updater.RollbackTo(ctx, entryID)
+}
+
+// CheckUpdates compares every service's local image with the registry digest of the
+// image an update would install, without pulling anything
+func (m *Manager) CheckUpdates(ctx context.Context, resolver DigestResolver) ([]UpdateCheck, error) {
+	names := make([]string, 0, len(m.services))
+	for _, name := range m.sortedServiceNames() {
+		if _, ok := m.services[name].(updatable); ok {
+			names = append(names, name)
+		}
+	}
+
+	results := runProbes(ctx, names, maxProbeWorkers, func(ctx context.Context, name string) (UpdateCheck, error) {
+		return m.services[name].(updatable).Updater().CheckUpdate(ctx, resolver), nil
+	})
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("update check interrupted: %w", ctx.Err())
+	}
+
+	checks := make([]UpdateCheck, 0, len(results))
+	for _, result := range results {
+		checks = append(checks, result.value)
+	}
+	return checks, nil
+}
+
+// ReconcileUpdates settles updates and rollbacks a crashed process left pending (see
+// ServiceUpdater.Reconcile) and returns the settled plans
+func (m *Manager) ReconcileUpdates(ctx context.Context) ([]*UpdatePlan, error) {
+	var plans []*UpdatePlan
+	var errs []error
+	for _, name := range m.sortedServiceNames() {
+		u, ok := m.services[name].(updatable)
+		if !ok {
+			continue
+		}
+		plan, err := u.Updater().Reconcile(ctx)
+		if plan != nil {
+			plans = append(plans, plan)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return plans, errors.Join(errs...)
+}
+
 // checkUpdatePolicy checks if updates are allowed based on configuration
 // Returns error if updates.mode is "pinned" and updates are blocked
 // Story T-035: Enforce update policy based on configuration
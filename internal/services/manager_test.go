@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"os"
 	"testing"
 
@@ -111,7 +113,7 @@ func TestManager_UpdateAllServices(t *testing.T) {
 	manager := NewMockManager()
 
 	// Run update all
-	result, err := manager.UpdateAllServices()
+	result, err := manager.UpdateAllServices(context.Background())
 	if err != nil {
 		t.Fatalf("UpdateAllServices() error = %v", err)
 	}
@@ -157,7 +159,7 @@ func TestManager_UpdateAllServices_Order(t *testing.T) {
 	manager := NewMockManager()
 
 	// Run update all
-	result, err := manager.UpdateAllServices()
+	result, err := manager.UpdateAllServices(context.Background())
 	if err != nil {
 		t.Fatalf("UpdateAllServices() error = %v", err)
 	}
@@ -204,7 +206,7 @@ func TestManager_UpdateAllServices_IndependentFailure(t *testing.T) {
 	manager := NewMockManager()
 
 	// Run update all - even if some fail, all should be attempted
-	result, err := manager.UpdateAllServices()
+	result, err := manager.UpdateAllServices(context.Background())
 	if err != nil {
 		t.Fatalf("UpdateAllServices() error = %v", err)
 	}
@@ -221,3 +223,37 @@ func TestManager_UpdateAllServices_IndependentFailure(t *testing.T) {
 			totalCounted, result.SuccessfulCount, result.FailedCount, result.RolledBackCount, result.UnchangedCount)
 	}
 }
+
+func TestManager_UpdateAllServices_Cancelled(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "aistack-update-all-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	origStateDir := os.Getenv("AISTACK_STATE_DIR")
+	os.Setenv("AISTACK_STATE_DIR", tmpDir)
+	defer os.Setenv("AISTACK_STATE_DIR", origStateDir)
+
+	manager := NewMockManager()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := manager.UpdateAllServices(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if result == nil {
+		t.Fatal("Expected partial result for cancelled update-all")
+	}
+
+	if result.FailedCount != 3 {
+		t.Errorf("Expected all 3 services to be skipped as failed, got %d", result.FailedCount)
+	}
+	for name, res := range result.ServiceResults {
+		if res.Success {
+			t.Errorf("Expected %s to be skipped after cancellation", name)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"aistack/internal/logging"
+	"context"
 	"fmt"
 )
 
@@ -26,12 +27,12 @@ func NewNetworkManager(runtime Runtime, logger *logging.Logger) *NetworkManager
 
 // EnsureNetwork creates the aistack network if it doesn't exist (idempotent)
 // Story T-005: Compose-Template: Netzwerk & Volumes
-func (nm *NetworkManager) EnsureNetwork() error {
+func (nm *NetworkManager) EnsureNetwork(ctx context.Context) error {
 	nm.logger.Info("network.ensure", "Ensuring aistack network exists", map[string]interface{}{
 		"network": AistackNetwork,
 	})
 
-	if err := nm.runtime.CreateNetwork(AistackNetwork); err != nil {
+	if err := nm.runtime.CreateNetwork(ctx, AistackNetwork); err != nil {
 		nm.logger.Error("network.create.error", "Failed to create network", map[string]interface{}{
 			"network": AistackNetwork,
 			"error":   err.Error(),
@@ -48,13 +49,13 @@ func (nm *NetworkManager) EnsureNetwork() error {
 
 // EnsureVolumes creates the required volumes if they don't exist (idempotent)
 // Story T-005: Compose-Template: Netzwerk & Volumes
-func (nm *NetworkManager) EnsureVolumes(volumes []string) error {
+func (nm *NetworkManager) EnsureVolumes(ctx context.Context, volumes []string) error {
 	for _, vol := range volumes {
 		nm.logger.Info("volume.ensure", "Ensuring volume exists", map[string]interface{}{
 			"volume": vol,
 		})
 
-		if err := nm.runtime.CreateVolume(vol); err != nil {
+		if err := nm.runtime.CreateVolume(ctx, vol); err != nil {
 			nm.logger.Error("volume.create.error", "Failed to create volume", map[string]interface{}{
 				"volume": vol,
 				"error":  err.Error(),
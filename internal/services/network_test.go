@@ -2,6 +2,8 @@ package services
 
 import (
 	"aistack/internal/logging"
+	"context"
+	"fmt"
 	"testing"
 )
 
@@ -14,9 +16,19 @@ type MockRuntime struct {
 	isRunning         bool
 	imageID           string
 	newImageID        string
-	ImageID           string                   // Exposed for test setup
-	containerStatuses map[string]ServiceStatus // For dynamic container status
-	startError        error                    // Simulate start failures
+	ImageID           string                     // Exposed for test setup
+	containerStatuses map[string]ServiceStatus   // For dynamic container status
+	missingContainers map[string]bool            // Containers that inspect as not found
+	publishedPorts    map[int]string             // Host port -> publishing container
+	containerLimits   map[string]ContainerLimits // Limits reported by inspect
+	execResults       map[string]ExecResult      // Exec results keyed by container name
+	execCommands      [][]string                 // Commands passed to ExecInContainer
+	startError        error                      // Simulate start failures
+	composeCalls      []string                   // "up <file>" / "down <file>" per compose call
+	containerOps      []string                   // "stop|start <name>" and "rename <name> <new>"
+	imageDigests      map[string]string          // Image ID -> registry digest
+	containerImages   map[string]string          // Container -> image ID it runs (default: imageID)
+	tagged            []string                   // "source -> target" per TagImage call
 }
 
 func NewMockRuntime() *MockRuntime {
@@ -30,35 +42,44 @@ func NewMockRuntime() *MockRuntime {
 		newImageID:        "sha256:mock456",
 		ImageID:           "sha256:mock123",
 		containerStatuses: make(map[string]ServiceStatus),
+		missingContainers: make(map[string]bool),
+		publishedPorts:    make(map[int]string),
+		containerLimits:   make(map[string]ContainerLimits),
+		execResults:       make(map[string]ExecResult),
 	}
 }
 
-func (m *MockRuntime) IsRunning() bool {
+func (m *MockRuntime) IsRunning(_ context.Context) bool {
 	return m.isRunning
 }
 
-func (m *MockRuntime) CreateNetwork(name string) error {
+func (m *MockRuntime) CreateNetwork(_ context.Context, name string) error {
 	m.networks[name] = true
 	return nil
 }
 
-func (m *MockRuntime) CreateVolume(name string) error {
+func (m *MockRuntime) CreateVolume(_ context.Context, name string) error {
 	m.volumes[name] = true
 	return nil
 }
 
-func (m *MockRuntime) ComposeUp(composeFile string, services ...string) error {
+func (m *MockRuntime) ComposeUp(_ context.Context, composeFile string, services ...string) error {
+	m.composeCalls = append(m.composeCalls, "up "+composeFile)
 	if m.startError != nil {
 		return m.startError
 	}
 	return nil
 }
 
-func (m *MockRuntime) ComposeDown(composeFile string) error {
+func (m *MockRuntime) ComposeDown(_ context.Context, composeFile string) error {
+	m.composeCalls = append(m.composeCalls, "down "+composeFile)
 	return nil
 }
 
-func (m *MockRuntime) GetContainerStatus(name string) (string, error) {
+func (m *MockRuntime) GetContainerStatus(_ context.Context, name string) (string, error) {
+	if m.missingContainers[name] {
+		return "", fmt.Errorf("no such container: %s", name)
+	}
 	// Check if we have a custom status for this container
 	if status, ok := m.containerStatuses[name]; ok {
 		return status.State, nil
@@ -67,61 +88,114 @@ func (m *MockRuntime) GetContainerStatus(name string) (string, error) {
 	return serviceStateRunning, nil
 }
 
-func (m *MockRuntime) PullImage(image string) error {
+func (m *MockRuntime) PullImage(_ context.Context, image string) error {
 	// Simulate image pull by updating imageID to newImageID
 	m.imageID = m.newImageID
 	return nil
 }
 
-func (m *MockRuntime) GetImageID(image string) (string, error) {
+func (m *MockRuntime) GetImageID(_ context.Context, image string) (string, error) {
 	return m.imageID, nil
 }
 
-func (m *MockRuntime) GetContainerLogs(name string, tail int) (string, error) {
+func (m *MockRuntime) GetImageDigest(_ context.Context, image string) (string, error) {
+	if digest, ok := m.imageDigests[image]; ok {
+		return digest, nil
+	}
+	return m.imageDigests[m.imageID], nil
+}
+
+func (m *MockRuntime) GetContainerImageID(_ context.Context, name string) (string, error) {
+	if m.missingContainers[name] {
+		return "", fmt.Errorf("no such container: %s", name)
+	}
+	if id, ok := m.containerImages[name]; ok {
+		return id, nil
+	}
+	return m.imageID, nil
+}
+
+func (m *MockRuntime) GetContainerLogs(_ context.Context, name string, tail int) (string, error) {
 	return "mock log output\nline 2\nline 3", nil
 }
 
-func (m *MockRuntime) RemoveVolume(name string) error {
+func (m *MockRuntime) RemoveVolume(_ context.Context, name string) error {
 	m.RemovedVolumes = append(m.RemovedVolumes, name)
 	delete(m.volumes, name)
 	return nil
 }
 
-func (m *MockRuntime) RemoveContainer(name string) error {
+func (m *MockRuntime) RemoveContainer(_ context.Context, name string) error {
 	m.RemovedContainers = append(m.RemovedContainers, name)
 	return nil
 }
 
-func (m *MockRuntime) TagImage(source, target string) error {
+func (m *MockRuntime) StopContainer(_ context.Context, name string) error {
+	m.containerOps = append(m.containerOps, "stop "+name)
+	return nil
+}
+
+func (m *MockRuntime) StartContainer(_ context.Context, name string) error {
+	m.containerOps = append(m.containerOps, "start "+name)
+	return nil
+}
+
+func (m *MockRuntime) RenameContainer(_ context.Context, name, newName string) error {
+	m.containerOps = append(m.containerOps, "rename "+name+" "+newName)
+	if m.missingContainers[newName] {
+		delete(m.missingContainers, newName)
+		m.missingContainers[name] = true
+	}
+	return nil
+}
+
+func (m *MockRuntime) TagImage(_ context.Context, source, target string) error {
+	m.tagged = append(m.tagged, source+" -> "+target)
 	m.imageID = source
 	return nil
 }
 
-func (m *MockRuntime) VolumeExists(name string) (bool, error) {
+func (m *MockRuntime) VolumeExists(_ context.Context, name string) (bool, error) {
 	if exists, ok := m.volumes[name]; ok {
 		return exists, nil
 	}
 	return false, nil
 }
 
-func (m *MockRuntime) RemoveNetwork(name string) error {
+func (m *MockRuntime) RemoveNetwork(_ context.Context, name string) error {
 	delete(m.networks, name)
 	return nil
 }
 
-func (m *MockRuntime) IsContainerRunning(name string) (bool, error) {
+func (m *MockRuntime) IsContainerRunning(_ context.Context, name string) (bool, error) {
 	if status, ok := m.containerStatuses[name]; ok {
 		return status.State == serviceStateRunning, nil
 	}
 	return false, nil
 }
 
+func (m *MockRuntime) ContainerByPort(_ context.Context, port int) (string, error) {
+	return m.publishedPorts[port], nil
+}
+
+func (m *MockRuntime) GetContainerLimits(_ context.Context, name string) (ContainerLimits, error) {
+	return m.containerLimits[name], nil
+}
+
+func (m *MockRuntime) ExecInContainer(_ context.Context, name string, command []string) (ExecResult, error) {
+	m.execCommands = append(m.execCommands, command)
+	if m.missingContainers[name] {
+		return ExecResult{}, fmt.Errorf("no such container: %s", name)
+	}
+	return m.execResults[name], nil
+}
+
 func TestNetworkManager_EnsureNetwork(t *testing.T) {
 	runtime := NewMockRuntime()
 	logger := logging.NewLogger(logging.LevelInfo)
 	nm := NewNetworkManager(runtime, logger)
 
-	err := nm.EnsureNetwork()
+	err := nm.EnsureNetwork(context.Background())
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
@@ -131,7 +205,7 @@ func TestNetworkManager_EnsureNetwork(t *testing.T) {
 	}
 
 	// Test idempotency - calling again should not fail
-	err = nm.EnsureNetwork()
+	err = nm.EnsureNetwork(context.Background())
 	if err != nil {
 		t.Fatalf("Expected idempotent call to succeed, got: %v", err)
 	}
@@ -143,7 +217,7 @@ func TestNetworkManager_EnsureVolumes(t *testing.T) {
 	nm := NewNetworkManager(runtime, logger)
 
 	volumes := []string{"vol1", "vol2", "vol3"}
-	err := nm.EnsureVolumes(volumes)
+	err := nm.EnsureVolumes(context.Background(), volumes)
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
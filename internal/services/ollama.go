@@ -1,12 +1,14 @@
 package services
 
 import (
+	"context"
+
 	"aistack/internal/fsutil"
 	"aistack/internal/logging"
 )
 
 const (
-	// OllamaImageName is the Docker image for Ollama
+	// OllamaImageName is the default Docker image for Ollama (see catalog.yaml)
 	OllamaImageName = "ollama/ollama:latest"
 )
 
@@ -19,18 +21,20 @@ type OllamaService struct {
 	updater *ServiceUpdater
 }
 
-// NewOllamaService creates a new Ollama service
+// NewOllamaService creates a new Ollama service from the shipped catalog entry
 func NewOllamaService(composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock) *OllamaService {
-	healthCheck := DefaultHealthCheck("http://localhost:11434/api/tags")
-	volumes := []string{"ollama_data"}
+	spec, _ := DefaultCatalog().Get("ollama")
+	return newOllamaService(spec, composeDir, runtime, logger, lock)
+}
 
-	base := NewBaseService("ollama", composeDir, healthCheck, volumes, runtime, logger)
+func newOllamaService(spec ServiceSpec, composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock) *OllamaService {
+	base := NewBaseServiceFromSpec(spec, composeDir, runtime, logger)
 
 	stateDir := fsutil.GetStateDir(defaultStateDir)
-	updater := NewServiceUpdater(base, runtime, OllamaImageName, healthCheck, logger, stateDir, lock)
+	updater := NewServiceUpdater(base, runtime, spec.Image, base.healthCheck, logger, stateDir, lock)
 
-	base.SetPreStartHook(func() error {
-		return updater.EnforceImagePolicy()
+	base.SetPreStartHook(func(ctx context.Context) error {
+		return updater.EnforceImagePolicy(ctx)
 	})
 
 	return &OllamaService{
@@ -39,8 +43,19 @@ func NewOllamaService(composeDir string, runtime Runtime, logger *logging.Logger
 	}
 }
 
+// Updater returns the updater that manages the service image
+func (s *OllamaService) Updater() *ServiceUpdater {
+	return s.updater
+}
+
+// SetTimeouts applies operation timeouts to the service and its updater
+func (s *OllamaService) SetTimeouts(timeouts OperationTimeouts) {
+	s.BaseService.SetTimeouts(timeouts)
+	s.updater.SetTimeouts(timeouts)
+}
+
 // Update updates the Ollama service to the latest version
 // Story T-018: Implements update with health validation and rollback
-func (s *OllamaService) Update() error {
-	return s.updater.Update()
+func (s *OllamaService) Update(ctx context.Context) error {
+	return s.updater.Update(ctx)
 }
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -10,7 +11,7 @@ import (
 )
 
 const (
-	// OpenWebUIImageName is the Docker image for Open WebUI
+	// OpenWebUIImageName is the default Docker image for Open WebUI (see catalog.yaml)
 	OpenWebUIImageName = "ghcr.io/open-webui/open-webui:main"
 )
 
@@ -25,15 +26,17 @@ type OpenWebUIService struct {
 	gpuLock        *gpulock.Manager
 }
 
-// NewOpenWebUIService creates a new Open WebUI service
+// NewOpenWebUIService creates a new Open WebUI service from the shipped catalog entry
 func NewOpenWebUIService(composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock, gpuLock *gpulock.Manager) *OpenWebUIService {
-	healthCheck := DefaultHealthCheck("http://localhost:3000/")
-	volumes := []string{"openwebui_data"}
+	spec, _ := DefaultCatalog().Get("openwebui")
+	return newOpenWebUIService(spec, composeDir, runtime, logger, lock, gpuLock)
+}
 
-	base := NewBaseService("openwebui", composeDir, healthCheck, volumes, runtime, logger)
+func newOpenWebUIService(spec ServiceSpec, composeDir string, runtime Runtime, logger *logging.Logger, lock *VersionLock, gpuLock *gpulock.Manager) *OpenWebUIService {
+	base := NewBaseServiceFromSpec(spec, composeDir, runtime, logger)
 
 	stateDir := fsutil.GetStateDir(defaultStateDir)
-	updater := NewServiceUpdater(base, runtime, OpenWebUIImageName, healthCheck, logger, stateDir, lock)
+	updater := NewServiceUpdater(base, runtime, spec.Image, base.healthCheck, logger, stateDir, lock)
 	bindingManager := NewBackendBindingManager(stateDir, logger)
 
 	service := &OpenWebUIService{
@@ -43,8 +46,8 @@ func NewOpenWebUIService(composeDir string, runtime Runtime, logger *logging.Log
 		gpuLock:        gpuLock,
 	}
 
-	base.SetPreStartHook(func() error {
-		if err := updater.EnforceImagePolicy(); err != nil {
+	base.SetPreStartHook(func(ctx context.Context) error {
+		if err := updater.EnforceImagePolicy(ctx); err != nil {
 			return err
 		}
 
@@ -57,26 +60,37 @@ func NewOpenWebUIService(composeDir string, runtime Runtime, logger *logging.Log
 			return fmt.Errorf("failed to set OLLAMA_BASE_URL: %w", err)
 		}
 
-		// Note: OpenWebUI does NOT acquire GPU lock
-		// Only backend services (Ollama, LocalAI) acquire GPU lock
-		// OpenWebUI is just a web UI that communicates with backends via HTTP
-
-		return nil
+		// Note: OpenWebUI does not acquire the GPU lock by default (gpu_lock: false
+		// in the catalog); it is just a web UI that talks to backends via HTTP
+		return acquireGPULock(spec, gpuLock)
 	})
 
-	// No post-stop hook needed - OpenWebUI doesn't hold GPU lock
+	base.SetPostStopHook(func(context.Context) error {
+		return releaseGPULock(spec, gpuLock)
+	})
 
 	return service
 }
 
+// Updater returns the updater that manages the service image
+func (s *OpenWebUIService) Updater() *ServiceUpdater {
+	return s.updater
+}
+
+// SetTimeouts applies operation timeouts to the service and its updater
+func (s *OpenWebUIService) SetTimeouts(timeouts OperationTimeouts) {
+	s.BaseService.SetTimeouts(timeouts)
+	s.updater.SetTimeouts(timeouts)
+}
+
 // Update updates the Open WebUI service to the latest version
-func (s *OpenWebUIService) Update() error {
-	return s.updater.Update()
+func (s *OpenWebUIService) Update(ctx context.Context) error {
+	return s.updater.Update(ctx)
 }
 
 // SwitchBackend switches the Open WebUI backend between Ollama and LocalAI
 // Story T-019: Backend-Switch (Ollama ↔ LocalAI)
-func (s *OpenWebUIService) SwitchBackend(backend BackendType) error {
+func (s *OpenWebUIService) SwitchBackend(ctx context.Context, backend BackendType) error {
 	s.logger.Info("openwebui.backend.switch.start", "Switching backend", map[string]interface{}{
 		"backend": backend,
 	})
@@ -113,13 +127,13 @@ func (s *OpenWebUIService) SwitchBackend(backend BackendType) error {
 		"url":  backendURL,
 	})
 
-	if err := s.Stop(); err != nil {
+	if err := s.Stop(ctx); err != nil {
 		s.logger.Warn("openwebui.backend.switch.stop_error", "Error stopping service", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
 
-	if err := s.Start(); err != nil {
+	if err := s.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start service with new backend: %w", err)
 	}
 
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// tcpStateListen is the LISTEN state in /proc/net/tcp
+	tcpStateListen = "0A"
+	// alternativePortRange is how many ports above a taken one are tried
+	alternativePortRange = 100
+)
+
+// procRoot is the procfs mount used to find port owners
+var procRoot = "/proc"
+
+// PortConflictError reports a published port that is already bound on the host
+type PortConflictError struct {
+	Service   string
+	Address   string
+	Port      int
+	PID       int
+	Process   string
+	Container string
+	// Suggested is a free port that services.<name>.port can be set to (0 if none)
+	Suggested int
+}
+
+// Owner describes who holds the port
+func (e *PortConflictError) Owner() string {
+	switch {
+	case e.Container != "":
+		return "container " + e.Container
+	case e.Process != "" && e.PID > 0:
+		return fmt.Sprintf("%s (pid %d)", e.Process, e.PID)
+	case e.PID > 0:
+		return fmt.Sprintf("pid %d", e.PID)
+	default:
+		return "another process"
+	}
+}
+
+func (e *PortConflictError) Error() string {
+	return fmt.Sprintf("port %d on %s for %s is already in use by %s", e.Port, e.Address, e.Service, e.Owner())
+}
+
+// publishedPort is a host port from a compose ports entry
+type publishedPort struct {
+	Address string
+	Port    int
+}
+
+// preflightPorts checks that every host port the compose file publishes can be bound.
+// It is skipped while the service's own container or front proxy runs (they hold the
+// ports themselves).
+func (s *BaseService) preflightPorts(ctx context.Context, composeFile string) error {
+	for _, container := range []string{"aistack-" + s.name, frontContainerName(s.name)} {
+		if running, err := s.runtime.IsContainerRunning(ctx, container); err == nil && running {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Clean(composeFile)) // #nosec G304 -- compose file comes from the catalog or the renderer
+	if err != nil {
+		// Compose reports a missing file itself
+		return nil
+	}
+
+	ports, err := parsePublishedPorts(data)
+	if err != nil {
+		s.logger.Debug("service.preflight.skipped", "Could not parse compose ports", map[string]interface{}{
+			"service": s.name,
+			"error":   err.Error(),
+		})
+		return nil
+	}
+
+	for _, published := range ports {
+		if err := s.checkPort(ctx, published); err != nil {
+			s.logger.Error("service.preflight.port_conflict", "Published port is not available", map[string]interface{}{
+				"service": s.name,
+				"address": published.Address,
+				"port":    published.Port,
+				"error":   err.Error(),
+			})
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPort binds the port briefly; a taken port is reported with its owner
+func (s *BaseService) checkPort(ctx context.Context, published publishedPort) error {
+	err := probeBind(published.Address, published.Port)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.EADDRNOTAVAIL):
+		return fmt.Errorf("bind address %s is not assigned to this host (check network.bind_address): %w", published.Address, err)
+	case !errors.Is(err, syscall.EADDRINUSE):
+		// e.g. EACCES for privileged ports when not running as root; compose decides
+		return nil
+	}
+
+	conflict := &PortConflictError{
+		Service: s.name,
+		Address: published.Address,
+		Port:    published.Port,
+	}
+	conflict.PID, conflict.Process = findPortOwner(published.Port)
+
+	if container, err := s.runtime.ContainerByPort(ctx, published.Port); err == nil && container != "" {
+		if container == "aistack-"+s.name || container == frontContainerName(s.name) {
+			return nil
+		}
+		conflict.Container = container
+	}
+
+	// Only the catalog port is configurable (services.<name>.port)
+	if s.renderer != nil && s.renderer.spec.Port == published.Port {
+		conflict.Suggested = findFreePort(published.Address, published.Port+1)
+	}
+	return conflict
+}
+
+// probeBind listens on address:port and closes the listener again
+func probeBind(address string, port int) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
+
+// findFreePort returns the first bindable port from start on, 0 if none is free
+func findFreePort(address string, start int) int {
+	for port := start; port < start+alternativePortRange && port <= 65535; port++ {
+		if probeBind(address, port) == nil {
+			return port
+		}
+	}
+	return 0
+}
+
+// parsePublishedPorts returns the TCP host ports published by a compose file
+func parsePublishedPorts(data []byte) ([]publishedPort, error) {
+	var compose struct {
+		Services map[string]struct {
+			Ports []interface{} `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+
+	var ports []publishedPort
+	for _, service := range compose.Services {
+		for _, entry := range service.Ports {
+			switch value := entry.(type) {
+			case string:
+				if published, ok := parsePortEntry(value); ok {
+					ports = append(ports, published)
+				}
+			case map[string]interface{}:
+				if published, ok := parseLongPortEntry(value); ok {
+					ports = append(ports, published)
+				}
+			}
+		}
+	}
+	return ports, nil
+}
+
+// parsePortEntry parses the short syntax: [[ip:]host:]container[/protocol]
+func parsePortEntry(entry string) (publishedPort, bool) {
+	spec, protocol, _ := strings.Cut(entry, "/")
+	if protocol != "" && protocol != "tcp" {
+		return publishedPort{}, false
+	}
+
+	address := "0.0.0.0"
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]:")
+		if end < 0 {
+			return publishedPort{}, false
+		}
+		address = spec[1:end]
+		spec = spec[end+2:]
+	}
+
+	parts := strings.Split(spec, ":")
+	var host string
+	switch len(parts) {
+	case 2:
+		host = parts[0]
+	case 3:
+		if parts[0] != "" {
+			address = parts[0]
+		}
+		host = parts[1]
+	default:
+		// Container port only: the runtime picks a free host port
+		return publishedPort{}, false
+	}
+
+	port, err := strconv.Atoi(host)
+	if err != nil || port < 1 || port > 65535 {
+		// Empty or ranged host ports are assigned by the runtime
+		return publishedPort{}, false
+	}
+	return publishedPort{Address: address, Port: port}, true
+}
+
+// parseLongPortEntry parses the long syntax (published/host_ip/protocol keys)
+func parseLongPortEntry(entry map[string]interface{}) (publishedPort, bool) {
+	if protocol, ok := entry["protocol"].(string); ok && protocol != "tcp" {
+		return publishedPort{}, false
+	}
+
+	port, err := strconv.Atoi(fmt.Sprint(entry["published"]))
+	if err != nil || port < 1 || port > 65535 {
+		return publishedPort{}, false
+	}
+
+	address := "0.0.0.0"
+	if hostIP, ok := entry["host_ip"].(string); ok && hostIP != "" {
+		address = hostIP
+	}
+	return publishedPort{Address: address, Port: port}, true
+}
+
+// findPortOwner looks up the process listening on a TCP port via procfs.
+// It is best effort: without root, sockets of other users' processes are not visible.
+func findPortOwner(port int) (int, string) {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"tcp", "tcp6"} {
+		for _, inode := range listeningInodes(filepath.Join(procRoot, "net", table), port) {
+			inodes[inode] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return 0, ""
+	}
+
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return 0, ""
+	}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join(procRoot, entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")] {
+				return pid, processName(pid)
+			}
+		}
+	}
+	return 0, ""
+}
+
+// listeningInodes returns the socket inodes listening on port in a /proc/net/tcp table
+func listeningInodes(path string, port int) []string {
+	file, err := os.Open(filepath.Clean(path)) // #nosec G304 -- path is a fixed procfs table
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+
+	// Local addresses are hex "ADDR:PORT", e.g. 00000000:0BB8 for *:3000
+	suffix := fmt.Sprintf(":%04X", port)
+	var inodes []string
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpStateListen {
+			continue
+		}
+		if strings.HasSuffix(fields[1], suffix) {
+			inodes = append(inodes, fields[9])
+		}
+	}
+	return inodes
+}
+
+func processName(pid int) string {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm")) // #nosec G304 -- procfs path built from a numeric pid
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
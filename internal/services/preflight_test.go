@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+)
+
+func TestParsePortEntry(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  publishedPort
+		ok    bool
+	}{
+		{entry: "3000:8080", want: publishedPort{Address: "0.0.0.0", Port: 3000}, ok: true},
+		{entry: "127.0.0.1:11434:11434", want: publishedPort{Address: "127.0.0.1", Port: 11434}, ok: true},
+		{entry: "[::1]:8080:8080/tcp", want: publishedPort{Address: "::1", Port: 8080}, ok: true},
+		{entry: "8080", ok: false},
+		{entry: "5353:53/udp", ok: false},
+		{entry: "8000-8010:8000-8010", ok: false},
+		{entry: "127.0.0.1::80", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, ok := parsePortEntry(tt.entry)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parsePortEntry(%q) = %+v, %v; want %+v, %v", tt.entry, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParsePublishedPorts_LongSyntax(t *testing.T) {
+	data := []byte(`services:
+  web:
+    ports:
+      - target: 80
+        published: 8088
+        host_ip: 127.0.0.1
+      - target: 53
+        published: 5353
+        protocol: udp
+`)
+
+	ports, err := parsePublishedPorts(data)
+	if err != nil {
+		t.Fatalf("parsePublishedPorts() error = %v", err)
+	}
+	if len(ports) != 1 || ports[0] != (publishedPort{Address: "127.0.0.1", Port: 8088}) {
+		t.Errorf("parsePublishedPorts() = %+v", ports)
+	}
+}
+
+func TestListeningInodes(t *testing.T) {
+	table := filepath.Join(t.TempDir(), "tcp")
+	content := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0BB8 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 4242 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0BB8 0100007F:9C40 01 00000000:00000000 00:00000000 00000000     0        0 4343 1 0000000000000000 100 0 0 10 0
+   2: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 4444 1 0000000000000000 100 0 0 10 0
+`
+	if err := os.WriteFile(table, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := listeningInodes(table, 3000)
+	if len(got) != 1 || got[0] != "4242" {
+		t.Errorf("listeningInodes(3000) = %v, want [4242] (established sockets are ignored)", got)
+	}
+}
+
+func TestBaseService_Start_PortConflict(t *testing.T) {
+	listener, port := listenLocal(t)
+	defer listener.Close()
+
+	runtime := NewMockRuntime()
+	runtime.publishedPorts[port] = "grafana"
+	service := newPreflightService(t, runtime, port)
+
+	err := service.Start(context.Background())
+
+	var conflict *PortConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected PortConflictError, got: %v", err)
+	}
+	if conflict.Port != port || conflict.Address != "127.0.0.1" {
+		t.Errorf("conflict = %+v", conflict)
+	}
+	if conflict.Container != "grafana" {
+		t.Errorf("Container = %q, want grafana", conflict.Container)
+	}
+	if _, statErr := os.Stat("/proc/net/tcp"); statErr == nil && conflict.PID != os.Getpid() {
+		t.Errorf("PID = %d, want test process %d", conflict.PID, os.Getpid())
+	}
+	if conflict.Suggested != 0 {
+		t.Errorf("Expected no suggestion for a port outside the catalog, got %d", conflict.Suggested)
+	}
+}
+
+func TestBaseService_Start_OwnContainerIsNotAConflict(t *testing.T) {
+	listener, port := listenLocal(t)
+	defer listener.Close()
+
+	runtime := NewMockRuntime()
+	runtime.publishedPorts[port] = "aistack-demo"
+	service := newPreflightService(t, runtime, port)
+
+	if err := service.Start(context.Background()); err != nil {
+		t.Errorf("Expected own container to be ignored, got: %v", err)
+	}
+}
+
+func TestBaseService_Start_SuggestsCatalogPort(t *testing.T) {
+	listener, port := listenLocal(t)
+	defer listener.Close()
+
+	composeDir := t.TempDir()
+	template := `services:
+  demo:
+    ports:
+      - "{{ .BindAddress }}:{{ .Port }}:80"
+`
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logging.NewLogger(logging.LevelError)
+	spec := ServiceSpec{Name: "demo", Image: "example/demo:latest", Port: port}
+	service := NewBaseServiceFromSpec(spec, composeDir, NewMockRuntime(), logger)
+	service.SetComposeRenderer(NewComposeRenderer(spec, composeDir, "127.0.0.1", nil, t.TempDir(), logger))
+
+	var conflict *PortConflictError
+	if err := service.Start(context.Background()); !errors.As(err, &conflict) {
+		t.Fatalf("Expected PortConflictError, got: %v", err)
+	}
+	if conflict.Suggested <= port {
+		t.Errorf("Suggested = %d, want a free port above %d", conflict.Suggested, port)
+	}
+}
+
+func TestCatalogService_Start_PortConflictKeepsGPULockFree(t *testing.T) {
+	listener, port := listenLocal(t)
+	defer listener.Close()
+
+	composeDir := t.TempDir()
+	compose := fmt.Sprintf("services:\n  demo:\n    ports:\n      - \"127.0.0.1:%d:80\"\n", port)
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(compose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AISTACK_STATE_DIR", t.TempDir())
+
+	logger := logging.NewLogger(logging.LevelError)
+	gpuLock := gpulock.NewManager(t.TempDir(), logger)
+	spec := ServiceSpec{Name: "demo", Image: "example/demo:latest", Port: port, GPULock: true}
+	service := NewCatalogService(spec, composeDir, NewMockRuntime(), logger, nil, gpuLock)
+
+	var conflict *PortConflictError
+	if err := service.Start(context.Background()); !errors.As(err, &conflict) {
+		t.Fatalf("Expected PortConflictError, got: %v", err)
+	}
+	status, err := gpuLock.GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Holder != gpulock.HolderNone {
+		t.Errorf("GPU lock held by %s after a failed preflight", status.Holder)
+	}
+}
+
+// listenLocal occupies a random loopback port
+func listenLocal(t *testing.T) (net.Listener, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	return listener, listener.Addr().(*net.TCPAddr).Port
+}
+
+// newPreflightService returns a service whose static compose file publishes port on loopback
+func newPreflightService(t *testing.T, runtime *MockRuntime, port int) *BaseService {
+	t.Helper()
+	composeDir := t.TempDir()
+	compose := fmt.Sprintf("services:\n  demo:\n    ports:\n      - \"127.0.0.1:%d:80\"\n", port)
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(compose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return NewBaseService("demo", composeDir, DefaultHealthCheck("http://localhost/"), nil, runtime, logging.NewLogger(logging.LevelError))
+}
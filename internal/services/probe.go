@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// maxProbeWorkers bounds how many services are probed at the same time
+const maxProbeWorkers = 4
+
+// probeResult pairs a probe value with its error
+type probeResult[T any] struct {
+	value T
+	err   error
+}
+
+// runProbes calls probe for every name on at most workers goroutines.
+// Results keep the order of names; names not started before ctx is done get ctx.Err().
+func runProbes[T any](ctx context.Context, names []string, workers int, probe func(ctx context.Context, name string) (T, error)) []probeResult[T] {
+	results := make([]probeResult[T], len(names))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					results[i].err = err
+					continue
+				}
+				results[i].value, results[i].err = probe(ctx, names[i])
+			}
+		}()
+	}
+
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// sortedServiceNames returns the manager's services in name order
+func (m *Manager) sortedServiceNames() []string {
+	names := m.ListServices()
+	sort.Strings(names)
+	return names
+}
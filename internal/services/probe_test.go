@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+// blockingHealthCheck never answers before its context is done
+type blockingHealthCheck struct{}
+
+func (blockingHealthCheck) Check(ctx context.Context) (HealthStatus, error) {
+	<-ctx.Done()
+	return HealthRed, ctx.Err()
+}
+
+func newProbeManager(deadline time.Duration, checks map[string]HealthChecker) *Manager {
+	logger := logging.NewLogger(logging.LevelInfo)
+	runtime := NewMockRuntime()
+	manager := &Manager{
+		runtime:  runtime,
+		logger:   logger,
+		services: make(map[string]Service),
+		timeouts: OperationTimeouts{StatusDeadline: deadline},
+	}
+	for name, check := range checks {
+		manager.services[name] = NewBaseService(name, "/tmp", check, nil, runtime, logger)
+	}
+	return manager
+}
+
+func TestRunProbes_BoundedAndOrdered(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+
+	var running, peak int32
+	results := runProbes(context.Background(), names, maxProbeWorkers, func(_ context.Context, name string) (string, error) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return strings.ToUpper(name), nil
+	})
+
+	if peak > maxProbeWorkers {
+		t.Errorf("Expected at most %d concurrent probes, saw %d", maxProbeWorkers, peak)
+	}
+	for i, result := range results {
+		if result.err != nil || result.value != strings.ToUpper(names[i]) {
+			t.Errorf("results[%d] = %+v, want %s", i, result, strings.ToUpper(names[i]))
+		}
+	}
+}
+
+func TestManager_StatusAll_SortedWithDeadline(t *testing.T) {
+	manager := newProbeManager(100*time.Millisecond, map[string]HealthChecker{
+		"zeta":  &MockHealthCheck{status: HealthGreen},
+		"alpha": &MockHealthCheck{status: HealthGreen},
+		"hung":  blockingHealthCheck{},
+	})
+
+	start := time.Now()
+	statuses, err := manager.StatusAll(context.Background())
+	if err != nil {
+		t.Fatalf("StatusAll() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("StatusAll() took %s, expected the deadline to cut it short", elapsed)
+	}
+
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		names = append(names, status.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"alpha", "hung", "zeta"}) {
+		t.Fatalf("StatusAll() order = %v", names)
+	}
+
+	if statuses[0].Health != HealthGreen || statuses[2].Health != HealthGreen {
+		t.Errorf("Expected responsive services to be green: %+v", statuses)
+	}
+	if statuses[1].Health != HealthRed || !strings.Contains(statuses[1].Message, "timed out") {
+		t.Errorf("Expected hung service to time out, got %+v", statuses[1])
+	}
+}
+
+func TestHealthReporter_GenerateReport_Deadline(t *testing.T) {
+	manager := newProbeManager(100*time.Millisecond, map[string]HealthChecker{
+		"ollama": &MockHealthCheck{status: HealthGreen},
+		"hung":   blockingHealthCheck{},
+	})
+	reporter := NewHealthReporter(manager, &MockGPUHealthChecker{shouldPass: true}, manager.logger)
+
+	report, err := reporter.GenerateReport(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateReport() error = %v", err)
+	}
+
+	if len(report.Services) != 2 || report.Services[0].Name != "hung" || report.Services[1].Name != "ollama" {
+		t.Fatalf("Unexpected services: %+v", report.Services)
+	}
+	if report.Services[0].Health != HealthRed || !strings.Contains(report.Services[0].Message, "timed out") {
+		t.Errorf("Expected hung service to time out, got %+v", report.Services[0])
+	}
+	if report.Services[1].Health != HealthGreen {
+		t.Errorf("Expected ollama green, got %+v", report.Services[1])
+	}
+	if !report.GPU.OK {
+		t.Error("Expected GPU result to be collected")
+	}
+}
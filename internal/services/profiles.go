@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+)
+
+const activeProfileFilename = "active_profile.json"
+
+var builtinProfileDescriptions = map[string]string{
+	"minimal":      "Smallest working stack (inference API only)",
+	"standard-gpu": "Full GPU stack with web UI and both inference backends",
+	"dev":          "Inference API and web UI without exclusive GPU services",
+}
+
+// Profile is an install profile resolved against the service catalog
+type Profile struct {
+	Name        string                            `json:"name"`
+	Description string                            `json:"description"`
+	Builtin     bool                              `json:"builtin"`
+	Services    []string                          `json:"services"`
+	Overrides   map[string]config.ServiceOverride `json:"overrides,omitempty"`
+}
+
+// ProfileDiff lists what applying a profile changes on the current install
+type ProfileDiff struct {
+	Profile string   `json:"profile"`
+	Install []string `json:"install"`
+	Remove  []string `json:"remove"`
+	Keep    []string `json:"keep"`
+}
+
+// IsEmpty reports whether the install already matches the profile
+func (d ProfileDiff) IsEmpty() bool {
+	return len(d.Install) == 0 && len(d.Remove) == 0
+}
+
+// activeProfileState is persisted after a profile has been installed or applied
+type activeProfileState struct {
+	Profile   string    `json:"profile"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// ResolveProfiles combines the built-in profiles (catalog tags) with the profiles
+// from the config file. Built-ins come first, then catalog tags and configured
+// profiles in name order.
+func ResolveProfiles(catalog *Catalog, configured map[string]config.ProfileConfig) ([]Profile, error) {
+	profiles := make([]Profile, 0, len(config.BuiltinProfiles)+len(configured))
+	index := make(map[string]int)
+
+	add := func(profile Profile) {
+		index[profile.Name] = len(profiles)
+		profiles = append(profiles, profile)
+	}
+
+	for _, name := range config.BuiltinProfiles {
+		add(Profile{
+			Name:        name,
+			Description: builtinProfileDescriptions[name],
+			Builtin:     true,
+			Services:    catalog.ProfileServices(name),
+		})
+	}
+
+	var extra []string
+	for _, name := range catalog.ProfileTags() {
+		if _, ok := index[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	for name := range configured {
+		if _, ok := index[name]; !ok && !containsString(extra, name) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		add(Profile{
+			Name:        name,
+			Description: fmt.Sprintf("Services tagged %s in the service catalog", name),
+			Services:    catalog.ProfileServices(name),
+		})
+	}
+
+	for name, entry := range configured {
+		profile := &profiles[index[name]]
+		if entry.Description != "" {
+			profile.Description = entry.Description
+		}
+		if entry.Services != nil {
+			profile.Services = append([]string(nil), entry.Services...)
+		}
+		if len(entry.Overrides) > 0 {
+			profile.Overrides = entry.Overrides
+		}
+
+		for _, service := range profile.Services {
+			if _, ok := catalog.Get(service); !ok {
+				return nil, fmt.Errorf("profile %s: unknown service %s", name, service)
+			}
+		}
+		for service := range profile.Overrides {
+			if _, ok := catalog.Get(service); !ok {
+				return nil, fmt.Errorf("profile %s: override for unknown service %s", name, service)
+			}
+		}
+	}
+
+	return profiles, nil
+}
+
+// withOverride applies a profile override to a catalog spec
+func (s ServiceSpec) withOverride(override config.ServiceOverride) ServiceSpec {
+	if override.Image != "" {
+		s.Image = override.Image
+	}
+	if override.HealthURL != "" {
+		s.HealthURL = override.HealthURL
+	}
+	if override.GPULock != nil {
+		s.GPULock = *override.GPULock
+	}
+	return s
+}
+
+// Profiles returns all install profiles known to the manager
+func (m *Manager) Profiles() ([]Profile, error) {
+	return ResolveProfiles(m.Catalog(), m.profiles)
+}
+
+// Profile returns a single install profile by name
+func (m *Manager) Profile(name string) (Profile, error) {
+	profiles, err := m.Profiles()
+	if err != nil {
+		return Profile{}, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			if len(profile.Services) == 0 {
+				return Profile{}, fmt.Errorf("profile %s has no services", name)
+			}
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile: %s", name)
+}
+
+// ActiveProfile returns the profile last installed or applied, if any
+func (m *Manager) ActiveProfile() string {
+	path := filepath.Join(fsutil.GetStateDir(defaultStateDir), activeProfileFilename)
+	data, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path is internal to the state directory
+	if err != nil {
+		return ""
+	}
+
+	var state activeProfileState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return ""
+	}
+	return state.Profile
+}
+
+// InstalledServices returns the catalog services that have a container
+func (m *Manager) InstalledServices(ctx context.Context) ([]string, error) {
+	installed := make([]string, 0)
+	for _, name := range m.ListServices() {
+		if _, err := m.runtime.GetContainerStatus(ctx, "aistack-"+name); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("listing installed services interrupted: %w", ctx.Err())
+			}
+			continue
+		}
+		installed = append(installed, name)
+	}
+	return installed, nil
+}
+
+// DiffProfile compares the current install with a profile
+func (m *Manager) DiffProfile(ctx context.Context, name string) (ProfileDiff, error) {
+	profile, err := m.Profile(name)
+	if err != nil {
+		return ProfileDiff{}, err
+	}
+
+	installed, err := m.InstalledServices(ctx)
+	if err != nil {
+		return ProfileDiff{}, err
+	}
+
+	diff := ProfileDiff{
+		Profile: name,
+		Install: make([]string, 0),
+		Remove:  make([]string, 0),
+		Keep:    make([]string, 0),
+	}
+	for _, service := range profile.Services {
+		if containsString(installed, service) {
+			diff.Keep = append(diff.Keep, service)
+		} else {
+			diff.Install = append(diff.Install, service)
+		}
+	}
+	for _, service := range installed {
+		if !containsString(profile.Services, service) {
+			diff.Remove = append(diff.Remove, service)
+		}
+	}
+
+	return diff, nil
+}
+
+// ApplyProfile installs missing profile services and removes services outside the
+// profile (data volumes are kept), then records the profile as active
+func (m *Manager) ApplyProfile(ctx context.Context, name string) (ProfileDiff, error) {
+	diff, err := m.DiffProfile(ctx, name)
+	if err != nil {
+		return diff, err
+	}
+
+	m.logger.Info("profile.apply.start", "Applying profile", map[string]interface{}{
+		"profile": name,
+		"install": diff.Install,
+		"remove":  diff.Remove,
+	})
+
+	profile, err := m.Profile(name)
+	if err != nil {
+		return diff, err
+	}
+	m.applyProfileOverrides(profile)
+
+	toRemove, err := m.StopOrder(diff.Remove)
+	if err != nil {
+		return diff, err
+	}
+	toInstall, err := m.StartOrder(diff.Install)
+	if err != nil {
+		return diff, err
+	}
+
+	for _, serviceName := range toRemove {
+		if err := m.services[serviceName].Remove(ctx, true); err != nil {
+			return diff, fmt.Errorf("failed to remove %s: %w", serviceName, err)
+		}
+	}
+
+	if err := m.recordOperatorStops(toInstall, false); err != nil {
+		return diff, err
+	}
+	for _, serviceName := range toInstall {
+		if err := m.services[serviceName].Install(ctx); err != nil {
+			return diff, fmt.Errorf("failed to install %s: %w", serviceName, err)
+		}
+	}
+
+	if err := m.recordActiveProfile(name); err != nil {
+		return diff, err
+	}
+
+	m.logger.Info("profile.apply.success", "Profile applied", map[string]interface{}{
+		"profile": name,
+		"kept":    diff.Keep,
+	})
+
+	return diff, nil
+}
+
+// applyProfileOverrides rebuilds every catalog service from its base spec and applies
+// the overrides of profile, so overrides of a previously active profile do not linger
+func (m *Manager) applyProfileOverrides(profile Profile) {
+	for _, spec := range m.Catalog().Specs() {
+		if override, ok := profile.Overrides[spec.Name]; ok {
+			spec = spec.withOverride(override)
+		}
+		m.services[spec.Name] = m.newService(spec)
+	}
+}
+
+func (m *Manager) recordActiveProfile(name string) error {
+	stateDir := fsutil.GetStateDir(defaultStateDir)
+	if err := fsutil.EnsureStateDirectory(stateDir); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(activeProfileState{Profile: name, AppliedAt: time.Now().UTC()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal active profile: %w", err)
+	}
+
+	return fsutil.AtomicWriteFile(filepath.Join(stateDir, activeProfileFilename), data, 0o600, m.logger)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
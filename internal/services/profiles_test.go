@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"aistack/internal/config"
+)
+
+func TestResolveProfiles_Builtins(t *testing.T) {
+	profiles, err := ResolveProfiles(DefaultCatalog(), nil)
+	if err != nil {
+		t.Fatalf("ResolveProfiles() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"minimal":      {"ollama"},
+		"standard-gpu": {"ollama", "openwebui", "localai"},
+		"dev":          {"ollama", "openwebui"},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("Expected %d profiles, got %d", len(want), len(profiles))
+	}
+	for _, profile := range profiles {
+		if !profile.Builtin {
+			t.Errorf("Expected %s to be built-in", profile.Name)
+		}
+		if !reflect.DeepEqual(profile.Services, want[profile.Name]) {
+			t.Errorf("%s services = %v, want %v", profile.Name, profile.Services, want[profile.Name])
+		}
+	}
+}
+
+func TestResolveProfiles_Configured(t *testing.T) {
+	configured := map[string]config.ProfileConfig{
+		"lab": {Description: "Lab box", Services: []string{"localai"}},
+		"dev": {Overrides: map[string]config.ServiceOverride{"ollama": {Image: "ollama/ollama:0.3.14"}}},
+	}
+
+	profiles, err := ResolveProfiles(DefaultCatalog(), configured)
+	if err != nil {
+		t.Fatalf("ResolveProfiles() error = %v", err)
+	}
+
+	byName := make(map[string]Profile)
+	for _, profile := range profiles {
+		byName[profile.Name] = profile
+	}
+
+	lab, ok := byName["lab"]
+	if !ok || lab.Builtin || !reflect.DeepEqual(lab.Services, []string{"localai"}) {
+		t.Errorf("Unexpected lab profile: %+v", lab)
+	}
+
+	dev := byName["dev"]
+	if !reflect.DeepEqual(dev.Services, []string{"ollama", "openwebui"}) {
+		t.Errorf("Expected dev to keep built-in services, got %v", dev.Services)
+	}
+	if dev.Overrides["ollama"].Image != "ollama/ollama:0.3.14" {
+		t.Errorf("Expected dev override, got %+v", dev.Overrides)
+	}
+}
+
+func TestResolveProfiles_UnknownService(t *testing.T) {
+	configured := map[string]config.ProfileConfig{
+		"lab": {Services: []string{"comfyui"}},
+	}
+
+	_, err := ResolveProfiles(DefaultCatalog(), configured)
+	if err == nil || !strings.Contains(err.Error(), "unknown service comfyui") {
+		t.Errorf("Expected unknown service error, got: %v", err)
+	}
+}
+
+func TestManager_ApplyProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	origStateDir := os.Getenv("AISTACK_STATE_DIR")
+	os.Setenv("AISTACK_STATE_DIR", tmpDir)
+	defer os.Setenv("AISTACK_STATE_DIR", origStateDir)
+
+	manager := NewMockManager()
+	manager.profiles = map[string]config.ProfileConfig{
+		"dev": {Overrides: map[string]config.ServiceOverride{"ollama": {Image: "ollama/ollama:0.3.14"}}},
+	}
+	runtime := manager.runtime.(*MockRuntime)
+	runtime.missingContainers["aistack-openwebui"] = true
+
+	diff, err := manager.DiffProfile(context.Background(), "dev")
+	if err != nil {
+		t.Fatalf("DiffProfile() error = %v", err)
+	}
+	if !reflect.DeepEqual(diff.Install, []string{"openwebui"}) ||
+		!reflect.DeepEqual(diff.Remove, []string{"localai"}) ||
+		!reflect.DeepEqual(diff.Keep, []string{"ollama"}) {
+		t.Fatalf("Unexpected diff: %+v", diff)
+	}
+
+	if _, err := manager.ApplyProfile(context.Background(), "dev"); err != nil {
+		t.Fatalf("ApplyProfile() error = %v", err)
+	}
+
+	for _, volume := range runtime.RemovedVolumes {
+		if volume == "localai_models" {
+			t.Error("Expected localai data volume to be kept")
+		}
+	}
+
+	ollama := manager.services["ollama"].(*OllamaService)
+	if ollama.updater.imageName != "ollama/ollama:0.3.14" {
+		t.Errorf("Expected profile override image, got %s", ollama.updater.imageName)
+	}
+
+	if active := manager.ActiveProfile(); active != "dev" {
+		t.Errorf("ActiveProfile() = %q, want dev", active)
+	}
+}
+
+func TestManager_ApplyProfile_ResetsPreviousOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	origStateDir := os.Getenv("AISTACK_STATE_DIR")
+	os.Setenv("AISTACK_STATE_DIR", tmpDir)
+	defer os.Setenv("AISTACK_STATE_DIR", origStateDir)
+
+	manager := NewMockManager()
+	manager.profiles = map[string]config.ProfileConfig{
+		"lab": {
+			Services:  []string{"ollama", "localai"},
+			Overrides: map[string]config.ServiceOverride{"localai": {Image: "localai/localai:v2.20.0"}},
+		},
+		"dev": {Overrides: map[string]config.ServiceOverride{"ollama": {Image: "ollama/ollama:0.3.14"}}},
+	}
+
+	if _, err := manager.ApplyProfile(context.Background(), "lab"); err != nil {
+		t.Fatalf("ApplyProfile(lab) error = %v", err)
+	}
+	if image := manager.services["localai"].(*LocalAIService).updater.imageName; image != "localai/localai:v2.20.0" {
+		t.Fatalf("Expected lab override image, got %s", image)
+	}
+
+	if _, err := manager.ApplyProfile(context.Background(), "dev"); err != nil {
+		t.Fatalf("ApplyProfile(dev) error = %v", err)
+	}
+	spec, _ := manager.Catalog().Get("localai")
+	if image := manager.services["localai"].(*LocalAIService).updater.imageName; image != spec.Image {
+		t.Errorf("Expected localai back on the catalog image %s, got %s", spec.Image, image)
+	}
+	if image := manager.services["ollama"].(*OllamaService).updater.imageName; image != "ollama/ollama:0.3.14" {
+		t.Errorf("Expected dev override image, got %s", image)
+	}
+}
+
+func TestManager_Profile_Unknown(t *testing.T) {
+	manager := NewMockManager()
+
+	if _, err := manager.Profile("nope"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+	if err := manager.InstallProfile(context.Background(), "nope"); err == nil {
+		t.Error("Expected InstallProfile to reject unknown profile")
+	}
+}
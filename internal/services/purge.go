@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -38,7 +39,7 @@ func NewPurgeManager(manager *Manager, logger *logging.Logger) *PurgeManager {
 }
 
 // PurgeAll removes all services, volumes, and optionally configs
-func (pm *PurgeManager) PurgeAll(removeConfigs bool) (*UninstallLog, error) {
+func (pm *PurgeManager) PurgeAll(ctx context.Context, removeConfigs bool) (*UninstallLog, error) {
 	pm.logger.Info("purge.started", "Starting full purge operation", map[string]interface{}{
 		"remove_configs": removeConfigs,
 	})
@@ -51,11 +52,16 @@ func (pm *PurgeManager) PurgeAll(removeConfigs bool) (*UninstallLog, error) {
 		Errors:       []string{},
 	}
 
-	// Get all services
-	services := []string{"ollama", "openwebui", "localai"}
+	// Remove all services with purge, dependents first
+	order, err := pm.manager.StopOrder(pm.manager.ListServices())
+	if err != nil {
+		pm.logger.Warn("purge.order.failed", "Falling back to catalog order", map[string]interface{}{
+			"error": err.Error(),
+		})
+		order = pm.manager.ListServices()
+	}
 
-	// Remove all services with purge
-	for _, serviceName := range services {
+	for _, serviceName := range order {
 		service, err := pm.manager.GetService(serviceName)
 		if err != nil {
 			log.Errors = append(log.Errors, fmt.Sprintf("failed to get service %s: %v", serviceName, err))
@@ -66,7 +72,7 @@ func (pm *PurgeManager) PurgeAll(removeConfigs bool) (*UninstallLog, error) {
 			"service": serviceName,
 		})
 
-		if err := service.Remove(false); err != nil {
+		if err := service.Remove(ctx, false); err != nil {
 			errMsg := fmt.Sprintf("failed to remove service %s: %v", serviceName, err)
 			log.Errors = append(log.Errors, errMsg)
 			pm.logger.Warn("purge.service.error", errMsg, nil)
@@ -77,7 +83,7 @@ func (pm *PurgeManager) PurgeAll(removeConfigs bool) (*UninstallLog, error) {
 
 	// Remove common network
 	pm.logger.Info("purge.network", "Removing aistack network", nil)
-	if err := pm.manager.runtime.RemoveNetwork("aistack-net"); err != nil {
+	if err := pm.manager.runtime.RemoveNetwork(ctx, "aistack-net"); err != nil {
 		errMsg := fmt.Sprintf("failed to remove network: %v", err)
 		log.Errors = append(log.Errors, errMsg)
 		pm.logger.Warn("purge.network.error", errMsg, nil)
@@ -180,25 +186,23 @@ func (pm *PurgeManager) removeConfigs(log *UninstallLog) error {
 }
 
 // VerifyClean checks if the system is clean after purge
-func (pm *PurgeManager) VerifyClean() (bool, []string) {
+func (pm *PurgeManager) VerifyClean(ctx context.Context) (bool, []string) {
 	pm.logger.Info("purge.verify", "Verifying system is clean", nil)
 
 	leftovers := []string{}
 
 	// Check for running containers
-	services := []string{"ollama", "openwebui", "localai"}
-	for _, serviceName := range services {
+	for _, serviceName := range pm.manager.ListServices() {
 		containerName := fmt.Sprintf("aistack-%s", serviceName)
-		running, err := pm.manager.runtime.IsContainerRunning(containerName)
+		running, err := pm.manager.runtime.IsContainerRunning(ctx, containerName)
 		if err == nil && running {
 			leftovers = append(leftovers, fmt.Sprintf("container:%s", containerName))
 		}
 	}
 
 	// Check for volumes
-	volumes := []string{"ollama_data", "openwebui_data", "localai_models"}
-	for _, volume := range volumes {
-		exists, err := pm.manager.runtime.VolumeExists(volume)
+	for _, volume := range pm.manager.Catalog().Volumes() {
+		exists, err := pm.manager.runtime.VolumeExists(ctx, volume)
 		if err == nil && exists {
 			leftovers = append(leftovers, fmt.Sprintf("volume:%s", volume))
 		}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -33,7 +34,7 @@ func TestPurgeManager_PurgeAll(t *testing.T) {
 	}
 
 	// Perform purge (without removeConfigs)
-	log, err := purgeManager.PurgeAll(false)
+	log, err := purgeManager.PurgeAll(context.Background(), false)
 	if err != nil {
 		t.Fatalf("PurgeAll() error = %v", err)
 	}
@@ -156,7 +157,7 @@ func TestPurgeManager_VerifyClean(t *testing.T) {
 	purgeManager := NewPurgeManager(mockManager, logger)
 
 	// Test with empty state dir (clean)
-	isClean, leftovers := purgeManager.VerifyClean()
+	isClean, leftovers := purgeManager.VerifyClean(context.Background())
 	if !isClean {
 		t.Errorf("VerifyClean() = false, want true for empty system")
 	}
@@ -170,7 +171,7 @@ func TestPurgeManager_VerifyClean(t *testing.T) {
 	}
 
 	// Test with leftover (not clean)
-	isClean, leftovers = purgeManager.VerifyClean()
+	isClean, leftovers = purgeManager.VerifyClean(context.Background())
 	if isClean {
 		t.Errorf("VerifyClean() = true, want false when leftovers exist")
 	}
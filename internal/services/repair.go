@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -28,7 +29,7 @@ type RepairResult struct {
 // 5. Wait for initialization
 // 6. Recheck health
 // 7. Return result (success if green, failed otherwise)
-func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
+func (m *Manager) RepairService(ctx context.Context, serviceName string) (RepairResult, error) {
 	m.logger.Info("service.repair.started", "Starting service repair", map[string]interface{}{
 		"service": serviceName,
 	})
@@ -47,7 +48,7 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 	}
 
 	// Check initial health
-	initialStatus, err := service.Status()
+	initialStatus, err := service.Status(ctx)
 	if err != nil {
 		result.HealthBefore = HealthRed
 		m.logger.Warn("service.repair.initial_status_failed", "Failed to get initial status", map[string]interface{}{
@@ -58,8 +59,17 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 		result.HealthBefore = initialStatus.Health
 	}
 
+	// A service that keeps flipping between states is repaired even when this probe is green
+	flapping := m.HealthHistory().Flapping(serviceName)
+	if flapping {
+		m.logger.Warn("service.repair.flapping", "Service health is flapping, repairing regardless of the current probe", map[string]interface{}{
+			"service": serviceName,
+			"health":  result.HealthBefore,
+		})
+	}
+
 	// If service is already healthy, skip repair (idempotent)
-	if result.HealthBefore == HealthGreen {
+	if result.HealthBefore == HealthGreen && !flapping {
 		m.logger.Info("service.repair.skipped", "Service is already healthy, repair not needed", map[string]interface{}{
 			"service": serviceName,
 		})
@@ -74,7 +84,7 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 		"service": serviceName,
 	})
 
-	if err = service.Stop(); err != nil {
+	if err = service.Stop(ctx); err != nil {
 		// Log warning but continue - service might already be stopped
 		m.logger.Warn("service.repair.stop_error", "Error stopping service (continuing)", map[string]interface{}{
 			"service": serviceName,
@@ -88,7 +98,7 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 	})
 
 	containerName := fmt.Sprintf("aistack-%s", serviceName)
-	if err = m.runtime.RemoveContainer(containerName); err != nil {
+	if err = m.runtime.RemoveContainer(ctx, containerName); err != nil {
 		// Log warning but continue - container might not exist
 		m.logger.Warn("service.repair.remove_error", "Error removing container (continuing)", map[string]interface{}{
 			"service":   serviceName,
@@ -102,7 +112,7 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 		"service": serviceName,
 	})
 
-	if err = service.Start(); err != nil {
+	if err = service.Start(ctx); err != nil {
 		result.Success = false
 		result.ErrorMessage = fmt.Sprintf("Failed to start service: %v", err)
 		m.logger.Error("service.repair.failed", "Failed to start service during repair", map[string]interface{}{
@@ -115,16 +125,21 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 	// Step 4: Wait for initialization
 	m.logger.Info("service.repair.waiting", "Waiting for service initialization", map[string]interface{}{
 		"service": serviceName,
-		"delay":   "5s",
+		"delay":   m.timeouts.StartupWait.String(),
 	})
-	time.Sleep(5 * time.Second)
+	if err = sleepContext(ctx, m.timeouts.StartupWait); err != nil {
+		result.Success = false
+		result.HealthAfter = HealthRed
+		result.ErrorMessage = fmt.Sprintf("Repair interrupted: %v", err)
+		return result, fmt.Errorf("repair of %s interrupted: %w", serviceName, err)
+	}
 
 	// Step 5: Recheck health
 	m.logger.Info("service.repair.health_check", "Checking service health after repair", map[string]interface{}{
 		"service": serviceName,
 	})
 
-	finalStatus, err := service.Status()
+	finalStatus, err := service.Status(ctx)
 	if err != nil {
 		result.Success = false
 		result.HealthAfter = HealthRed
@@ -160,12 +175,16 @@ func (m *Manager) RepairService(serviceName string) (RepairResult, error) {
 }
 
 // RepairAll repairs all services that are not healthy
-func (m *Manager) RepairAll() ([]RepairResult, error) {
+func (m *Manager) RepairAll(ctx context.Context) ([]RepairResult, error) {
 	m.logger.Info("service.repair_all.started", "Starting repair for all unhealthy services", nil)
 
 	results := make([]RepairResult, 0)
 
 	for _, serviceName := range m.ListServices() {
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("repair-all interrupted: %w", ctx.Err())
+		}
+
 		service, err := m.GetService(serviceName)
 		if err != nil {
 			m.logger.Warn("service.repair_all.skip", "Skipping service due to error", map[string]interface{}{
@@ -176,9 +195,9 @@ func (m *Manager) RepairAll() ([]RepairResult, error) {
 		}
 
 		// Check if service needs repair
-		status, err := service.Status()
+		status, err := service.Status(ctx)
 		if err != nil || status.Health != HealthGreen {
-			result, err := m.RepairService(serviceName)
+			result, err := m.RepairService(ctx, serviceName)
 			if err != nil {
 				m.logger.Warn("service.repair_all.error", "Error repairing service", map[string]interface{}{
 					"service": serviceName,
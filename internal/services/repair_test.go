@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -86,7 +87,7 @@ func TestManager_RepairService(t *testing.T) {
 			manager.services[tt.serviceName] = service
 
 			// Perform repair
-			result, err := manager.RepairService(tt.serviceName)
+			result, err := manager.RepairService(context.Background(), tt.serviceName)
 
 			// Verify error handling
 			if tt.startError != nil {
@@ -159,7 +160,7 @@ func TestManager_RepairService_VolumesPreserved(t *testing.T) {
 	manager.services["ollama"] = service
 
 	// Perform repair
-	_, err := manager.RepairService("ollama")
+	_, err := manager.RepairService(context.Background(), "ollama")
 	if err != nil {
 		t.Fatalf("RepairService() error = %v", err)
 	}
@@ -221,7 +222,7 @@ func TestManager_RepairAll(t *testing.T) {
 	}
 
 	// Perform repair all
-	results, err := manager.RepairAll()
+	results, err := manager.RepairAll(context.Background())
 	if err != nil {
 		t.Fatalf("RepairAll() error = %v", err)
 	}
@@ -247,7 +248,7 @@ type DynamicMockHealthCheck struct {
 	hasRepaired   bool
 }
 
-func (m *DynamicMockHealthCheck) Check() (HealthStatus, error) {
+func (m *DynamicMockHealthCheck) Check(_ context.Context) (HealthStatus, error) {
 	// First call returns initial status, subsequent calls return final status
 	if !m.hasRepaired {
 		m.hasRepaired = true
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+)
+
+// ContainerLimits are the resource limits a container actually runs with (0 means unlimited)
+type ContainerLimits struct {
+	CPUs       float64          `json:"cpus,omitempty"`
+	Memory     int64            `json:"memory,omitempty"`      // bytes
+	MemorySwap int64            `json:"memory_swap,omitempty"` // bytes; -1 is unlimited swap
+	ShmSize    int64            `json:"shm_size,omitempty"`    // bytes
+	PidsLimit  int64            `json:"pids_limit,omitempty"`
+	Ulimits    map[string]int64 `json:"ulimits,omitempty"` // soft limit per name
+}
+
+// hostConfig is the part of a container inspect HostConfig that holds resource limits
+// (same shape for the Docker and Podman CLIs and APIs)
+type hostConfig struct {
+	NanoCPUs   int64  `json:"NanoCpus"`
+	Memory     int64  `json:"Memory"`
+	MemorySwap int64  `json:"MemorySwap"`
+	ShmSize    int64  `json:"ShmSize"`
+	PidsLimit  *int64 `json:"PidsLimit"`
+	Ulimits    []struct {
+		Name string `json:"Name"`
+		Soft int64  `json:"Soft"`
+		Hard int64  `json:"Hard"`
+	} `json:"Ulimits"`
+}
+
+// limits converts the inspect values; Podman reports ulimits as RLIMIT_NOFILE
+func (h hostConfig) limits() ContainerLimits {
+	limits := ContainerLimits{
+		CPUs:       float64(h.NanoCPUs) / 1e9,
+		Memory:     h.Memory,
+		MemorySwap: h.MemorySwap,
+		ShmSize:    h.ShmSize,
+	}
+	if h.PidsLimit != nil && *h.PidsLimit > 0 {
+		limits.PidsLimit = *h.PidsLimit
+	}
+	for _, ulimit := range h.Ulimits {
+		if limits.Ulimits == nil {
+			limits.Ulimits = make(map[string]int64, len(h.Ulimits))
+		}
+		name := strings.ToLower(strings.TrimPrefix(ulimit.Name, "RLIMIT_"))
+		limits.Ulimits[name] = ulimit.Soft
+	}
+	return limits
+}
+
+// String formats the limits for status output, e.g. "cpus=2 memory=8.0GiB pids=512"
+func (l ContainerLimits) String() string {
+	var parts []string
+	if l.CPUs > 0 {
+		parts = append(parts, "cpus="+strconv.FormatFloat(l.CPUs, 'f', -1, 64))
+	}
+	if l.Memory > 0 {
+		parts = append(parts, "memory="+formatByteSize(l.Memory))
+	}
+	if l.MemorySwap > 0 {
+		parts = append(parts, "memory_swap="+formatByteSize(l.MemorySwap))
+	}
+	if l.ShmSize > 0 {
+		parts = append(parts, "shm_size="+formatByteSize(l.ShmSize))
+	}
+	if l.PidsLimit > 0 {
+		parts = append(parts, fmt.Sprintf("pids_limit=%d", l.PidsLimit))
+	}
+	names := make([]string, 0, len(l.Ulimits))
+	for name := range l.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, l.Ulimits[name]))
+	}
+	if len(parts) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Drift lists the configured limits the container does not run with
+// (the container was created before the config changed and must be recreated)
+func (l ContainerLimits) Drift(configured config.ResourcesConfig) []string {
+	var drift []string
+	if configured.CPUs > 0 && math.Abs(l.CPUs-configured.CPUs) > 0.001 {
+		drift = append(drift, "cpus")
+	}
+	if !sizeMatches(configured.Memory, l.Memory) {
+		drift = append(drift, "memory")
+	}
+	if configured.MemorySwap == "-1" {
+		if l.MemorySwap != -1 {
+			drift = append(drift, "memory_swap")
+		}
+	} else if !sizeMatches(configured.MemorySwap, l.MemorySwap) {
+		drift = append(drift, "memory_swap")
+	}
+	if !sizeMatches(configured.ShmSize, l.ShmSize) {
+		drift = append(drift, "shm_size")
+	}
+	switch {
+	case configured.PidsLimit > 0 && l.PidsLimit != configured.PidsLimit,
+		configured.PidsLimit == -1 && l.PidsLimit > 0:
+		drift = append(drift, "pids_limit")
+	}
+	names := make([]string, 0, len(configured.Ulimits))
+	for name := range configured.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if actual, ok := l.Ulimits[name]; !ok || actual != configured.Ulimits[name] {
+			drift = append(drift, "ulimits."+name)
+		}
+	}
+	return drift
+}
+
+// sizeMatches compares a configured size (empty = not set) with the actual bytes
+func sizeMatches(configured string, actual int64) bool {
+	if configured == "" {
+		return true
+	}
+	want, err := config.ParseByteSize(configured)
+	return err == nil && want == actual
+}
+
+// applyResourceLimits sets the compose resource keys (cpus, mem_limit, memswap_limit,
+// shm_size, pids_limit, ulimits) on a compose service node
+func applyResourceLimits(target *yaml.Node, resources config.ResourcesConfig) error {
+	values := map[string]interface{}{}
+	if resources.CPUs > 0 {
+		values["cpus"] = resources.CPUs
+	}
+	if resources.Memory != "" {
+		values["mem_limit"] = resources.Memory
+	}
+	switch resources.MemorySwap {
+	case "":
+	case "-1":
+		values["memswap_limit"] = -1
+	default:
+		values["memswap_limit"] = resources.MemorySwap
+	}
+	if resources.ShmSize != "" {
+		values["shm_size"] = resources.ShmSize
+	}
+	if resources.PidsLimit != 0 {
+		values["pids_limit"] = resources.PidsLimit
+	}
+	if len(resources.Ulimits) > 0 {
+		values["ulimits"] = resources.Ulimits
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := yamlMappingValue(target, key).Encode(values[key]); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// formatByteSize formats bytes with binary units, e.g. 8.0GiB
+func formatByteSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
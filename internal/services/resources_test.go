@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+func TestComposeRenderer_AppliesResourceLimits(t *testing.T) {
+	composeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(composeDir, "ollama.yaml"), []byte(gpuTestCompose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := ServiceSpec{
+		Name:      "ollama",
+		Image:     "ollama/ollama:latest",
+		Resources: config.ResourcesConfig{Memory: "16g", ShmSize: "1g", Ulimits: map[string]int64{"nofile": 1024}},
+	}
+	spec = spec.withServiceConfig("0.0.0.0", config.ServiceConfig{
+		Resources: config.ResourcesConfig{CPUs: 2.5, Memory: "8g", MemorySwap: "-1", PidsLimit: 512, Ulimits: map[string]int64{"nofile": 65536}},
+	})
+
+	renderer := NewComposeRenderer(spec, composeDir, "0.0.0.0", nil, t.TempDir(), logging.NewLogger(logging.LevelError))
+	rendered, err := renderer.Render(true)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	var parsed struct {
+		Services map[string]struct {
+			Image        string           `yaml:"image"`
+			CPUs         float64          `yaml:"cpus"`
+			MemLimit     string           `yaml:"mem_limit"`
+			MemswapLimit int64            `yaml:"memswap_limit"`
+			ShmSize      string           `yaml:"shm_size"`
+			PidsLimit    int64            `yaml:"pids_limit"`
+			Ulimits      map[string]int64 `yaml:"ulimits"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(rendered, &parsed); err != nil {
+		t.Fatalf("rendered file is not valid YAML: %v\n%s", err, rendered)
+	}
+
+	ollama := parsed.Services["ollama"]
+	if ollama.Image != "ollama/ollama:latest" {
+		t.Errorf("Expected existing keys to be kept, got image %q", ollama.Image)
+	}
+	if ollama.CPUs != 2.5 || ollama.MemLimit != "8g" || ollama.MemswapLimit != -1 || ollama.ShmSize != "1g" || ollama.PidsLimit != 512 {
+		t.Errorf("limits = %+v", ollama)
+	}
+	if !reflect.DeepEqual(ollama.Ulimits, map[string]int64{"nofile": 65536}) {
+		t.Errorf("ulimits = %v, want config value over catalog default", ollama.Ulimits)
+	}
+}
+
+func TestComposeRenderer_NoResourcesKeepsFile(t *testing.T) {
+	composeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(composeDir, "ollama.yaml"), []byte(gpuTestCompose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	renderer := NewComposeRenderer(ServiceSpec{Name: "ollama", Image: "x"}, composeDir, "0.0.0.0", nil, t.TempDir(), logging.NewLogger(logging.LevelError))
+	rendered, err := renderer.Render(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rendered) != gpuTestCompose {
+		t.Errorf("Expected the rendered template unchanged without limits:\n%s", rendered)
+	}
+}
+
+func TestHostConfig_PodmanUlimitNames(t *testing.T) {
+	pids := int64(0)
+	host := hostConfig{PidsLimit: &pids}
+	host.Ulimits = append(host.Ulimits, struct {
+		Name string `json:"Name"`
+		Soft int64  `json:"Soft"`
+		Hard int64  `json:"Hard"`
+	}{Name: "RLIMIT_NOFILE", Soft: 4096, Hard: 8192})
+
+	limits := host.limits()
+	if limits.PidsLimit != 0 {
+		t.Errorf("PidsLimit = %d, want 0 (unlimited)", limits.PidsLimit)
+	}
+	if limits.Ulimits["nofile"] != 4096 {
+		t.Errorf("Ulimits = %v, want nofile=4096", limits.Ulimits)
+	}
+}
+
+func TestContainerLimits_Drift(t *testing.T) {
+	configured := config.ResourcesConfig{CPUs: 2, Memory: "8g", MemorySwap: "-1", PidsLimit: 512, Ulimits: map[string]int64{"nofile": 65536}}
+
+	applied := ContainerLimits{CPUs: 2, Memory: 8 << 30, MemorySwap: -1, PidsLimit: 512, Ulimits: map[string]int64{"nofile": 65536, "nproc": 4096}}
+	if drift := applied.Drift(configured); len(drift) != 0 {
+		t.Errorf("Drift() = %v, want none", drift)
+	}
+
+	stale := ContainerLimits{CPUs: 1, Memory: 4 << 30}
+	want := []string{"cpus", "memory", "memory_swap", "pids_limit", "ulimits.nofile"}
+	if drift := stale.Drift(configured); !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift() = %v, want %v", drift, want)
+	}
+
+	if drift := stale.Drift(config.ResourcesConfig{}); len(drift) != 0 {
+		t.Errorf("Drift() without configured limits = %v, want none", drift)
+	}
+}
+
+func TestContainerLimits_String(t *testing.T) {
+	limits := ContainerLimits{CPUs: 1.5, Memory: 8 << 30, PidsLimit: 256, Ulimits: map[string]int64{"nofile": 1024}}
+	if got, want := limits.String(), "cpus=1.5 memory=8.0GiB pids_limit=256 nofile=1024"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := (ContainerLimits{}).String(); got != "unlimited" {
+		t.Errorf("String() = %q, want unlimited", got)
+	}
+}
+
+func TestBaseService_Status_ReportsLimits(t *testing.T) {
+	composeDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(composeDir, "ollama.yaml"), []byte(gpuTestCompose), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.NewLogger(logging.LevelError)
+
+	runtime := NewMockRuntime()
+	runtime.containerLimits["aistack-ollama"] = ContainerLimits{Memory: 4 << 30}
+
+	spec := ServiceSpec{Name: "ollama", Image: "ollama/ollama:latest", Resources: config.ResourcesConfig{Memory: "8g"}}
+	service := NewBaseServiceFromSpec(spec, composeDir, runtime, logger)
+	service.SetComposeRenderer(NewComposeRenderer(spec, composeDir, "127.0.0.1", nil, t.TempDir(), logger))
+
+	status, err := service.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Limits == nil || status.Limits.Memory != 4<<30 {
+		t.Fatalf("Limits = %+v, want the inspected limits", status.Limits)
+	}
+	if !strings.Contains(status.Message, "memory") {
+		t.Errorf("Expected drift in message, got %q", status.Message)
+	}
+
+	runtime.containerLimits["aistack-ollama"] = ContainerLimits{Memory: 8 << 30}
+	status, _ = service.Status(context.Background())
+	if status.Message != "" {
+		t.Errorf("Expected no drift once limits are applied, got %q", status.Message)
+	}
+}
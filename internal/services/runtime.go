@@ -2,6 +2,9 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -16,38 +19,60 @@ const (
 // Runtime represents a container runtime (Docker or Podman)
 type Runtime interface {
 	// ComposeUp starts services defined in a compose file
-	ComposeUp(composeFile string, services ...string) error
+	ComposeUp(ctx context.Context, composeFile string, services ...string) error
 	// ComposeDown stops and removes services
-	ComposeDown(composeFile string) error
+	ComposeDown(ctx context.Context, composeFile string) error
 	// IsRunning checks if the runtime is available
-	IsRunning() bool
+	IsRunning(ctx context.Context) bool
 	// CreateNetwork creates a network if it doesn't exist
-	CreateNetwork(name string) error
+	CreateNetwork(ctx context.Context, name string) error
 	// CreateVolume creates a volume if it doesn't exist
-	CreateVolume(name string) error
+	CreateVolume(ctx context.Context, name string) error
 	// GetContainerStatus returns the status of a container
-	GetContainerStatus(name string) (string, error)
+	GetContainerStatus(ctx context.Context, name string) (string, error)
 	// PullImage pulls a container image
-	PullImage(image string) error
+	PullImage(ctx context.Context, image string) error
 	// GetImageID returns the image ID for a given image name
-	GetImageID(image string) (string, error)
+	GetImageID(ctx context.Context, image string) (string, error)
+	// GetImageDigest returns the registry digest of an image ("" for images without one)
+	GetImageDigest(ctx context.Context, image string) (string, error)
+	// GetContainerImageID returns the ID of the image a container was created from
+	GetContainerImageID(ctx context.Context, name string) (string, error)
 	// GetContainerLogs returns logs from a container
-	GetContainerLogs(name string, tail int) (string, error)
+	GetContainerLogs(ctx context.Context, name string, tail int) (string, error)
 	// RemoveVolume removes a volume
-	RemoveVolume(name string) error
+	RemoveVolume(ctx context.Context, name string) error
 	// RemoveContainer removes a container
-	RemoveContainer(name string) error
+	RemoveContainer(ctx context.Context, name string) error
+	// StopContainer stops a container without removing it
+	StopContainer(ctx context.Context, name string) error
+	// StartContainer starts an existing, stopped container
+	StartContainer(ctx context.Context, name string) error
+	// RenameContainer renames a container
+	RenameContainer(ctx context.Context, name, newName string) error
 	// TagImage retags an image reference (digest or ID) to a target reference
-	TagImage(source string, target string) error
+	TagImage(ctx context.Context, source string, target string) error
 	// VolumeExists checks if a volume exists
-	VolumeExists(name string) (bool, error)
+	VolumeExists(ctx context.Context, name string) (bool, error)
 	// RemoveNetwork removes a network
-	RemoveNetwork(name string) error
+	RemoveNetwork(ctx context.Context, name string) error
 	// IsContainerRunning checks if a container is running
-	IsContainerRunning(name string) (bool, error)
+	IsContainerRunning(ctx context.Context, name string) (bool, error)
+	// ContainerByPort returns the running container publishing a host port ("" if none)
+	ContainerByPort(ctx context.Context, port int) (string, error)
+	// GetContainerLimits returns the resource limits a container runs with
+	GetContainerLimits(ctx context.Context, name string) (ContainerLimits, error)
+	// ExecInContainer runs a command inside a running container
+	ExecInContainer(ctx context.Context, name string, command []string) (ExecResult, error)
 }
 
-func fetchContainerLogs(binary, label, name string, tail int) (string, error) {
+// ExecResult is the outcome of a command run inside a container
+type ExecResult struct {
+	ExitCode int
+	Output   string // combined stdout and stderr
+}
+
+func fetchContainerLogs(ctx context.Context, binary, label, name string, tail int) (string, error) {
 	args := []string{"logs"}
 	if tail > 0 {
 		args = append(args, "--tail", fmt.Sprintf("%d", tail))
@@ -55,7 +80,7 @@ func fetchContainerLogs(binary, label, name string, tail int) (string, error) {
 	args = append(args, name)
 
 	// #nosec G204 — container identifiers are validated before invocation
-	cmd := exec.Command(binary, args...)
+	cmd := exec.CommandContext(ctx, binary, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -77,19 +102,24 @@ func NewGenericRuntime(binary string) *GenericRuntime {
 	return &GenericRuntime{binary: binary}
 }
 
+// Binary returns the CLI binary (docker or podman) the runtime drives
+func (r *GenericRuntime) Binary() string {
+	return r.binary
+}
+
 // IsRunning checks if the runtime daemon is running
-func (r *GenericRuntime) IsRunning() bool {
-	cmd := exec.Command(r.binary, "info")
+func (r *GenericRuntime) IsRunning(ctx context.Context) bool {
+	cmd := exec.CommandContext(ctx, r.binary, "info")
 	return cmd.Run() == nil
 }
 
 // ComposeUp starts services using compose
-func (r *GenericRuntime) ComposeUp(composeFile string, services ...string) error {
+func (r *GenericRuntime) ComposeUp(ctx context.Context, composeFile string, services ...string) error {
 	args := []string{"compose", "-f", composeFile, "up", "-d"}
 	args = append(args, services...)
 
 	// #nosec G204 — compose arguments originate from curated templates and service names.
-	cmd := exec.Command(r.binary, args...)
+	cmd := exec.CommandContext(ctx, r.binary, args...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -100,9 +130,9 @@ func (r *GenericRuntime) ComposeUp(composeFile string, services ...string) error
 }
 
 // ComposeDown stops and removes services
-func (r *GenericRuntime) ComposeDown(composeFile string) error {
+func (r *GenericRuntime) ComposeDown(ctx context.Context, composeFile string) error {
 	// #nosec G204 — compose arguments originate from curated templates.
-	cmd := exec.Command(r.binary, "compose", "-f", composeFile, "down")
+	cmd := exec.CommandContext(ctx, r.binary, "compose", "-f", composeFile, "down")
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -113,10 +143,10 @@ func (r *GenericRuntime) ComposeDown(composeFile string) error {
 }
 
 // CreateNetwork creates a network if it doesn't exist (idempotent)
-func (r *GenericRuntime) CreateNetwork(name string) error {
+func (r *GenericRuntime) CreateNetwork(ctx context.Context, name string) error {
 	// Check if network exists
 	// #nosec G204 — network name is controlled by application logic.
-	checkCmd := exec.Command(r.binary, "network", "inspect", name)
+	checkCmd := exec.CommandContext(ctx, r.binary, "network", "inspect", name)
 	if checkCmd.Run() == nil {
 		// Network already exists
 		return nil
@@ -124,7 +154,7 @@ func (r *GenericRuntime) CreateNetwork(name string) error {
 
 	// Create network
 	// #nosec G204 — network name is controlled by application logic.
-	cmd := exec.Command(r.binary, "network", "create", name)
+	cmd := exec.CommandContext(ctx, r.binary, "network", "create", name)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -135,10 +165,10 @@ func (r *GenericRuntime) CreateNetwork(name string) error {
 }
 
 // CreateVolume creates a volume if it doesn't exist (idempotent)
-func (r *GenericRuntime) CreateVolume(name string) error {
+func (r *GenericRuntime) CreateVolume(ctx context.Context, name string) error {
 	// Check if volume exists
 	// #nosec G204 — volume name is controlled by application logic.
-	checkCmd := exec.Command(r.binary, "volume", "inspect", name)
+	checkCmd := exec.CommandContext(ctx, r.binary, "volume", "inspect", name)
 	if checkCmd.Run() == nil {
 		// Volume already exists
 		return nil
@@ -146,7 +176,7 @@ func (r *GenericRuntime) CreateVolume(name string) error {
 
 	// Create volume
 	// #nosec G204 — volume name is controlled by application logic.
-	cmd := exec.Command(r.binary, "volume", "create", name)
+	cmd := exec.CommandContext(ctx, r.binary, "volume", "create", name)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -157,9 +187,9 @@ func (r *GenericRuntime) CreateVolume(name string) error {
 }
 
 // GetContainerStatus returns the status of a container
-func (r *GenericRuntime) GetContainerStatus(name string) (string, error) {
+func (r *GenericRuntime) GetContainerStatus(ctx context.Context, name string) (string, error) {
 	// #nosec G204 — container names originate from predefined service IDs.
-	cmd := exec.Command(r.binary, "inspect", "-f", "{{.State.Status}}", name)
+	cmd := exec.CommandContext(ctx, r.binary, "inspect", "-f", "{{.State.Status}}", name)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -172,9 +202,9 @@ func (r *GenericRuntime) GetContainerStatus(name string) (string, error) {
 }
 
 // PullImage pulls a container image
-func (r *GenericRuntime) PullImage(image string) error {
+func (r *GenericRuntime) PullImage(ctx context.Context, image string) error {
 	// #nosec G204 — image name is validated before use
-	cmd := exec.Command(r.binary, "pull", image)
+	cmd := exec.CommandContext(ctx, r.binary, "pull", image)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -185,7 +215,7 @@ func (r *GenericRuntime) PullImage(image string) error {
 }
 
 // GetImageID returns the image ID for a given image name
-func (r *GenericRuntime) GetImageID(image string) (string, error) {
+func (r *GenericRuntime) GetImageID(ctx context.Context, image string) (string, error) {
 	// For Docker: use "inspect -f {{.Id}}"
 	// For Podman: use "image inspect -f {{.Id}}"
 	args := []string{}
@@ -195,7 +225,7 @@ func (r *GenericRuntime) GetImageID(image string) (string, error) {
 	args = append(args, "inspect", "-f", "{{.Id}}", image)
 
 	// #nosec G204 — image name is validated before use
-	cmd := exec.Command(r.binary, args...)
+	cmd := exec.CommandContext(ctx, r.binary, args...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -207,15 +237,59 @@ func (r *GenericRuntime) GetImageID(image string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+// GetContainerImageID returns the ID of the image a container was created from
+func (r *GenericRuntime) GetContainerImageID(ctx context.Context, name string) (string, error) {
+	// #nosec G204 — container names originate from predefined service IDs.
+	cmd := exec.CommandContext(ctx, r.binary, "inspect", "-f", "{{.Image}}", name)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to get %s container image: %w, stderr: %s", r.binary, err, stderr.String())
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// GetImageDigest returns the first repo digest of an image ("" for images without one)
+func (r *GenericRuntime) GetImageDigest(ctx context.Context, image string) (string, error) {
+	// #nosec G204 — image name is validated before use
+	cmd := exec.CommandContext(ctx, r.binary, "image", "inspect", "-f", "{{json .RepoDigests}}", image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to get %s image digest: %w, stderr: %s", r.binary, err, stderr.String())
+	}
+
+	var digests []string
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &digests); err != nil {
+		return "", fmt.Errorf("failed to parse %s image digests: %w", r.binary, err)
+	}
+	return firstRepoDigest(digests), nil
+}
+
+// firstRepoDigest returns the digest part (sha256:...) of the first repo digest
+func firstRepoDigest(repoDigests []string) string {
+	for _, repoDigest := range repoDigests {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+			return digest
+		}
+	}
+	return ""
+}
+
 // GetContainerLogs returns logs from a container
-func (r *GenericRuntime) GetContainerLogs(name string, tail int) (string, error) {
-	return fetchContainerLogs(r.binary, r.binary, name, tail)
+func (r *GenericRuntime) GetContainerLogs(ctx context.Context, name string, tail int) (string, error) {
+	return fetchContainerLogs(ctx, r.binary, r.binary, name, tail)
 }
 
 // RemoveVolume removes a volume
-func (r *GenericRuntime) RemoveVolume(name string) error {
+func (r *GenericRuntime) RemoveVolume(ctx context.Context, name string) error {
 	// #nosec G204 — volume name is validated before use
-	cmd := exec.Command(r.binary, "volume", "rm", name)
+	cmd := exec.CommandContext(ctx, r.binary, "volume", "rm", name)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -227,9 +301,9 @@ func (r *GenericRuntime) RemoveVolume(name string) error {
 }
 
 // RemoveContainer removes a container
-func (r *GenericRuntime) RemoveContainer(name string) error {
+func (r *GenericRuntime) RemoveContainer(ctx context.Context, name string) error {
 	// #nosec G204 — container name is validated before use
-	cmd := exec.Command(r.binary, "rm", "-f", name)
+	cmd := exec.CommandContext(ctx, r.binary, "rm", "-f", name)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -240,10 +314,49 @@ func (r *GenericRuntime) RemoveContainer(name string) error {
 	return nil
 }
 
+// StopContainer stops a container without removing it
+func (r *GenericRuntime) StopContainer(ctx context.Context, name string) error {
+	// #nosec G204 — container name is validated before use
+	cmd := exec.CommandContext(ctx, r.binary, "stop", name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to stop %s container %s: %w, stderr: %s", r.binary, name, err, stderr.String())
+	}
+	return nil
+}
+
+// StartContainer starts an existing, stopped container
+func (r *GenericRuntime) StartContainer(ctx context.Context, name string) error {
+	// #nosec G204 — container name is validated before use
+	cmd := exec.CommandContext(ctx, r.binary, "start", name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to start %s container %s: %w, stderr: %s", r.binary, name, err, stderr.String())
+	}
+	return nil
+}
+
+// RenameContainer renames a container
+func (r *GenericRuntime) RenameContainer(ctx context.Context, name, newName string) error {
+	// #nosec G204 — container names are validated before use
+	cmd := exec.CommandContext(ctx, r.binary, "rename", name, newName)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to rename %s container %s to %s: %w, stderr: %s", r.binary, name, newName, err, stderr.String())
+	}
+	return nil
+}
+
 // TagImage retags an image reference
-func (r *GenericRuntime) TagImage(source, target string) error {
+func (r *GenericRuntime) TagImage(ctx context.Context, source, target string) error {
 	// #nosec G204 — image references are validated before use.
-	cmd := exec.Command(r.binary, "tag", source, target)
+	cmd := exec.CommandContext(ctx, r.binary, "tag", source, target)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -254,9 +367,9 @@ func (r *GenericRuntime) TagImage(source, target string) error {
 }
 
 // VolumeExists checks if a volume exists
-func (r *GenericRuntime) VolumeExists(name string) (bool, error) {
+func (r *GenericRuntime) VolumeExists(ctx context.Context, name string) (bool, error) {
 	// #nosec G204 — volume names are validated before use
-	cmd := exec.Command(r.binary, "volume", "inspect", name)
+	cmd := exec.CommandContext(ctx, r.binary, "volume", "inspect", name)
 	err := cmd.Run()
 	if err != nil {
 		// Volume doesn't exist
@@ -266,9 +379,9 @@ func (r *GenericRuntime) VolumeExists(name string) (bool, error) {
 }
 
 // RemoveNetwork removes a network
-func (r *GenericRuntime) RemoveNetwork(name string) error {
+func (r *GenericRuntime) RemoveNetwork(ctx context.Context, name string) error {
 	// #nosec G204 — network names are validated before use
-	cmd := exec.Command(r.binary, "network", "rm", name)
+	cmd := exec.CommandContext(ctx, r.binary, "network", "rm", name)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -283,14 +396,76 @@ func (r *GenericRuntime) RemoveNetwork(name string) error {
 }
 
 // IsContainerRunning checks if a container is running
-func (r *GenericRuntime) IsContainerRunning(name string) (bool, error) {
-	status, err := r.GetContainerStatus(name)
+func (r *GenericRuntime) IsContainerRunning(ctx context.Context, name string) (bool, error) {
+	status, err := r.GetContainerStatus(ctx, name)
 	if err != nil {
 		return false, nil
 	}
 	return status == containerStatusRunning, nil
 }
 
+// ContainerByPort returns the running container publishing a host port ("" if none)
+func (r *GenericRuntime) ContainerByPort(ctx context.Context, port int) (string, error) {
+	// #nosec G204 — arguments are constant
+	cmd := exec.CommandContext(ctx, r.binary, "ps", "--format", "{{.Names}}\t{{.Ports}}")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to list %s containers: %w, stderr: %s", r.binary, err, stderr.String())
+	}
+
+	// Ports look like "0.0.0.0:3000->8080/tcp, :::3000->8080/tcp"
+	needle := fmt.Sprintf(":%d->", port)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		name, ports, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if found && strings.Contains(ports, needle) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// GetContainerLimits returns the resource limits a container runs with
+func (r *GenericRuntime) GetContainerLimits(ctx context.Context, name string) (ContainerLimits, error) {
+	// #nosec G204 — container names originate from predefined service IDs.
+	cmd := exec.CommandContext(ctx, r.binary, "inspect", "-f", "{{json .HostConfig}}", name)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return ContainerLimits{}, fmt.Errorf("failed to inspect %s container limits: %w, stderr: %s", r.binary, err, stderr.String())
+	}
+
+	var host hostConfig
+	if err := json.Unmarshal(stdout.Bytes(), &host); err != nil {
+		return ContainerLimits{}, fmt.Errorf("failed to parse %s container limits: %w", r.binary, err)
+	}
+	return host.limits(), nil
+}
+
+// ExecInContainer runs a command inside a running container
+func (r *GenericRuntime) ExecInContainer(ctx context.Context, name string, command []string) (ExecResult, error) {
+	args := append([]string{"exec", name}, command...)
+	// #nosec G204 — container names originate from predefined service IDs, the command from config
+	cmd := exec.CommandContext(ctx, r.binary, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return ExecResult{ExitCode: exitErr.ExitCode(), Output: output.String()}, nil
+	}
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to exec in %s container %s: %w", r.binary, name, err)
+	}
+	return ExecResult{Output: output.String()}, nil
+}
+
 // DockerRuntime implements Runtime for Docker
 type DockerRuntime struct {
 	*GenericRuntime
@@ -315,8 +490,10 @@ func NewPodmanRuntime() *PodmanRuntime {
 	}
 }
 
-// DetectRuntime detects and returns the available container runtime
-func DetectRuntime() (Runtime, error) {
+// DetectRuntime detects and returns the available container runtime.
+// AISTACK_RUNTIME selects docker|podman (CLI), docker-api|podman-api (Engine API
+// over the unix socket) or auto. Auto prefers the Engine API when its socket answers.
+func DetectRuntime(ctx context.Context) (Runtime, error) {
 	desired := strings.ToLower(strings.TrimSpace(os.Getenv("AISTACK_RUNTIME")))
 
 	docker := NewDockerRuntime()
@@ -324,24 +501,40 @@ func DetectRuntime() (Runtime, error) {
 
 	switch desired {
 	case "docker":
-		if docker.IsRunning() {
+		if docker.IsRunning(ctx) {
 			return docker, nil
 		}
 		return nil, fmt.Errorf("docker requested via AISTACK_RUNTIME but not available")
 	case "podman":
-		if podman.IsRunning() {
+		if podman.IsRunning(ctx) {
 			return podman, nil
 		}
 		return nil, fmt.Errorf("podman requested via AISTACK_RUNTIME but not available")
+	case "docker-api":
+		if api := detectAPIRuntime(ctx, "docker"); api != nil {
+			return api, nil
+		}
+		return nil, fmt.Errorf("docker-api requested via AISTACK_RUNTIME but no Engine API socket answered")
+	case "podman-api":
+		if api := detectAPIRuntime(ctx, "podman"); api != nil {
+			return api, nil
+		}
+		return nil, fmt.Errorf("podman-api requested via AISTACK_RUNTIME but no Podman API socket answered")
 	case "", "auto":
-		if docker.IsRunning() {
+		if api := detectAPIRuntime(ctx, "docker"); api != nil {
+			return api, nil
+		}
+		if docker.IsRunning(ctx) {
 			return docker, nil
 		}
-		if podman.IsRunning() {
+		if api := detectAPIRuntime(ctx, "podman"); api != nil {
+			return api, nil
+		}
+		if podman.IsRunning(ctx) {
 			return podman, nil
 		}
 	default:
-		return nil, fmt.Errorf("unknown container runtime '%s' (expected docker|podman|auto)", desired)
+		return nil, fmt.Errorf("unknown container runtime '%s' (expected docker|podman|docker-api|podman-api|auto)", desired)
 	}
 
 	return nil, fmt.Errorf("no container runtime detected (Docker or Podman required)")
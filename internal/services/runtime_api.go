@@ -0,0 +1,551 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDockerSocket is the default Docker Engine API socket
+	DefaultDockerSocket = "/var/run/docker.sock"
+	// DefaultPodmanSocket is the default rootful Podman API socket
+	DefaultPodmanSocket = "/run/podman/podman.sock"
+
+	// engineAPIVersion is supported by Docker >= 20.10 and Podman's compat API
+	engineAPIVersion = "v1.41"
+)
+
+var (
+	// ErrNotFound indicates that the requested container, image, volume or network does not exist.
+	ErrNotFound = errors.New("runtime object not found")
+	// ErrConflict indicates that the runtime object is in a conflicting state (e.g. volume in use).
+	ErrConflict = errors.New("runtime object conflict")
+)
+
+// APIError represents a non-success response from the Engine API
+type APIError struct {
+	Op         string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s failed: engine API returned %d: %s", e.Op, e.StatusCode, e.Message)
+}
+
+// Is maps HTTP status codes to the typed runtime errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	default:
+		return false
+	}
+}
+
+// APIRuntime implements Runtime by talking to the Docker Engine API (or Podman's
+// compatible API) over a unix socket. Compose has no Engine API equivalent, so
+// ComposeUp/ComposeDown are still delegated to the CLI binary.
+type APIRuntime struct {
+	*GenericRuntime
+	socketPath string
+	client     *http.Client
+}
+
+// NewAPIRuntime creates an Engine API runtime using the given CLI binary for compose
+func NewAPIRuntime(binary, socketPath string) *APIRuntime {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+
+	return &APIRuntime{
+		GenericRuntime: NewGenericRuntime(binary),
+		socketPath:     socketPath,
+		client:         &http.Client{Transport: transport},
+	}
+}
+
+// NewDockerAPIRuntime creates an Engine API runtime for Docker
+func NewDockerAPIRuntime(socketPath string) *APIRuntime {
+	return NewAPIRuntime("docker", socketPath)
+}
+
+// NewPodmanAPIRuntime creates an Engine API runtime for Podman's compat API
+func NewPodmanAPIRuntime(socketPath string) *APIRuntime {
+	return NewAPIRuntime("podman", socketPath)
+}
+
+// SocketPath returns the unix socket used by the runtime
+func (r *APIRuntime) SocketPath() string {
+	return r.socketPath
+}
+
+// IsRunning checks if the Engine API answers on the socket
+func (r *APIRuntime) IsRunning(ctx context.Context) bool {
+	return r.call(ctx, "ping", http.MethodGet, "/_ping", nil, nil, nil) == nil
+}
+
+// CreateNetwork creates a network if it doesn't exist (idempotent)
+func (r *APIRuntime) CreateNetwork(ctx context.Context, name string) error {
+	err := r.call(ctx, "inspect network", http.MethodGet, "/networks/"+name, nil, nil, nil)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	body := map[string]interface{}{"Name": name, "CheckDuplicate": true}
+	err = r.call(ctx, "create network", http.MethodPost, "/networks/create", nil, body, nil)
+	if errors.Is(err, ErrConflict) {
+		// Created concurrently by someone else
+		return nil
+	}
+	return err
+}
+
+// CreateVolume creates a volume if it doesn't exist (idempotent)
+func (r *APIRuntime) CreateVolume(ctx context.Context, name string) error {
+	exists, err := r.VolumeExists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return r.call(ctx, "create volume", http.MethodPost, "/volumes/create", nil, map[string]string{"Name": name}, nil)
+}
+
+// GetContainerStatus returns the status of a container
+func (r *APIRuntime) GetContainerStatus(ctx context.Context, name string) (string, error) {
+	var inspect struct {
+		State struct {
+			Status string `json:"Status"`
+		} `json:"State"`
+	}
+	if err := r.call(ctx, "inspect container", http.MethodGet, "/containers/"+name+"/json", nil, nil, &inspect); err != nil {
+		return "", err
+	}
+	return inspect.State.Status, nil
+}
+
+// PullImage pulls a container image and waits for the pull stream to finish
+func (r *APIRuntime) PullImage(ctx context.Context, image string) error {
+	repo, tag := splitImageReference(image)
+	query := url.Values{"fromImage": {repo}}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+
+	resp, err := r.do(ctx, http.MethodPost, "/images/create", query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+	defer closeResponse(resp)
+
+	if err := checkResponse("pull image", resp); err != nil {
+		return err
+	}
+
+	// The pull progress is streamed as JSON messages; failures are reported in-band
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error       string `json:"error"`
+			ErrorDetail struct {
+				Message string `json:"message"`
+			} `json:"errorDetail"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read pull progress for %s: %w", image, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to pull image %s: %s", image, msg.Error)
+		}
+	}
+}
+
+// GetImageID returns the image ID for a given image name
+func (r *APIRuntime) GetImageID(ctx context.Context, image string) (string, error) {
+	var inspect struct {
+		ID string `json:"Id"`
+	}
+	if err := r.call(ctx, "inspect image", http.MethodGet, "/images/"+image+"/json", nil, nil, &inspect); err != nil {
+		return "", err
+	}
+	return inspect.ID, nil
+}
+
+// GetImageDigest returns the first repo digest of an image ("" for images without one)
+func (r *APIRuntime) GetImageDigest(ctx context.Context, image string) (string, error) {
+	var inspect struct {
+		RepoDigests []string `json:"RepoDigests"`
+	}
+	if err := r.call(ctx, "inspect image", http.MethodGet, "/images/"+image+"/json", nil, nil, &inspect); err != nil {
+		return "", err
+	}
+	return firstRepoDigest(inspect.RepoDigests), nil
+}
+
+// GetContainerImageID returns the ID of the image a container was created from
+func (r *APIRuntime) GetContainerImageID(ctx context.Context, name string) (string, error) {
+	var inspect struct {
+		Image string `json:"Image"`
+	}
+	if err := r.call(ctx, "inspect container", http.MethodGet, "/containers/"+name+"/json", nil, nil, &inspect); err != nil {
+		return "", err
+	}
+	return inspect.Image, nil
+}
+
+// GetContainerLogs returns logs from a container
+func (r *APIRuntime) GetContainerLogs(ctx context.Context, name string, tail int) (string, error) {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}}
+	if tail > 0 {
+		query.Set("tail", strconv.Itoa(tail))
+	}
+
+	resp, err := r.do(ctx, http.MethodGet, "/containers/"+name+"/logs", query, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get container logs: %w", err)
+	}
+	defer closeResponse(resp)
+
+	if err := checkResponse("container logs", resp); err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read container logs: %w", err)
+	}
+
+	return demuxLogStream(data), nil
+}
+
+// RemoveVolume removes a volume
+func (r *APIRuntime) RemoveVolume(ctx context.Context, name string) error {
+	return r.call(ctx, "remove volume", http.MethodDelete, "/volumes/"+name, nil, nil, nil)
+}
+
+// RemoveContainer removes a container (forcefully, like `rm -f`)
+func (r *APIRuntime) RemoveContainer(ctx context.Context, name string) error {
+	return r.call(ctx, "remove container", http.MethodDelete, "/containers/"+name, url.Values{"force": {"true"}}, nil, nil)
+}
+
+// StopContainer stops a container without removing it (an already stopped container is not an error)
+func (r *APIRuntime) StopContainer(ctx context.Context, name string) error {
+	return ignoreNotModified(r.call(ctx, "stop container", http.MethodPost, "/containers/"+name+"/stop", nil, nil, nil))
+}
+
+// StartContainer starts an existing container (an already running container is not an error)
+func (r *APIRuntime) StartContainer(ctx context.Context, name string) error {
+	return ignoreNotModified(r.call(ctx, "start container", http.MethodPost, "/containers/"+name+"/start", nil, nil, nil))
+}
+
+// RenameContainer renames a container
+func (r *APIRuntime) RenameContainer(ctx context.Context, name, newName string) error {
+	return r.call(ctx, "rename container", http.MethodPost, "/containers/"+name+"/rename", url.Values{"name": {newName}}, nil, nil)
+}
+
+// ignoreNotModified drops the 304 the Engine API answers when a container already is in the requested state
+func ignoreNotModified(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	return err
+}
+
+// TagImage retags an image reference
+func (r *APIRuntime) TagImage(ctx context.Context, source, target string) error {
+	repo, tag := splitImageReference(target)
+	query := url.Values{"repo": {repo}}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	return r.call(ctx, "tag image", http.MethodPost, "/images/"+source+"/tag", query, nil, nil)
+}
+
+// VolumeExists checks if a volume exists
+func (r *APIRuntime) VolumeExists(ctx context.Context, name string) (bool, error) {
+	err := r.call(ctx, "inspect volume", http.MethodGet, "/volumes/"+name, nil, nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// RemoveNetwork removes a network (missing networks are not an error)
+func (r *APIRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	err := r.call(ctx, "remove network", http.MethodDelete, "/networks/"+name, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// IsContainerRunning checks if a container is running
+func (r *APIRuntime) IsContainerRunning(ctx context.Context, name string) (bool, error) {
+	status, err := r.GetContainerStatus(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return status == containerStatusRunning, nil
+}
+
+// ContainerByPort returns the running container publishing a host port ("" if none)
+func (r *APIRuntime) ContainerByPort(ctx context.Context, port int) (string, error) {
+	var containers []struct {
+		Names []string `json:"Names"`
+		Ports []struct {
+			PublicPort int `json:"PublicPort"`
+		} `json:"Ports"`
+	}
+	if err := r.call(ctx, "list containers", http.MethodGet, "/containers/json", nil, nil, &containers); err != nil {
+		return "", err
+	}
+
+	for _, container := range containers {
+		for _, published := range container.Ports {
+			if published.PublicPort == port && len(container.Names) > 0 {
+				return strings.TrimPrefix(container.Names[0], "/"), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// GetContainerLimits returns the resource limits a container runs with
+func (r *APIRuntime) GetContainerLimits(ctx context.Context, name string) (ContainerLimits, error) {
+	var inspect struct {
+		HostConfig hostConfig `json:"HostConfig"`
+	}
+	if err := r.call(ctx, "inspect container", http.MethodGet, "/containers/"+name+"/json", nil, nil, &inspect); err != nil {
+		return ContainerLimits{}, err
+	}
+	return inspect.HostConfig.limits(), nil
+}
+
+// ExecInContainer runs a command inside a running container (create exec, start attached, inspect exit code)
+func (r *APIRuntime) ExecInContainer(ctx context.Context, name string, command []string) (ExecResult, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	request := map[string]interface{}{"Cmd": command, "AttachStdout": true, "AttachStderr": true}
+	if err := r.call(ctx, "create exec", http.MethodPost, "/containers/"+name+"/exec", nil, request, &created); err != nil {
+		return ExecResult{}, err
+	}
+
+	resp, err := r.do(ctx, http.MethodPost, "/exec/"+created.ID+"/start", nil, map[string]interface{}{"Detach": false, "Tty": false})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("start exec failed: %w", err)
+	}
+	defer closeResponse(resp)
+	if err := checkResponse("start exec", resp); err != nil {
+		return ExecResult{}, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	var inspect struct {
+		ExitCode int  `json:"ExitCode"`
+		Running  bool `json:"Running"`
+	}
+	if err := r.call(ctx, "inspect exec", http.MethodGet, "/exec/"+created.ID+"/json", nil, nil, &inspect); err != nil {
+		return ExecResult{}, err
+	}
+	if inspect.Running {
+		return ExecResult{}, fmt.Errorf("exec in %s still running after its output ended", name)
+	}
+
+	return ExecResult{ExitCode: inspect.ExitCode, Output: demuxLogStream(data)}, nil
+}
+
+// call performs an API request and decodes the JSON response into out (if non-nil)
+func (r *APIRuntime) call(ctx context.Context, op, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := r.do(ctx, method, path, query, body)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", op, err)
+	}
+	defer closeResponse(resp)
+
+	if err := checkResponse(op, resp); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s failed: invalid response: %w", op, err)
+	}
+	return nil
+}
+
+func (r *APIRuntime) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := url.URL{
+		Scheme:   "http",
+		Host:     "engine",
+		Path:     "/" + engineAPIVersion + path,
+		RawQuery: query.Encode(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return r.client.Do(req)
+}
+
+// checkResponse converts non-2xx responses into APIError values
+func checkResponse(op string, resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(data))
+	if err == nil {
+		var payload struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &payload) == nil && payload.Message != "" {
+			message = payload.Message
+		}
+	}
+
+	return &APIError{Op: op, StatusCode: resp.StatusCode, Message: message}
+}
+
+func closeResponse(resp *http.Response) {
+	if cerr := resp.Body.Close(); cerr != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to close engine API response body: %v\n", cerr)
+	}
+}
+
+// splitImageReference splits "repo:tag" or "repo@digest" into repository and tag/digest.
+// A port in the registry host ("host:5000/repo") is not mistaken for a tag.
+func splitImageReference(ref string) (string, string) {
+	if idx := strings.Index(ref, "@"); idx != -1 {
+		return ref[:idx], ref[idx+1:]
+	}
+
+	lastSlash := strings.LastIndex(ref, "/")
+	if idx := strings.LastIndex(ref, ":"); idx > lastSlash {
+		return ref[:idx], ref[idx+1:]
+	}
+
+	return ref, ""
+}
+
+// demuxLogStream strips the 8-byte stream headers the Engine API adds to logs and exec
+// output of containers without a TTY. Raw (TTY) output is returned unchanged.
+func demuxLogStream(data []byte) string {
+	var out strings.Builder
+	rest := data
+
+	for len(rest) > 0 {
+		if len(rest) < 8 || rest[0] > 2 || rest[1] != 0 || rest[2] != 0 || rest[3] != 0 {
+			// Not a multiplexed frame - treat the remainder as raw output
+			out.Write(rest)
+			break
+		}
+
+		size := int(binary.BigEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		if size > len(rest) {
+			size = len(rest)
+		}
+		out.Write(rest[:size])
+		rest = rest[size:]
+	}
+
+	return out.String()
+}
+
+// resolveAPISocket returns the Engine API socket for the given binary, or "" if none is present.
+// AISTACK_RUNTIME_SOCKET takes precedence, then DOCKER_HOST (unix:// only), then the defaults.
+func resolveAPISocket(binary string) string {
+	if env := strings.TrimSpace(os.Getenv("AISTACK_RUNTIME_SOCKET")); env != "" {
+		return strings.TrimPrefix(env, "unix://")
+	}
+
+	candidates := []string{}
+	switch binary {
+	case "docker":
+		if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+			candidates = append(candidates, strings.TrimPrefix(host, "unix://"))
+		}
+		candidates = append(candidates, DefaultDockerSocket)
+	case "podman":
+		candidates = append(candidates, DefaultPodmanSocket)
+		if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+			candidates = append(candidates, filepath.Join(xdg, "podman", "podman.sock"))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// detectAPIRuntime returns an Engine API runtime for the binary if its socket answers
+func detectAPIRuntime(ctx context.Context, binary string) *APIRuntime {
+	socket := resolveAPISocket(binary)
+	if socket == "" {
+		return nil
+	}
+
+	runtime := NewAPIRuntime(binary, socket)
+	if !runtime.IsRunning(ctx) {
+		return nil
+	}
+	return runtime
+}
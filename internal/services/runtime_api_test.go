@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// startFakeEngine serves handler on a unix socket and returns an APIRuntime bound to it
+func startFakeEngine(t *testing.T, handler http.Handler) *APIRuntime {
+	t.Helper()
+
+	// Keep the socket path short (unix socket paths are limited to ~108 bytes)
+	dir, err := os.MkdirTemp("", "aistack-engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "engine.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on unix socket: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	return NewDockerAPIRuntime(socket)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+func TestAPIRuntime_IsRunning(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+engineAPIVersion+"/_ping" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if !runtime.IsRunning(context.Background()) {
+		t.Error("Expected runtime to be running")
+	}
+
+	missing := NewDockerAPIRuntime(filepath.Join(os.TempDir(), "does-not-exist.sock"))
+	if missing.IsRunning(context.Background()) {
+		t.Error("Expected runtime without socket to be unavailable")
+	}
+}
+
+func TestAPIRuntime_GetContainerStatus(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + engineAPIVersion + "/containers/aistack-ollama/json":
+			writeJSON(w, http.StatusOK, `{"State":{"Status":"running"},"Image":"sha256:abc123"}`)
+		default:
+			writeJSON(w, http.StatusNotFound, `{"message":"No such container"}`)
+		}
+	}))
+
+	status, err := runtime.GetContainerStatus(context.Background(), "aistack-ollama")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if status != containerStatusRunning {
+		t.Errorf("Expected status running, got: %s", status)
+	}
+
+	if imageID, err := runtime.GetContainerImageID(context.Background(), "aistack-ollama"); err != nil || imageID != "sha256:abc123" {
+		t.Errorf("GetContainerImageID() = %q, %v", imageID, err)
+	}
+
+	_, err = runtime.GetContainerStatus(context.Background(), "aistack-missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+
+	running, err := runtime.IsContainerRunning(context.Background(), "aistack-missing")
+	if err != nil || running {
+		t.Errorf("Expected missing container to be reported as not running without error, got: %v, %v", running, err)
+	}
+}
+
+func TestAPIRuntime_TypedErrors(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/networks/aistack-net"):
+			writeJSON(w, http.StatusNotFound, `{"message":"network aistack-net not found"}`)
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/volumes/ollama_data"):
+			writeJSON(w, http.StatusConflict, `{"message":"volume is in use"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	if err := runtime.RemoveNetwork(context.Background(), "aistack-net"); err != nil {
+		t.Errorf("Expected missing network removal to succeed, got: %v", err)
+	}
+
+	err := runtime.RemoveVolume(context.Background(), "ollama_data")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got: %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "volume is in use" {
+		t.Errorf("Expected APIError with engine message, got: %v", err)
+	}
+}
+
+func TestAPIRuntime_CreateNetworkIdempotent(t *testing.T) {
+	created := 0
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/networks/aistack-net"):
+			if created > 0 {
+				writeJSON(w, http.StatusOK, `{"Name":"aistack-net"}`)
+				return
+			}
+			writeJSON(w, http.StatusNotFound, `{"message":"not found"}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/networks/create"):
+			created++
+			writeJSON(w, http.StatusCreated, `{"Id":"abc"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	for i := 0; i < 2; i++ {
+		if err := runtime.CreateNetwork(context.Background(), "aistack-net"); err != nil {
+			t.Fatalf("CreateNetwork() error = %v", err)
+		}
+	}
+
+	if created != 1 {
+		t.Errorf("Expected network to be created once, got %d", created)
+	}
+}
+
+func TestAPIRuntime_PullImage(t *testing.T) {
+	var gotQuery string
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		if strings.Contains(r.URL.Query().Get("fromImage"), "broken") {
+			writeJSON(w, http.StatusOK, `{"status":"Pulling"}`+"\n"+`{"error":"manifest unknown"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"status":"Pulling"}`+"\n"+`{"status":"Downloaded newer image"}`)
+	}))
+
+	if err := runtime.PullImage(context.Background(), "ghcr.io/open-webui/open-webui:main"); err != nil {
+		t.Fatalf("PullImage() error = %v", err)
+	}
+	if gotQuery != "fromImage=ghcr.io%2Fopen-webui%2Fopen-webui&tag=main" {
+		t.Errorf("Unexpected pull query: %s", gotQuery)
+	}
+
+	err := runtime.PullImage(context.Background(), "example.com/broken:latest")
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("Expected in-band pull error to be surfaced, got: %v", err)
+	}
+}
+
+func TestAPIRuntime_TagImageAndImageID(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/"+engineAPIVersion+"/images/sha256:old/tag":
+			if r.URL.Query().Get("repo") != "localhost:5000/ollama/ollama" || r.URL.Query().Get("tag") != "latest" {
+				t.Errorf("Unexpected tag query: %s", r.URL.RawQuery)
+			}
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/"+engineAPIVersion+"/images/ollama/ollama:latest/json":
+			writeJSON(w, http.StatusOK, `{"Id":"sha256:abc"}`)
+		default:
+			writeJSON(w, http.StatusNotFound, `{"message":"No such image"}`)
+		}
+	}))
+
+	if err := runtime.TagImage(context.Background(), "sha256:old", "localhost:5000/ollama/ollama:latest"); err != nil {
+		t.Fatalf("TagImage() error = %v", err)
+	}
+
+	id, err := runtime.GetImageID(context.Background(), "ollama/ollama:latest")
+	if err != nil {
+		t.Fatalf("GetImageID() error = %v", err)
+	}
+	if id != "sha256:abc" {
+		t.Errorf("Expected image ID sha256:abc, got %s", id)
+	}
+}
+
+func TestAPIRuntime_ContainerLifecycle(t *testing.T) {
+	var calls []string
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/"+engineAPIVersion)+"?"+r.URL.RawQuery)
+		switch r.URL.Path {
+		case "/" + engineAPIVersion + "/containers/aistack-ollama/start":
+			// Already running
+			w.WriteHeader(http.StatusNotModified)
+		case "/" + engineAPIVersion + "/containers/missing/stop":
+			writeJSON(w, http.StatusNotFound, `{"message":"No such container"}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	ctx := context.Background()
+	if err := runtime.StopContainer(ctx, "aistack-ollama"); err != nil {
+		t.Errorf("StopContainer() error = %v", err)
+	}
+	if err := runtime.RenameContainer(ctx, "aistack-ollama", "aistack-ollama-previous"); err != nil {
+		t.Errorf("RenameContainer() error = %v", err)
+	}
+	if err := runtime.StartContainer(ctx, "aistack-ollama"); err != nil {
+		t.Errorf("StartContainer() on a running container error = %v", err)
+	}
+	if err := runtime.StopContainer(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("StopContainer() on a missing container error = %v, want ErrNotFound", err)
+	}
+
+	want := []string{
+		"/containers/aistack-ollama/stop?",
+		"/containers/aistack-ollama/rename?name=aistack-ollama-previous",
+		"/containers/aistack-ollama/start?",
+		"/containers/missing/stop?",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestAPIRuntime_GetContainerLogs(t *testing.T) {
+	frame := func(stream byte, payload string) []byte {
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+		return append(header, payload...)
+	}
+
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tail") != "50" {
+			t.Errorf("Expected tail=50, got %s", r.URL.Query().Get("tail"))
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(frame(1, "line 1\n"))
+		_, _ = w.Write(frame(2, "error line\n"))
+	}))
+
+	logs, err := runtime.GetContainerLogs(context.Background(), "aistack-ollama", 50)
+	if err != nil {
+		t.Fatalf("GetContainerLogs() error = %v", err)
+	}
+	if logs != "line 1\nerror line\n" {
+		t.Errorf("Unexpected demuxed logs: %q", logs)
+	}
+}
+
+func TestAPIRuntime_ContainerByPort(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+engineAPIVersion+"/containers/json" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		writeJSON(w, http.StatusOK, `[
+			{"Names":["/other"],"Ports":[{"PrivatePort":80,"PublicPort":8081}]},
+			{"Names":["/grafana"],"Ports":[{"PrivatePort":3000,"PublicPort":3000}]}
+		]`)
+	}))
+
+	name, err := runtime.ContainerByPort(context.Background(), 3000)
+	if err != nil {
+		t.Fatalf("ContainerByPort() error = %v", err)
+	}
+	if name != "grafana" {
+		t.Errorf("ContainerByPort(3000) = %q, want grafana", name)
+	}
+
+	if name, _ := runtime.ContainerByPort(context.Background(), 9999); name != "" {
+		t.Errorf("ContainerByPort(9999) = %q, want empty", name)
+	}
+}
+
+func TestSplitImageReference(t *testing.T) {
+	tests := []struct {
+		ref      string
+		wantRepo string
+		wantTag  string
+	}{
+		{"ollama/ollama:latest", "ollama/ollama", "latest"},
+		{"ollama/ollama", "ollama/ollama", ""},
+		{"localhost:5000/ollama", "localhost:5000/ollama", ""},
+		{"ghcr.io/open-webui/open-webui@sha256:abc", "ghcr.io/open-webui/open-webui", "sha256:abc"},
+	}
+
+	for _, tt := range tests {
+		repo, tag := splitImageReference(tt.ref)
+		if repo != tt.wantRepo || tag != tt.wantTag {
+			t.Errorf("splitImageReference(%q) = (%q, %q), want (%q, %q)", tt.ref, repo, tag, tt.wantRepo, tt.wantTag)
+		}
+	}
+}
+
+func TestAPIRuntime_GetContainerLimits(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+engineAPIVersion+"/containers/aistack-ollama/json" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		writeJSON(w, http.StatusOK, `{"HostConfig":{
+			"NanoCpus":2500000000,"Memory":8589934592,"MemorySwap":-1,"ShmSize":1073741824,"PidsLimit":512,
+			"Ulimits":[{"Name":"nofile","Soft":65536,"Hard":65536}]
+		}}`)
+	}))
+
+	limits, err := runtime.GetContainerLimits(context.Background(), "aistack-ollama")
+	if err != nil {
+		t.Fatalf("GetContainerLimits() error = %v", err)
+	}
+	want := ContainerLimits{
+		CPUs:       2.5,
+		Memory:     8 << 30,
+		MemorySwap: -1,
+		ShmSize:    1 << 30,
+		PidsLimit:  512,
+		Ulimits:    map[string]int64{"nofile": 65536},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("GetContainerLimits() = %+v, want %+v", limits, want)
+	}
+}
+
+func TestAPIRuntime_ExecInContainer(t *testing.T) {
+	runtime := startFakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + engineAPIVersion + "/containers/aistack-ollama/exec":
+			writeJSON(w, http.StatusCreated, `{"Id":"exec1"}`)
+		case "/" + engineAPIVersion + "/exec/exec1/start":
+			frame := []byte{1, 0, 0, 0, 0, 0, 0, 6}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(append(frame, []byte("NAME\n\n")[:6]...))
+		case "/" + engineAPIVersion + "/exec/exec1/json":
+			writeJSON(w, http.StatusOK, `{"ExitCode":3,"Running":false}`)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+
+	result, err := runtime.ExecInContainer(context.Background(), "aistack-ollama", []string{"ollama", "list"})
+	if err != nil {
+		t.Fatalf("ExecInContainer() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Output != "NAME\n\n" {
+		t.Errorf("ExecInContainer() = %+v", result)
+	}
+}
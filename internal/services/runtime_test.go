@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"testing"
 )
 
@@ -20,7 +21,7 @@ func TestDockerRuntime_CreateNetwork(t *testing.T) {
 func TestDetectRuntime(t *testing.T) {
 	// This test will pass only if Docker is available
 	// In CI, this should be mocked or skipped if Docker is not available
-	runtime, err := DetectRuntime()
+	runtime, err := DetectRuntime(context.Background())
 
 	// We don't fail if Docker is not available in test environment
 	// Just verify the function returns expected types
@@ -2,9 +2,13 @@ package services
 
 import (
 	"aistack/internal/logging"
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const serviceStateRunning = "running"
@@ -12,22 +16,26 @@ const serviceStateRunning = "running"
 // Service represents a container service
 type Service interface {
 	Name() string
-	Install() error
-	Start() error
-	Stop() error
-	Status() (ServiceStatus, error)
-	Health() (HealthStatus, error)
-	Remove(keepData bool) error
-	Update() error
-	Logs(tail int) (string, error)
+	Install(ctx context.Context) error
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Status(ctx context.Context) (ServiceStatus, error)
+	Health(ctx context.Context) (HealthStatus, error)
+	Remove(ctx context.Context, keepData bool) error
+	Update(ctx context.Context) error
+	Logs(ctx context.Context, tail int) (string, error)
 }
 
 // ServiceStatus represents the status of a service
 type ServiceStatus struct {
-	Name    string       `json:"name"`
-	State   string       `json:"state"`  // running, stopped, unknown
-	Health  HealthStatus `json:"health"` // green, yellow, red
-	Message string       `json:"message"`
+	Name    string           `json:"name"`
+	State   string           `json:"state"`  // running, stopped, unknown
+	Health  HealthStatus     `json:"health"` // green, yellow, red
+	Message string           `json:"message"`
+	Limits  *ContainerLimits `json:"limits,omitempty"`     // limits of the running container
+	Latency time.Duration    `json:"latency_ns,omitempty"` // duration of the health probe; 0 when none ran
+	// LockDrift says which image runs instead of the versions.lock pin; empty when in sync
+	LockDrift string `json:"lock_drift,omitempty"`
 }
 
 // BaseService provides common service functionality
@@ -39,8 +47,12 @@ type BaseService struct {
 	runtime      Runtime
 	logger       *logging.Logger
 	netManager   *NetworkManager
-	preStartHook func() error
-	postStopHook func() error
+	timeouts     OperationTimeouts
+	preStartHook func(ctx context.Context) error
+	postStopHook func(ctx context.Context) error
+	renderer     *ComposeRenderer
+	// latencyLimit overrides timeouts.HealthLatency (services.<name>.health.latency_ms)
+	latencyLimit time.Duration
 }
 
 // NewBaseService creates a new base service
@@ -53,32 +65,56 @@ func NewBaseService(name, composeDir string, healthCheck HealthChecker, volumes
 		runtime:     runtime,
 		logger:      logger,
 		netManager:  NewNetworkManager(runtime, logger),
+		timeouts:    DefaultOperationTimeouts(),
 	}
 }
 
+// SetTimeouts overrides the operation timeouts used for health checks
+func (s *BaseService) SetTimeouts(timeouts OperationTimeouts) {
+	s.timeouts = timeouts
+}
+
+// NewBaseServiceFromSpec creates a base service from a catalog entry; the health
+// checker follows spec.Health (services.<name>.health)
+func NewBaseServiceFromSpec(spec ServiceSpec, composeDir string, runtime Runtime, logger *logging.Logger) *BaseService {
+	healthCheck, err := newHealthChecker(spec, spec.Health, runtime)
+	if err != nil {
+		logger.Warn("service.health.config_invalid", "Invalid health check config, using the catalog health_url", map[string]interface{}{
+			"service": spec.Name,
+			"error":   err.Error(),
+		})
+		healthCheck = DefaultHealthCheck(spec.HealthURL)
+	}
+
+	base := NewBaseService(spec.Name, composeDir, healthCheck, spec.Volumes, runtime, logger)
+	base.composeFile = spec.ComposePath(composeDir)
+	base.latencyLimit = time.Duration(spec.Health.LatencyMs) * time.Millisecond
+	return base
+}
+
 // Name returns the service name
 func (s *BaseService) Name() string {
 	return s.name
 }
 
 // Install installs the service (ensures network, volumes, and starts)
-func (s *BaseService) Install() error {
+func (s *BaseService) Install(ctx context.Context) error {
 	s.logger.Info("service.install.start", fmt.Sprintf("Installing %s service", s.name), map[string]interface{}{
 		"service": s.name,
 	})
 
 	// Ensure network exists
-	if err := s.netManager.EnsureNetwork(); err != nil {
+	if err := s.netManager.EnsureNetwork(ctx); err != nil {
 		return fmt.Errorf("failed to ensure network: %w", err)
 	}
 
 	// Ensure volumes exist
-	if err := s.netManager.EnsureVolumes(s.volumes); err != nil {
+	if err := s.netManager.EnsureVolumes(ctx, s.volumes); err != nil {
 		return fmt.Errorf("failed to ensure volumes: %w", err)
 	}
 
 	// Start the service
-	if err := s.Start(); err != nil {
+	if err := s.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start service: %w", err)
 	}
 
@@ -89,33 +125,89 @@ func (s *BaseService) Install() error {
 	return nil
 }
 
-// Start starts the service using docker compose
-func (s *BaseService) Start() error {
-	if err := s.executePreStartHook(); err != nil {
-		return err
-	}
-	return s.runComposeAction("start", func(composeFile string) error {
-		return s.runtime.ComposeUp(composeFile)
+// Start starts the service using docker compose. Published ports are checked before
+// the pre-start hook, so a port conflict does not leave the GPU lock taken. A
+// blue-green service starts behind its front proxy, which publishes the ports.
+func (s *BaseService) Start(ctx context.Context) error {
+	return s.runComposeAction(ctx, "start", func(ctx context.Context, composeFile string) error {
+		front, err := s.frontComposeFile(ctx)
+		if err != nil {
+			return err
+		}
+		published := composeFile
+		if front != "" {
+			published = front
+		}
+		if err := s.preflightPorts(ctx, published); err != nil {
+			return err
+		}
+		if err := s.executePreStartHook(ctx); err != nil {
+			return err
+		}
+		if err := s.runtime.ComposeUp(ctx, composeFile); err != nil {
+			return err
+		}
+		if front != "" {
+			return s.runtime.ComposeUp(ctx, front)
+		}
+		return nil
 	})
 }
 
 // Stop stops the service
-func (s *BaseService) Stop() error {
-	err := s.runComposeAction("stop", s.runtime.ComposeDown)
+func (s *BaseService) Stop(ctx context.Context) error {
+	err := s.runComposeAction(ctx, "stop", func(ctx context.Context, composeFile string) error {
+		if s.renderer != nil && s.renderer.hasFrontFile() {
+			if err := s.runtime.ComposeDown(ctx, s.renderer.frontFile()); err != nil {
+				return err
+			}
+		}
+		return s.runtime.ComposeDown(ctx, composeFile)
+	})
 	if err != nil {
 		return err
 	}
-	return s.executePostStopHook()
+	return s.executePostStopHook(ctx)
+}
+
+// frontComposeFile returns the rendered front proxy of a blue-green service, "" for
+// other services. The front proxy of a service that is no longer updated blue-green
+// is removed, so the service can publish its ports itself again.
+func (s *BaseService) frontComposeFile(ctx context.Context) (string, error) {
+	if s.renderer == nil || !s.renderer.hasFrontFile() {
+		return "", nil
+	}
+	front := s.renderer.frontFile()
+	if s.renderer.blueGreen() {
+		return front, nil
+	}
+
+	if err := s.runtime.ComposeDown(ctx, front); err != nil {
+		return "", fmt.Errorf("failed to remove front proxy: %w", err)
+	}
+	if err := os.Remove(front); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove front proxy: %w", err)
+	}
+	return "", nil
 }
 
-func (s *BaseService) runComposeAction(action string, execFn func(string) error) error {
+func (s *BaseService) runComposeAction(ctx context.Context, action string, execFn func(context.Context, string) error) error {
 	verb := actionVerb(action)
 	baseEvent := fmt.Sprintf("service.%s", action)
 	serviceFields := map[string]interface{}{"service": s.name}
 
 	s.logger.Info(baseEvent, fmt.Sprintf("%s %s service", verb, s.name), serviceFields)
 
-	if err := execFn(s.composeFile); err != nil {
+	composeFile, err := s.effectiveComposeFile()
+	if err != nil {
+		s.logger.Error(baseEvent+".error", fmt.Sprintf("Failed to %s service", action), map[string]interface{}{
+			"service": s.name,
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("failed to %s %s: %w", action, s.name, err)
+	}
+
+	if err := execFn(ctx, composeFile); err != nil {
 		s.logger.Error(baseEvent+".error", fmt.Sprintf("Failed to %s service", action), map[string]interface{}{
 			"service": s.name,
 			"error":   err.Error(),
@@ -127,17 +219,35 @@ func (s *BaseService) runComposeAction(action string, execFn func(string) error)
 	return nil
 }
 
+// SetComposeRenderer makes the service render its compose template before each compose call
+func (s *BaseService) SetComposeRenderer(renderer *ComposeRenderer) {
+	s.renderer = renderer
+}
+
+// ComposeRenderer returns the compose renderer, nil when the compose file is used as-is
+func (s *BaseService) ComposeRenderer() *ComposeRenderer {
+	return s.renderer
+}
+
+// effectiveComposeFile renders the compose template if configured, else returns the static file
+func (s *BaseService) effectiveComposeFile() (string, error) {
+	if s.renderer == nil {
+		return s.composeFile, nil
+	}
+	return s.renderer.Write()
+}
+
 // SetPreStartHook registers a hook executed before ComposeUp during Start/Install
-func (s *BaseService) SetPreStartHook(hook func() error) {
+func (s *BaseService) SetPreStartHook(hook func(ctx context.Context) error) {
 	s.preStartHook = hook
 }
 
-func (s *BaseService) executePreStartHook() error {
+func (s *BaseService) executePreStartHook(ctx context.Context) error {
 	if s.preStartHook == nil {
 		return nil
 	}
 
-	if err := s.preStartHook(); err != nil {
+	if err := s.preStartHook(ctx); err != nil {
 		s.logger.Error("service.start.prehook_failed", "Pre-start hook failed", map[string]interface{}{
 			"service": s.name,
 			"error":   err.Error(),
@@ -149,16 +259,16 @@ func (s *BaseService) executePreStartHook() error {
 }
 
 // SetPostStopHook registers a hook executed after ComposeDown during Stop
-func (s *BaseService) SetPostStopHook(hook func() error) {
+func (s *BaseService) SetPostStopHook(hook func(ctx context.Context) error) {
 	s.postStopHook = hook
 }
 
-func (s *BaseService) executePostStopHook() error {
+func (s *BaseService) executePostStopHook(ctx context.Context) error {
 	if s.postStopHook == nil {
 		return nil
 	}
 
-	if err := s.postStopHook(); err != nil {
+	if err := s.postStopHook(ctx); err != nil {
 		s.logger.Warn("service.stop.posthook_failed", "Post-stop hook failed", map[string]interface{}{
 			"service": s.name,
 			"error":   err.Error(),
@@ -196,10 +306,13 @@ func pastTense(action string) string {
 }
 
 // Status returns the current status of the service
-func (s *BaseService) Status() (ServiceStatus, error) {
+func (s *BaseService) Status(ctx context.Context) (ServiceStatus, error) {
 	containerName := fmt.Sprintf("aistack-%s", s.name)
-	state, err := s.runtime.GetContainerStatus(containerName)
+	state, err := s.runtime.GetContainerStatus(ctx, containerName)
 	if err != nil {
+		if ctx.Err() != nil {
+			return ServiceStatus{}, fmt.Errorf("status of %s interrupted: %w", s.name, ctx.Err())
+		}
 		return ServiceStatus{
 			Name:    s.name,
 			State:   "unknown",
@@ -208,36 +321,98 @@ func (s *BaseService) Status() (ServiceStatus, error) {
 		}, nil
 	}
 
+	status := ServiceStatus{
+		Name:   s.name,
+		State:  state,
+		Health: HealthRed,
+	}
+	if state != serviceStateRunning {
+		return status, nil
+	}
+
 	// Get health status
-	health := HealthRed
-	if state == serviceStateRunning {
-		healthStatus, err := s.Health()
-		if err == nil {
-			health = healthStatus
-		}
+	healthStatus, latency, err := s.probeHealth(ctx)
+	if err != nil && ctx.Err() != nil {
+		return ServiceStatus{}, fmt.Errorf("status of %s interrupted: %w", s.name, ctx.Err())
+	}
+	status.Latency = latency
+	if err == nil {
+		status.Health, status.Message = s.gradeLatency(healthStatus, latency)
 	}
 
-	return ServiceStatus{
-		Name:   s.name,
-		State:  state,
-		Health: health,
-	}, nil
+	s.inspectLimits(ctx, containerName, &status)
+	return status, nil
+}
+
+// inspectLimits reads the container's actual limits and flags configured limits it
+// does not run with; inspect errors leave Limits unset
+func (s *BaseService) inspectLimits(ctx context.Context, containerName string, status *ServiceStatus) {
+	limits, err := s.runtime.GetContainerLimits(ctx, containerName)
+	if err != nil {
+		s.logger.Debug("service.limits.inspect_failed", "Could not read container limits", map[string]interface{}{
+			"service": s.name,
+			"error":   err.Error(),
+		})
+		return
+	}
+	status.Limits = &limits
+
+	if s.renderer == nil {
+		return
+	}
+	if drift := limits.Drift(s.renderer.spec.Resources); len(drift) > 0 {
+		message := fmt.Sprintf("container limits differ from config (%s); restart the service to apply them", strings.Join(drift, ", "))
+		if status.Message != "" {
+			message = status.Message + "; " + message
+		}
+		status.Message = message
+	}
 }
 
 // Health performs a health check on the service
-func (s *BaseService) Health() (HealthStatus, error) {
-	return s.healthCheck.Check()
+func (s *BaseService) Health(ctx context.Context) (HealthStatus, error) {
+	status, latency, err := s.probeHealth(ctx)
+	if err != nil {
+		return status, err
+	}
+	if graded, message := s.gradeLatency(status, latency); message != "" {
+		return graded, errors.New(message)
+	}
+	return status, nil
+}
+
+// probeHealth runs the health check once and measures how long it took
+func (s *BaseService) probeHealth(ctx context.Context) (HealthStatus, time.Duration, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.HealthCheck)
+	defer cancel()
+
+	start := time.Now()
+	status, err := s.healthCheck.Check(ctx)
+	return status, time.Since(start), err
+}
+
+// gradeLatency downgrades a green probe slower than the latency limit to yellow
+// and explains why; other results pass through unchanged
+func (s *BaseService) gradeLatency(status HealthStatus, latency time.Duration) (HealthStatus, string) {
+	limit := s.latencyLimit
+	if limit == 0 {
+		limit = s.timeouts.HealthLatency
+	}
+	if status != HealthGreen || limit <= 0 || latency <= limit {
+		return status, ""
+	}
+	return HealthYellow, fmt.Sprintf("health check took %s (limit %s)", latency.Round(time.Millisecond), limit)
 }
 
 // Remove removes the service (optionally keeping data volumes)
-func (s *BaseService) Remove(keepData bool) error {
+func (s *BaseService) Remove(ctx context.Context, keepData bool) error {
 	s.logger.Info("service.remove", fmt.Sprintf("Removing %s service", s.name), map[string]interface{}{
 		"service":   s.name,
 		"keep_data": keepData,
 	})
 
 	// First stop the service
-	if err := s.Stop(); err != nil {
+	if err := s.Stop(ctx); err != nil {
 		// Log but continue - service might already be stopped
 		s.logger.Warn("service.remove.stop_error", "Error stopping service during removal", map[string]interface{}{
 			"service": s.name,
@@ -245,10 +420,13 @@ func (s *BaseService) Remove(keepData bool) error {
 		})
 	}
 
+	// A container left by an interrupted blue-green update would hold on to the volumes
+	_ = s.runtime.RemoveContainer(ctx, previousContainerName(s.name))
+
 	// Remove volumes if requested
 	if !keepData {
 		for _, volume := range s.volumes {
-			if err := s.runtime.RemoveVolume(volume); err != nil {
+			if err := s.runtime.RemoveVolume(ctx, volume); err != nil {
 				s.logger.Warn("service.remove.volume_error", "Error removing volume", map[string]interface{}{
 					"service": s.name,
 					"volume":  volume,
@@ -266,14 +444,14 @@ func (s *BaseService) Remove(keepData bool) error {
 }
 
 // Update performs a service update - must be implemented by concrete services
-func (s *BaseService) Update() error {
+func (s *BaseService) Update(ctx context.Context) error {
 	return fmt.Errorf("update not implemented for base service")
 }
 
 // Logs retrieves logs from the service container
-func (s *BaseService) Logs(tail int) (string, error) {
+func (s *BaseService) Logs(ctx context.Context, tail int) (string, error) {
 	containerName := fmt.Sprintf("aistack-%s", s.name)
-	logs, err := s.runtime.GetContainerLogs(containerName, tail)
+	logs, err := s.runtime.GetContainerLogs(ctx, containerName, tail)
 	if err != nil {
 		return "", fmt.Errorf("failed to get logs for %s: %w", s.name, err)
 	}
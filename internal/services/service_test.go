@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -78,7 +79,7 @@ func TestBaseService_Remove_KeepData(t *testing.T) {
 	service := NewBaseService("test-service", "./compose", hc, []string{"test_volume"}, runtime, logger)
 
 	// Remove with keepData = true
-	err := service.Remove(true)
+	err := service.Remove(context.Background(), true)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -97,7 +98,7 @@ func TestBaseService_Remove_PurgeData(t *testing.T) {
 	service := NewBaseService("test-service", "./compose", hc, []string{"test_volume", "test_volume2"}, runtime, logger)
 
 	// Remove with keepData = false (purge)
-	err := service.Remove(false)
+	err := service.Remove(context.Background(), false)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -130,7 +131,7 @@ func TestLocalAIService_Update(t *testing.T) {
 
 	// Update should work without error (using mock runtime)
 	// Note: In real scenario, this would pull image and restart service
-	err := service.Update()
+	err := service.Update(context.Background())
 
 	// We expect an error because MockRuntime doesn't have PullImage properly set up
 	// This is fine - we're testing that Update() method exists and is callable
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aistack/internal/fsutil"
+)
+
+const (
+	operatorStopsFilename = "operator_stops.json"
+	operatorStopsLockName = "operator_stops.lock"
+)
+
+// operatorStops maps services an operator stopped to when they were stopped.
+// The agent leaves these services down until an operator starts them again.
+type operatorStops map[string]time.Time
+
+func operatorStopsPath() string {
+	return filepath.Join(fsutil.GetStateDir(defaultStateDir), operatorStopsFilename)
+}
+
+// loadOperatorStops reads the recorded operator stops; a missing file has none
+func loadOperatorStops() (operatorStops, error) {
+	data, err := os.ReadFile(filepath.Clean(operatorStopsPath())) // #nosec G304 -- path is internal to the state directory
+	if err != nil {
+		if os.IsNotExist(err) {
+			return operatorStops{}, nil
+		}
+		return nil, fmt.Errorf("failed to read operator stops: %w", err)
+	}
+
+	stops := operatorStops{}
+	if err := json.Unmarshal(data, &stops); err != nil {
+		return nil, fmt.Errorf("failed to parse operator stops: %w", err)
+	}
+	return stops, nil
+}
+
+// recordOperatorStops marks names as stopped (stopped true) or started by an operator
+func (m *Manager) recordOperatorStops(names []string, stopped bool) error {
+	stateDir := fsutil.GetStateDir(defaultStateDir)
+	if err := fsutil.EnsureStateDirectory(stateDir); err != nil {
+		return err
+	}
+	// Concurrent stops (CLI and API jobs) must not drop each other's records
+	unlock, err := fsutil.Lock(filepath.Join(stateDir, operatorStopsLockName))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stops, err := loadOperatorStops()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, name := range names {
+		_, recorded := stops[name]
+		switch {
+		case stopped && !recorded:
+			stops[name] = time.Now().UTC()
+			changed = true
+		case !stopped && recorded:
+			delete(stops, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(stops, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal operator stops: %w", err)
+	}
+	return fsutil.AtomicWriteFile(operatorStopsPath(), data, 0o600, m.logger)
+}
+
+// StartService starts a service for an operator; a recorded operator stop is cleared
+// so the agent supervises the service again
+func (m *Manager) StartService(ctx context.Context, name string) error {
+	service, err := m.GetService(name)
+	if err != nil {
+		return err
+	}
+	if err := m.recordOperatorStops([]string{name}, false); err != nil {
+		return err
+	}
+	return service.Start(ctx)
+}
+
+// StopService stops a service for an operator; the agent does not restart it until
+// it is started again
+func (m *Manager) StopService(ctx context.Context, name string) error {
+	service, err := m.GetService(name)
+	if err != nil {
+		return err
+	}
+	// Recorded first, so an agent check during the stop does not repair the service
+	if err := m.recordOperatorStops([]string{name}, true); err != nil {
+		return err
+	}
+	return service.Stop(ctx)
+}
+
+// InstallService installs a service for an operator and clears a recorded operator stop
+func (m *Manager) InstallService(ctx context.Context, name string) error {
+	service, err := m.GetService(name)
+	if err != nil {
+		return err
+	}
+	if err := m.recordOperatorStops([]string{name}, false); err != nil {
+		return err
+	}
+	return service.Install(ctx)
+}
+
+// SupervisedServices returns the services that are meant to be running: they have a
+// container (InstalledServices) and no operator stopped them
+func (m *Manager) SupervisedServices(ctx context.Context) ([]string, error) {
+	installed, err := m.InstalledServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stops, err := loadOperatorStops()
+	if err != nil {
+		return nil, err
+	}
+
+	supervised := make([]string, 0, len(installed))
+	for _, name := range installed {
+		if _, stopped := stops[name]; !stopped {
+			supervised = append(supervised, name)
+		}
+	}
+	return supervised, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestManager_SupervisedServices(t *testing.T) {
+	t.Setenv("AISTACK_STATE_DIR", t.TempDir())
+
+	manager := NewMockManager()
+	runtime := manager.runtime.(*MockRuntime)
+	runtime.missingContainers["aistack-localai"] = true
+	ctx := context.Background()
+
+	supervised, err := manager.SupervisedServices(ctx)
+	if err != nil {
+		t.Fatalf("SupervisedServices() error = %v", err)
+	}
+	if !reflect.DeepEqual(supervised, []string{"ollama", "openwebui"}) {
+		t.Fatalf("SupervisedServices() = %v, want the installed services", supervised)
+	}
+
+	if err := manager.StopService(ctx, "ollama"); err != nil {
+		t.Fatalf("StopService() error = %v", err)
+	}
+	if supervised, _ := manager.SupervisedServices(ctx); !reflect.DeepEqual(supervised, []string{"openwebui"}) {
+		t.Errorf("SupervisedServices() after a stop = %v, want the stopped service left out", supervised)
+	}
+
+	if err := manager.StartService(ctx, "ollama"); err != nil {
+		t.Fatalf("StartService() error = %v", err)
+	}
+	if supervised, _ := manager.SupervisedServices(ctx); !reflect.DeepEqual(supervised, []string{"ollama", "openwebui"}) {
+		t.Errorf("SupervisedServices() after a start = %v, want ollama supervised again", supervised)
+	}
+
+	if _, err := manager.StopWithDependents(ctx, "ollama"); err != nil {
+		t.Fatalf("StopWithDependents() error = %v", err)
+	}
+	if supervised, _ := manager.SupervisedServices(ctx); len(supervised) != 0 {
+		t.Errorf("SupervisedServices() after stopping ollama and its dependents = %v, want none", supervised)
+	}
+}
+
+func TestManager_RecordOperatorStopsConcurrently(t *testing.T) {
+	t.Setenv("AISTACK_STATE_DIR", t.TempDir())
+	manager := NewMockManager()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := manager.recordOperatorStops([]string{name}, true); err != nil {
+				t.Errorf("recordOperatorStops(%s) error = %v", name, err)
+			}
+		}(fmt.Sprintf("svc%d", i))
+	}
+	wg.Wait()
+
+	stops, err := loadOperatorStops()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) != 20 {
+		t.Errorf("recorded %d stops, want all 20", len(stops))
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"aistack/internal/config"
+)
+
+// OperationTimeouts holds per-operation deadlines applied to runtime and health calls
+type OperationTimeouts struct {
+	Compose     time.Duration
+	Pull        time.Duration
+	Inspect     time.Duration
+	HealthCheck time.Duration
+	StartupWait time.Duration
+	// DependencyWait bounds how long a dependency may take to turn healthy
+	DependencyWait time.Duration
+	// StatusDeadline bounds a whole status or health report across all services
+	StatusDeadline time.Duration
+	// HealthLatency grades a green probe that answers slower than this as yellow
+	HealthLatency time.Duration
+}
+
+// TimeoutsFromConfig converts the timeouts config section into durations
+func TimeoutsFromConfig(cfg config.TimeoutsConfig) OperationTimeouts {
+	return OperationTimeouts{
+		Compose:        time.Duration(cfg.ComposeSeconds) * time.Second,
+		Pull:           time.Duration(cfg.PullSeconds) * time.Second,
+		Inspect:        time.Duration(cfg.InspectSeconds) * time.Second,
+		HealthCheck:    time.Duration(cfg.HealthCheckSeconds) * time.Second,
+		StartupWait:    time.Duration(cfg.StartupWaitSeconds) * time.Second,
+		DependencyWait: time.Duration(cfg.DependencyWaitSeconds) * time.Second,
+		StatusDeadline: time.Duration(cfg.StatusDeadlineSeconds) * time.Second,
+		HealthLatency:  time.Duration(cfg.HealthLatencyMs) * time.Millisecond,
+	}
+}
+
+// DefaultOperationTimeouts returns the timeouts of the default configuration
+func DefaultOperationTimeouts() OperationTimeouts {
+	return TimeoutsFromConfig(config.DefaultConfig().Timeouts)
+}
+
+// rollbackBudget bounds the detached context used to roll back a cancelled update:
+// stop + retag + start + startup wait + health check
+func (t OperationTimeouts) rollbackBudget() time.Duration {
+	return 2*t.Compose + t.Inspect + t.StartupWait + t.HealthCheck
+}
+
+// timeoutConfigurable is implemented by services that accept operation timeouts
+type timeoutConfigurable interface {
+	SetTimeouts(timeouts OperationTimeouts)
+}
+
+// withTimeout derives a context bounded by d; a non-positive d leaves ctx unchanged
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// timeoutRuntime decorates a Runtime with per-operation deadlines
+type timeoutRuntime struct {
+	inner    Runtime
+	timeouts OperationTimeouts
+}
+
+// WithTimeouts wraps runtime so every call is bounded by the matching deadline
+func WithTimeouts(runtime Runtime, timeouts OperationTimeouts) Runtime {
+	return &timeoutRuntime{inner: runtime, timeouts: timeouts}
+}
+
+func (r *timeoutRuntime) ComposeUp(ctx context.Context, composeFile string, services ...string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Compose)
+	defer cancel()
+	return r.inner.ComposeUp(ctx, composeFile, services...)
+}
+
+func (r *timeoutRuntime) ComposeDown(ctx context.Context, composeFile string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Compose)
+	defer cancel()
+	return r.inner.ComposeDown(ctx, composeFile)
+}
+
+func (r *timeoutRuntime) IsRunning(ctx context.Context) bool {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.IsRunning(ctx)
+}
+
+func (r *timeoutRuntime) CreateNetwork(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.CreateNetwork(ctx, name)
+}
+
+func (r *timeoutRuntime) CreateVolume(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.CreateVolume(ctx, name)
+}
+
+func (r *timeoutRuntime) GetContainerStatus(ctx context.Context, name string) (string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.GetContainerStatus(ctx, name)
+}
+
+func (r *timeoutRuntime) PullImage(ctx context.Context, image string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Pull)
+	defer cancel()
+	return r.inner.PullImage(ctx, image)
+}
+
+func (r *timeoutRuntime) GetImageID(ctx context.Context, image string) (string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.GetImageID(ctx, image)
+}
+
+func (r *timeoutRuntime) GetImageDigest(ctx context.Context, image string) (string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.GetImageDigest(ctx, image)
+}
+
+func (r *timeoutRuntime) GetContainerImageID(ctx context.Context, name string) (string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.GetContainerImageID(ctx, name)
+}
+
+func (r *timeoutRuntime) GetContainerLogs(ctx context.Context, name string, tail int) (string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.GetContainerLogs(ctx, name, tail)
+}
+
+func (r *timeoutRuntime) RemoveVolume(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.RemoveVolume(ctx, name)
+}
+
+func (r *timeoutRuntime) RemoveContainer(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.RemoveContainer(ctx, name)
+}
+
+func (r *timeoutRuntime) TagImage(ctx context.Context, source, target string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.TagImage(ctx, source, target)
+}
+
+func (r *timeoutRuntime) StopContainer(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Compose)
+	defer cancel()
+	return r.inner.StopContainer(ctx, name)
+}
+
+func (r *timeoutRuntime) StartContainer(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Compose)
+	defer cancel()
+	return r.inner.StartContainer(ctx, name)
+}
+
+func (r *timeoutRuntime) RenameContainer(ctx context.Context, name, newName string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.RenameContainer(ctx, name, newName)
+}
+
+func (r *timeoutRuntime) VolumeExists(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.VolumeExists(ctx, name)
+}
+
+func (r *timeoutRuntime) RemoveNetwork(ctx context.Context, name string) error {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.RemoveNetwork(ctx, name)
+}
+
+func (r *timeoutRuntime) IsContainerRunning(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.IsContainerRunning(ctx, name)
+}
+
+func (r *timeoutRuntime) ContainerByPort(ctx context.Context, port int) (string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.ContainerByPort(ctx, port)
+}
+
+func (r *timeoutRuntime) GetContainerLimits(ctx context.Context, name string) (ContainerLimits, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.GetContainerLimits(ctx, name)
+}
+
+func (r *timeoutRuntime) ExecInContainer(ctx context.Context, name string, command []string) (ExecResult, error) {
+	ctx, cancel := withTimeout(ctx, r.timeouts.Inspect)
+	defer cancel()
+	return r.inner.ExecInContainer(ctx, name, command)
+}
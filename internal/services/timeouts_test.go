@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+)
+
+// blockingRuntime blocks image pulls until the context is done
+type blockingRuntime struct {
+	*MockRuntime
+}
+
+func (b *blockingRuntime) PullImage(ctx context.Context, image string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWithTimeouts_BoundsOperation(t *testing.T) {
+	runtime := WithTimeouts(&blockingRuntime{MockRuntime: NewMockRuntime()}, OperationTimeouts{Pull: 20 * time.Millisecond})
+
+	start := time.Now()
+	err := runtime.PullImage(context.Background(), "ollama/ollama:latest")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Pull was not bounded by timeout, took %v", elapsed)
+	}
+}
+
+func TestWithTimeouts_ZeroMeansUnbounded(t *testing.T) {
+	runtime := WithTimeouts(NewMockRuntime(), OperationTimeouts{})
+
+	if err := runtime.CreateNetwork(context.Background(), AistackNetwork); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestTimeoutsFromConfig(t *testing.T) {
+	timeouts := TimeoutsFromConfig(config.TimeoutsConfig{
+		ComposeSeconds:     60,
+		PullSeconds:        600,
+		InspectSeconds:     5,
+		HealthCheckSeconds: 3,
+		StartupWaitSeconds: 2,
+	})
+
+	if timeouts.Compose != time.Minute || timeouts.Pull != 10*time.Minute {
+		t.Errorf("Unexpected compose/pull timeouts: %+v", timeouts)
+	}
+	if timeouts.Inspect != 5*time.Second || timeouts.HealthCheck != 3*time.Second || timeouts.StartupWait != 2*time.Second {
+		t.Errorf("Unexpected inspect/health/startup timeouts: %+v", timeouts)
+	}
+}
+
+func TestSleepContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Outcomes of an update check
+const (
+	UpdateCheckUpToDate     = "up-to-date"       // the local image matches the remote digest
+	UpdateCheckAvailable    = "update-available" // an update would install another image
+	UpdateCheckNotInstalled = "not-installed"    // no local image yet
+	UpdateCheckUnknown      = "unknown"          // a digest could not be determined (see Error)
+)
+
+// DigestResolver looks up the registry digest of an image reference without pulling it
+// (registry.Client)
+type DigestResolver interface {
+	Digest(ctx context.Context, image string) (string, error)
+}
+
+// UpdateCheck compares a service's local image with what an update would install
+type UpdateCheck struct {
+	Service      string `json:"service"`
+	Image        string `json:"image"`  // reference an update pulls
+	Pinned       bool   `json:"pinned"` // versions.lock pins the reference
+	LocalImageID string `json:"local_image_id,omitempty"`
+	LocalDigest  string `json:"local_digest,omitempty"`
+	RemoteDigest string `json:"remote_digest,omitempty"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+}
+
+// CheckUpdate compares the local image with the remote digest of the reference an
+// update would pull; a versions.lock digest pin is the remote digest itself
+func (u *ServiceUpdater) CheckUpdate(ctx context.Context, resolver DigestResolver) UpdateCheck {
+	check := UpdateCheck{Service: u.service.Name(), Image: u.imageName}
+	ref, err := u.resolveImageReference()
+	if err != nil {
+		check.Status, check.Error = UpdateCheckUnknown, err.Error()
+		return check
+	}
+	check.Image = ref.PullRef
+	check.Pinned = ref.PullRef != ref.TagRef
+
+	if imageID, err := u.runtime.GetImageID(ctx, ref.TagRef); err == nil {
+		check.LocalImageID = imageID
+		if digest, err := u.runtime.GetImageDigest(ctx, ref.TagRef); err == nil {
+			check.LocalDigest = digest
+		}
+	}
+
+	if _, digest, ok := strings.Cut(ref.PullRef, "@"); ok {
+		check.RemoteDigest = digest
+	} else if check.RemoteDigest, err = resolver.Digest(ctx, ref.PullRef); err != nil {
+		check.Status, check.Error = UpdateCheckUnknown, err.Error()
+		return check
+	}
+
+	switch {
+	case check.LocalImageID == "":
+		check.Status = UpdateCheckNotInstalled
+	case check.LocalDigest == "":
+		// Locally built or loaded images have no repo digest to compare
+		check.Status = UpdateCheckUnknown
+		check.Error = fmt.Sprintf("local image %s has no registry digest", ShortImageID(check.LocalImageID))
+	case check.LocalDigest == check.RemoteDigest:
+		check.Status = UpdateCheckUpToDate
+	default:
+		check.Status = UpdateCheckAvailable
+	}
+	return check
+}
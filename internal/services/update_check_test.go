@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeResolver returns fixed digests per image reference
+type fakeResolver map[string]string
+
+func (f fakeResolver) Digest(_ context.Context, image string) (string, error) {
+	if digest, ok := f[image]; ok {
+		return digest, nil
+	}
+	return "", errors.New("manifest unknown")
+}
+
+func TestServiceUpdater_CheckUpdate(t *testing.T) {
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+
+	check := updater.CheckUpdate(context.Background(), fakeResolver{"example/demo:latest": "sha256:olddigest"})
+	if check.Status != UpdateCheckUpToDate || check.LocalDigest != "sha256:olddigest" || check.Pinned {
+		t.Errorf("CheckUpdate() = %+v, want up to date", check)
+	}
+
+	check = updater.CheckUpdate(context.Background(), fakeResolver{"example/demo:latest": "sha256:newdigest"})
+	if check.Status != UpdateCheckAvailable || check.RemoteDigest != "sha256:newdigest" {
+		t.Errorf("CheckUpdate() = %+v, want an update available", check)
+	}
+	// Nothing is pulled by a check
+	if runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("image = %s, want the local image untouched", runtime.imageID)
+	}
+
+	if check = updater.CheckUpdate(context.Background(), fakeResolver{}); check.Status != UpdateCheckUnknown || check.Error == "" {
+		t.Errorf("CheckUpdate() with an unreachable registry = %+v", check)
+	}
+
+	runtime.imageID = ""
+	if check = updater.CheckUpdate(context.Background(), fakeResolver{"example/demo:latest": "sha256:newdigest"}); check.Status != UpdateCheckNotInstalled {
+		t.Errorf("CheckUpdate() without a local image = %+v", check)
+	}
+}
+
+func TestServiceUpdater_CheckUpdatePinned(t *testing.T) {
+	lock := &VersionLock{entries: map[string]string{"demo": "example/demo@sha256:olddigest"}}
+	updater, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, lock)
+
+	// The pin is the target: the registry is not asked
+	check := updater.CheckUpdate(context.Background(), fakeResolver{})
+	if check.Status != UpdateCheckUpToDate || !check.Pinned || check.Image != "example/demo@sha256:olddigest" {
+		t.Errorf("CheckUpdate() = %+v, want the pinned image up to date", check)
+	}
+
+	lock.entries["demo"] = "example/demo@sha256:pinned"
+	if check = updater.CheckUpdate(context.Background(), fakeResolver{}); check.Status != UpdateCheckAvailable || check.RemoteDigest != "sha256:pinned" {
+		t.Errorf("CheckUpdate() = %+v, want the new pin pending", check)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aistack/internal/fsutil"
+)
+
+// updateLedgerDir holds one append-only JSONL file of finished updates per service
+const updateLedgerDir = "update_history"
+
+// UpdateLedgerEntry records one finished update or rollback of a service
+type UpdateLedgerEntry struct {
+	ID            int       `json:"id"`     // 1-based position in the service's ledger
+	Action        string    `json:"action"` // update or rollback
+	Strategy      string    `json:"strategy,omitempty"`
+	Status        string    `json:"status"`         // completed, rolled_back or failed
+	Step          string    `json:"step,omitempty"` // where the operation ended, e.g. image_unchanged
+	Image         string    `json:"image"`          // tag the service runs
+	PullReference string    `json:"pull_reference,omitempty"`
+	OldImageID    string    `json:"old_image_id,omitempty"`
+	NewImageID    string    `json:"new_image_id,omitempty"`
+	OldDigest     string    `json:"old_digest,omitempty"`
+	NewDigest     string    `json:"new_digest,omitempty"`
+	Health        string    `json:"health,omitempty"` // health after the swap
+	StartedAt     time.Time `json:"started_at"`
+	CompletedAt   time.Time `json:"completed_at"`
+}
+
+// Installed reports whether the entry left the service running its new image
+func (e UpdateLedgerEntry) Installed() bool {
+	return e.Status == planStatusCompleted && e.NewImageID != "" && e.NewImageID != e.OldImageID
+}
+
+// updateLedgerPath returns the ledger file of a service
+func updateLedgerPath(stateDir, service string) string {
+	return filepath.Join(filepath.Clean(stateDir), updateLedgerDir, service+".jsonl")
+}
+
+// appendUpdateLedger records a finished plan and returns the entry with its ID
+func appendUpdateLedger(stateDir string, plan *UpdatePlan, step string) (UpdateLedgerEntry, error) {
+	entries, err := LoadUpdateLedger(plan.ServiceName, stateDir)
+	if err != nil {
+		return UpdateLedgerEntry{}, err
+	}
+
+	action := plan.Action
+	if action == "" {
+		action = planActionUpdate
+	}
+	entry := UpdateLedgerEntry{
+		ID:            len(entries) + 1,
+		Action:        action,
+		Strategy:      plan.Strategy,
+		Status:        plan.Status,
+		Step:          step,
+		Image:         plan.NewImage,
+		PullReference: plan.PullReference,
+		OldImageID:    plan.OldImageID,
+		NewImageID:    plan.NewImageID,
+		OldDigest:     plan.OldDigest,
+		NewDigest:     plan.NewDigest,
+		Health:        plan.HealthAfterSwap,
+		StartedAt:     plan.StartedAt,
+		CompletedAt:   plan.CompletedAt,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return UpdateLedgerEntry{}, fmt.Errorf("failed to marshal update ledger entry: %w", err)
+	}
+
+	path := updateLedgerPath(stateDir, plan.ServiceName)
+	if err := fsutil.EnsureStateDirectory(filepath.Dir(path)); err != nil {
+		return UpdateLedgerEntry{}, err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600) // #nosec G304 -- path is derived from internal state directory
+	if err != nil {
+		return UpdateLedgerEntry{}, fmt.Errorf("failed to open update ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return UpdateLedgerEntry{}, fmt.Errorf("failed to write update ledger: %w", err)
+	}
+	return entry, nil
+}
+
+// LoadUpdateLedger reads the update ledger of a service, oldest entry first;
+// empty when the service was never updated
+func LoadUpdateLedger(serviceName, stateDir string) ([]UpdateLedgerEntry, error) {
+	file, err := os.Open(updateLedgerPath(stateDir, serviceName)) // #nosec G304 -- path is derived from internal state directory
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read update ledger: %w", err)
+	}
+	defer file.Close()
+
+	var entries []UpdateLedgerEntry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry UpdateLedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse update ledger line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read update ledger: %w", err)
+	}
+	return entries, nil
+}
+
+// rollbackTarget picks the image a rollback restores. entryID selects the image the
+// entry installed; 0 selects the image the current one replaced.
+func rollbackTarget(entries []UpdateLedgerEntry, entryID int, currentImageID string) (UpdateLedgerEntry, string, error) {
+	if entryID != 0 {
+		for _, entry := range entries {
+			if entry.ID != entryID {
+				continue
+			}
+			if !entry.Installed() {
+				return entry, "", fmt.Errorf("entry %d did not install an image (status %s)", entryID, entry.Status)
+			}
+			return entry, entry.NewImageID, nil
+		}
+		return UpdateLedgerEntry{}, "", fmt.Errorf("no update history entry %d", entryID)
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Installed() && entry.NewImageID == currentImageID && entry.OldImageID != "" {
+			return entry, entry.OldImageID, nil
+		}
+	}
+	return UpdateLedgerEntry{}, "", fmt.Errorf("no previous image recorded for the current image %s", ShortImageID(currentImageID))
+}
+
+// ShortImageID abbreviates an image ID for display (sha256:0123456789ab)
+func ShortImageID(id string) string {
+	const length = len("sha256:") + 12
+	if len(id) > length {
+		return id[:length]
+	}
+	return id
+}
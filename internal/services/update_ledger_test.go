@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+func newLedgerTestUpdater(t *testing.T, health HealthChecker, lock *VersionLock) (*ServiceUpdater, *MockRuntime) {
+	t.Helper()
+	logger := logging.NewLogger(logging.LevelError)
+	runtime := &MockRuntime{
+		imageID:    "sha256:oldimage123",
+		newImageID: "sha256:newimage456",
+		imageDigests: map[string]string{
+			"sha256:oldimage123": "sha256:olddigest",
+			"sha256:newimage456": "sha256:newdigest",
+		},
+	}
+	base := &BaseService{name: "demo", runtime: runtime, logger: logger}
+	updater := NewServiceUpdater(base, runtime, "example/demo:latest", health, logger, t.TempDir(), lock)
+	updater.SetTimeouts(OperationTimeouts{HealthCheck: time.Second})
+	return updater, runtime
+}
+
+func TestUpdateLedger_RecordsEveryUpdate(t *testing.T) {
+	updater, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := updater.Update(context.Background()); err != nil {
+			t.Fatalf("Update() #%d error = %v", i+1, err)
+		}
+	}
+
+	entries, err := updater.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("History() = %+v, want 2 entries", entries)
+	}
+
+	first := entries[0]
+	if first.ID != 1 || first.Action != planActionUpdate || first.Status != planStatusCompleted || !first.Installed() {
+		t.Errorf("entry 1 = %+v", first)
+	}
+	if first.OldDigest != "sha256:olddigest" || first.NewDigest != "sha256:newdigest" || first.Health != string(HealthGreen) {
+		t.Errorf("entry 1 digests/health = %+v", first)
+	}
+	if second := entries[1]; second.ID != 2 || second.Installed() || second.Health != healthStatusUnchanged || second.Step != "image_unchanged" {
+		t.Errorf("entry 2 = %+v", second)
+	}
+}
+
+func TestServiceUpdater_RollbackTo(t *testing.T) {
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an entry the image the update replaced comes back
+	plan, err := updater.RollbackTo(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("RollbackTo(0) error = %v", err)
+	}
+	if runtime.imageID != "sha256:oldimage123" || plan.Status != planStatusCompleted || plan.NewDigest != "sha256:olddigest" {
+		t.Errorf("after rollback image = %s, plan = %+v", runtime.imageID, plan)
+	}
+
+	// Entry 1 installed the new image: rolling "back" to it moves forward again
+	if _, err := updater.RollbackTo(context.Background(), 1); err != nil {
+		t.Fatalf("RollbackTo(1) error = %v", err)
+	}
+	if runtime.imageID != "sha256:newimage456" {
+		t.Errorf("image = %s, want the image entry 1 installed", runtime.imageID)
+	}
+	if _, err := updater.RollbackTo(context.Background(), 1); err == nil || !strings.Contains(err.Error(), "already runs") {
+		t.Errorf("RollbackTo() to the current image error = %v", err)
+	}
+
+	entries, _ := updater.History()
+	if len(entries) != 3 || entries[1].Action != planActionRollback || entries[2].Action != planActionRollback {
+		t.Errorf("History() = %+v", entries)
+	}
+}
+
+func TestServiceUpdater_RollbackToUnhealthyImageRestoresCurrent(t *testing.T) {
+	health := &sequenceHealthCheck{}
+	updater, runtime := newLedgerTestUpdater(t, health, nil)
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The old image is red, the restored current image green again
+	health.results, health.calls = []HealthStatus{HealthRed}, 0
+	plan, err := updater.RollbackTo(context.Background(), 0)
+	if err == nil || !strings.Contains(err.Error(), "current image restored") {
+		t.Fatalf("RollbackTo() error = %v", err)
+	}
+	if runtime.imageID != "sha256:newimage456" {
+		t.Errorf("image = %s, want the current image restored", runtime.imageID)
+	}
+	if plan.Status != planStatusRolledBack || plan.HealthAfterSwap != string(HealthRed) {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+func TestServiceUpdater_RollbackToErrors(t *testing.T) {
+	updater, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+	if _, err := updater.RollbackTo(context.Background(), 0); err == nil || !strings.Contains(err.Error(), "no previous image recorded") {
+		t.Errorf("RollbackTo() without history error = %v", err)
+	}
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := updater.RollbackTo(context.Background(), 2); err == nil || !strings.Contains(err.Error(), "did not install an image") {
+		t.Errorf("RollbackTo() to an unchanged entry error = %v", err)
+	}
+	if _, err := updater.RollbackTo(context.Background(), 9); err == nil || !strings.Contains(err.Error(), "no update history entry 9") {
+		t.Errorf("RollbackTo() to a missing entry error = %v", err)
+	}
+
+	lock := &VersionLock{entries: map[string]string{"demo": "example/demo@sha256:abc"}}
+	pinned, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, lock)
+	if _, err := pinned.RollbackTo(context.Background(), 0); err == nil || !strings.Contains(err.Error(), "versions.lock") {
+		t.Errorf("RollbackTo() of a pinned service error = %v", err)
+	}
+}
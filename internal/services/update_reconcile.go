@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+)
+
+// PendingUpdates returns the services whose last update or rollback plan is still
+// pending: the operation is running, or the process running it died
+func PendingUpdates(stateDir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(filepath.Clean(stateDir), "*_update_plan.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list update plans: %w", err)
+	}
+
+	var pending []string
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), "_update_plan.json")
+		plan, err := LoadUpdatePlan(name, stateDir)
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil && plan.Status == planStatusPending {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+// Reconcile settles an update or rollback that a crashed process left pending. When
+// the service runs the plan's new image and is healthy the plan completes; otherwise
+// the old image is restored. The outcome is saved in the plan and the update ledger.
+// It returns nil when nothing is pending or the operation still runs in another process.
+func (u *ServiceUpdater) Reconcile(ctx context.Context) (*UpdatePlan, error) {
+	name := u.service.Name()
+	plan, err := LoadUpdatePlan(name, u.stateDir)
+	if err != nil || plan == nil || plan.Status != planStatusPending {
+		return nil, err
+	}
+
+	unlock, err := u.lock()
+	if errors.Is(err, fsutil.ErrLocked) {
+		u.logger.Debug("service.update.reconcile.busy", "Update still running in another process", map[string]interface{}{
+			"service": name,
+		})
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// The operation may have finished while we took the lock
+	plan, err = LoadUpdatePlan(name, u.stateDir)
+	if err != nil || plan == nil || plan.Status != planStatusPending {
+		return nil, err
+	}
+
+	u.logger.Warn("service.update.reconcile", "Found an interrupted update, reconciling", map[string]interface{}{
+		"service":      name,
+		"action":       plan.Action,
+		"strategy":     plan.Strategy,
+		"started_at":   plan.StartedAt,
+		"old_image_id": plan.OldImageID,
+		"new_image_id": plan.NewImageID,
+	})
+
+	if health, ok := u.runsNewImage(ctx, plan); ok {
+		if plan.Strategy == config.UpdateStrategyBlueGreen {
+			if err := u.finishBlueGreenCutover(ctx); err != nil {
+				return plan, u.settlePlan(plan, planStatusFailed, "reconciled_cutover_failed",
+					fmt.Errorf("failed to finish the cutover of %s: %w", name, err))
+			}
+		}
+		plan.HealthAfterSwap = string(health)
+		return plan, u.settlePlan(plan, planStatusCompleted, "reconciled_completed", nil)
+	}
+
+	if plan.OldImageID == "" {
+		return plan, u.settlePlan(plan, planStatusFailed, "reconciled_no_previous_image",
+			fmt.Errorf("interrupted update of %s left no previous image to restore", name))
+	}
+
+	if err := u.restoreInterrupted(ctx, plan); err != nil {
+		return plan, u.settlePlan(plan, planStatusFailed, "reconciled_restore_failed",
+			fmt.Errorf("failed to restore %s after an interrupted update: %w", name, err))
+	}
+	return plan, u.settlePlan(plan, planStatusRolledBack, "reconciled_rolled_back", nil)
+}
+
+// runsNewImage reports whether the live container runs the plan's new image and is
+// not red, i.e. the interrupted operation got as far as it needed to
+func (u *ServiceUpdater) runsNewImage(ctx context.Context, plan *UpdatePlan) (HealthStatus, bool) {
+	live := "aistack-" + u.service.Name()
+	if plan.NewImageID == "" {
+		return "", false
+	}
+	if running, _ := u.runtime.IsContainerRunning(ctx, live); !running {
+		return "", false
+	}
+	if imageID, err := u.runtime.GetContainerImageID(ctx, live); err != nil || imageID != plan.NewImageID {
+		return "", false
+	}
+	health, err := u.checkHealth(ctx)
+	return health, err == nil && health != HealthRed
+}
+
+// restoreInterrupted brings the service back to the plan's old image. Before a new
+// image ID was recorded the service was never restarted, so only the tag is restored.
+func (u *ServiceUpdater) restoreInterrupted(ctx context.Context, plan *UpdatePlan) error {
+	if plan.NewImageID == "" {
+		return u.runtime.TagImage(ctx, plan.OldImageID, plan.NewImage)
+	}
+
+	if plan.Strategy == config.UpdateStrategyBlueGreen {
+		if err := u.discardBlueGreenLeftovers(ctx); err != nil {
+			return err
+		}
+	}
+
+	// A container that still runs the old image (blue-green, or a crash before the
+	// restart) only needs to run again; otherwise the service is recreated
+	live := "aistack-" + u.service.Name()
+	if imageID, err := u.runtime.GetContainerImageID(ctx, live); err != nil || imageID != plan.OldImageID {
+		return u.Rollback(ctx, plan)
+	}
+	if err := u.runtime.TagImage(ctx, plan.OldImageID, plan.NewImage); err != nil {
+		return fmt.Errorf("failed to retag image: %w", err)
+	}
+	if running, _ := u.runtime.IsContainerRunning(ctx, live); !running {
+		if err := u.runtime.StartContainer(ctx, live); err != nil {
+			return err
+		}
+		if err := sleepContext(ctx, u.timeouts.StartupWait); err != nil {
+			return err
+		}
+	}
+
+	health, err := u.checkHealth(ctx)
+	plan.HealthAfterSwap = string(health)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	if health == HealthRed {
+		return fmt.Errorf("health check failed: service is %s", health)
+	}
+	return nil
+}
+
+// finishBlueGreenCutover completes a blue-green cutover whose new container took over
+// before the process died: the service stays in the candidate's project and the
+// replaced container is removed
+func (u *ServiceUpdater) finishBlueGreenCutover(ctx context.Context) error {
+	renderable, ok := u.service.(composeRenderable)
+	if !ok || renderable.ComposeRenderer() == nil {
+		return nil
+	}
+	renderer := renderable.ComposeRenderer()
+
+	candidateFile := renderer.candidateFile()
+	if _, err := os.Stat(candidateFile); err == nil {
+		project, err := renderedProject(candidateFile)
+		if err != nil {
+			return err
+		}
+		if err := renderer.setProject(project); err != nil {
+			return err
+		}
+	}
+	u.retireReplaced(ctx)
+	return nil
+}
+
+// discardBlueGreenLeftovers removes the candidate of an interrupted blue-green update
+// (after the cutover: the new live container) and gives the name back to the
+// replaced container
+func (u *ServiceUpdater) discardBlueGreenLeftovers(ctx context.Context) error {
+	renderable, ok := u.service.(composeRenderable)
+	if !ok || renderable.ComposeRenderer() == nil {
+		return nil
+	}
+	renderer := renderable.ComposeRenderer()
+	name := u.service.Name()
+	live, previous := "aistack-"+name, previousContainerName(name)
+
+	candidateFile := renderer.candidateFile()
+	if _, err := os.Stat(candidateFile); err == nil {
+		candidateProject, err := renderedProject(candidateFile)
+		if err != nil {
+			return err
+		}
+		if err := u.runtime.ComposeDown(ctx, candidateFile); err != nil {
+			return fmt.Errorf("failed to remove candidate: %w", err)
+		}
+		u.removeCandidateFile()
+
+		// The cutover had moved the service into the candidate's project
+		current, err := renderer.project()
+		if err != nil {
+			return err
+		}
+		if current == candidateProject {
+			if err := renderer.setProject(renderer.alternateProject(current)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The cutover renamed the live container before promoting the candidate
+	if _, err := u.runtime.GetContainerStatus(ctx, live); err != nil {
+		if _, err := u.runtime.GetContainerStatus(ctx, previous); err == nil {
+			if err := u.runtime.RenameContainer(ctx, previous, live); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeCandidateFile deletes the rendered blue-green candidate, if any
+func (u *ServiceUpdater) removeCandidateFile() {
+	renderable, ok := u.service.(composeRenderable)
+	if !ok || renderable.ComposeRenderer() == nil {
+		return
+	}
+	if err := os.Remove(renderable.ComposeRenderer().candidateFile()); err != nil && !os.IsNotExist(err) {
+		u.logger.Debug("service.update.candidate_cleanup_failed", "Failed to remove candidate compose file", map[string]interface{}{
+			"service": u.service.Name(),
+			"error":   err.Error(),
+		})
+	}
+}
+
+// settlePlan records the outcome of a reconciled plan and returns cause
+func (u *ServiceUpdater) settlePlan(plan *UpdatePlan, status, step string, cause error) error {
+	now := time.Now()
+	plan.Status = status
+	plan.CompletedAt = now
+	plan.ReconciledAt = now
+	u.persistPlan(plan, step)
+
+	fields := map[string]interface{}{
+		"service": u.service.Name(),
+		"status":  status,
+		"health":  plan.HealthAfterSwap,
+	}
+	if cause != nil {
+		fields["error"] = cause.Error()
+		u.logger.Error("service.update.reconcile.failed", "Interrupted update could not be settled", fields)
+		return cause
+	}
+	u.logger.Info("service.update.reconciled", "Interrupted update settled", fields)
+	return nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+)
+
+// interruptedPlan saves a pending plan as a process that died after the pull would leave it
+func interruptedPlan(t *testing.T, updater *ServiceUpdater, strategy string) *UpdatePlan {
+	t.Helper()
+	plan := &UpdatePlan{
+		ServiceName: updater.service.Name(),
+		Action:      planActionUpdate,
+		OldImageID:  "sha256:oldimage123",
+		NewImage:    "example/demo:latest",
+		NewImageID:  "sha256:newimage456",
+		StartedAt:   time.Now().Add(-time.Hour),
+		Status:      planStatusPending,
+		Strategy:    strategy,
+	}
+	if err := updater.savePlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	return plan
+}
+
+func TestServiceUpdater_ReconcileRestoresOldImage(t *testing.T) {
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+	interruptedPlan(t, updater, config.UpdateStrategyRecreate)
+	// Died between Stop and Start: the container is gone, the tag points at the new image
+	runtime.imageID = "sha256:newimage456"
+	runtime.missingContainers = map[string]bool{"aistack-demo": true}
+
+	plan, err := updater.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if plan == nil || plan.Status != planStatusRolledBack || plan.ReconciledAt.IsZero() {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("image = %s, want the old image restored", runtime.imageID)
+	}
+
+	entries, _ := updater.History()
+	if len(entries) != 1 || entries[0].Status != planStatusRolledBack || entries[0].Step != "reconciled_rolled_back" {
+		t.Errorf("History() = %+v", entries)
+	}
+
+	// Settled plans are left alone
+	if plan, err := updater.Reconcile(context.Background()); plan != nil || err != nil {
+		t.Errorf("second Reconcile() = %+v, %v", plan, err)
+	}
+}
+
+func TestServiceUpdater_ReconcileCompletesHealthyNewImage(t *testing.T) {
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+	interruptedPlan(t, updater, config.UpdateStrategyRecreate)
+	// Died during the health check: the new container runs
+	runtime.imageID = "sha256:newimage456"
+	runtime.containerStatuses = map[string]ServiceStatus{"aistack-demo": {State: serviceStateRunning}}
+
+	plan, err := updater.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if plan.Status != planStatusCompleted || plan.HealthAfterSwap != string(HealthGreen) {
+		t.Errorf("plan = %+v", plan)
+	}
+	if runtime.imageID != "sha256:newimage456" {
+		t.Errorf("image = %s, want the new image kept", runtime.imageID)
+	}
+}
+
+func TestServiceUpdater_ReconcileSkipsRunningUpdate(t *testing.T) {
+	updater, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+	interruptedPlan(t, updater, config.UpdateStrategyRecreate)
+
+	unlock, err := fsutil.TryLock(filepath.Join(updater.stateDir, "demo_update.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	if plan, err := updater.Reconcile(context.Background()); plan != nil || err != nil {
+		t.Errorf("Reconcile() during an update = %+v, %v", plan, err)
+	}
+	if err := updater.Update(context.Background()); !errors.Is(err, fsutil.ErrLocked) {
+		t.Errorf("concurrent Update() error = %v, want ErrLocked", err)
+	}
+	if plan, _ := LoadUpdatePlan("demo", updater.stateDir); plan.Status != planStatusPending {
+		t.Errorf("plan = %+v, want it left pending", plan)
+	}
+}
+
+func TestServiceUpdater_ReconcileBlueGreenAfterCutover(t *testing.T) {
+	updater, runtime, renderer, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+	interruptedPlan(t, updater, config.UpdateStrategyBlueGreen)
+
+	// Died after the cutover moved the service: the live container was renamed to
+	// -previous and the candidate's project took over
+	candidateFile, err := renderer.writeCandidate(41234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renderer.setProject("aistack-demo-green"); err != nil {
+		t.Fatal(err)
+	}
+	runtime.imageID = "sha256:newimage456"
+	runtime.containerStatuses = nil
+	runtime.missingContainers = map[string]bool{"aistack-demo": true}
+	runtime.containerImages = map[string]string{"aistack-demo": "sha256:oldimage123"}
+
+	plan, err := updater.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if plan.Status != planStatusRolledBack {
+		t.Errorf("plan = %+v", plan)
+	}
+	if want := []string{"down " + candidateFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+	if want := []string{"rename aistack-demo-previous aistack-demo", "start aistack-demo"}; !reflect.DeepEqual(runtime.containerOps, want) {
+		t.Errorf("container ops = %v, want %v", runtime.containerOps, want)
+	}
+	if project, _ := renderer.project(); project != "" {
+		t.Errorf("project = %q, want the previous container's project", project)
+	}
+	if runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("image = %s, want the old image retagged", runtime.imageID)
+	}
+	if pending, _ := PendingUpdates(stateDir); len(pending) != 0 {
+		t.Errorf("PendingUpdates() = %v", pending)
+	}
+}
+
+func TestServiceUpdater_ReconcileBlueGreenPromoted(t *testing.T) {
+	updater, runtime, renderer, _ := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+	interruptedPlan(t, updater, config.UpdateStrategyBlueGreen)
+
+	// Died after the candidate took over the name, before the project was switched:
+	// the new image serves, the replaced container still runs as -previous
+	candidateFile, err := renderer.writeCandidate(41234)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runtime.imageID = "sha256:newimage456"
+
+	plan, err := updater.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if plan.Status != planStatusCompleted {
+		t.Errorf("plan = %+v", plan)
+	}
+	if project, _ := renderer.project(); project != "aistack-demo-green" {
+		t.Errorf("project = %q, want the candidate's project", project)
+	}
+	if want := []string{"stop aistack-demo-previous"}; !reflect.DeepEqual(runtime.containerOps, want) {
+		t.Errorf("container ops = %v, want %v", runtime.containerOps, want)
+	}
+	if want := []string{"aistack-demo-previous", "aistack-demo-next-front"}; !reflect.DeepEqual(runtime.RemovedContainers, want) {
+		t.Errorf("removed containers = %v, want %v", runtime.RemovedContainers, want)
+	}
+	if _, err := os.Stat(candidateFile); !os.IsNotExist(err) {
+		t.Error("Expected the candidate compose file to be removed")
+	}
+}
+
+func TestPendingUpdates(t *testing.T) {
+	updater, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, nil)
+	if pending, err := PendingUpdates(updater.stateDir); err != nil || len(pending) != 0 {
+		t.Errorf("PendingUpdates() without plans = %v, %v", pending, err)
+	}
+
+	interruptedPlan(t, updater, config.UpdateStrategyRecreate)
+	pending, err := PendingUpdates(updater.stateDir)
+	if err != nil || !reflect.DeepEqual(pending, []string{"demo"}) {
+		t.Errorf("PendingUpdates() = %v, %v", pending, err)
+	}
+
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := PendingUpdates(updater.stateDir); len(pending) != 0 {
+		t.Errorf("PendingUpdates() after an update = %v", pending)
+	}
+}
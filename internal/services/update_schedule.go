@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/fsutil"
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+	"aistack/internal/models"
+	"aistack/internal/schedule"
+)
+
+// UpdateScheduleFileName is the scheduled update state file in the state directory
+const UpdateScheduleFileName = "update_schedule.json"
+
+// updateScheduleLockName keeps the agent and the systemd timer from running the same activation twice
+const updateScheduleLockName = "update_schedule.lock"
+
+// Outcomes of a scheduled update
+const (
+	ScheduledRunning   = "running"   // still running, or interrupted when it stays
+	ScheduledCompleted = "completed" // every service updated or unchanged
+	ScheduledPartial   = "partial"   // some services failed or rolled back
+	ScheduledFailed    = "failed"    // no service updated
+	ScheduledSkipped   = "skipped"   // updates.mode is pinned
+)
+
+// ScheduledRun records one scheduled update-all
+type ScheduledRun struct {
+	ScheduledFor time.Time        `json:"scheduled_for"`
+	Started      time.Time        `json:"started"`
+	Finished     time.Time        `json:"finished,omitempty"`
+	Outcome      string           `json:"outcome"`
+	Reason       string           `json:"reason,omitempty"`
+	Result       *UpdateAllResult `json:"result,omitempty"`
+}
+
+// ScheduleDeferral explains why a due update has not run yet
+type ScheduleDeferral struct {
+	Since  time.Time `json:"since"`
+	Reason string    `json:"reason"`
+}
+
+// UpdateScheduleState is persisted in UpdateScheduleFileName
+type UpdateScheduleState struct {
+	LastScheduled time.Time         `json:"last_scheduled"` // latest activation handled
+	Deferred      *ScheduleDeferral `json:"deferred,omitempty"`
+	LastRun       *ScheduledRun     `json:"last_run,omitempty"`
+}
+
+// UpdateAllRunner runs update-all (implemented by Manager)
+type UpdateAllRunner interface {
+	UpdateAllServices(ctx context.Context) (*UpdateAllResult, error)
+}
+
+// UpdateScheduler runs update-all at the activations of updates.schedule. A due
+// run waits until the maintenance window is open, the GPU lock is free and no
+// model download is running.
+type UpdateScheduler struct {
+	updater  UpdateAllRunner
+	cron     *schedule.Cron
+	window   *schedule.Window
+	location *time.Location
+	pinned   bool
+	stateDir string
+	gpuLock  *gpulock.Manager
+	logger   *logging.Logger
+	now      func() time.Time
+}
+
+// NewUpdateScheduler creates the scheduler for the updates config section; nil
+// when no schedule is configured
+func NewUpdateScheduler(cfg config.UpdatesConfig, updater UpdateAllRunner, stateDir string, logger *logging.Logger) (*UpdateScheduler, error) {
+	if cfg.Schedule == "" {
+		return nil, nil
+	}
+
+	cron, err := schedule.ParseCron(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid updates.schedule: %w", err)
+	}
+	location := time.Local
+	if cfg.Timezone != "" {
+		if location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid updates.timezone: %w", err)
+		}
+	}
+	window, err := schedule.ParseWindow(cfg.Window.Start, cfg.Window.End, cfg.Window.Days)
+	if err != nil {
+		return nil, fmt.Errorf("invalid updates.window: %w", err)
+	}
+
+	return &UpdateScheduler{
+		updater:  updater,
+		cron:     cron,
+		window:   window,
+		location: location,
+		pinned:   cfg.Mode == "pinned",
+		stateDir: stateDir,
+		gpuLock:  gpulock.NewManager(stateDir, logger),
+		logger:   logger,
+		now:      time.Now,
+	}, nil
+}
+
+// Next returns the first activation after t in the schedule's time zone
+func (s *UpdateScheduler) Next(t time.Time) time.Time {
+	return s.cron.Next(t.In(s.location))
+}
+
+// Window returns the maintenance window (nil: always open)
+func (s *UpdateScheduler) Window() *schedule.Window {
+	return s.window
+}
+
+// RunDue runs update-all when an activation is due and records the outcome.
+// Missed activations collapse into one run; a blocked run is deferred and
+// retried on the next call. It returns the recorded run, or nil when nothing ran.
+func (s *UpdateScheduler) RunDue(ctx context.Context) (*ScheduledRun, error) {
+	unlock, err := s.lock()
+	if errors.Is(err, fsutil.ErrLocked) {
+		s.logger.Debug("updates.scheduled.busy", "Another process is running the scheduled update", nil)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	state, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().In(s.location)
+	if state.LastScheduled.IsZero() {
+		// First call: activations from now on count
+		state.LastScheduled = now
+		return nil, s.save(state)
+	}
+
+	due := s.cron.Next(state.LastScheduled.In(s.location))
+	if due.IsZero() || due.After(now) {
+		return nil, nil
+	}
+	for next := s.cron.Next(due); !next.IsZero() && !next.After(now); next = s.cron.Next(next) {
+		due = next
+	}
+
+	if s.pinned {
+		run := &ScheduledRun{ScheduledFor: due, Started: now, Finished: now, Outcome: ScheduledSkipped, Reason: "updates.mode is pinned"}
+		s.logger.Info("updates.scheduled.skipped", "Scheduled update skipped", map[string]interface{}{
+			"scheduled_for": due.Format(time.RFC3339),
+			"reason":        run.Reason,
+		})
+		state.LastScheduled, state.Deferred, state.LastRun = due, nil, run
+		return run, s.save(state)
+	}
+
+	if reason := s.blocker(now); reason != "" {
+		if state.Deferred != nil && state.Deferred.Reason == reason {
+			return nil, nil
+		}
+		s.logger.Info("updates.scheduled.deferred", "Scheduled update deferred", map[string]interface{}{
+			"scheduled_for": due.Format(time.RFC3339),
+			"reason":        reason,
+		})
+		since := now
+		if state.Deferred != nil {
+			since = state.Deferred.Since
+		}
+		state.Deferred = &ScheduleDeferral{Since: since, Reason: reason}
+		return nil, s.save(state)
+	}
+
+	// Recorded before running so an interrupted run is not repeated
+	run := &ScheduledRun{ScheduledFor: due, Started: now, Outcome: ScheduledRunning}
+	state.LastScheduled, state.Deferred, state.LastRun = due, nil, run
+	if err := s.save(state); err != nil {
+		return nil, err
+	}
+	s.logger.Info("updates.scheduled.started", "Starting scheduled update of all services", map[string]interface{}{
+		"scheduled_for": due.Format(time.RFC3339),
+	})
+
+	result, updateErr := s.updater.UpdateAllServices(ctx)
+	run.Finished = s.now().In(s.location)
+	run.Result = result
+	run.Outcome, run.Reason = scheduledOutcome(result, updateErr)
+
+	fields := map[string]interface{}{
+		"scheduled_for": due.Format(time.RFC3339),
+		"duration":      run.Finished.Sub(run.Started).Round(time.Second).String(),
+	}
+	if run.Reason != "" {
+		fields["reason"] = run.Reason
+	}
+	switch run.Outcome {
+	case ScheduledCompleted:
+		s.logger.Info("updates.scheduled.completed", "Scheduled update completed", fields)
+	case ScheduledPartial:
+		s.logger.Warn("updates.scheduled.partial", "Scheduled update partially failed", fields)
+	default:
+		s.logger.Error("updates.scheduled.failed", "Scheduled update failed", fields)
+	}
+
+	return run, s.save(state)
+}
+
+// blocker returns why an update cannot run now, or "" when it can
+func (s *UpdateScheduler) blocker(now time.Time) string {
+	if !s.window.Contains(now) {
+		return fmt.Sprintf("outside maintenance window %s", s.window)
+	}
+
+	// LocalAI holds the lock for as long as it runs (or crashed without a stop), so
+	// only a lock within its lease counts: a GPU switch or a service that just started
+	locked, err := s.gpuLock.IsLocked()
+	if err != nil {
+		return fmt.Sprintf("GPU lock unreadable: %v", err)
+	}
+	if locked {
+		holder := "unknown"
+		if info, err := s.gpuLock.GetStatus(); err == nil {
+			holder = info.Holder.String()
+		}
+		return fmt.Sprintf("GPU lock held by %s", holder)
+	}
+
+	downloads, err := models.ActiveDownloads(s.stateDir)
+	if err != nil {
+		return fmt.Sprintf("model downloads unreadable: %v", err)
+	}
+	if len(downloads) > 0 {
+		names := make([]string, 0, len(downloads))
+		for _, download := range downloads {
+			names = append(names, download.Model)
+		}
+		return fmt.Sprintf("model download in progress: %s", strings.Join(names, ", "))
+	}
+	return ""
+}
+
+// scheduledOutcome classifies an update-all result
+func scheduledOutcome(result *UpdateAllResult, err error) (string, string) {
+	if result == nil {
+		if err == nil {
+			err = errors.New("update-all returned no result")
+		}
+		return ScheduledFailed, err.Error()
+	}
+	if result.FailedCount == 0 && result.RolledBackCount == 0 {
+		return ScheduledCompleted, ""
+	}
+
+	reason := fmt.Sprintf("%d failed, %d rolled back", result.FailedCount, result.RolledBackCount)
+	if result.SuccessfulCount+result.UnchangedCount == 0 {
+		return ScheduledFailed, reason
+	}
+	return ScheduledPartial, reason
+}
+
+// State loads the scheduled update state; empty when nothing was recorded yet
+func (s *UpdateScheduler) State() (*UpdateScheduleState, error) {
+	return LoadUpdateScheduleState(s.stateDir)
+}
+
+// LoadUpdateScheduleState reads UpdateScheduleFileName from stateDir
+func LoadUpdateScheduleState(stateDir string) (*UpdateScheduleState, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, UpdateScheduleFileName))
+	if os.IsNotExist(err) {
+		return &UpdateScheduleState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read update schedule state: %w", err)
+	}
+
+	var state UpdateScheduleState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse update schedule state: %w", err)
+	}
+	return &state, nil
+}
+
+func (s *UpdateScheduler) save(state *UpdateScheduleState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal update schedule state: %w", err)
+	}
+	if err := fsutil.EnsureStateDirectory(s.stateDir); err != nil {
+		return err
+	}
+	if err := fsutil.AtomicWriteFile(filepath.Join(s.stateDir, UpdateScheduleFileName), data, fsutil.DefaultFilePermissions, s.logger); err != nil {
+		return fmt.Errorf("failed to save update schedule state: %w", err)
+	}
+	return nil
+}
+
+// lock takes the scheduler's file lock without waiting (fsutil.ErrLocked when held)
+func (s *UpdateScheduler) lock() (func(), error) {
+	if err := fsutil.EnsureStateDirectory(s.stateDir); err != nil {
+		return nil, err
+	}
+	unlock, err := fsutil.TryLock(filepath.Join(s.stateDir, updateScheduleLockName))
+	if err != nil && !errors.Is(err, fsutil.ErrLocked) {
+		return nil, fmt.Errorf("failed to lock update schedule: %w", err)
+	}
+	return unlock, err
+}
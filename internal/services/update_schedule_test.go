@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+	"aistack/internal/models"
+)
+
+// fakeUpdateAll counts update-all runs and returns a fixed result
+type fakeUpdateAll struct {
+	runs   int
+	result *UpdateAllResult
+	err    error
+}
+
+func (f *fakeUpdateAll) UpdateAllServices(context.Context) (*UpdateAllResult, error) {
+	f.runs++
+	return f.result, f.err
+}
+
+// newTestScheduler returns a scheduler for a daily 03:00 UTC schedule with a
+// 02:00-05:00 window, whose clock starts on 2025-01-01 12:00 UTC
+func newTestScheduler(t *testing.T, cfg config.UpdatesConfig, updater UpdateAllRunner) (*UpdateScheduler, *time.Time) {
+	t.Helper()
+	if cfg.Schedule == "" {
+		cfg.Schedule = "0 3 * * *"
+	}
+	cfg.Timezone = "UTC"
+	scheduler, err := NewUpdateScheduler(cfg, updater, t.TempDir(), logging.NewLogger(logging.LevelError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return clock }
+	return scheduler, &clock
+}
+
+func runDue(t *testing.T, scheduler *UpdateScheduler) *ScheduledRun {
+	t.Helper()
+	run, err := scheduler.RunDue(context.Background())
+	if err != nil {
+		t.Fatalf("RunDue() error = %v", err)
+	}
+	return run
+}
+
+func TestNewUpdateScheduler_Disabled(t *testing.T) {
+	scheduler, err := NewUpdateScheduler(config.UpdatesConfig{Mode: "rolling"}, &fakeUpdateAll{}, t.TempDir(), logging.NewLogger(logging.LevelError))
+	if err != nil || scheduler != nil {
+		t.Errorf("NewUpdateScheduler() without schedule = %v, %v, want nil", scheduler, err)
+	}
+}
+
+func TestUpdateScheduler_RunsDueActivationOnce(t *testing.T) {
+	updater := &fakeUpdateAll{result: &UpdateAllResult{TotalServices: 2, SuccessfulCount: 1, UnchangedCount: 1}}
+	scheduler, clock := newTestScheduler(t, config.UpdatesConfig{}, updater)
+
+	if run := runDue(t, scheduler); run != nil || updater.runs != 0 {
+		t.Fatalf("Expected the first call only to record the start, got %+v", run)
+	}
+
+	// Three missed activations collapse into the latest
+	*clock = time.Date(2025, 1, 4, 3, 10, 0, 0, time.UTC)
+	run := runDue(t, scheduler)
+	if run == nil || run.Outcome != ScheduledCompleted || updater.runs != 1 {
+		t.Fatalf("RunDue() = %+v after %d runs, want one completed run", run, updater.runs)
+	}
+	if want := time.Date(2025, 1, 4, 3, 0, 0, 0, time.UTC); !run.ScheduledFor.Equal(want) {
+		t.Errorf("ScheduledFor = %v, want %v", run.ScheduledFor, want)
+	}
+
+	*clock = clock.Add(30 * time.Minute)
+	if run := runDue(t, scheduler); run != nil || updater.runs != 1 {
+		t.Errorf("Expected no second run for the same activation, got %+v", run)
+	}
+
+	state, err := scheduler.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.LastRun == nil || state.LastRun.Outcome != ScheduledCompleted || state.LastRun.Result.UnchangedCount != 1 {
+		t.Errorf("persisted state = %+v", state)
+	}
+}
+
+func TestUpdateScheduler_DefersUntilWindowOpens(t *testing.T) {
+	updater := &fakeUpdateAll{result: &UpdateAllResult{TotalServices: 1, SuccessfulCount: 1}}
+	scheduler, clock := newTestScheduler(t, config.UpdatesConfig{
+		Schedule: "@daily",
+		Window:   config.MaintenanceWindowConfig{Start: "02:00", End: "05:00"},
+	}, updater)
+	runDue(t, scheduler)
+
+	*clock = time.Date(2025, 1, 2, 0, 5, 0, 0, time.UTC)
+	if run := runDue(t, scheduler); run != nil || updater.runs != 0 {
+		t.Fatalf("Expected the midnight activation to wait for the window, got %+v", run)
+	}
+	state, _ := scheduler.State()
+	if state.Deferred == nil || !strings.Contains(state.Deferred.Reason, "outside maintenance window 02:00-05:00") {
+		t.Errorf("Deferred = %+v", state.Deferred)
+	}
+
+	*clock = time.Date(2025, 1, 2, 2, 0, 0, 0, time.UTC)
+	if run := runDue(t, scheduler); run == nil || updater.runs != 1 {
+		t.Fatal("Expected the deferred update to run when the window opens")
+	}
+	if state, _ := scheduler.State(); state.Deferred != nil {
+		t.Errorf("Expected the deferral to be cleared, got %+v", state.Deferred)
+	}
+}
+
+func TestUpdateScheduler_DefersWhileGPUBusy(t *testing.T) {
+	updater := &fakeUpdateAll{result: &UpdateAllResult{TotalServices: 1, SuccessfulCount: 1}}
+	scheduler, clock := newTestScheduler(t, config.UpdatesConfig{}, updater)
+	runDue(t, scheduler)
+	*clock = time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	lock := gpulock.NewManager(scheduler.stateDir, logging.NewLogger(logging.LevelError))
+	if err := lock.Acquire(gpulock.HolderLocalAI); err != nil {
+		t.Fatal(err)
+	}
+	runDue(t, scheduler)
+	if state, _ := scheduler.State(); state.Deferred == nil || state.Deferred.Reason != "GPU lock held by localai" {
+		t.Errorf("Deferred = %+v", state.Deferred)
+	}
+	if err := lock.Release(gpulock.HolderLocalAI); err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := models.BeginDownload(scheduler.stateDir, models.ProviderOllama, "llama3:8b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runDue(t, scheduler)
+	if state, _ := scheduler.State(); state.Deferred == nil || state.Deferred.Reason != "model download in progress: llama3:8b" {
+		t.Errorf("Deferred = %+v", state.Deferred)
+	}
+	done()
+
+	if run := runDue(t, scheduler); run == nil || updater.runs != 1 {
+		t.Errorf("Expected the update to run once the GPU is idle, got %+v", run)
+	}
+}
+
+func TestUpdateScheduler_RunsWhileLocalAIHoldsTheGPU(t *testing.T) {
+	updater := &fakeUpdateAll{result: &UpdateAllResult{TotalServices: 1, SuccessfulCount: 1}}
+	scheduler, clock := newTestScheduler(t, config.UpdatesConfig{}, updater)
+	runDue(t, scheduler)
+	*clock = time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	// LocalAI keeps the lock from its start until it is stopped, here for hours
+	held := fmt.Sprintf(`{"holder":"localai","since_ts":%q}`, time.Now().Add(-6*time.Hour).Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(scheduler.stateDir, gpulock.LockFileName), []byte(held), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := runDue(t, scheduler)
+	if run == nil || run.Outcome != ScheduledCompleted || updater.runs != 1 {
+		t.Errorf("Expected the scheduled update to complete while LocalAI runs, got %+v (runs %d)", run, updater.runs)
+	}
+}
+
+func TestUpdateScheduler_Outcomes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		result  *UpdateAllResult
+		err     error
+		outcome string
+		runs    int
+	}{
+		{"pinned", "pinned", nil, nil, ScheduledSkipped, 0},
+		{"partial", "rolling", &UpdateAllResult{TotalServices: 2, SuccessfulCount: 1, RolledBackCount: 1}, nil, ScheduledPartial, 1},
+		{"all failed", "rolling", &UpdateAllResult{TotalServices: 1, FailedCount: 1}, nil, ScheduledFailed, 1},
+		{"error", "rolling", nil, errors.New("runtime unavailable"), ScheduledFailed, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := &fakeUpdateAll{result: tt.result, err: tt.err}
+			scheduler, clock := newTestScheduler(t, config.UpdatesConfig{Mode: tt.mode}, updater)
+			runDue(t, scheduler)
+			*clock = time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+
+			run := runDue(t, scheduler)
+			if run == nil || run.Outcome != tt.outcome || updater.runs != tt.runs {
+				t.Errorf("RunDue() = %+v after %d runs, want %s after %d", run, updater.runs, tt.outcome, tt.runs)
+			}
+		})
+	}
+}
+
+func TestUpdateScheduler_SkipsWhileAnotherRunHoldsTheLock(t *testing.T) {
+	updater := &fakeUpdateAll{result: &UpdateAllResult{}}
+	scheduler, clock := newTestScheduler(t, config.UpdatesConfig{}, updater)
+	runDue(t, scheduler)
+	*clock = time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+
+	unlock, err := scheduler.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run := runDue(t, scheduler); run != nil || updater.runs != 0 {
+		t.Errorf("Expected no run while the lock is held, got %+v", run)
+	}
+	unlock()
+
+	if run := runDue(t, scheduler); run == nil || updater.runs != 1 {
+		t.Error("Expected the run after the lock was released")
+	}
+}
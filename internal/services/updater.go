@@ -1,14 +1,18 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
+	"aistack/internal/config"
 	"aistack/internal/fsutil"
 	"aistack/internal/logging"
+	"aistack/internal/signing"
 )
 
 const (
@@ -16,18 +20,26 @@ const (
 	healthStatusUnchanged = "unchanged"
 )
 
+// ErrUpdateRolledBack reports that an update was reverted to the previous image
+var ErrUpdateRolledBack = errors.New("rolled back to previous version")
+
 // UpdatePlan tracks an update operation for rollback capability
 // Story T-018: Ollama Update & Rollback (Service-specific)
 type UpdatePlan struct {
 	ServiceName     string    `json:"service_name"`
+	Action          string    `json:"action,omitempty"` // update or rollback
 	OldImageID      string    `json:"old_image_id"`
 	NewImage        string    `json:"new_image"`
 	NewImageID      string    `json:"new_image_id,omitempty"`
+	OldDigest       string    `json:"old_digest,omitempty"`
+	NewDigest       string    `json:"new_digest,omitempty"`
 	PullReference   string    `json:"pull_reference,omitempty"`
 	StartedAt       time.Time `json:"started_at"`
 	CompletedAt     time.Time `json:"completed_at,omitempty"`
 	Status          string    `json:"status"` // pending, completed, rolled_back, failed
 	HealthAfterSwap string    `json:"health_after_swap,omitempty"`
+	Strategy        string    `json:"strategy,omitempty"`      // recreate or blue-green
+	ReconciledAt    time.Time `json:"reconciled_at,omitempty"` // set when a later start settled an interrupted plan
 }
 
 // ServiceUpdater handles service updates with rollback capability
@@ -39,6 +51,9 @@ type ServiceUpdater struct {
 	imageName   string
 	healthCheck HealthChecker
 	imageLock   *VersionLock
+	trustedKeys *signing.KeyRing
+	policyErr   error
+	timeouts    OperationTimeouts
 }
 
 // NewServiceUpdater creates a new service updater
@@ -51,16 +66,32 @@ func NewServiceUpdater(service Service, runtime Runtime, imageName string, healt
 		imageName:   imageName,
 		healthCheck: healthCheck,
 		imageLock:   lock,
+		timeouts:    DefaultOperationTimeouts(),
 	}
 }
 
+// SetTimeouts overrides the startup wait and health check deadlines used during updates
+func (u *ServiceUpdater) SetTimeouts(timeouts OperationTimeouts) {
+	u.timeouts = timeouts
+}
+
 // Update performs a service update with health validation and rollback on failure
 // Story T-018: Implements update with health-gating and automatic rollback
-func (u *ServiceUpdater) Update() error {
+// Cancelling ctx after the plan is saved restores the previous image before returning.
+func (u *ServiceUpdater) Update(ctx context.Context) error {
+	unlock, err := u.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	ref, err := u.resolveImageReference()
 	if err != nil {
 		return err
 	}
+	if err := u.verifyImageTrust(); err != nil {
+		return err
+	}
 
 	u.logger.Info("service.update.start", fmt.Sprintf("Starting update for %s", u.service.Name()), map[string]interface{}{
 		"service": u.service.Name(),
@@ -71,15 +102,24 @@ func (u *ServiceUpdater) Update() error {
 	// Create update plan
 	plan := &UpdatePlan{
 		ServiceName:   u.service.Name(),
+		Action:        planActionUpdate,
 		NewImage:      ref.TagRef,
 		PullReference: ref.PullRef,
 		StartedAt:     time.Now(),
 		Status:        planStatusPending,
+		Strategy:      config.UpdateStrategyRecreate,
+	}
+	renderer := u.blueGreenRenderer()
+	if renderer != nil {
+		plan.Strategy = config.UpdateStrategyBlueGreen
 	}
 
 	// Get current image ID for rollback
-	oldImageID, err := u.runtime.GetImageID(ref.TagRef)
+	oldImageID, err := u.runtime.GetImageID(ctx, ref.TagRef)
 	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("update of %s cancelled: %w", u.service.Name(), ctx.Err())
+		}
 		// Service might not be installed yet, this is OK
 		u.logger.Warn("service.update.no_old_image", "No existing image found", map[string]interface{}{
 			"service": u.service.Name(),
@@ -87,6 +127,9 @@ func (u *ServiceUpdater) Update() error {
 		oldImageID = ""
 	}
 	plan.OldImageID = oldImageID
+	if oldImageID != "" {
+		plan.OldDigest = u.imageDigest(ctx, ref.TagRef)
+	}
 
 	// Save plan for potential rollback
 	if err = u.savePlan(plan); err != nil {
@@ -99,7 +142,10 @@ func (u *ServiceUpdater) Update() error {
 		"image":   ref.PullRef,
 	})
 
-	if err = u.runtime.PullImage(ref.PullRef); err != nil {
+	if err = u.runtime.PullImage(ctx, ref.PullRef); err != nil {
+		if ctx.Err() != nil {
+			return u.abortUpdate(ctx, plan, false)
+		}
 		plan.Status = planStatusFailed
 		plan.CompletedAt = time.Now()
 		u.persistPlan(plan, "pull_image")
@@ -107,7 +153,10 @@ func (u *ServiceUpdater) Update() error {
 	}
 
 	if ref.PullRef != ref.TagRef {
-		if err = u.runtime.TagImage(ref.PullRef, ref.TagRef); err != nil {
+		if err = u.runtime.TagImage(ctx, ref.PullRef, ref.TagRef); err != nil {
+			if ctx.Err() != nil {
+				return u.abortUpdate(ctx, plan, false)
+			}
 			plan.Status = planStatusFailed
 			plan.CompletedAt = time.Now()
 			u.persistPlan(plan, "tag_image")
@@ -116,14 +165,18 @@ func (u *ServiceUpdater) Update() error {
 	}
 
 	// Get new image ID
-	newImageID, err := u.runtime.GetImageID(ref.TagRef)
+	newImageID, err := u.runtime.GetImageID(ctx, ref.TagRef)
 	if err != nil {
+		if ctx.Err() != nil {
+			return u.abortUpdate(ctx, plan, false)
+		}
 		plan.Status = planStatusFailed
 		plan.CompletedAt = time.Now()
 		u.persistPlan(plan, "get_new_image_id")
 		return fmt.Errorf("failed to get new image ID: %w", err)
 	}
 	plan.NewImageID = newImageID
+	plan.NewDigest = u.imageDigest(ctx, ref.TagRef)
 
 	// Check if image actually changed
 	if oldImageID != "" && oldImageID == newImageID {
@@ -138,19 +191,37 @@ func (u *ServiceUpdater) Update() error {
 		return nil
 	}
 
+	if renderer != nil {
+		blocker := u.blueGreenBlocker(ctx, renderer)
+		if blocker == "" {
+			return u.updateBlueGreen(ctx, plan, renderer)
+		}
+		plan.Strategy = config.UpdateStrategyRecreate
+		u.logger.Info("service.update.blue_green_skipped", "Blue-green not possible, updating by recreate", map[string]interface{}{
+			"service": u.service.Name(),
+			"reason":  blocker,
+		})
+	}
+
 	// Restart service with new image
 	u.logger.Info("service.update.restart", "Restarting service with new image", map[string]interface{}{
 		"service": u.service.Name(),
 	})
 
-	if err = u.service.Stop(); err != nil {
+	if err = u.service.Stop(ctx); err != nil {
+		if ctx.Err() != nil {
+			return u.abortUpdate(ctx, plan, true)
+		}
 		u.logger.Warn("service.update.stop_error", "Error stopping service", map[string]interface{}{
 			"service": u.service.Name(),
 			"error":   err.Error(),
 		})
 	}
 
-	if err = u.service.Start(); err != nil {
+	if err = u.service.Start(ctx); err != nil {
+		if ctx.Err() != nil {
+			return u.abortUpdate(ctx, plan, true)
+		}
 		plan.Status = planStatusFailed
 		plan.CompletedAt = time.Now()
 		u.persistPlan(plan, "start_service")
@@ -158,14 +229,19 @@ func (u *ServiceUpdater) Update() error {
 	}
 
 	// Wait a bit for service to initialize
-	time.Sleep(5 * time.Second)
+	if err = sleepContext(ctx, u.timeouts.StartupWait); err != nil {
+		return u.abortUpdate(ctx, plan, true)
+	}
 
 	// Perform health check
 	u.logger.Info("service.update.health_check", "Performing health check", map[string]interface{}{
 		"service": u.service.Name(),
 	})
 
-	health, err := u.healthCheck.Check()
+	health, err := u.checkHealth(ctx)
+	if ctx.Err() != nil {
+		return u.abortUpdate(ctx, plan, true)
+	}
 	plan.HealthAfterSwap = string(health)
 
 	if err != nil || health == HealthRed {
@@ -175,8 +251,11 @@ func (u *ServiceUpdater) Update() error {
 			"error":   err,
 		})
 
-		// Attempt rollback
-		if rollbackErr := u.Rollback(plan); rollbackErr != nil {
+		// Attempt rollback; it runs detached so an interrupt cannot leave it half-done
+		cleanupCtx, cancel := u.cleanupContext(ctx)
+		defer cancel()
+
+		if rollbackErr := u.Rollback(cleanupCtx, plan); rollbackErr != nil {
 			plan.Status = planStatusFailed
 			plan.CompletedAt = time.Now()
 			u.persistPlan(plan, "rollback_failed")
@@ -186,7 +265,7 @@ func (u *ServiceUpdater) Update() error {
 		plan.Status = planStatusRolledBack
 		plan.CompletedAt = time.Now()
 		u.persistPlan(plan, "rollback_success")
-		return fmt.Errorf("update failed health check, rolled back to previous version")
+		return fmt.Errorf("update failed health check, %w", ErrUpdateRolledBack)
 	}
 
 	// Update succeeded
@@ -203,6 +282,64 @@ func (u *ServiceUpdater) Update() error {
 	return nil
 }
 
+// abortUpdate restores the previous image after the update context was cancelled.
+// restarted reports whether the service was already stopped or started with the new image.
+func (u *ServiceUpdater) abortUpdate(ctx context.Context, plan *UpdatePlan, restarted bool) error {
+	cause := ctx.Err()
+	u.logger.Warn("service.update.cancelled", "Update cancelled, restoring previous image", map[string]interface{}{
+		"service":   u.service.Name(),
+		"restarted": restarted,
+		"error":     cause.Error(),
+	})
+
+	if plan.OldImageID == "" {
+		plan.Status = planStatusFailed
+		plan.CompletedAt = time.Now()
+		u.persistPlan(plan, "cancelled")
+		return fmt.Errorf("update of %s cancelled: %w", u.service.Name(), cause)
+	}
+
+	cleanupCtx, cancel := u.cleanupContext(ctx)
+	defer cancel()
+
+	var rollbackErr error
+	if restarted {
+		rollbackErr = u.Rollback(cleanupCtx, plan)
+	} else {
+		// Service still runs the old container; only the tag may have moved
+		rollbackErr = u.runtime.TagImage(cleanupCtx, plan.OldImageID, plan.NewImage)
+	}
+
+	if rollbackErr != nil {
+		plan.Status = planStatusFailed
+		plan.CompletedAt = time.Now()
+		u.persistPlan(plan, "cancelled_rollback_failed")
+		return fmt.Errorf("update of %s cancelled and rollback failed: %w, rollback_err=%w", u.service.Name(), cause, rollbackErr)
+	}
+
+	plan.Status = planStatusRolledBack
+	plan.CompletedAt = time.Now()
+	u.persistPlan(plan, "cancelled_rollback_success")
+	return fmt.Errorf("update of %s cancelled: %w, %w", u.service.Name(), cause, ErrUpdateRolledBack)
+}
+
+// cleanupContext detaches from ctx cancellation but keeps a deadline for rollback work
+func (u *ServiceUpdater) cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(context.WithoutCancel(ctx), u.timeouts.rollbackBudget())
+}
+
+// checkHealth runs the health checker bounded by the health check timeout
+func (u *ServiceUpdater) checkHealth(ctx context.Context) (HealthStatus, error) {
+	return u.checkHealthWith(ctx, u.healthCheck)
+}
+
+func (u *ServiceUpdater) checkHealthWith(ctx context.Context, checker HealthChecker) (HealthStatus, error) {
+	ctx, cancel := withTimeout(ctx, u.timeouts.HealthCheck)
+	defer cancel()
+	return checker.Check(ctx)
+}
+
+// persistPlan saves a finished plan and appends it to the service's update ledger
 func (u *ServiceUpdater) persistPlan(plan *UpdatePlan, context string) {
 	if err := u.savePlan(plan); err != nil {
 		u.logger.Warn("service.update.plan_save_failed", "Failed to persist update plan", map[string]interface{}{
@@ -211,10 +348,34 @@ func (u *ServiceUpdater) persistPlan(plan *UpdatePlan, context string) {
 			"error":   err.Error(),
 		})
 	}
+	if plan.Status == planStatusPending {
+		return
+	}
+	if _, err := appendUpdateLedger(u.stateDir, plan, context); err != nil {
+		u.logger.Warn("service.update.ledger_failed", "Failed to record update history", map[string]interface{}{
+			"service": u.service.Name(),
+			"context": context,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// imageDigest returns the registry digest of image; "" when it has none or inspect fails
+func (u *ServiceUpdater) imageDigest(ctx context.Context, image string) string {
+	digest, err := u.runtime.GetImageDigest(ctx, image)
+	if err != nil {
+		u.logger.Debug("service.update.digest_unavailable", "Image digest unavailable", map[string]interface{}{
+			"service": u.service.Name(),
+			"image":   image,
+			"error":   err.Error(),
+		})
+		return ""
+	}
+	return digest
 }
 
 // Rollback rolls back to the previous image version
-func (u *ServiceUpdater) Rollback(plan *UpdatePlan) error {
+func (u *ServiceUpdater) Rollback(ctx context.Context, plan *UpdatePlan) error {
 	if plan.OldImageID == "" {
 		return fmt.Errorf("no previous image to rollback to")
 	}
@@ -224,38 +385,198 @@ func (u *ServiceUpdater) Rollback(plan *UpdatePlan) error {
 		"old_image_id": plan.OldImageID,
 	})
 
-	// Stop current service
-	if err := u.service.Stop(); err != nil {
-		u.logger.Warn("service.update.rollback.stop_error", "Error stopping service during rollback", map[string]interface{}{
+	health, err := u.swapImage(ctx, plan.OldImageID, plan.NewImage, "service.update.rollback")
+	if err != nil {
+		return fmt.Errorf("rollback failed: %w", err)
+	}
+
+	u.logger.Info("service.update.rollback.success", "Rollback completed successfully", map[string]interface{}{
+		"service": u.service.Name(),
+		"health":  health,
+	})
+
+	return nil
+}
+
+// swapImage restarts the service with imageID tagged as tag and checks its health;
+// event prefixes the warning logged when the stop fails
+func (u *ServiceUpdater) swapImage(ctx context.Context, imageID, tag, event string) (HealthStatus, error) {
+	if err := u.service.Stop(ctx); err != nil {
+		u.logger.Warn(event+".stop_error", "Error stopping service before the image swap", map[string]interface{}{
 			"service": u.service.Name(),
 			"error":   err.Error(),
 		})
 	}
 
-	if err := u.runtime.TagImage(plan.OldImageID, plan.NewImage); err != nil {
-		return fmt.Errorf("failed to retag image during rollback: %w", err)
+	if err := u.runtime.TagImage(ctx, imageID, tag); err != nil {
+		return "", fmt.Errorf("failed to retag image: %w", err)
 	}
 
-	// Start service (will use old image)
-	if err := u.service.Start(); err != nil {
-		return fmt.Errorf("failed to start service during rollback: %w", err)
+	if err := u.service.Start(ctx); err != nil {
+		return "", fmt.Errorf("failed to start service: %w", err)
 	}
 
-	// Wait for initialization
-	time.Sleep(5 * time.Second)
+	if err := sleepContext(ctx, u.timeouts.StartupWait); err != nil {
+		return "", fmt.Errorf("interrupted: %w", err)
+	}
 
-	// Verify health
-	health, err := u.healthCheck.Check()
-	if err != nil || health == HealthRed {
-		return fmt.Errorf("rollback failed health check: %w", err)
+	health, err := u.checkHealth(ctx)
+	if err != nil {
+		return health, fmt.Errorf("health check failed: %w", err)
+	}
+	if health == HealthRed {
+		return health, fmt.Errorf("health check failed: service is %s", health)
 	}
+	return health, nil
+}
 
-	u.logger.Info("service.update.rollback.success", "Rollback completed successfully", map[string]interface{}{
-		"service": u.service.Name(),
-		"health":  health,
+// History returns the service's update ledger, oldest entry first
+func (u *ServiceUpdater) History() ([]UpdateLedgerEntry, error) {
+	return LoadUpdateLedger(u.service.Name(), u.stateDir)
+}
+
+// RollbackTo restarts the service with an image recorded in the update ledger.
+// entryID selects the image an entry installed; 0 selects the image the current one
+// replaced. The swap is health-gated: an unhealthy image is replaced by the current
+// one again. The rollback is recorded in the ledger.
+func (u *ServiceUpdater) RollbackTo(ctx context.Context, entryID int) (*UpdatePlan, error) {
+	if u.trustedKeys.Len() > 0 {
+		// Trusted keys require a signed digest pin, and the pre-start hook would pull
+		// that pin over the rollback again
+		return nil, fmt.Errorf("%s cannot be rolled back while trusted keys are configured; pin and sign the earlier digest from 'aistack update history %s' in versions.lock instead", u.service.Name(), u.service.Name())
+	}
+
+	unlock, err := u.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ref, err := u.resolveImageReference()
+	if err != nil {
+		return nil, err
+	}
+	if ref.PullRef != ref.TagRef {
+		// The pre-start hook would pull the pinned image over the rollback again
+		return nil, fmt.Errorf("%s is pinned to %s by versions.lock; change the lock file instead", u.service.Name(), ref.PullRef)
+	}
+	if err := u.verifyImageTrust(); err != nil {
+		return nil, err
+	}
+
+	entries, err := u.History()
+	if err != nil {
+		return nil, err
+	}
+	currentImageID, err := u.runtime.GetImageID(ctx, ref.TagRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect current image %s: %w", ref.TagRef, err)
+	}
+	entry, targetImageID, err := rollbackTarget(entries, entryID, currentImageID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot roll back %s: %w", u.service.Name(), err)
+	}
+	if targetImageID == currentImageID {
+		return nil, fmt.Errorf("%s already runs image %s (entry %d)", u.service.Name(), ShortImageID(targetImageID), entry.ID)
+	}
+
+	plan := &UpdatePlan{
+		ServiceName: u.service.Name(),
+		Action:      planActionRollback,
+		OldImageID:  currentImageID,
+		NewImage:    ref.TagRef,
+		NewImageID:  targetImageID,
+		OldDigest:   u.imageDigest(ctx, currentImageID),
+		NewDigest:   u.imageDigest(ctx, targetImageID),
+		StartedAt:   time.Now(),
+		Status:      planStatusPending,
+		Strategy:    config.UpdateStrategyRecreate,
+	}
+	renderer := u.blueGreenRenderer()
+	if renderer != nil {
+		if blocker := u.blueGreenBlocker(ctx, renderer); blocker != "" {
+			u.logger.Info("service.rollback.blue_green_skipped", "Blue-green not possible, rolling back by recreate", map[string]interface{}{
+				"service": u.service.Name(),
+				"reason":  blocker,
+			})
+			renderer = nil
+		} else {
+			plan.Strategy = config.UpdateStrategyBlueGreen
+		}
+	}
+	if err := u.savePlan(plan); err != nil {
+		return nil, fmt.Errorf("failed to save update plan: %w", err)
+	}
+
+	u.logger.Info("service.rollback.start", fmt.Sprintf("Rolling back %s", u.service.Name()), map[string]interface{}{
+		"service":      u.service.Name(),
+		"entry":        entry.ID,
+		"from":         currentImageID,
+		"to":           targetImageID,
+		"target_image": ref.TagRef,
 	})
 
-	return nil
+	if renderer != nil {
+		// The target is health-checked next to the running container before it takes over
+		health, err := u.rollbackBlueGreen(ctx, plan, renderer)
+		if err != nil {
+			return plan, err
+		}
+		u.completeRollback(plan, health)
+		return plan, nil
+	}
+
+	health, err := u.swapImage(ctx, targetImageID, ref.TagRef, "service.rollback")
+	plan.HealthAfterSwap = string(health)
+	if err != nil {
+		u.logger.Error("service.rollback.failed", "Rollback target failed, restoring current image", map[string]interface{}{
+			"service": u.service.Name(),
+			"error":   err.Error(),
+		})
+
+		cleanupCtx, cancel := u.cleanupContext(ctx)
+		defer cancel()
+
+		plan.CompletedAt = time.Now()
+		if restoreErr := u.Rollback(cleanupCtx, plan); restoreErr != nil {
+			plan.Status = planStatusFailed
+			u.persistPlan(plan, "restore_failed")
+			return plan, fmt.Errorf("rollback of %s failed and restoring the current image also failed: %w, restore_err=%w", u.service.Name(), err, restoreErr)
+		}
+		plan.Status = planStatusRolledBack
+		u.persistPlan(plan, "restored_current")
+		return plan, fmt.Errorf("rollback of %s failed, current image restored: %w", u.service.Name(), err)
+	}
+
+	u.completeRollback(plan, health)
+	return plan, nil
+}
+
+// completeRollback records a rollback whose target image is healthy
+func (u *ServiceUpdater) completeRollback(plan *UpdatePlan, health HealthStatus) {
+	u.logger.Info("service.rollback.success", "Rollback completed successfully", map[string]interface{}{
+		"service":  u.service.Name(),
+		"image_id": plan.NewImageID,
+		"health":   health,
+	})
+
+	plan.Status = planStatusCompleted
+	plan.CompletedAt = time.Now()
+	u.persistPlan(plan, "completed")
+}
+
+// lock takes the service's update lock for a whole update or rollback, so a second
+// one and the startup reconciler leave the running operation alone
+func (u *ServiceUpdater) lock() (func(), error) {
+	stateDir := filepath.Clean(u.stateDir)
+	if err := fsutil.EnsureStateDirectory(stateDir); err != nil {
+		return nil, err
+	}
+	unlock, err := fsutil.TryLock(filepath.Join(stateDir, fmt.Sprintf("%s_update.lock", u.service.Name())))
+	if errors.Is(err, fsutil.ErrLocked) {
+		return nil, fmt.Errorf("another update or rollback of %s is running: %w", u.service.Name(), err)
+	}
+	return unlock, err
 }
 
 // savePlan saves the update plan to disk
@@ -300,22 +621,32 @@ func (u *ServiceUpdater) resolveImageReference() (ImageReference, error) {
 	return ref, nil
 }
 
-// EnforceImagePolicy ensures the configured image reference is present and tagged
-func (u *ServiceUpdater) EnforceImagePolicy() error {
+// EnforceImagePolicy ensures the configured image reference is present and tagged,
+// for a blue-green service also the front proxy image. With trusted keys, an image
+// whose versions.lock pin is not validly signed is refused.
+func (u *ServiceUpdater) EnforceImagePolicy(ctx context.Context) error {
 	ref, err := u.resolveImageReference()
 	if err != nil {
 		return err
 	}
+	if err := u.verifyImageTrust(); err != nil {
+		return err
+	}
+	if u.frontProxied() {
+		if err := u.enforceFrontProxyImage(ctx); err != nil {
+			return err
+		}
+	}
 
 	if ref.PullRef == ref.TagRef {
 		return nil
 	}
 
-	if err := u.runtime.PullImage(ref.PullRef); err != nil {
+	if err := u.runtime.PullImage(ctx, ref.PullRef); err != nil {
 		return fmt.Errorf("failed to pull image %s: %w", ref.PullRef, err)
 	}
 
-	if err := u.runtime.TagImage(ref.PullRef, ref.TagRef); err != nil {
+	if err := u.runtime.TagImage(ctx, ref.PullRef, ref.TagRef); err != nil {
 		return fmt.Errorf("failed to tag image %s as %s: %w", ref.PullRef, ref.TagRef, err)
 	}
 
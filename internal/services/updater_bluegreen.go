@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"aistack/internal/config"
+)
+
+// candidateContainerName is the container a blue-green update starts the new image in
+func candidateContainerName(service string) string {
+	return "aistack-" + service + "-next"
+}
+
+// previousContainerName is the container a blue-green cutover replaces; it is removed
+// once the new container is healthy
+func previousContainerName(service string) string {
+	return "aistack-" + service + "-previous"
+}
+
+// blueGreenRenderer returns the compose renderer when the service is updated blue-green,
+// nil for the recreate strategy. Static compose files cannot be re-rendered for a
+// candidate, and services that may not run twice on their volumes are recreated too.
+func (u *ServiceUpdater) blueGreenRenderer() *ComposeRenderer {
+	renderable, ok := u.service.(composeRenderable)
+	if !ok || renderable.ComposeRenderer() == nil {
+		return nil
+	}
+	renderer := renderable.ComposeRenderer()
+	if renderer.spec.UpdateStrategy != config.UpdateStrategyBlueGreen {
+		return nil
+	}
+	if !renderer.blueGreen() {
+		u.logger.Warn("service.update.blue_green_unsupported", "Service cannot run twice on its volumes, updating by recreate", map[string]interface{}{
+			"service":            u.service.Name(),
+			"parallel_instances": renderer.spec.ParallelInstances,
+			"gpu_lock":           renderer.spec.GPULock,
+		})
+		return nil
+	}
+	return renderer
+}
+
+// blueGreenBlocker returns why a candidate cannot take over from the running service,
+// "" when it can. A stopped service has nothing to keep serving, and one that still
+// publishes its ports itself (started before blue-green was configured) is recreated
+// once to put it behind its front proxy.
+func (u *ServiceUpdater) blueGreenBlocker(ctx context.Context, renderer *ComposeRenderer) string {
+	name := u.service.Name()
+	if running, _ := u.runtime.IsContainerRunning(ctx, "aistack-"+name); !running {
+		return "service not running"
+	}
+	if _, err := renderer.Write(); err != nil {
+		return err.Error()
+	}
+	if renderer.hasFrontFile() {
+		if running, _ := u.runtime.IsContainerRunning(ctx, frontContainerName(name)); !running {
+			return "front proxy not running"
+		}
+	}
+	return ""
+}
+
+// updateBlueGreen updates a running service through a health-checked candidate
+func (u *ServiceUpdater) updateBlueGreen(ctx context.Context, plan *UpdatePlan, renderer *ComposeRenderer) error {
+	health, err := u.swapBlueGreen(ctx, plan, renderer)
+	if err != nil {
+		return err
+	}
+
+	u.logger.Info("service.update.success", "Update completed successfully", map[string]interface{}{
+		"service":      u.service.Name(),
+		"new_image_id": plan.NewImageID,
+		"health":       health,
+		"strategy":     config.UpdateStrategyBlueGreen,
+	})
+
+	plan.Status = planStatusCompleted
+	plan.CompletedAt = time.Now()
+	u.persistPlan(plan, "completed")
+	return nil
+}
+
+// rollbackBlueGreen switches a running service to the plan's rollback target through a
+// health-checked candidate, like an update
+func (u *ServiceUpdater) rollbackBlueGreen(ctx context.Context, plan *UpdatePlan, renderer *ComposeRenderer) (HealthStatus, error) {
+	if err := u.runtime.TagImage(ctx, plan.NewImageID, plan.NewImage); err != nil {
+		plan.Status = planStatusFailed
+		plan.CompletedAt = time.Now()
+		u.persistPlan(plan, "tag_image")
+		return "", fmt.Errorf("failed to retag image: %w", err)
+	}
+	return u.swapBlueGreen(ctx, plan, renderer)
+}
+
+// swapBlueGreen starts the image tagged for the service next to the running container
+// and health-checks it before any traffic moves. At the cutover the candidate is
+// promoted as it runs: it takes over the container name, which the front proxy and
+// the other services resolve for every new connection. Once the service is healthy
+// on its real port the replaced container is stopped and removed; a failed cutover
+// gives the name back to it. On failure the plan is settled and the tag restored.
+func (u *ServiceUpdater) swapBlueGreen(ctx context.Context, plan *UpdatePlan, renderer *ComposeRenderer) (HealthStatus, error) {
+	name := u.service.Name()
+	live := "aistack-" + name
+	previous := previousContainerName(name)
+	candidate := candidateContainerName(name)
+
+	// Left behind by an interrupted update
+	_ = u.runtime.RemoveContainer(ctx, previous)
+
+	port, err := freeTCPPort(renderer.bindAddress)
+	if err != nil {
+		return "", u.discardCandidate(ctx, plan, "", fmt.Errorf("no free port for the candidate: %w", err))
+	}
+	candidateFile, err := renderer.writeCandidate(port)
+	if err != nil {
+		return "", u.discardCandidate(ctx, plan, "", err)
+	}
+
+	u.logger.Info("service.update.candidate", "Starting new image next to the running service", map[string]interface{}{
+		"service":   name,
+		"container": candidate,
+		"port":      port,
+	})
+	if err = u.runtime.ComposeUp(ctx, candidateFile); err != nil {
+		return "", u.discardCandidate(ctx, plan, candidateFile, fmt.Errorf("failed to start candidate: %w", err))
+	}
+	if err = sleepContext(ctx, u.timeouts.StartupWait); err != nil {
+		return "", u.discardCandidate(ctx, plan, candidateFile, err)
+	}
+
+	checker := retargetHealthCheck(u.healthCheck, renderer.spec.Port, port, live, candidate)
+	health, err := u.checkHealthWith(ctx, checker)
+	if err == nil && health == HealthRed {
+		err = errors.New("health is red")
+	}
+	if err != nil {
+		return "", u.discardCandidate(ctx, plan, candidateFile, fmt.Errorf("candidate failed health check: %w", err))
+	}
+
+	u.logger.Info("service.update.cutover", "Candidate healthy, handing the service over", map[string]interface{}{
+		"service": name,
+		"health":  health,
+	})
+
+	current, err := renderer.project()
+	if err != nil {
+		return "", u.discardCandidate(ctx, plan, candidateFile, err)
+	}
+	if err = u.runtime.RenameContainer(ctx, live, previous); err != nil {
+		return "", u.discardCandidate(ctx, plan, candidateFile, fmt.Errorf("failed to rename %s: %w", live, err))
+	}
+	if err = u.runtime.RenameContainer(ctx, candidate, live); err != nil {
+		return "", u.revertCutover(ctx, plan, renderer, current, candidateFile, false, fmt.Errorf("failed to promote %s: %w", candidate, err))
+	}
+	if err = renderer.setProject(renderer.alternateProject(current)); err != nil {
+		return "", u.revertCutover(ctx, plan, renderer, current, candidateFile, true, err)
+	}
+
+	health, err = u.checkHealth(ctx)
+	plan.HealthAfterSwap = string(health)
+	if err == nil && health == HealthRed {
+		err = errors.New("health is red")
+	}
+	if err != nil {
+		return "", u.revertCutover(ctx, plan, renderer, current, candidateFile, true, fmt.Errorf("health check after cutover failed: %w", err))
+	}
+
+	u.retireReplaced(ctx)
+	return health, nil
+}
+
+// retireReplaced removes the container a cutover replaced and the candidate's proxy.
+// The container is stopped first, so requests in flight get the stop timeout to finish.
+func (u *ServiceUpdater) retireReplaced(ctx context.Context) {
+	name := u.service.Name()
+	previous := previousContainerName(name)
+	if _, err := u.runtime.GetContainerStatus(ctx, previous); err == nil {
+		_ = u.runtime.StopContainer(ctx, previous)
+		if err := u.runtime.RemoveContainer(ctx, previous); err != nil {
+			u.logger.Warn("service.update.retire_failed", "Failed to remove the replaced container", map[string]interface{}{
+				"service":   name,
+				"container": previous,
+				"error":     err.Error(),
+			})
+		}
+	}
+	_ = u.runtime.RemoveContainer(ctx, candidateFrontName(name))
+	u.removeCandidateFile()
+}
+
+// discardCandidate removes the candidate and restores the image tag before the
+// cutover; the live container kept serving
+func (u *ServiceUpdater) discardCandidate(ctx context.Context, plan *UpdatePlan, candidateFile string, cause error) error {
+	name := u.service.Name()
+	u.logger.Error("service.update.candidate_failed", "New image rejected, keeping the running service", map[string]interface{}{
+		"service": name,
+		"error":   cause.Error(),
+	})
+
+	cleanupCtx, cancel := u.cleanupContext(ctx)
+	defer cancel()
+
+	var errs []error
+	if candidateFile != "" {
+		if err := u.runtime.ComposeDown(cleanupCtx, candidateFile); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove candidate: %w", err))
+		}
+		_ = os.Remove(candidateFile)
+	}
+	if plan.OldImageID != "" {
+		if err := u.runtime.TagImage(cleanupCtx, plan.OldImageID, plan.NewImage); err != nil {
+			errs = append(errs, fmt.Errorf("failed to retag image: %w", err))
+		}
+	}
+
+	plan.CompletedAt = time.Now()
+	if len(errs) > 0 {
+		plan.Status = planStatusFailed
+		u.persistPlan(plan, "candidate_cleanup_failed")
+		return fmt.Errorf("%s of %s failed: %w, cleanup_err=%w", plan.Action, name, cause, errors.Join(errs...))
+	}
+	plan.Status = planStatusRolledBack
+	u.persistPlan(plan, "candidate_discarded")
+	return fmt.Errorf("%s of %s failed before the cutover: %w, %w", plan.Action, name, cause, ErrUpdateRolledBack)
+}
+
+// revertCutover gives the container name and compose project back to the replaced
+// container, which kept running, and removes the new one (promoted: it already took
+// the name)
+func (u *ServiceUpdater) revertCutover(ctx context.Context, plan *UpdatePlan, renderer *ComposeRenderer, project, candidateFile string, promoted bool, cause error) error {
+	name := u.service.Name()
+	live := "aistack-" + name
+	u.logger.Error("service.update.health_failed", "New image failed after the cutover, restoring previous container", map[string]interface{}{
+		"service": name,
+		"error":   cause.Error(),
+	})
+
+	cleanupCtx, cancel := u.cleanupContext(ctx)
+	defer cancel()
+
+	var errs []error
+	if promoted {
+		if err := u.runtime.RenameContainer(cleanupCtx, live, candidateContainerName(name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := u.runtime.RenameContainer(cleanupCtx, previousContainerName(name), live); err != nil {
+		errs = append(errs, err)
+	}
+	// The candidate file names the new project, so this removes the new container too
+	if err := u.runtime.ComposeDown(cleanupCtx, candidateFile); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove new container: %w", err))
+	}
+	_ = os.Remove(candidateFile)
+	if err := renderer.setProject(project); err != nil {
+		errs = append(errs, err)
+	}
+	if plan.OldImageID != "" {
+		if err := u.runtime.TagImage(cleanupCtx, plan.OldImageID, plan.NewImage); err != nil {
+			errs = append(errs, fmt.Errorf("failed to retag image: %w", err))
+		}
+	}
+	if len(errs) == 0 {
+		if health, err := u.checkHealth(cleanupCtx); err != nil {
+			errs = append(errs, fmt.Errorf("rollback failed health check: %w", err))
+		} else if health == HealthRed {
+			errs = append(errs, errors.New("rollback failed health check: health is red"))
+		}
+	}
+
+	plan.CompletedAt = time.Now()
+	if len(errs) > 0 {
+		plan.Status = planStatusFailed
+		u.persistPlan(plan, "rollback_failed")
+		return fmt.Errorf("%s failed and rollback also failed: %w, rollback_err=%w", plan.Action, cause, errors.Join(errs...))
+	}
+
+	plan.Status = planStatusRolledBack
+	u.persistPlan(plan, "rollback_success")
+	u.logger.Info("service.update.rollback.success", "Rollback completed successfully", map[string]interface{}{
+		"service": name,
+	})
+	return fmt.Errorf("%s failed after the cutover, %w", plan.Action, ErrUpdateRolledBack)
+}
+
+// retargetHealthCheck points a service's health checker at the candidate: HTTP and TCP
+// checks on the service port move to port, exec checks run in container
+func retargetHealthCheck(checker HealthChecker, fromPort, port int, fromContainer, container string) HealthChecker {
+	switch c := checker.(type) {
+	case HealthCheck:
+		if parsed, err := url.Parse(c.URL); err == nil && parsed.Port() == strconv.Itoa(fromPort) {
+			parsed.Host = net.JoinHostPort(parsed.Hostname(), strconv.Itoa(port))
+			c.URL = parsed.String()
+		}
+		return c
+	case TCPHealthCheck:
+		if host, p, err := net.SplitHostPort(c.Address); err == nil && p == strconv.Itoa(fromPort) {
+			c.Address = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		return c
+	case ExecHealthCheck:
+		if c.Container == fromContainer {
+			c.Container = container
+		}
+		return c
+	case CompositeHealthCheck:
+		checks := make([]HealthChecker, len(c.Checks))
+		for i, nested := range c.Checks {
+			checks[i] = retargetHealthCheck(nested, fromPort, port, fromContainer, container)
+		}
+		c.Checks = checks
+		return c
+	}
+	return checker
+}
+
+// freeTCPPort asks the kernel for a free port on the bind address
+func freeTCPPort(bindAddress string) (int, error) {
+	host := bindAddress
+	if host == "" {
+		host = "0.0.0.0"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/config"
+	"aistack/internal/logging"
+)
+
+// sequenceHealthCheck reports the given results in order, then green
+type sequenceHealthCheck struct {
+	results []HealthStatus
+	calls   int
+}
+
+func (c *sequenceHealthCheck) Check(context.Context) (HealthStatus, error) {
+	c.calls++
+	if c.calls <= len(c.results) {
+		return c.results[c.calls-1], nil
+	}
+	return HealthGreen, nil
+}
+
+// newBlueGreenUpdater returns an updater for a running "demo" service rendered from a
+// minimal template, configured for blue-green updates
+func newBlueGreenUpdater(t *testing.T, health HealthChecker) (*ServiceUpdater, *MockRuntime, *ComposeRenderer, string) {
+	t.Helper()
+	composeDir, stateDir := t.TempDir(), t.TempDir()
+	template := `services:
+  {{ .Name }}:
+    image: {{ .Image }}
+    container_name: {{ .ContainerName }}
+    ports:
+      - "{{ .BindAddress }}:{{ .Port }}:80"
+`
+	if err := os.WriteFile(filepath.Join(composeDir, "demo.yaml"), []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logging.NewLogger(logging.LevelError)
+	runtime := &MockRuntime{
+		imageID:    "sha256:oldimage123",
+		newImageID: "sha256:newimage456",
+		containerStatuses: map[string]ServiceStatus{
+			"aistack-demo":       {State: serviceStateRunning},
+			"aistack-demo-front": {State: serviceStateRunning},
+		},
+	}
+	spec := ServiceSpec{Name: "demo", Image: "example/demo:latest", Port: 8080, HealthURL: "http://127.0.0.1:8080/health", ParallelInstances: true}
+	spec = spec.withServiceConfig("127.0.0.1", config.ServiceConfig{UpdateStrategy: config.UpdateStrategyBlueGreen})
+
+	base := &BaseService{name: "demo", runtime: runtime, logger: logger}
+	renderer := NewComposeRenderer(spec, composeDir, "127.0.0.1", nil, stateDir, logger)
+	base.SetComposeRenderer(renderer)
+
+	updater := NewServiceUpdater(base, runtime, spec.Image, health, logger, stateDir, nil)
+	updater.SetTimeouts(OperationTimeouts{HealthCheck: time.Second})
+	return updater, runtime, renderer, stateDir
+}
+
+func TestServiceUpdater_BlueGreen_Cutover(t *testing.T) {
+	updater, runtime, renderer, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// The healthy candidate is promoted as it runs: no compose up on the real port
+	candidateFile := filepath.Join(stateDir, "compose", "demo-candidate.yaml")
+	if want := []string{"up " + candidateFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+	want := []string{
+		"rename aistack-demo aistack-demo-previous",
+		"rename aistack-demo-next aistack-demo",
+		"stop aistack-demo-previous",
+	}
+	if !reflect.DeepEqual(runtime.containerOps, want) {
+		t.Errorf("container ops = %v, want %v", runtime.containerOps, want)
+	}
+	removed := runtime.RemovedContainers[len(runtime.RemovedContainers)-2:]
+	if want := []string{"aistack-demo-previous", "aistack-demo-next-front"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed containers = %v, want %v last", runtime.RemovedContainers, want)
+	}
+
+	// The service now lives in the candidate's project
+	if project, _ := renderer.project(); project != "aistack-demo-green" {
+		t.Errorf("project = %q, want aistack-demo-green", project)
+	}
+	if _, err := os.Stat(candidateFile); !os.IsNotExist(err) {
+		t.Error("Expected the candidate compose file to be removed")
+	}
+
+	plan, err := LoadUpdatePlan("demo", stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Status != planStatusCompleted || plan.Strategy != config.UpdateStrategyBlueGreen || plan.HealthAfterSwap != string(HealthGreen) {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+func TestServiceUpdater_BlueGreen_UnhealthyCandidateKeepsLiveService(t *testing.T) {
+	updater, runtime, renderer, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{results: []HealthStatus{HealthRed}})
+
+	err := updater.Update(context.Background())
+	if !errors.Is(err, ErrUpdateRolledBack) {
+		t.Fatalf("Update() error = %v, want ErrUpdateRolledBack", err)
+	}
+
+	candidateFile := filepath.Join(stateDir, "compose", "demo-candidate.yaml")
+	if want := []string{"up " + candidateFile, "down " + candidateFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+	if len(runtime.containerOps) != 0 {
+		t.Errorf("Expected the live container to be left alone, got %v", runtime.containerOps)
+	}
+	if runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("Expected old image to be retagged, got %s", runtime.imageID)
+	}
+	if project, _ := renderer.project(); project != "" {
+		t.Errorf("project = %q, want the default project", project)
+	}
+}
+
+func TestServiceUpdater_BlueGreen_FailedCutoverRestoresPrevious(t *testing.T) {
+	// Candidate green, promoted container red, restored container green
+	updater, runtime, renderer, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{results: []HealthStatus{HealthGreen, HealthRed}})
+
+	err := updater.Update(context.Background())
+	if !errors.Is(err, ErrUpdateRolledBack) {
+		t.Fatalf("Update() error = %v, want ErrUpdateRolledBack", err)
+	}
+
+	// The replaced container kept running and only gets its name back
+	want := []string{
+		"rename aistack-demo aistack-demo-previous",
+		"rename aistack-demo-next aistack-demo",
+		"rename aistack-demo aistack-demo-next",
+		"rename aistack-demo-previous aistack-demo",
+	}
+	if !reflect.DeepEqual(runtime.containerOps, want) {
+		t.Errorf("container ops = %v, want %v", runtime.containerOps, want)
+	}
+	candidateFile := filepath.Join(stateDir, "compose", "demo-candidate.yaml")
+	if last := runtime.composeCalls[len(runtime.composeCalls)-1]; last != "down "+candidateFile {
+		t.Errorf("Expected the new container to be removed with its project, got %v", runtime.composeCalls)
+	}
+	if project, _ := renderer.project(); project != "" {
+		t.Errorf("project = %q, want the previous container's project", project)
+	}
+	if runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("Expected old image to be retagged, got %s", runtime.imageID)
+	}
+
+	plan, err := LoadUpdatePlan("demo", stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Status != planStatusRolledBack || plan.HealthAfterSwap != string(HealthRed) {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+func TestServiceUpdater_BlueGreen_FallsBackToRecreate(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*MockRuntime, *ComposeRenderer)
+	}{
+		{
+			name:  "service stopped",
+			setup: func(runtime *MockRuntime, _ *ComposeRenderer) { runtime.containerStatuses = nil },
+		},
+		{
+			name: "ports published without front proxy",
+			setup: func(runtime *MockRuntime, _ *ComposeRenderer) {
+				delete(runtime.containerStatuses, "aistack-demo-front")
+			},
+		},
+		{
+			name:  "two instances not allowed on the volumes",
+			setup: func(_ *MockRuntime, renderer *ComposeRenderer) { renderer.spec.ParallelInstances = false },
+		},
+		{
+			name:  "GPU lock",
+			setup: func(_ *MockRuntime, renderer *ComposeRenderer) { renderer.spec.GPULock = true },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater, runtime, renderer, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+			tt.setup(runtime, renderer)
+
+			if err := updater.Update(context.Background()); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			for _, call := range runtime.composeCalls {
+				if strings.Contains(call, "candidate") {
+					t.Errorf("Expected no candidate, got %v", runtime.composeCalls)
+				}
+			}
+			if plan, _ := LoadUpdatePlan("demo", stateDir); plan == nil || plan.Strategy != config.UpdateStrategyRecreate {
+				t.Errorf("plan = %+v", plan)
+			}
+		})
+	}
+}
+
+func TestServiceUpdater_BlueGreen_RollbackTo(t *testing.T) {
+	updater, runtime, renderer, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+	if err := updater.Update(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	runtime.composeCalls, runtime.containerOps = nil, nil
+
+	plan, err := updater.RollbackTo(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("RollbackTo() error = %v", err)
+	}
+	if plan.Status != planStatusCompleted || plan.Strategy != config.UpdateStrategyBlueGreen || runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("image = %s, plan = %+v", runtime.imageID, plan)
+	}
+
+	// The old image is promoted from a candidate like an update, back into the default project
+	candidateFile := filepath.Join(stateDir, "compose", "demo-candidate.yaml")
+	if want := []string{"up " + candidateFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+	if len(runtime.containerOps) < 2 || runtime.containerOps[1] != "rename aistack-demo-next aistack-demo" {
+		t.Errorf("container ops = %v", runtime.containerOps)
+	}
+	if project, _ := renderer.project(); project != "" {
+		t.Errorf("project = %q, want the default project", project)
+	}
+}
+
+func TestBaseService_StartBlueGreenBehindFrontProxy(t *testing.T) {
+	updater, runtime, _, stateDir := newBlueGreenUpdater(t, &sequenceHealthCheck{})
+	runtime.containerStatuses = nil
+	service := updater.service.(*BaseService)
+
+	if err := service.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	liveFile := filepath.Join(stateDir, "compose", "demo.yaml")
+	frontFile := filepath.Join(stateDir, "compose", "demo-front.yaml")
+	if want := []string{"up " + liveFile, "up " + frontFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+	rendered, err := os.ReadFile(liveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rendered), "ports") {
+		t.Errorf("Expected the front proxy to publish the ports, got\n%s", rendered)
+	}
+
+	runtime.composeCalls = nil
+	if err := service.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if want := []string{"down " + frontFile, "down " + liveFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+
+	// Back on recreate the front proxy is removed and the service publishes its ports
+	service.renderer.spec.UpdateStrategy = config.UpdateStrategyRecreate
+	runtime.composeCalls = nil
+	if err := service.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if want := []string{"down " + frontFile, "up " + liveFile}; !reflect.DeepEqual(runtime.composeCalls, want) {
+		t.Errorf("compose calls = %v, want %v", runtime.composeCalls, want)
+	}
+	if _, err := os.Stat(frontFile); !os.IsNotExist(err) {
+		t.Error("Expected the front proxy compose file to be removed")
+	}
+}
+
+func TestRetargetHealthCheck(t *testing.T) {
+	checker := CompositeHealthCheck{RequireAll: true, Checks: []HealthChecker{
+		DefaultHealthCheck("http://localhost:3000/health"),
+		TCPHealthCheck{Address: "localhost:3000"},
+		TCPHealthCheck{Address: "localhost:11434"},
+		ExecHealthCheck{Container: "aistack-openwebui", Command: []string{"true"}},
+	}}
+
+	retargeted := retargetHealthCheck(checker, 3000, 41234, "aistack-openwebui", "aistack-openwebui-next").(CompositeHealthCheck)
+
+	if url := retargeted.Checks[0].(HealthCheck).URL; url != "http://localhost:41234/health" {
+		t.Errorf("http URL = %s", url)
+	}
+	if address := retargeted.Checks[1].(TCPHealthCheck).Address; address != "localhost:41234" {
+		t.Errorf("tcp address = %s", address)
+	}
+	if address := retargeted.Checks[2].(TCPHealthCheck).Address; address != "localhost:11434" {
+		t.Errorf("Expected a check on another service's port to stay, got %s", address)
+	}
+	if container := retargeted.Checks[3].(ExecHealthCheck).Container; container != "aistack-openwebui-next" {
+		t.Errorf("exec container = %s", container)
+	}
+	if url := checker.Checks[0].(HealthCheck).URL; url != "http://localhost:3000/health" {
+		t.Errorf("Expected the original checker to be unchanged, got %s", url)
+	}
+}
@@ -1,9 +1,12 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"aistack/internal/logging"
 )
@@ -35,7 +38,7 @@ func TestServiceUpdater_Update_NewImage(t *testing.T) {
 	updater := NewServiceUpdater(baseService, mockRuntime, "ollama/ollama:latest", healthCheck, logger, tmpDir, nil)
 
 	// Run update
-	if err = updater.Update(); err != nil {
+	if err = updater.Update(context.Background()); err != nil {
 		t.Fatalf("Update failed: %v", err)
 	}
 
@@ -93,7 +96,7 @@ func TestServiceUpdater_Update_HealthFails(t *testing.T) {
 	updater := NewServiceUpdater(baseService, mockRuntime, "ollama/ollama:latest", healthCheck, logger, tmpDir, nil)
 
 	// Run update - should fail due to health check and rollback
-	if err = updater.Update(); err == nil {
+	if err = updater.Update(context.Background()); err == nil {
 		t.Error("Expected update to fail due to health check, but it succeeded")
 	}
 
@@ -139,7 +142,7 @@ func TestServiceUpdater_Update_NoChange(t *testing.T) {
 	updater := NewServiceUpdater(baseService, mockRuntime, "ollama/ollama:latest", healthCheck, logger, tmpDir, nil)
 
 	// Run update
-	if err = updater.Update(); err != nil {
+	if err = updater.Update(context.Background()); err != nil {
 		t.Fatalf("Update failed: %v", err)
 	}
 
@@ -162,6 +165,55 @@ func TestServiceUpdater_Update_NoChange(t *testing.T) {
 	}
 }
 
+func TestServiceUpdater_Update_CancelledRollsBack(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "aistack-update-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logger := logging.NewLogger(logging.LevelInfo)
+	mockRuntime := &MockRuntime{
+		imageID:    "sha256:oldimage123",
+		newImageID: "sha256:newimage456",
+	}
+
+	baseService := &BaseService{
+		name:    "ollama",
+		runtime: mockRuntime,
+		logger:  logger,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Simulate Ctrl-C while the post-swap health check is running
+	healthCheck := &cancellingHealthCheck{cancel: cancel}
+
+	updater := NewServiceUpdater(baseService, mockRuntime, "ollama/ollama:latest", healthCheck, logger, tmpDir, nil)
+	updater.SetTimeouts(OperationTimeouts{HealthCheck: time.Second})
+
+	err = updater.Update(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if !errors.Is(err, ErrUpdateRolledBack) {
+		t.Errorf("Expected ErrUpdateRolledBack, got: %v", err)
+	}
+
+	if mockRuntime.imageID != "sha256:oldimage123" {
+		t.Errorf("Expected old image to be retagged, got %s", mockRuntime.imageID)
+	}
+
+	plan, err := LoadUpdatePlan("ollama", tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to load plan: %v", err)
+	}
+	if plan == nil || plan.Status != planStatusRolledBack {
+		t.Errorf("Expected plan status 'rolled_back', got %+v", plan)
+	}
+}
+
 func TestLoadUpdatePlan_NotExists(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "aistack-update-test")
 	if err != nil {
@@ -187,7 +239,7 @@ type UpdaterMockHealthCheck struct {
 	callCount      int
 }
 
-func (m *UpdaterMockHealthCheck) Check() (HealthStatus, error) {
+func (m *UpdaterMockHealthCheck) Check(_ context.Context) (HealthStatus, error) {
 	m.callCount++
 	if m.passAfterCalls > 0 && m.callCount > m.passAfterCalls {
 		return HealthGreen, nil
@@ -197,3 +249,22 @@ func (m *UpdaterMockHealthCheck) Check() (HealthStatus, error) {
 	}
 	return HealthRed, nil
 }
+
+// cancellingHealthCheck cancels the update context on its first call and
+// reports green afterwards (the rollback runs on a detached context)
+type cancellingHealthCheck struct {
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (c *cancellingHealthCheck) Check(ctx context.Context) (HealthStatus, error) {
+	c.calls++
+	if c.calls == 1 {
+		c.cancel()
+		return HealthRed, ctx.Err()
+	}
+	if ctx.Err() != nil {
+		return HealthRed, ctx.Err()
+	}
+	return HealthGreen, nil
+}
@@ -2,12 +2,21 @@ package services
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 
 	"aistack/internal/configdir"
+	"aistack/internal/signing"
 )
 
 // ImageReference represents a resolved container image policy
@@ -16,10 +25,88 @@ type ImageReference struct {
 	TagRef  string
 }
 
+// Formats of versions.lock
+const (
+	VersionLockFormatLines = 1 // service:image[@digest] lines
+	VersionLockFormatYAML  = 2 // version: 2 YAML with per-entry metadata
+)
+
+// digestPattern is the only digest form accepted in versions.lock
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
 // VersionLock keeps deterministic image references per service
 type VersionLock struct {
-	entries map[string]string
+	entries map[string]string    // service -> image[@digest] to pull
+	details map[string]LockEntry // service -> entry with metadata
 	path    string
+	format  int
+}
+
+// LockEntry pins the image of one service. The line format only carries image and digest.
+type LockEntry struct {
+	Image    string    `yaml:"image" json:"image"` // repository[:tag], without digest
+	Digest   string    `yaml:"digest,omitempty" json:"digest,omitempty"`
+	PinnedAt time.Time `yaml:"pinned_at,omitempty" json:"pinned_at,omitempty"`
+	Reason   string    `yaml:"reason,omitempty" json:"reason,omitempty"`
+	Platform string    `yaml:"platform,omitempty" json:"platform,omitempty"` // e.g. linux/amd64, for reference
+	// Signature is the base64 ed25519 signature of the service, image and digest
+	// (aistack versions sign)
+	Signature string `yaml:"signature,omitempty" json:"signature,omitempty"`
+}
+
+// Reference returns the image reference the entry pulls: image@digest, or image
+func (e LockEntry) Reference() string {
+	if e.Digest == "" {
+		return e.Image
+	}
+	return e.Image + "@" + e.Digest
+}
+
+// lockEntryFromReference splits an image[@digest] reference into an entry
+func lockEntryFromReference(ref string) LockEntry {
+	image, digest, _ := strings.Cut(ref, "@")
+	return LockEntry{Image: image, Digest: digest}
+}
+
+// versionLockFile is the YAML layout of a version 2 versions.lock
+type versionLockFile struct {
+	Version  int                  `yaml:"version"`
+	Services map[string]LockEntry `yaml:"services"`
+}
+
+// Path returns the file the lock was read from
+func (l *VersionLock) Path() string {
+	return l.path
+}
+
+// Format returns VersionLockFormatLines or VersionLockFormatYAML
+func (l *VersionLock) Format() int {
+	return l.format
+}
+
+// Services returns the pinned services in name order
+func (l *VersionLock) Services() []string {
+	names := make([]string, 0, len(l.entries))
+	for name := range l.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Entry returns the pin of a service
+func (l *VersionLock) Entry(service string) (LockEntry, bool) {
+	if l == nil {
+		return LockEntry{}, false
+	}
+	if entry, ok := l.details[service]; ok {
+		return entry, true
+	}
+	ref, ok := l.entries[service]
+	if !ok {
+		return LockEntry{}, false
+	}
+	return lockEntryFromReference(ref), true
 }
 
 // Resolve returns the pull and tag references for a service
@@ -45,6 +132,21 @@ func (l *VersionLock) Resolve(serviceName, defaultImage string) (ImageReference,
 	return ImageReference{PullRef: ref, TagRef: defaultImage}, nil
 }
 
+// validateServices rejects pins of services the catalog does not know, which would
+// otherwise be ignored silently (typically a typo); front-proxy pins the front proxy image
+func (l *VersionLock) validateServices(catalog *Catalog) error {
+	if l == nil || catalog == nil {
+		return nil
+	}
+	for _, service := range l.Services() {
+		if _, ok := catalog.Get(service); !ok && service != frontProxyLockName {
+			return fmt.Errorf("versions.lock pins unknown service %q (file: %s); known services: %s",
+				service, l.path, strings.Join(catalog.Names(), ", "))
+		}
+	}
+	return nil
+}
+
 // loadVersionLock loads the versions.lock file if present
 func loadVersionLock() (*VersionLock, error) {
 	path := locateVersionLock()
@@ -52,50 +154,145 @@ func loadVersionLock() (*VersionLock, error) {
 		return nil, nil
 	}
 
-	file, err := os.Open(filepath.Clean(path)) // #nosec G304 -- path is derived from controlled configuration locations
+	lock, err := ReadVersionLock(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to open versions.lock: %w", err)
+		return nil, err
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			fmt.Fprintf(os.Stderr, "warning: failed to close versions.lock: %v\n", cerr)
-		}
-	}()
+	return lock, nil
+}
 
-	scanner := bufio.NewScanner(file)
-	entries := make(map[string]string)
-	lineNo := 0
+// ReadVersionLock parses a versions.lock file in either format
+func ReadVersionLock(path string) (*VersionLock, error) {
+	data, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path is derived from controlled configuration locations
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read versions.lock: %w", err)
+	}
+	if isYAMLVersionLock(data) {
+		return parseVersionLockYAML(data, path)
+	}
+	return parseVersionLock(bytes.NewReader(data), path)
+}
 
-	for scanner.Scan() {
-		lineNo++
-		line := strings.TrimSpace(scanner.Text())
+// isYAMLVersionLock reports whether the first statement of a lock file is "version:"
+func isYAMLVersionLock(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		return strings.HasPrefix(line, "version:")
+	}
+	return false
+}
 
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid versions.lock entry on line %d (file: %s): expected 'service:image[@digest]'", lineNo, path)
-		}
+// parseVersionLockYAML reads a version 2 lock; unknown keys are rejected
+func parseVersionLockYAML(data []byte, path string) (*VersionLock, error) {
+	var file versionLockFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid versions.lock (file: %s): %w", path, err)
+	}
+	if file.Version != VersionLockFormatYAML {
+		return nil, fmt.Errorf("unsupported versions.lock version %d (file: %s): expected %d", file.Version, path, VersionLockFormatYAML)
+	}
 
-		service := strings.TrimSpace(parts[0])
-		ref := strings.TrimSpace(parts[1])
+	lock := &VersionLock{
+		entries: make(map[string]string, len(file.Services)),
+		details: make(map[string]LockEntry, len(file.Services)),
+		path:    path,
+		format:  VersionLockFormatYAML,
+	}
+	for service, entry := range file.Services {
+		entry.Image = strings.TrimSpace(entry.Image)
+		entry.Digest = strings.TrimSpace(entry.Digest)
+		if err := validateLockEntry(entry); err != nil {
+			return nil, fmt.Errorf("invalid versions.lock entry for %s (file: %s): %w", service, path, err)
+		}
+		lock.entries[service] = entry.Reference()
+		lock.details[service] = entry
+	}
+	return lock, nil
+}
 
-		if service == "" || ref == "" {
-			return nil, fmt.Errorf("invalid versions.lock entry on line %d (file: %s): empty service or reference", lineNo, path)
+// validateLockEntry checks the image and digest of an entry
+func validateLockEntry(entry LockEntry) error {
+	if entry.Image == "" {
+		return fmt.Errorf("image is empty")
+	}
+	if strings.Contains(entry.Image, "@") {
+		return fmt.Errorf("image %q contains a digest; set it in the digest field", entry.Image)
+	}
+	if entry.Digest != "" && !digestPattern.MatchString(entry.Digest) {
+		return fmt.Errorf("invalid digest %q: expected sha256: and 64 lowercase hex characters", entry.Digest)
+	}
+	if entry.Signature != "" {
+		if entry.Digest == "" {
+			return fmt.Errorf("signature without digest: only digest pins can be signed")
+		}
+		if _, err := signing.DecodeSignature(entry.Signature); err != nil {
+			return fmt.Errorf("invalid signature: %w", err)
 		}
+	}
+	return nil
+}
 
-		entries[service] = ref
+// parseVersionLock reads service:image[@digest] lines; blank lines and # comments are skipped
+func parseVersionLock(r io.Reader, path string) (*VersionLock, error) {
+	scanner := bufio.NewScanner(r)
+	lock := &VersionLock{
+		entries: make(map[string]string),
+		details: make(map[string]LockEntry),
+		path:    path,
+		format:  VersionLockFormatLines,
+	}
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		service, ref, isEntry, err := parseVersionLockLine(scanner.Text())
+		if err == nil && isEntry {
+			err = validateLockEntry(lockEntryFromReference(ref))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions.lock entry on line %d (file: %s): %w", lineNo, path, err)
+		}
+		if isEntry {
+			lock.entries[service] = ref
+			lock.details[service] = lockEntryFromReference(ref)
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("failed to read versions.lock: %w", err)
 	}
+	return lock, nil
+}
 
-	return &VersionLock{entries: entries, path: path}, nil
+// parseVersionLockLine splits an entry line; comments and blank lines are no entry
+func parseVersionLockLine(line string) (service, ref string, isEntry bool, err error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false, nil
+	}
+
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false, fmt.Errorf("expected 'service:image[@digest]'")
+	}
+
+	service = strings.TrimSpace(parts[0])
+	ref = strings.TrimSpace(parts[1])
+	if service == "" || ref == "" {
+		return "", "", false, fmt.Errorf("empty service or reference")
+	}
+	return service, ref, true, nil
 }
 
 func locateVersionLock() string {
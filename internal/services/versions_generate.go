@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"aistack/internal/configdir"
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+)
+
+// versionLockFilePermissions keeps versions.lock readable like the other files in /etc/aistack
+const versionLockFilePermissions = 0o644
+
+// versionLockHeader starts a line format versions.lock written where none existed
+const versionLockHeader = `# aistack versions.lock - generated by 'aistack versions lock'
+# Format: service:image[@digest]
+# With updates.mode: pinned, services keep running exactly these images.
+`
+
+// versionLockYAMLHeader starts a version 2 versions.lock written from scratch
+const versionLockYAMLHeader = `aistack versions.lock - generated by 'aistack versions lock'
+With updates.mode: pinned, services keep running exactly these images.`
+
+// errServiceNotRunning leaves a service's lock entry as it is
+var errServiceNotRunning = errors.New("not running")
+
+// LockProposal is a versions.lock generated from the images the running stack uses
+type LockProposal struct {
+	Path    string               `json:"path,omitempty"` // current versions.lock; empty when none exists
+	Format  int                  `json:"format"`         // format Render writes
+	Current map[string]LockEntry `json:"current"`        // entries of the current lock
+	Entries map[string]LockEntry `json:"entries"`        // proposed entries
+	Skipped map[string]string    `json:"skipped"`        // service -> why its entry was not pinned
+}
+
+// LockChange is one entry that differs between two locks; an empty side means the
+// entry is missing there
+type LockChange struct {
+	Service  string `json:"service"`
+	Current  string `json:"current,omitempty"`
+	Proposed string `json:"proposed,omitempty"`
+}
+
+// ProposeVersionLock pins every running service to the repo digest of the image its
+// container runs. Services that are stopped or run an image without a registry digest
+// keep their current entry. New pins are stamped with the time and reason.
+func (m *Manager) ProposeVersionLock(ctx context.Context, reason string) (*LockProposal, error) {
+	proposal := &LockProposal{
+		Format:  VersionLockFormatYAML,
+		Current: make(map[string]LockEntry),
+		Entries: make(map[string]LockEntry),
+		Skipped: make(map[string]string),
+	}
+	if m.imageLock != nil {
+		proposal.Path = m.imageLock.path
+		if m.imageLock.format != 0 {
+			proposal.Format = m.imageLock.format
+		}
+		for _, service := range m.imageLock.Services() {
+			entry, _ := m.imageLock.Entry(service)
+			proposal.Current[service] = entry
+			proposal.Entries[service] = entry
+		}
+	}
+
+	names := make([]string, 0, len(m.services))
+	for _, name := range m.sortedServiceNames() {
+		if _, ok := m.services[name].(updatable); ok {
+			names = append(names, name)
+		}
+	}
+
+	results := runProbes(ctx, names, maxProbeWorkers, func(ctx context.Context, name string) (LockEntry, error) {
+		return m.services[name].(updatable).Updater().pinnedEntry(ctx)
+	})
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("version lock interrupted: %w", ctx.Err())
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, result := range results {
+		name := names[i]
+		if result.err != nil {
+			proposal.Skipped[name] = result.err.Error()
+			continue
+		}
+		current, locked := proposal.Current[name]
+		if locked && current.Reference() == result.value.Reference() {
+			continue
+		}
+		entry := result.value
+		entry.PinnedAt = now
+		entry.Reason = reason
+		entry.Platform = current.Platform
+		proposal.Entries[name] = entry
+	}
+
+	// The front proxies of blue-green services run one image, pinned like a service
+	if entry, err := m.frontProxyPin(ctx); err == nil {
+		if current, locked := proposal.Current[frontProxyLockName]; !locked || current.Reference() != entry.Reference() {
+			entry.PinnedAt, entry.Reason, entry.Platform = now, reason, current.Platform
+			proposal.Entries[frontProxyLockName] = entry
+		}
+	} else if !errors.Is(err, errServiceNotRunning) {
+		proposal.Skipped[frontProxyLockName] = err.Error()
+	}
+	return proposal, nil
+}
+
+// frontProxyPin pins the front proxy image to the digest a running front proxy uses
+// (errServiceNotRunning when no blue-green service runs behind one)
+func (m *Manager) frontProxyPin(ctx context.Context) (LockEntry, error) {
+	for _, name := range m.sortedServiceNames() {
+		service, ok := m.services[name].(updatable)
+		if !ok || !service.Updater().frontProxied() {
+			continue
+		}
+		front := frontContainerName(name)
+		if running, err := m.runtime.IsContainerRunning(ctx, front); err != nil || !running {
+			continue
+		}
+		imageID, err := m.runtime.GetContainerImageID(ctx, front)
+		if err != nil {
+			return LockEntry{}, fmt.Errorf("failed to inspect container %s: %w", front, err)
+		}
+		digest, err := m.runtime.GetImageDigest(ctx, imageID)
+		if err != nil {
+			return LockEntry{}, fmt.Errorf("failed to inspect image %s: %w", ShortImageID(imageID), err)
+		}
+		if digest == "" {
+			return LockEntry{}, fmt.Errorf("image %s has no registry digest", ShortImageID(imageID))
+		}
+		ref, err := m.imageLock.Resolve(frontProxyLockName, frontProxyImage)
+		if err != nil {
+			return LockEntry{}, err
+		}
+		return LockEntry{Image: imageRepository(ref.PullRef), Digest: digest}, nil
+	}
+	return LockEntry{}, errServiceNotRunning
+}
+
+// pinnedEntry pins the repository to the digest of the image the service's container
+// runs. The repository of the current lock entry wins over the catalog image, so a lock
+// pointing at a private registry keeps pointing there.
+func (u *ServiceUpdater) pinnedEntry(ctx context.Context) (LockEntry, error) {
+	live := "aistack-" + u.service.Name()
+	if running, err := u.runtime.IsContainerRunning(ctx, live); err != nil || !running {
+		return LockEntry{}, errServiceNotRunning
+	}
+	imageID, err := u.runtime.GetContainerImageID(ctx, live)
+	if err != nil {
+		return LockEntry{}, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	digest, err := u.runtime.GetImageDigest(ctx, imageID)
+	if err != nil {
+		return LockEntry{}, fmt.Errorf("failed to inspect image %s: %w", ShortImageID(imageID), err)
+	}
+	if digest == "" {
+		// Locally built or loaded images were never pulled from a registry
+		return LockEntry{}, fmt.Errorf("image %s has no registry digest", ShortImageID(imageID))
+	}
+
+	ref, err := u.resolveImageReference()
+	if err != nil {
+		return LockEntry{}, err
+	}
+	return LockEntry{Image: imageRepository(ref.PullRef), Digest: digest}, nil
+}
+
+// imageRepository strips the tag and digest from an image reference
+func imageRepository(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	// A colon after the last slash separates the tag (one before it is a registry port)
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name
+}
+
+// Changes lists the entries the proposal would add, change or remove, by service
+func (p *LockProposal) Changes() []LockChange {
+	return DiffVersionLock(p.Current, p.Entries)
+}
+
+// DiffVersionLock compares the references of two sets of lock entries
+func DiffVersionLock(current, proposed map[string]LockEntry) []LockChange {
+	var changes []LockChange
+	for service, entry := range proposed {
+		if old, ok := current[service]; !ok || old.Reference() != entry.Reference() {
+			changes = append(changes, LockChange{Service: service, Current: current[service].Reference(), Proposed: entry.Reference()})
+		}
+	}
+	for service, entry := range current {
+		if _, ok := proposed[service]; !ok {
+			changes = append(changes, LockChange{Service: service, Current: entry.Reference()})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Service < changes[j].Service })
+	return changes
+}
+
+// DefaultVersionLockPath is where a versions.lock is written when none exists yet
+func DefaultVersionLockPath() string {
+	return filepath.Join(configdir.ConfigDir(), "versions.lock")
+}
+
+// Render returns the proposed lock file in p.Format: the current file with its entries
+// replaced in place, so comments and layout survive, and new entries appended. A lock
+// converted to the other format is written from scratch.
+func (p *LockProposal) Render() ([]byte, error) {
+	var existing []byte
+	if p.Path != "" {
+		data, err := os.ReadFile(filepath.Clean(p.Path)) // #nosec G304 -- path is the located versions.lock
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read versions.lock: %w", err)
+		}
+		existing = data
+	}
+	if strings.TrimSpace(string(existing)) != "" && isYAMLVersionLock(existing) != (p.Format == VersionLockFormatYAML) {
+		existing = nil
+	}
+
+	if p.Format == VersionLockFormatLines {
+		refs := make(map[string]string, len(p.Entries))
+		for service, entry := range p.Entries {
+			refs[service] = entry.Reference()
+		}
+		return renderVersionLock(existing, refs)
+	}
+	return renderVersionLockYAML(existing, p.Entries)
+}
+
+// renderVersionLock rewrites the entry lines of existing for entries. Entries missing
+// from existing are appended in name order; lines of services not in entries are dropped.
+func renderVersionLock(existing []byte, entries map[string]string) ([]byte, error) {
+	text := string(existing)
+	if strings.TrimSpace(text) == "" {
+		text = versionLockHeader
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	out := make([]string, 0, len(lines)+len(entries))
+	written := make(map[string]bool, len(entries))
+	for i, line := range lines {
+		service, _, isEntry, err := parseVersionLockLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions.lock entry on line %d: %w", i+1, err)
+		}
+		if !isEntry {
+			out = append(out, line)
+			continue
+		}
+		ref, keep := entries[service]
+		if !keep || written[service] {
+			continue
+		}
+		out = append(out, service+":"+ref)
+		written[service] = true
+	}
+
+	var added []string
+	for service := range entries {
+		if !written[service] {
+			added = append(added, service)
+		}
+	}
+	sort.Strings(added)
+	for _, service := range added {
+		out = append(out, service+":"+entries[service])
+	}
+
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// renderVersionLockYAML rewrites the services of a version 2 lock for entries. Unchanged
+// entries keep their nodes and comments; new entries are appended in name order.
+func renderVersionLockYAML(existing []byte, entries map[string]LockEntry) ([]byte, error) {
+	var doc yaml.Node
+	if strings.TrimSpace(string(existing)) != "" {
+		if err := yaml.Unmarshal(existing, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse versions.lock: %w", err)
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, HeadComment: versionLockYAMLHeader, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("versions.lock is not a YAML mapping")
+	}
+
+	setMappingValue(root, "version", &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.Itoa(VersionLockFormatYAML)})
+	services := mappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		services = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(root, "services", services)
+	}
+	services.Style = 0
+
+	content := make([]*yaml.Node, 0, 2*len(entries))
+	written := make(map[string]bool, len(entries))
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		key, value := services.Content[i], services.Content[i+1]
+		entry, keep := entries[key.Value]
+		if !keep || written[key.Value] {
+			continue
+		}
+		var current LockEntry
+		if err := value.Decode(&current); err != nil || !sameLockEntry(current, entry) {
+			if value, err = lockEntryNode(entry); err != nil {
+				return nil, err
+			}
+		}
+		content = append(content, key, value)
+		written[key.Value] = true
+	}
+
+	var added []string
+	for service := range entries {
+		if !written[service] {
+			added = append(added, service)
+		}
+	}
+	sort.Strings(added)
+	for _, service := range added {
+		value, err := lockEntryNode(entries[service])
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Value: service}, value)
+	}
+	services.Content = content
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to encode versions.lock: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode versions.lock: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func lockEntryNode(entry LockEntry) (*yaml.Node, error) {
+	var node yaml.Node
+	if err := node.Encode(entry); err != nil {
+		return nil, fmt.Errorf("failed to encode versions.lock entry: %w", err)
+	}
+	return &node, nil
+}
+
+func sameLockEntry(a, b LockEntry) bool {
+	return a.Image == b.Image && a.Digest == b.Digest && a.PinnedAt.Equal(b.PinnedAt) &&
+		a.Reason == b.Reason && a.Platform == b.Platform && a.Signature == b.Signature
+}
+
+// mappingValue returns the value node of key in a mapping node
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of key, or appends key
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// Write saves the rendered proposal to path atomically
+func (p *LockProposal) Write(path string, logger *logging.Logger) error {
+	data, err := p.Render()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create versions.lock directory: %w", err)
+	}
+	if err := fsutil.AtomicWriteFile(path, data, versionLockFilePermissions, logger); err != nil {
+		return fmt.Errorf("failed to write versions.lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"aistack/internal/logging"
+)
+
+// testDigest returns a valid sha256 digest made of one hex character
+func testDigest(c string) string {
+	return "sha256:" + strings.Repeat(c, 64)
+}
+
+func newLockTestManager(t *testing.T, runtime *MockRuntime, lock *VersionLock) *Manager {
+	t.Helper()
+	t.Setenv("AISTACK_STATE_DIR", t.TempDir())
+	logger := logging.NewLogger(logging.LevelError)
+	manager := &Manager{runtime: runtime, logger: logger, services: make(map[string]Service), imageLock: lock}
+	for _, spec := range []ServiceSpec{
+		{Name: "demo", Image: "example/demo:latest"},
+		{Name: "web", Image: "registry.lan:5000/team/web:main"},
+		{Name: "local", Image: "example/local:dev"},
+		{Name: "idle", Image: "example/idle:latest"},
+	} {
+		manager.services[spec.Name] = NewCatalogService(spec, t.TempDir(), runtime, logger, lock, nil)
+	}
+	return manager
+}
+
+func TestManager_ProposeVersionLock(t *testing.T) {
+	runtime := NewMockRuntime()
+	for _, name := range []string{"demo", "web", "local"} {
+		runtime.containerStatuses["aistack-"+name] = ServiceStatus{State: serviceStateRunning}
+	}
+	runtime.containerImages = map[string]string{
+		"aistack-demo":  "sha256:demoimage",
+		"aistack-web":   "sha256:webimage",
+		"aistack-local": "sha256:localimage",
+	}
+	runtime.imageDigests = map[string]string{
+		"sha256:demoimage": "sha256:demodigest",
+		"sha256:webimage":  "sha256:webdigest",
+	}
+	lock := &VersionLock{path: "/etc/aistack/versions.lock", format: VersionLockFormatLines, entries: map[string]string{
+		"web":   "mirror.lan/team/web:main",
+		"idle":  "example/idle@sha256:idledigest",
+		"local": "example/local@sha256:olddigest",
+	}}
+
+	proposal, err := newLockTestManager(t, runtime, lock).ProposeVersionLock(context.Background(), "known good")
+	if err != nil {
+		t.Fatalf("ProposeVersionLock() error = %v", err)
+	}
+
+	want := map[string]string{
+		"demo":  "example/demo@sha256:demodigest",
+		"web":   "mirror.lan/team/web@sha256:webdigest", // the locked repository is kept
+		"idle":  "example/idle@sha256:idledigest",       // stopped: entry kept
+		"local": "example/local@sha256:olddigest",       // no registry digest: entry kept
+	}
+	got := make(map[string]string, len(proposal.Entries))
+	for service, entry := range proposal.Entries {
+		got[service] = entry.Reference()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Entries = %v, want %v", got, want)
+	}
+	if proposal.Path != lock.path || proposal.Format != VersionLockFormatLines || proposal.Current["web"].Reference() != "mirror.lan/team/web:main" {
+		t.Errorf("proposal = %+v, want the current lock recorded", proposal)
+	}
+	// New pins are stamped, kept entries stay as they were
+	if demo := proposal.Entries["demo"]; demo.Reason != "known good" || demo.PinnedAt.IsZero() {
+		t.Errorf("demo entry = %+v, want it stamped", demo)
+	}
+	if idle := proposal.Entries["idle"]; idle.Reason != "" || !idle.PinnedAt.IsZero() {
+		t.Errorf("idle entry = %+v, want it unchanged", idle)
+	}
+	if !strings.Contains(proposal.Skipped["local"], "no registry digest") || proposal.Skipped["idle"] != "not running" {
+		t.Errorf("Skipped = %v", proposal.Skipped)
+	}
+
+	changes := proposal.Changes()
+	wantChanges := []LockChange{
+		{Service: "demo", Proposed: "example/demo@sha256:demodigest"},
+		{Service: "web", Current: "mirror.lan/team/web:main", Proposed: "mirror.lan/team/web@sha256:webdigest"},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("Changes() = %+v, want %+v", changes, wantChanges)
+	}
+}
+
+func TestDiffVersionLock_Removed(t *testing.T) {
+	changes := DiffVersionLock(map[string]LockEntry{"demo": {Image: "example/demo:1"}}, map[string]LockEntry{})
+	if len(changes) != 1 || changes[0].Current != "example/demo:1" || changes[0].Proposed != "" {
+		t.Errorf("DiffVersionLock() = %+v, want the entry removed", changes)
+	}
+}
+
+func TestLockProposal_WritePreservesComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.lock")
+	existing := `# Pinned for the spring release
+
+# Ollama
+ollama:ollama/ollama:0.1.0
+#ollama:ollama/ollama@sha256:commented
+
+openwebui:ghcr.io/open-webui/open-webui:main
+`
+	if err := os.WriteFile(path, []byte(existing), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	proposal := &LockProposal{Path: path, Format: VersionLockFormatLines, Entries: map[string]LockEntry{
+		"ollama":    {Image: "ollama/ollama", Digest: testDigest("a")},
+		"openwebui": {Image: "ghcr.io/open-webui/open-webui:main"},
+		"localai":   {Image: "localai/localai", Digest: testDigest("b")},
+	}}
+	if err := proposal.Write(path, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Pinned for the spring release
+
+# Ollama
+ollama:ollama/ollama@` + testDigest("a") + `
+#ollama:ollama/ollama@sha256:commented
+
+openwebui:ghcr.io/open-webui/open-webui:main
+localai:localai/localai@` + testDigest("b") + `
+`
+	if string(data) != want {
+		t.Errorf("written lock =\n%s\nwant\n%s", data, want)
+	}
+
+	// The written file is a valid lock with exactly the proposed entries
+	lock, err := ReadVersionLock(path)
+	if err != nil || !reflect.DeepEqual(lock.details, proposal.Entries) {
+		t.Errorf("ReadVersionLock() = %+v, %v, want %v", lock, err, proposal.Entries)
+	}
+}
+
+func TestLockProposal_RenderWithoutLock(t *testing.T) {
+	data, err := (&LockProposal{Format: VersionLockFormatLines, Entries: map[string]LockEntry{"demo": {Image: "example/demo", Digest: testDigest("a")}}}).Render()
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# aistack versions.lock") || !strings.HasSuffix(string(data), "\ndemo:example/demo@"+testDigest("a")+"\n") {
+		t.Errorf("Render() =\n%s", data)
+	}
+}
+
+func TestLockProposal_WriteYAMLKeepsComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.lock")
+	existing := `# Pinned for the spring release
+version: 2
+services:
+  # Keep ollama on the tested build
+  ollama:
+    image: ollama/ollama
+    digest: ` + testDigest("a") + `
+    pinned_at: 2026-03-01T10:00:00Z
+    reason: tested with llama3
+  openwebui:
+    image: ghcr.io/open-webui/open-webui
+    digest: ` + testDigest("b") + `
+    platform: linux/amd64
+`
+	if err := os.WriteFile(path, []byte(existing), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := ReadVersionLock(path)
+	if err != nil {
+		t.Fatalf("ReadVersionLock() error = %v", err)
+	}
+
+	pinnedAt := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	entries := map[string]LockEntry{
+		"ollama":    lock.details["ollama"],
+		"openwebui": {Image: "ghcr.io/open-webui/open-webui", Digest: testDigest("c"), PinnedAt: pinnedAt, Reason: "security fix", Platform: "linux/amd64"},
+		"localai":   {Image: "localai/localai", Digest: testDigest("d"), PinnedAt: pinnedAt},
+	}
+	proposal := &LockProposal{Path: path, Format: VersionLockFormatYAML, Current: lock.details, Entries: entries}
+	if err := proposal.Write(path, nil); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Pinned for the spring release", "# Keep ollama on the tested build", "reason: security fix", "localai:"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("written lock misses %q:\n%s", want, data)
+		}
+	}
+
+	written, err := ReadVersionLock(path)
+	if err != nil {
+		t.Fatalf("ReadVersionLock() of the written lock error = %v\n%s", err, data)
+	}
+	if written.Format() != VersionLockFormatYAML || len(written.details) != 3 {
+		t.Fatalf("written lock = %+v", written)
+	}
+	for service, entry := range entries {
+		if !sameLockEntry(written.details[service], entry) {
+			t.Errorf("%s = %+v, want %+v", service, written.details[service], entry)
+		}
+	}
+}
+
+func TestLockProposal_RenderConvertsFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.lock")
+	if err := os.WriteFile(path, []byte("# old\nollama:ollama/ollama@"+testDigest("a")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	proposal := &LockProposal{Path: path, Format: VersionLockFormatYAML, Entries: map[string]LockEntry{
+		"ollama": {Image: "ollama/ollama", Digest: testDigest("a")},
+	}}
+	data, err := proposal.Render()
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !isYAMLVersionLock(data) || !strings.Contains(string(data), "digest: "+testDigest("a")) {
+		t.Errorf("Render() =\n%s", data)
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"aistack/internal/configdir"
+	"aistack/internal/fsutil"
+	"aistack/internal/logging"
+	"aistack/internal/signing"
+)
+
+// TrustedKeysDir holds the public keys versions.lock signatures are verified against,
+// inside the config directory
+const TrustedKeysDir = "trusted-keys"
+
+// ErrUntrustedImage reports an image that is not pinned to a digest signed by a trusted key
+var ErrUntrustedImage = errors.New("image is not approved by a trusted signature")
+
+// TrustedKeysPath returns the trusted keys directory
+func TrustedKeysPath() string {
+	return filepath.Join(configdir.ConfigDir(), TrustedKeysDir)
+}
+
+// lockEntryPayload is what a versions.lock signature covers: the service and the image
+// it pins, so a signature cannot be moved to another service or digest
+func lockEntryPayload(service string, entry LockEntry) []byte {
+	return []byte("aistack-versions-lock-v1\n" + service + "\n" + entry.Image + "\n" + entry.Digest + "\n")
+}
+
+// SignVersionLock signs the digest pins of a format 2 lock in place (all pins when
+// services is empty) and returns the signed services. Pins without a digest are
+// skipped unless named; their tag can point at another image tomorrow.
+func SignVersionLock(path string, key ed25519.PrivateKey, services []string, logger *logging.Logger) ([]string, error) {
+	lock, err := ReadVersionLock(path)
+	if err != nil {
+		return nil, err
+	}
+	if lock.Format() != VersionLockFormatYAML {
+		return nil, fmt.Errorf("signatures need versions.lock format 2 (%s); convert it with: aistack versions lock --format v2", path)
+	}
+
+	named := len(services) > 0
+	if !named {
+		services = lock.Services()
+	}
+	entries := make(map[string]LockEntry, len(lock.details))
+	for service, entry := range lock.details {
+		entries[service] = entry
+	}
+
+	var signed []string
+	for _, service := range services {
+		entry, ok := entries[service]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%s is not pinned in %s", service, path)
+		case entry.Digest == "" && named:
+			return nil, fmt.Errorf("%s is pinned to a tag; only digest pins can be signed", service)
+		case entry.Digest == "":
+			continue
+		}
+		entry.Signature = signing.Sign(key, lockEntryPayload(service, entry))
+		entries[service] = entry
+		signed = append(signed, service)
+	}
+
+	existing, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path is the located versions.lock
+	if err != nil {
+		return nil, fmt.Errorf("failed to read versions.lock: %w", err)
+	}
+	data, err := renderVersionLockYAML(existing, entries)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.AtomicWriteFile(path, data, versionLockFilePermissions, logger); err != nil {
+		return nil, fmt.Errorf("failed to write versions.lock: %w", err)
+	}
+	return signed, nil
+}
+
+// SetTrustedKeys makes the updater refuse images whose versions.lock pin is not signed
+// by one of keys; an empty key ring accepts every image
+func (u *ServiceUpdater) SetTrustedKeys(keys *signing.KeyRing) {
+	u.trustedKeys = keys
+}
+
+// SetPolicyError makes the updater refuse to start or update the service with err,
+// the reason versions.lock cannot be enforced; nil lifts the refusal
+func (u *ServiceUpdater) SetPolicyError(err error) {
+	u.policyErr = err
+}
+
+// verifyImageTrust checks that versions.lock pins the service to a digest with a valid
+// signature, and the front proxy image of a blue-green service as well. It runs before
+// anything is pulled or tagged and needs no network.
+func (u *ServiceUpdater) verifyImageTrust() error {
+	if u.policyErr != nil {
+		return fmt.Errorf("refusing to run %s: %w", u.service.Name(), u.policyErr)
+	}
+	if u.trustedKeys.Len() == 0 {
+		return nil
+	}
+	if err := u.verifyPinTrust(u.service.Name()); err != nil {
+		return err
+	}
+	if u.frontProxied() {
+		return u.verifyPinTrust(frontProxyLockName)
+	}
+	return nil
+}
+
+// verifyPinTrust checks the versions.lock entry name against the trusted keys
+func (u *ServiceUpdater) verifyPinTrust(name string) error {
+	entry, pinned := u.imageLock.Entry(name)
+	switch {
+	case !pinned:
+		return fmt.Errorf("%s is not pinned in versions.lock; with trusted keys in %s only signed digest pins run: %w", name, TrustedKeysPath(), ErrUntrustedImage)
+	case entry.Digest == "":
+		return fmt.Errorf("%s is pinned to the tag %s; only signed digest pins run: %w", name, entry.Image, ErrUntrustedImage)
+	case entry.Signature == "":
+		return fmt.Errorf("versions.lock pin of %s is not signed (aistack versions sign): %w", name, ErrUntrustedImage)
+	}
+
+	keyName, err := u.trustedKeys.Verify(lockEntryPayload(name, entry), entry.Signature)
+	if err != nil {
+		u.logger.Error("service.image.untrusted", "Image signature does not verify", map[string]interface{}{
+			"service": name,
+			"image":   entry.Reference(),
+			"error":   err.Error(),
+		})
+		return fmt.Errorf("signature of %s in versions.lock does not verify: %v: %w", entry.Reference(), err, ErrUntrustedImage)
+	}
+	u.logger.Debug("service.image.trusted", "Image signature verified", map[string]interface{}{
+		"service": name,
+		"image":   entry.Reference(),
+		"key":     keyName,
+	})
+	return nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aistack/internal/gpulock"
+	"aistack/internal/signing"
+)
+
+func newTestSigningKey(t *testing.T) (ed25519.PrivateKey, *signing.KeyRing) {
+	t.Helper()
+	key, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, signing.NewKeyRing(signing.TrustedKey{Name: "release", Key: key.Public().(ed25519.PublicKey)})
+}
+
+func TestSignVersionLock(t *testing.T) {
+	key, ring := newTestSigningKey(t)
+	path := filepath.Join(t.TempDir(), "versions.lock")
+	content := `version: 2
+services:
+  # Approved for production
+  ollama:
+    image: ollama/ollama
+    digest: ` + testDigest("a") + `
+  openwebui:
+    image: ghcr.io/open-webui/open-webui
+    digest: ` + testDigest("b") + `
+  localai:
+    image: quay.io/go-skynet/local-ai:v2.8.0
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := SignVersionLock(path, key, nil, nil)
+	if err != nil {
+		t.Fatalf("SignVersionLock() error = %v", err)
+	}
+	if strings.Join(signed, ",") != "ollama,openwebui" {
+		t.Errorf("signed = %v, want the digest pins", signed)
+	}
+
+	lock, err := ReadVersionLock(path)
+	if err != nil {
+		t.Fatalf("ReadVersionLock() error = %v", err)
+	}
+	for _, service := range signed {
+		entry, _ := lock.Entry(service)
+		if _, err := ring.Verify(lockEntryPayload(service, entry), entry.Signature); err != nil {
+			t.Errorf("signature of %s does not verify: %v", service, err)
+		}
+	}
+	if entry, _ := lock.Entry("localai"); entry.Signature != "" {
+		t.Errorf("tag pin was signed: %+v", entry)
+	}
+	// A signature covers its service: copying it to another service does not verify
+	ollama, _ := lock.Entry("ollama")
+	if _, err := ring.Verify(lockEntryPayload("openwebui", ollama), ollama.Signature); err == nil {
+		t.Error("signature verified for another service")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# Approved for production") {
+		t.Errorf("comment lost:\n%s", data)
+	}
+
+	if _, err := SignVersionLock(path, key, []string{"localai"}, nil); err == nil {
+		t.Error("SignVersionLock() of a named tag pin should fail")
+	}
+	if _, err := SignVersionLock(path, key, []string{"searxng"}, nil); err == nil {
+		t.Error("SignVersionLock() of an unpinned service should fail")
+	}
+
+	linesPath := filepath.Join(t.TempDir(), "versions.lock")
+	if err := os.WriteFile(linesPath, []byte("ollama:ollama/ollama@"+testDigest("a")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignVersionLock(linesPath, key, nil, nil); err == nil || !strings.Contains(err.Error(), "format 2") {
+		t.Errorf("SignVersionLock() of a line format lock error = %v", err)
+	}
+}
+
+func TestReadVersionLock_InvalidSignature(t *testing.T) {
+	tests := map[string]string{
+		"not base64": "version: 2\nservices:\n  ollama:\n    image: ollama/ollama\n    digest: " + testDigest("a") + "\n    signature: '***'\n",
+		"no digest":  "version: 2\nservices:\n  ollama:\n    image: ollama/ollama:latest\n    signature: " + strings.Repeat("A", 88) + "\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "versions.lock")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ReadVersionLock(path); err == nil {
+				t.Error("ReadVersionLock() should reject the signature")
+			}
+		})
+	}
+}
+
+func TestEnforceImagePolicy_TrustedKeys(t *testing.T) {
+	key, ring := newTestSigningKey(t)
+	entry := LockEntry{Image: "example/demo", Digest: testDigest("a")}
+	entry.Signature = signing.Sign(key, lockEntryPayload("demo", entry))
+	lock := &VersionLock{
+		entries: map[string]string{"demo": entry.Reference()},
+		details: map[string]LockEntry{"demo": entry},
+	}
+
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, lock)
+	updater.SetTrustedKeys(ring)
+	if err := updater.EnforceImagePolicy(context.Background()); err != nil {
+		t.Fatalf("EnforceImagePolicy() with a valid signature error = %v", err)
+	}
+	if runtime.imageID != entry.Reference() {
+		t.Errorf("image = %s, want the pinned image tagged", runtime.imageID)
+	}
+
+	// A pin changed after signing is refused before anything is pulled or tagged
+	tampered := entry
+	tampered.Digest = testDigest("b")
+	lock.entries["demo"], lock.details["demo"] = tampered.Reference(), tampered
+	runtime.imageID = "sha256:oldimage123"
+	if err := updater.EnforceImagePolicy(context.Background()); !errors.Is(err, ErrUntrustedImage) {
+		t.Errorf("EnforceImagePolicy() with a tampered pin error = %v, want ErrUntrustedImage", err)
+	}
+	if err := updater.Update(context.Background()); !errors.Is(err, ErrUntrustedImage) {
+		t.Errorf("Update() with a tampered pin error = %v, want ErrUntrustedImage", err)
+	}
+	if runtime.imageID != "sha256:oldimage123" {
+		t.Errorf("image = %s, want nothing pulled or tagged", runtime.imageID)
+	}
+	if plan, err := LoadUpdatePlan("demo", updater.stateDir); err != nil || plan != nil {
+		t.Errorf("LoadUpdatePlan() = %+v, %v, want no update started", plan, err)
+	}
+
+	// Without trusted keys the signature is not required
+	updater.SetTrustedKeys(nil)
+	if err := updater.EnforceImagePolicy(context.Background()); err != nil {
+		t.Errorf("EnforceImagePolicy() without trusted keys error = %v", err)
+	}
+}
+
+func TestVerifyImageTrust_Unsigned(t *testing.T) {
+	_, ring := newTestSigningKey(t)
+	tests := map[string]*VersionLock{
+		"unpinned": nil,
+		"tag pin":  {entries: map[string]string{"demo": "example/demo:1.0"}},
+		"unsigned": {entries: map[string]string{"demo": "example/demo@" + testDigest("a")}},
+	}
+	for name, lock := range tests {
+		t.Run(name, func(t *testing.T) {
+			updater, _ := newLedgerTestUpdater(t, &sequenceHealthCheck{}, lock)
+			updater.SetTrustedKeys(ring)
+			if err := updater.EnforceImagePolicy(context.Background()); !errors.Is(err, ErrUntrustedImage) {
+				t.Errorf("EnforceImagePolicy() error = %v, want ErrUntrustedImage", err)
+			}
+		})
+	}
+}
+
+func TestManager_InvalidTrustedKeyRefusesStartAndUpdate(t *testing.T) {
+	keysDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(keysDir, "broken.pub"), []byte("not a key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, keysErr := signing.LoadTrustedKeys(keysDir)
+	if keysErr == nil {
+		t.Fatal("Expected the broken key to be reported")
+	}
+
+	lock := &VersionLock{entries: map[string]string{"demo": "example/demo@" + testDigest("a")}}
+	manager := newLockTestManager(t, NewMockRuntime(), lock)
+	manager.composeDir, manager.trustKeys, manager.policyErr = t.TempDir(), keys, keysErr
+	manager.gpuLock = gpulock.NewManager(t.TempDir(), manager.logger)
+	manager.services["demo"] = manager.newService(ServiceSpec{Name: "demo", Image: "example/demo:latest"})
+
+	// The versions commands keep working
+	if _, err := manager.VerifyVersionLock(context.Background()); err != nil {
+		t.Errorf("VerifyVersionLock() error = %v", err)
+	}
+
+	// Start and update fail closed instead of running without signature checks
+	updater := manager.services["demo"].(updatable).Updater()
+	if err := updater.EnforceImagePolicy(context.Background()); !errors.Is(err, keysErr) {
+		t.Errorf("EnforceImagePolicy() error = %v, want the invalid key", err)
+	}
+	if err := updater.Update(context.Background()); !errors.Is(err, keysErr) {
+		t.Errorf("Update() error = %v, want the invalid key", err)
+	}
+}
+
+func TestRollbackTo_RefusedWithTrustedKeys(t *testing.T) {
+	key, ring := newTestSigningKey(t)
+	entry := LockEntry{Image: "example/demo", Digest: testDigest("a")}
+	entry.Signature = signing.Sign(key, lockEntryPayload("demo", entry))
+	lock := &VersionLock{
+		entries: map[string]string{"demo": entry.Reference()},
+		details: map[string]LockEntry{"demo": entry},
+	}
+
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, lock)
+	updater.SetTrustedKeys(ring)
+	runtime.imageID = "sha256:newimage456"
+	_, err := updater.RollbackTo(context.Background(), 0)
+	if err == nil || !strings.Contains(err.Error(), "trusted keys are configured") {
+		t.Fatalf("RollbackTo() error = %v, want a refusal naming the trusted keys", err)
+	}
+	if runtime.imageID != "sha256:newimage456" {
+		t.Errorf("image = %s, want nothing re-tagged", runtime.imageID)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -132,11 +133,11 @@ func TestLoadVersionLock_ValidFile(t *testing.T) {
 
 	content := fmt.Sprintf(`# Version lock file
 ollama:ollama/ollama:v0.1.0
-openwebui:ghcr.io/open-webui/open-webui@sha256:abc123
+openwebui:ghcr.io/open-webui/open-webui@%s
 localai:%s
 
 # Comment line
-`, LocalAIImageName)
+`, testDigest("a"), LocalAIImageName)
 	if err := os.WriteFile(lockPath, []byte(content), 0o640); err != nil {
 		t.Fatalf("Failed to create test file: %v", err)
 	}
@@ -161,8 +162,8 @@ localai:%s
 	if lock.entries["ollama"] != "ollama/ollama:v0.1.0" {
 		t.Errorf("ollama entry = %s, want ollama/ollama:v0.1.0", lock.entries["ollama"])
 	}
-	if lock.entries["openwebui"] != "ghcr.io/open-webui/open-webui@sha256:abc123" {
-		t.Errorf("openwebui entry = %s, want ghcr.io/open-webui/open-webui@sha256:abc123", lock.entries["openwebui"])
+	if want := "ghcr.io/open-webui/open-webui@" + testDigest("a"); lock.entries["openwebui"] != want {
+		t.Errorf("openwebui entry = %s, want %s", lock.entries["openwebui"], want)
 	}
 	if lock.entries["localai"] != LocalAIImageName {
 		t.Errorf("localai entry = %s, want %s", lock.entries["localai"], LocalAIImageName)
@@ -195,6 +196,16 @@ func TestLoadVersionLock_InvalidFormat(t *testing.T) {
 			content: "ollama:   \n",
 			wantErr: "invalid versions.lock entry on line 1",
 		},
+		{
+			name:    "short digest",
+			content: "ollama:ollama/ollama@sha256:abc123\n",
+			wantErr: "invalid digest",
+		},
+		{
+			name:    "unsupported digest algorithm",
+			content: "ollama:ollama/ollama@md5:" + strings.Repeat("a", 64) + "\n",
+			wantErr: "invalid digest",
+		},
 	}
 
 	for _, tt := range tests {
@@ -310,3 +321,81 @@ func findSubstring(str, substr string) bool {
 	}
 	return false
 }
+
+func TestReadVersionLock_YAML(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "versions.lock")
+	content := `# Pinned for production
+version: 2
+services:
+  ollama:
+    image: ollama/ollama
+    digest: ` + testDigest("a") + `
+    pinned_at: 2026-10-01T08:00:00Z
+    reason: tested with llama3
+    platform: linux/amd64
+  localai:
+    image: quay.io/go-skynet/local-ai:v2.8.0
+`
+	if err := os.WriteFile(lockPath, []byte(content), 0o640); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	lock, err := ReadVersionLock(lockPath)
+	if err != nil {
+		t.Fatalf("ReadVersionLock() error = %v", err)
+	}
+	if lock.Format() != VersionLockFormatYAML || len(lock.Services()) != 2 {
+		t.Fatalf("lock = %+v, want 2 entries in format 2", lock)
+	}
+
+	ref, err := lock.Resolve("ollama", OllamaImageName)
+	if err != nil || ref.PullRef != "ollama/ollama@"+testDigest("a") || ref.TagRef != OllamaImageName {
+		t.Errorf("Resolve(ollama) = %+v, %v", ref, err)
+	}
+	entry, ok := lock.Entry("ollama")
+	if !ok || entry.Reason != "tested with llama3" || entry.Platform != "linux/amd64" || entry.PinnedAt.IsZero() {
+		t.Errorf("Entry(ollama) = %+v, %v", entry, ok)
+	}
+	if ref, _ := lock.Resolve("localai", LocalAIImageName); ref.PullRef != "quay.io/go-skynet/local-ai:v2.8.0" {
+		t.Errorf("Resolve(localai) PullRef = %s", ref.PullRef)
+	}
+}
+
+func TestReadVersionLock_YAMLInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unsupported version", "version: 3\nservices: {}\n", "unsupported versions.lock version 3"},
+		{"unknown field", "version: 2\nservices:\n  ollama:\n    image: ollama/ollama\n    tag: latest\n", "field tag not found"},
+		{"missing image", "version: 2\nservices:\n  ollama:\n    digest: " + testDigest("a") + "\n", "image is empty"},
+		{"digest in image", "version: 2\nservices:\n  ollama:\n    image: ollama/ollama@" + testDigest("a") + "\n", "contains a digest"},
+		{"uppercase digest", "version: 2\nservices:\n  ollama:\n    image: ollama/ollama\n    digest: sha256:" + strings.Repeat("A", 64) + "\n", "invalid digest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockPath := filepath.Join(t.TempDir(), "versions.lock")
+			if err := os.WriteFile(lockPath, []byte(tt.content), 0o640); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			if _, err := ReadVersionLock(lockPath); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadVersionLock() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVersionLock_ValidateServices(t *testing.T) {
+	lock := &VersionLock{path: "/etc/aistack/versions.lock", entries: map[string]string{"ollama": OllamaImageName}}
+	if err := lock.validateServices(DefaultCatalog()); err != nil {
+		t.Errorf("validateServices() error = %v", err)
+	}
+
+	lock.entries["olama"] = OllamaImageName
+	err := lock.validateServices(DefaultCatalog())
+	if err == nil || !strings.Contains(err.Error(), `unknown service "olama"`) {
+		t.Errorf("validateServices() error = %v, want the typo reported", err)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Outcomes of verifying a service against versions.lock
+const (
+	LockInSync     = "in-sync"     // the container runs the pinned image
+	LockDrift      = "drift"       // the container runs another image
+	LockNotRunning = "not-running" // pinned, but no container runs
+	LockUnpinned   = "unpinned"    // running without a versions.lock entry
+	LockUnknown    = "unknown"     // the images could not be compared (see Error)
+)
+
+// ErrNoVersionLock reports that no versions.lock was found
+var ErrNoVersionLock = errors.New("no versions.lock found")
+
+// LockVerification compares the image a service's container runs with its pin
+type LockVerification struct {
+	Service        string `json:"service"`
+	Status         string `json:"status"`
+	Locked         string `json:"locked,omitempty"`           // reference versions.lock pins
+	RunningImageID string `json:"running_image_id,omitempty"` // image the container runs
+	RunningDigest  string `json:"running_digest,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// VerifyLock checks that the service's container runs the image versions.lock pins.
+// A digest pin matches the repo digest of the running image; a tag pin matches when
+// the pinned reference names the running image locally.
+func (u *ServiceUpdater) VerifyLock(ctx context.Context) LockVerification {
+	name := u.service.Name()
+	result := LockVerification{Service: name, Status: LockUnpinned}
+	entry, pinned := u.imageLock.Entry(name)
+	if pinned {
+		result.Locked = entry.Reference()
+	}
+
+	live := "aistack-" + name
+	if running, _ := u.runtime.IsContainerRunning(ctx, live); !running {
+		if pinned {
+			result.Status = LockNotRunning
+		}
+		return result
+	}
+	imageID, err := u.runtime.GetContainerImageID(ctx, live)
+	if err != nil {
+		result.Status, result.Error = LockUnknown, fmt.Sprintf("failed to inspect container: %v", err)
+		return result
+	}
+	result.RunningImageID = imageID
+	if digest, err := u.runtime.GetImageDigest(ctx, imageID); err == nil {
+		result.RunningDigest = digest
+	}
+	if !pinned {
+		return result
+	}
+
+	if entry.Digest != "" && entry.Digest == result.RunningDigest {
+		result.Status = LockInSync
+		return result
+	}
+	// The running image may have been pulled under another repository
+	if pinnedID, err := u.runtime.GetImageID(ctx, entry.Reference()); err == nil && pinnedID == imageID {
+		result.Status = LockInSync
+		return result
+	}
+	result.Status = LockDrift
+	return result
+}
+
+// Drift describes a LockDrift result for status output
+func (v LockVerification) Drift() string {
+	if v.Status != LockDrift {
+		return ""
+	}
+	running := ShortImageID(v.RunningImageID)
+	if v.RunningDigest != "" {
+		running = v.RunningDigest
+	}
+	return fmt.Sprintf("runs %s, versions.lock pins %s", running, v.Locked)
+}
+
+// VerifyVersionLock checks every running or pinned service against versions.lock
+func (m *Manager) VerifyVersionLock(ctx context.Context) ([]LockVerification, error) {
+	if m.imageLock == nil {
+		return nil, ErrNoVersionLock
+	}
+
+	names := make([]string, 0, len(m.services))
+	for _, name := range m.sortedServiceNames() {
+		if _, ok := m.services[name].(updatable); ok {
+			names = append(names, name)
+		}
+	}
+	results := runProbes(ctx, names, maxProbeWorkers, func(ctx context.Context, name string) (LockVerification, error) {
+		return m.services[name].(updatable).Updater().VerifyLock(ctx), nil
+	})
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("version lock verification interrupted: %w", ctx.Err())
+	}
+
+	verifications := make([]LockVerification, 0, len(results))
+	for _, result := range results {
+		if result.value.Status == LockUnpinned && result.value.RunningImageID == "" {
+			continue // neither pinned nor running
+		}
+		verifications = append(verifications, result.value)
+	}
+	return verifications, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"aistack/internal/gpulock"
+	"aistack/internal/logging"
+)
+
+func TestManager_VerifyVersionLock(t *testing.T) {
+	runtime := NewMockRuntime()
+	runtime.imageID = "sha256:unrelated"
+	for _, name := range []string{"demo", "web", "local"} {
+		runtime.containerStatuses["aistack-"+name] = ServiceStatus{State: serviceStateRunning}
+	}
+	runtime.containerImages = map[string]string{
+		"aistack-demo":  "sha256:demoimage",
+		"aistack-web":   "sha256:webimage",
+		"aistack-local": "sha256:localimage",
+	}
+	runtime.imageDigests = map[string]string{
+		"sha256:demoimage": testDigest("a"),
+		"sha256:webimage":  testDigest("c"),
+	}
+	lock := &VersionLock{entries: map[string]string{
+		"demo": "example/demo@" + testDigest("a"),
+		"web":  "registry.lan:5000/team/web@" + testDigest("b"),
+		"idle": "example/idle@" + testDigest("d"),
+	}}
+	manager := newLockTestManager(t, runtime, lock)
+
+	verifications, err := manager.VerifyVersionLock(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyVersionLock() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, verification := range verifications {
+		got[verification.Service] = verification.Status
+	}
+	want := map[string]string{"demo": LockInSync, "web": LockDrift, "idle": LockNotRunning, "local": LockUnpinned}
+	for service, status := range want {
+		if got[service] != status {
+			t.Errorf("%s status = %q, want %q (all: %v)", service, got[service], status, got)
+		}
+	}
+
+	// status reports the drift of running services
+	statuses, err := manager.StatusAll(context.Background())
+	if err != nil {
+		t.Fatalf("StatusAll() error = %v", err)
+	}
+	for _, status := range statuses {
+		switch status.Name {
+		case "web":
+			if !strings.Contains(status.LockDrift, testDigest("c")) || !strings.Contains(status.LockDrift, testDigest("b")) {
+				t.Errorf("web LockDrift = %q, want running and pinned digests", status.LockDrift)
+			}
+		default:
+			if status.LockDrift != "" {
+				t.Errorf("%s LockDrift = %q, want none", status.Name, status.LockDrift)
+			}
+		}
+	}
+}
+
+func TestVerifyLock_TagPin(t *testing.T) {
+	lock := &VersionLock{entries: map[string]string{"demo": "example/demo:1.2"}}
+	updater, runtime := newLedgerTestUpdater(t, &sequenceHealthCheck{}, lock)
+	runtime.containerStatuses = map[string]ServiceStatus{"aistack-demo": {State: serviceStateRunning}}
+
+	// The pinned tag names the image the container runs
+	if verification := updater.VerifyLock(context.Background()); verification.Status != LockInSync {
+		t.Errorf("VerifyLock() = %+v, want in sync", verification)
+	}
+
+	runtime.containerImages = map[string]string{"aistack-demo": "sha256:newimage456"}
+	verification := updater.VerifyLock(context.Background())
+	if verification.Status != LockDrift || verification.Drift() == "" {
+		t.Errorf("VerifyLock() = %+v, want drift", verification)
+	}
+}
+
+func TestManager_VerifyVersionLockWithoutLock(t *testing.T) {
+	manager := newLockTestManager(t, NewMockRuntime(), nil)
+	if _, err := manager.VerifyVersionLock(context.Background()); !errors.Is(err, ErrNoVersionLock) {
+		t.Errorf("VerifyVersionLock() error = %v, want ErrNoVersionLock", err)
+	}
+}
+
+func TestManager_InvalidLockOnlyRefusesStartAndUpdate(t *testing.T) {
+	lock := &VersionLock{path: "/etc/aistack/versions.lock", entries: map[string]string{
+		"demo":  "example/demo@" + testDigest("a"),
+		"olama": OllamaImageName,
+	}}
+	policyErr := lock.validateServices(DefaultCatalog())
+	if policyErr == nil {
+		t.Fatal("Expected the unknown service to be reported")
+	}
+
+	runtime := NewMockRuntime()
+	logger := logging.NewLogger(logging.LevelError)
+	manager := &Manager{
+		runtime:    runtime,
+		logger:     logger,
+		composeDir: t.TempDir(),
+		services:   make(map[string]Service),
+		imageLock:  lock,
+		policyErr:  policyErr,
+		gpuLock:    gpulock.NewManager(t.TempDir(), logger),
+	}
+	manager.services["demo"] = manager.newService(ServiceSpec{Name: "demo", Image: "example/demo:latest"})
+
+	// versions verify still reports against the lock
+	if _, err := manager.VerifyVersionLock(context.Background()); err != nil {
+		t.Errorf("VerifyVersionLock() error = %v", err)
+	}
+
+	updater := manager.services["demo"].(updatable).Updater()
+	if err := updater.EnforceImagePolicy(context.Background()); !errors.Is(err, policyErr) {
+		t.Errorf("EnforceImagePolicy() error = %v, want the invalid lock", err)
+	}
+	if err := updater.Update(context.Background()); !errors.Is(err, policyErr) {
+		t.Errorf("Update() error = %v, want the invalid lock", err)
+	}
+}
@@ -0,0 +1,176 @@
+// Package signing signs and verifies payloads with ed25519 keys. Keys are PEM files:
+// PKCS #8 private keys and PKIX public keys, as `openssl genpkey -algorithm ed25519`
+// and `openssl pkey -pubout` write them.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNoValidSignature reports a signature no trusted key verifies
+var ErrNoValidSignature = errors.New("no trusted key verifies the signature")
+
+// TrustedKey is a public key and the file it was loaded from
+type TrustedKey struct {
+	Name string // file name without extension
+	Key  ed25519.PublicKey
+}
+
+// KeyRing holds the public keys signatures are verified against
+type KeyRing struct {
+	keys []TrustedKey
+}
+
+// NewKeyRing creates a key ring from public keys
+func NewKeyRing(keys ...TrustedKey) *KeyRing {
+	return &KeyRing{keys: keys}
+}
+
+// LoadTrustedKeys reads every *.pub and *.pem public key in dir. A missing dir is an
+// empty key ring; a file that is no ed25519 public key is an error.
+func LoadTrustedKeys(dir string) (*KeyRing, error) {
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &KeyRing{}, nil
+		}
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+
+	ring := &KeyRing{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".pub" && ext != ".pem") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path) // #nosec G304 -- path is inside the trusted keys directory
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trusted key: %w", err)
+		}
+		key, err := ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted key %s: %w", path, err)
+		}
+		ring.keys = append(ring.keys, TrustedKey{Name: strings.TrimSuffix(entry.Name(), ext), Key: key})
+	}
+	sort.Slice(ring.keys, func(i, j int) bool { return ring.keys[i].Name < ring.keys[j].Name })
+	return ring, nil
+}
+
+// Len returns the number of trusted keys; a nil ring has none
+func (r *KeyRing) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.keys)
+}
+
+// Verify checks a base64 signature of payload and returns the name of the key that
+// made it
+func (r *KeyRing) Verify(payload []byte, signature string) (string, error) {
+	sig, err := DecodeSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	if r != nil {
+		for _, key := range r.keys {
+			if ed25519.Verify(key.Key, payload, sig) {
+				return key.Name, nil
+			}
+		}
+	}
+	return "", ErrNoValidSignature
+}
+
+// Sign returns the base64 signature of payload
+func Sign(key ed25519.PrivateKey, payload []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+}
+
+// DecodeSignature decodes a base64 ed25519 signature
+func DecodeSignature(signature string) ([]byte, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return nil, fmt.Errorf("signature is not base64: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("signature has %d bytes, expected %d", len(sig), ed25519.SignatureSize)
+	}
+	return sig, nil
+}
+
+// GenerateKey creates a new private key
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// EncodePrivateKey returns key as PKCS #8 PEM
+func EncodePrivateKey(key ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// EncodePublicKey returns key as PKIX PEM
+func EncodePublicKey(key ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// LoadPrivateKey reads a PKCS #8 PEM ed25519 private key
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(filepath.Clean(path)) // #nosec G304 -- path is given by the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s is not a PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 private key", path)
+	}
+	return key, nil
+}
+
+// ParsePublicKey parses a PKIX PEM ed25519 public key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("not a PEM public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("not an ed25519 public key")
+	}
+	return key, nil
+}
@@ -0,0 +1,96 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKeyPair(t *testing.T, dir, name string) string {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	private, err := EncodePrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := EncodePublicKey(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath := filepath.Join(t.TempDir(), name+".key")
+	if err := os.WriteFile(privatePath, private, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".pub"), public, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath
+}
+
+func TestSignAndVerify(t *testing.T) {
+	trusted := t.TempDir()
+	releasePath := writeKeyPair(t, trusted, "release")
+	otherPath := writeKeyPair(t, t.TempDir(), "other")
+
+	ring, err := LoadTrustedKeys(trusted)
+	if err != nil || ring.Len() != 1 {
+		t.Fatalf("LoadTrustedKeys() = %v, %v", ring, err)
+	}
+
+	release, err := LoadPrivateKey(releasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte("ollama\nollama/ollama\nsha256:abc\n")
+	signature := Sign(release, payload)
+
+	if name, err := ring.Verify(payload, signature); err != nil || name != "release" {
+		t.Errorf("Verify() = %q, %v, want release", name, err)
+	}
+	if _, err := ring.Verify([]byte("ollama\nollama/ollama\nsha256:def\n"), signature); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify() of another payload error = %v, want ErrNoValidSignature", err)
+	}
+
+	other, err := LoadPrivateKey(otherPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.Verify(payload, Sign(other, payload)); !errors.Is(err, ErrNoValidSignature) {
+		t.Errorf("Verify() of an untrusted key's signature error = %v, want ErrNoValidSignature", err)
+	}
+	if _, err := ring.Verify(payload, "bm90IGEgc2lnbmF0dXJl"); err == nil {
+		t.Error("Verify() of a short signature should fail")
+	}
+}
+
+func TestLoadTrustedKeys(t *testing.T) {
+	ring, err := LoadTrustedKeys(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || ring.Len() != 0 {
+		t.Errorf("LoadTrustedKeys() of a missing dir = %v, %v, want an empty ring", ring, err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if ring, err := LoadTrustedKeys(dir); err != nil || ring.Len() != 0 {
+		t.Errorf("LoadTrustedKeys() = %v, %v, want other files ignored", ring, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.pub"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTrustedKeys(dir); err == nil {
+		t.Error("LoadTrustedKeys() with a broken key should fail")
+	}
+
+	var nilRing *KeyRing
+	if nilRing.Len() != 0 {
+		t.Error("nil ring should be empty")
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"aistack/internal/logging"
 )
 
+// notifyFlushTimeout bounds how long a suspend waits for queued notifications
+const notifyFlushTimeout = 10 * time.Second
+
 // Executor handles suspend logic and execution
 type Executor struct {
 	detector *Detector
@@ -93,6 +96,9 @@ func (e *Executor) CheckAndSuspend() error {
 		return nil
 	}
 
+	// Let queued notifications (suspend.executing) go out before the network sleeps
+	logging.Flush(notifyFlushTimeout)
+
 	// Execute systemctl suspend
 	cmd := exec.Command("systemctl", "suspend")
 	if err := cmd.Run(); err != nil {